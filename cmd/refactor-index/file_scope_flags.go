@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+// FileScopeSettings holds the flags that bring generated and vendored files
+// back into list commands, reports and doc-hits ingestion. Ingestion
+// classifies the files it adds; ingest files and ingest range reclassify
+// them against a tree, which then applies to every commit.
+type FileScopeSettings struct {
+	IncludeGenerated bool `glazed:"include-generated"`
+	IncludeVendored  bool `glazed:"include-vendored"`
+}
+
+func fileScopeFlags() []*fields.Definition {
+	return []*fields.Definition{
+		fields.New(
+			"include-generated",
+			fields.TypeBool,
+			fields.WithHelp("Include files marked \"Code generated ... DO NOT EDIT.\""),
+			fields.WithDefault(false),
+		),
+		fields.New(
+			"include-vendored",
+			fields.TypeBool,
+			fields.WithHelp("Include files under vendor, node_modules and third_party"),
+			fields.WithDefault(false),
+		),
+	}
+}
+
+func (s *FileScopeSettings) scope() refactorindex.FileScope {
+	return refactorindex.FileScope{
+		IncludeGenerated: s.IncludeGenerated,
+		IncludeVendored:  s.IncludeVendored,
+	}
+}
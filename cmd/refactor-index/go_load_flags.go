@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+// GoLoadSettings holds the package loading flags shared by the go/packages
+// based commands. It is decoded from the default section next to each
+// command's own settings.
+type GoLoadSettings struct {
+	Patterns []string `glazed:"pattern"`
+	Tags     []string `glazed:"tags"`
+	Tests    bool     `glazed:"tests"`
+	GOOS     string   `glazed:"goos"`
+	GOARCH   string   `glazed:"goarch"`
+	Env      []string `glazed:"env"`
+}
+
+func goLoadFlags() []*fields.Definition {
+	return []*fields.Definition{
+		fields.New(
+			"pattern",
+			fields.TypeStringList,
+			fields.WithHelp("Package patterns to load (default ./...)"),
+			fields.WithDefault([]string{}),
+		),
+		fields.New(
+			"tags",
+			fields.TypeStringList,
+			fields.WithHelp("Build tags used when loading packages"),
+			fields.WithDefault([]string{}),
+		),
+		fields.New(
+			"tests",
+			fields.TypeBool,
+			fields.WithHelp("Include _test.go files and external test packages"),
+			fields.WithDefault(false),
+		),
+		fields.New(
+			"goos",
+			fields.TypeString,
+			fields.WithHelp("GOOS to load packages for (default: host)"),
+			fields.WithDefault(""),
+		),
+		fields.New(
+			"goarch",
+			fields.TypeString,
+			fields.WithHelp("GOARCH to load packages for (default: host)"),
+			fields.WithDefault(""),
+		),
+		fields.New(
+			"env",
+			fields.TypeStringList,
+			fields.WithHelp("Extra KEY=VALUE environment for go list, e.g. CGO_ENABLED=0"),
+			fields.WithDefault([]string{}),
+		),
+	}
+}
+
+func (s *GoLoadSettings) options() refactorindex.GoLoadOptions {
+	return refactorindex.GoLoadOptions{
+		Patterns: s.Patterns,
+		Tags:     s.Tags,
+		Tests:    s.Tests,
+		GOOS:     s.GOOS,
+		GOARCH:   s.GOARCH,
+		Env:      s.Env,
+	}
+}
+
+func matrixFlag() *fields.Definition {
+	return fields.New(
+		"matrix",
+		fields.TypeStringList,
+		fields.WithHelp("Build configurations to load, as goos/goarch[:tag+tag], e.g. linux/amd64,windows/amd64"),
+		fields.WithDefault([]string{}),
+	)
+}
+
+func parseMatrix(specs []string) ([]refactorindex.BuildConfig, error) {
+	var matrix []refactorindex.BuildConfig
+	for _, spec := range specs {
+		config, err := refactorindex.ParseBuildConfig(spec)
+		if err != nil {
+			return nil, err
+		}
+		matrix = append(matrix, config)
+	}
+	return matrix, nil
+}
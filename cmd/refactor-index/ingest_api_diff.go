@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestAPIDiffCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestAPIDiffSettings struct {
+	DBPath     string `glazed:"db"`
+	SourcesDir string `glazed:"sources-dir"`
+	Module     string `glazed:"module"`
+	OldDir     string `glazed:"old-dir"`
+	NewDir     string `glazed:"new-dir"`
+	OldVersion string `glazed:"old-version"`
+	NewVersion string `glazed:"new-version"`
+}
+
+var _ cmds.GlazeCommand = &IngestAPIDiffCommand{}
+
+func NewIngestAPIDiffCommand() (*IngestAPIDiffCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"api-diff",
+		cmds.WithShort("Compare two versions of a dependency's exported API"),
+		cmds.WithLong("Load two versions of a dependency from directories (module cache entries or vendored copies) or from the module cache by version, and record the exported symbols that were removed or whose signature changed. Parameter names are ignored. Dependencies are resolved through each version's go.mod; symbols whose signature uses a type that cannot be resolved are recorded as unknown. Join the run against external uses with list upgrade-impact."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"module",
+				fields.TypeString,
+				fields.WithHelp("Module path to compare under (defaults to each directory's go.mod; required for vendored copies and module cache lookups)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"old-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory of the old version (optional with --module and --old-version)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"new-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory of the new version (optional with --module and --new-version)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"old-version",
+				fields.TypeString,
+				fields.WithHelp("Old version, looked up in the module cache when --old-dir is empty"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"new-version",
+				fields.TypeString,
+				fields.WithHelp("New version, looked up in the module cache when --new-dir is empty"),
+				fields.WithDefault(""),
+			),
+		),
+	)
+
+	return &IngestAPIDiffCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestAPIDiffCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestAPIDiffSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestAPIDiff(ctx, refactorindex.IngestAPIDiffConfig{
+		DBPath:     settings.DBPath,
+		SourcesDir: settings.SourcesDir,
+		Module:     settings.Module,
+		OldDir:     settings.OldDir,
+		NewDir:     settings.NewDir,
+		OldVersion: settings.OldVersion,
+		NewVersion: settings.NewVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("old_packages", result.OldPackages),
+		types.MRP("new_packages", result.NewPackages),
+		types.MRP("removed", result.Removed),
+		types.MRP("changed", result.Changed),
+		types.MRP("unknown", result.Unknown),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest api diff row")
+	}
+
+	return nil
+}
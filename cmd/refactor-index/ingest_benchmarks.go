@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestBenchmarksCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestBenchmarksSettings struct {
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	Input      string   `glazed:"input"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Packages   []string `glazed:"package"`
+	Bench      string   `glazed:"bench"`
+	Count      int      `glazed:"count"`
+	Benchtime  string   `glazed:"benchtime"`
+	Tags       []string `glazed:"tags"`
+	Timeout    string   `glazed:"timeout"`
+}
+
+var _ cmds.GlazeCommand = &IngestBenchmarksCommand{}
+
+func NewIngestBenchmarksCommand() (*IngestBenchmarksCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"benchmarks",
+		cmds.WithShort("Ingest go test -bench results"),
+		cmds.WithLong("Run go test -bench -benchmem in a module root, or read saved benchmark output, and store one row per benchmark sample and metric. Results are linked to the root's HEAD commit when it has been ingested."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Module root to run benchmarks in (required without --input)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"input",
+				fields.TypeString,
+				fields.WithHelp("Saved benchmark output to ingest instead of running go test"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"package",
+				fields.TypeStringList,
+				fields.WithHelp("Package patterns to benchmark (default ./...)"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"bench",
+				fields.TypeString,
+				fields.WithHelp("Benchmark regular expression for go test -bench"),
+				fields.WithDefault("."),
+			),
+			fields.New(
+				"count",
+				fields.TypeInteger,
+				fields.WithHelp("Number of samples per benchmark (go test -count)"),
+				fields.WithDefault(1),
+			),
+			fields.New(
+				"benchtime",
+				fields.TypeString,
+				fields.WithHelp("go test -benchtime value, e.g. 100x or 1s (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"tags",
+				fields.TypeStringList,
+				fields.WithHelp("Build tags passed to go test"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"timeout",
+				fields.TypeString,
+				fields.WithHelp("go test -timeout value, e.g. 5m (default: go's own)"),
+				fields.WithDefault(""),
+			),
+		),
+	)
+
+	return &IngestBenchmarksCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestBenchmarksCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestBenchmarksSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	timeout, err := parseTestTimeout(settings.Timeout)
+	if err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestBenchmarks(ctx, refactorindex.IngestBenchmarksConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		OutputPath: settings.Input,
+		Packages:   settings.Packages,
+		Pattern:    settings.Bench,
+		Count:      settings.Count,
+		Benchtime:  settings.Benchtime,
+		Tags:       settings.Tags,
+		Timeout:    timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("commit_id", result.CommitID),
+		types.MRP("exit_code", result.ExitCode),
+		types.MRP("benchmarks", result.Benchmarks),
+		types.MRP("samples", result.Samples),
+		types.MRP("metrics", result.Metrics),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest benchmarks row")
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestBlameCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestBlameSettings struct {
+	DBPath     string   `glazed:"db"`
+	RepoPath   string   `glazed:"repo"`
+	Commit     string   `glazed:"commit"`
+	Paths      []string `glazed:"path"`
+	SourcesDir string   `glazed:"sources-dir"`
+}
+
+var _ cmds.GlazeCommand = &IngestBlameCommand{}
+
+func NewIngestBlameCommand() (*IngestBlameCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"blame",
+		cmds.WithShort("Ingest git blame for files at a commit"),
+		cmds.WithLong("Record per-line author, commit and original line from git blame --porcelain. Combine with code-unit runs ('list unit-authors') or diff runs ('list removed-authors')."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"repo",
+				fields.TypeString,
+				fields.WithHelp("Path to the git repository"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"commit",
+				fields.TypeString,
+				fields.WithHelp("Commit to blame at"),
+				fields.WithDefault("HEAD"),
+			),
+			fields.New(
+				"path",
+				fields.TypeStringList,
+				fields.WithHelp("Pathspecs to blame (default: all text files)"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+		),
+	)
+
+	return &IngestBlameCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestBlameCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestBlameSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestBlame(ctx, refactorindex.IngestBlameConfig{
+		DBPath:     settings.DBPath,
+		RepoPath:   settings.RepoPath,
+		Commit:     settings.Commit,
+		Paths:      settings.Paths,
+		SourcesDir: settings.SourcesDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("commit", result.Commit),
+		types.MRP("files", result.Files),
+		types.MRP("lines", result.Lines),
+		types.MRP("commits", result.Commits),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest blame row")
+	}
+
+	return nil
+}
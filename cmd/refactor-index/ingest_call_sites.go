@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestCallSitesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestCallSitesSettings struct {
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Targets    []string `glazed:"target"`
+	Tolerant   bool     `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestCallSitesCommand{}
+
+func NewIngestCallSitesCommand() (*IngestCallSitesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"call-sites",
+		cmds.WithShort("Ingest calls of selected functions with their arguments"),
+		cmds.WithLong("Record every call of the given fully qualified functions and methods with its span, enclosing code unit, and the source text, type and constant value (when known) of each argument."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory to scan for Go packages"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"target",
+				fields.TypeStringList,
+				fields.WithHelp("Fully qualified function or method, e.g. github.com/pkg/errors.Wrap or (*example.com/lib.Client).Do (repeatable)"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+		cmds.WithFlags(goLoadFlags()...),
+	)
+
+	return &IngestCallSitesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestCallSitesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestCallSitesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestCallSites(ctx, refactorindex.IngestCallSitesConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Load:       loadSettings.options(),
+		Targets:    settings.Targets,
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("packages", result.Packages),
+		types.MRP("call_sites", result.CallSites),
+		types.MRP("args", result.Args),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest call sites row")
+	}
+
+	return nil
+}
@@ -19,9 +19,11 @@ type IngestCodeUnitsCommand struct {
 }
 
 type IngestCodeUnitsSettings struct {
-	DBPath     string `glazed:"db"`
-	RootDir    string `glazed:"root"`
-	SourcesDir string `glazed:"sources-dir"`
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Tolerant   bool     `glazed:"tolerant"`
+	Matrix     []string `glazed:"matrix"`
 }
 
 var _ cmds.GlazeCommand = &IngestCodeUnitsCommand{}
@@ -50,7 +52,15 @@ func NewIngestCodeUnitsCommand() (*IngestCodeUnitsCommand, error) {
 				fields.WithHelp("Directory to write raw tool outputs"),
 				fields.WithDefault("sources"),
 			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+			matrixFlag(),
 		),
+		cmds.WithFlags(goLoadFlags()...),
 	)
 
 	return &IngestCodeUnitsCommand{CommandDescription: cmdDesc}, nil
@@ -65,31 +75,44 @@ func (c *IngestCodeUnitsCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+	matrix, err := parseMatrix(settings.Matrix)
+	if err != nil {
+		return err
+	}
 
 	result, err := refactorindex.IngestCodeUnits(ctx, refactorindex.IngestCodeUnitsConfig{
 		DBPath:     settings.DBPath,
 		RootDir:    settings.RootDir,
 		SourcesDir: settings.SourcesDir,
+		Load:       loadSettings.options(),
+		Matrix:     matrix,
+		Tolerant:   settings.Tolerant,
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := gp.AddRow(ctx, ingestCodeUnitsRow(result.RunID, result.CodeUnits, result.Snapshots, result.Packages, result.Files, result.BodyBytes, result.DocEntries)); err != nil {
+	if err := gp.AddRow(ctx, ingestCodeUnitsRow(result)); err != nil {
 		return errors.Wrap(err, "add ingest code-units row")
 	}
 
 	return nil
 }
 
-func ingestCodeUnitsRow(runID int64, codeUnits int, snapshots int, packages int, files int, bodyBytes int, docEntries int) types.Row {
+func ingestCodeUnitsRow(result *refactorindex.IngestCodeUnitsResult) types.Row {
 	return types.NewRow(
-		types.MRP("run_id", runID),
-		types.MRP("code_units", codeUnits),
-		types.MRP("snapshots", snapshots),
-		types.MRP("packages", packages),
-		types.MRP("files", files),
-		types.MRP("body_bytes", bodyBytes),
-		types.MRP("doc_entries", docEntries),
+		types.MRP("run_id", result.RunID),
+		types.MRP("code_units", result.CodeUnits),
+		types.MRP("snapshots", result.Snapshots),
+		types.MRP("packages", result.Packages),
+		types.MRP("files", result.Files),
+		types.MRP("body_bytes", result.BodyBytes),
+		types.MRP("doc_entries", result.DocEntries),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
 	)
 }
@@ -23,6 +23,8 @@ type IngestCommitsSettings struct {
 	RepoPath string `glazed:"repo"`
 	FromRef  string `glazed:"from"`
 	ToRef    string `glazed:"to"`
+
+	IssuePatterns []string `glazed:"issue-pattern"`
 }
 
 var _ cmds.GlazeCommand = &IngestCommitsCommand{}
@@ -31,7 +33,7 @@ func NewIngestCommitsCommand() (*IngestCommitsCommand, error) {
 	cmdDesc := cmds.NewCommandDescription(
 		"commits",
 		cmds.WithShort("Ingest commit lineage into the refactor index"),
-		cmds.WithLong("Capture commit metadata, file changes, and blob stats into SQLite."),
+		cmds.WithLong("Capture commit metadata, file changes, blob stats, conventional-commit fields, trailers, and issue references into SQLite."),
 		cmds.WithFlags(
 			fields.New(
 				"db",
@@ -57,6 +59,12 @@ func NewIngestCommitsCommand() (*IngestCommitsCommand, error) {
 				fields.WithHelp("Git ref for the end of the range"),
 				fields.WithRequired(true),
 			),
+			fields.New(
+				"issue-pattern",
+				fields.TypeStringList,
+				fields.WithHelp("Regular expression for issue references; the first capture group is stored (defaults to #123 and ABC-456)"),
+				fields.WithDefault([]string{}),
+			),
 		),
 	)
 
@@ -78,23 +86,28 @@ func (c *IngestCommitsCommand) RunIntoGlazeProcessor(
 		RepoPath: settings.RepoPath,
 		FromRef:  settings.FromRef,
 		ToRef:    settings.ToRef,
+
+		IssuePatterns: settings.IssuePatterns,
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := gp.AddRow(ctx, ingestCommitsRow(result.RunID, result.CommitCount, result.FileCount, result.BlobCount)); err != nil {
+	if err := gp.AddRow(ctx, ingestCommitsRow(result)); err != nil {
 		return errors.Wrap(err, "add ingest commits row")
 	}
 
 	return nil
 }
 
-func ingestCommitsRow(runID int64, commits int, files int, blobs int) types.Row {
+func ingestCommitsRow(result *refactorindex.IngestCommitsResult) types.Row {
 	return types.NewRow(
-		types.MRP("run_id", runID),
-		types.MRP("commits", commits),
-		types.MRP("files", files),
-		types.MRP("blobs", blobs),
+		types.MRP("run_id", result.RunID),
+		types.MRP("commits", result.CommitCount),
+		types.MRP("files", result.FileCount),
+		types.MRP("blobs", result.BlobCount),
+		types.MRP("conventional", result.ConventionalCount),
+		types.MRP("trailers", result.TrailerCount),
+		types.MRP("issue_refs", result.IssueRefCount),
 	)
 }
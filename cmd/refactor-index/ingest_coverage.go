@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestCoverageCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestCoverageSettings struct {
+	DBPath         string   `glazed:"db"`
+	RootDir        string   `glazed:"root"`
+	Profile        string   `glazed:"profile"`
+	CodeUnitsRunID int64    `glazed:"code-units-run-id"`
+	SourcesDir     string   `glazed:"sources-dir"`
+	Packages       []string `glazed:"package"`
+	Tags           []string `glazed:"tags"`
+	Timeout        string   `glazed:"timeout"`
+}
+
+var _ cmds.GlazeCommand = &IngestCoverageCommand{}
+
+func NewIngestCoverageCommand() (*IngestCoverageCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"coverage",
+		cmds.WithShort("Ingest Go statement coverage per code unit"),
+		cmds.WithLong("Read a -coverprofile file, or run go test -coverprofile in the root, and map its blocks onto the code unit snapshots of a code-units run."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Module root to run go test in (required without --profile)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"profile",
+				fields.TypeString,
+				fields.WithHelp("Existing coverage profile to ingest instead of running tests"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"code-units-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Code units run to map coverage onto (default: the run for the root's HEAD commit when it was ingested, which must have code units; otherwise the latest when only one commit has code units)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"package",
+				fields.TypeStringList,
+				fields.WithHelp("Package patterns to test (default ./...)"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"tags",
+				fields.TypeStringList,
+				fields.WithHelp("Build tags passed to go test"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"timeout",
+				fields.TypeString,
+				fields.WithHelp("go test -timeout value, e.g. 5m (default: go's own)"),
+				fields.WithDefault(""),
+			),
+		),
+	)
+
+	return &IngestCoverageCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestCoverageCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestCoverageSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	timeout, err := parseTestTimeout(settings.Timeout)
+	if err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestCoverage(ctx, refactorindex.IngestCoverageConfig{
+		DBPath:         settings.DBPath,
+		RootDir:        settings.RootDir,
+		SourcesDir:     settings.SourcesDir,
+		CodeUnitsRunID: settings.CodeUnitsRunID,
+		ProfilePath:    settings.Profile,
+		Packages:       settings.Packages,
+		Tags:           settings.Tags,
+		Timeout:        timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("code_units_run_id", result.CodeUnitsRunID),
+		types.MRP("mode", result.Mode),
+		types.MRP("files", result.Files),
+		types.MRP("blocks", result.Blocks),
+		types.MRP("matched_blocks", result.MatchedBlocks),
+		types.MRP("code_units", result.CodeUnits),
+		types.MRP("covered_statements", result.CoveredStatements),
+		types.MRP("total_statements", result.TotalStatements),
+		types.MRP("test_exit_code", result.TestExitCode),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest coverage row")
+	}
+
+	return nil
+}
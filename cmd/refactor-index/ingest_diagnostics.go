@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestDiagnosticsCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestDiagnosticsSettings struct {
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Analyzers  []string `glazed:"analyzer"`
+	SkipVet    bool     `glazed:"skip-vet"`
+	Tolerant   bool     `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestDiagnosticsCommand{}
+
+func NewIngestDiagnosticsCommand() (*IngestDiagnosticsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"diagnostics",
+		cmds.WithShort("Ingest go/analysis diagnostics"),
+		cmds.WithLong("Run the go vet analyzer suite, plus optional extra analyzers, in-process on the packages of a module root and store each diagnostic with its span, enclosing code unit and suggested fixes."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Module root to analyze"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"analyzer",
+				fields.TypeStringList,
+				fields.WithHelp("Extra analyzers to run by name, e.g. nilness or shadow"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"skip-vet",
+				fields.TypeBool,
+				fields.WithHelp("Run only the analyzers given with --analyzer"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+		cmds.WithFlags(goLoadFlags()...),
+	)
+
+	return &IngestDiagnosticsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestDiagnosticsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestDiagnosticsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestDiagnostics(ctx, refactorindex.IngestDiagnosticsConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Analyzers:  settings.Analyzers,
+		SkipVet:    settings.SkipVet,
+		Load:       loadSettings.options(),
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("analyzers", result.Analyzers),
+		types.MRP("packages", result.Packages),
+		types.MRP("diagnostics", result.Diagnostics),
+		types.MRP("fixes", result.Fixes),
+		types.MRP("analyzer_errors", result.AnalyzerErrors),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest diagnostics row")
+	}
+
+	return nil
+}
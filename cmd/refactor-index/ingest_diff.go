@@ -19,11 +19,16 @@ type IngestDiffCommand struct {
 }
 
 type IngestDiffSettings struct {
-	DBPath     string `glazed:"db"`
-	RepoPath   string `glazed:"repo"`
-	FromRef    string `glazed:"from"`
-	ToRef      string `glazed:"to"`
-	SourcesDir string `glazed:"sources-dir"`
+	DBPath          string `glazed:"db"`
+	RepoPath        string `glazed:"repo"`
+	FromRef         string `glazed:"from"`
+	ToRef           string `glazed:"to"`
+	SourcesDir      string `glazed:"sources-dir"`
+	Mode            string `glazed:"mode"`
+	PatchPath       string `glazed:"patch"`
+	Context         int    `glazed:"context"`
+	MinMoved        int    `glazed:"min-moved-lines"`
+	SkipAnnotations bool   `glazed:"skip-annotations"`
 }
 
 var _ cmds.GlazeCommand = &IngestDiffCommand{}
@@ -32,7 +37,7 @@ func NewIngestDiffCommand() (*IngestDiffCommand, error) {
 	cmdDesc := cmds.NewCommandDescription(
 		"diff",
 		cmds.WithShort("Ingest git diff metadata into the refactor index"),
-		cmds.WithLong("Capture git diff name-status and unified patch data into SQLite."),
+		cmds.WithLong("Capture git diff name-status and unified patch data into SQLite. Besides two refs, the diff can be the working tree (untracked files included) or the index against --from (default HEAD), or a patch file (--patch, '-' for stdin)."),
 		cmds.WithFlags(
 			fields.New(
 				"db",
@@ -43,20 +48,56 @@ func NewIngestDiffCommand() (*IngestDiffCommand, error) {
 			fields.New(
 				"repo",
 				fields.TypeString,
-				fields.WithHelp("Path to the git repository"),
-				fields.WithRequired(true),
+				fields.WithHelp("Path to the git repository (optional in patch mode)"),
+				fields.WithDefault(""),
 			),
 			fields.New(
 				"from",
 				fields.TypeString,
-				fields.WithHelp("Git ref for the start of the diff"),
-				fields.WithRequired(true),
+				fields.WithHelp("Git ref for the start of the diff (base commit for worktree/staged modes)"),
+				fields.WithDefault(""),
 			),
 			fields.New(
 				"to",
 				fields.TypeString,
-				fields.WithHelp("Git ref for the end of the diff"),
-				fields.WithRequired(true),
+				fields.WithHelp("Git ref for the end of the diff (refs mode only)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"mode",
+				fields.TypeChoice,
+				fields.WithChoices(
+					refactorindex.DiffModeRefs,
+					refactorindex.DiffModeWorktree,
+					refactorindex.DiffModeStaged,
+					refactorindex.DiffModePatch,
+				),
+				fields.WithHelp("What to diff: refs, worktree, staged or patch"),
+				fields.WithDefault(refactorindex.DiffModeRefs),
+			),
+			fields.New(
+				"patch",
+				fields.TypeString,
+				fields.WithHelp("Unified diff file to ingest in patch mode ('-' for stdin)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"context",
+				fields.TypeInteger,
+				fields.WithHelp("Lines of context around changes (git diff -U)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"min-moved-lines",
+				fields.TypeInteger,
+				fields.WithHelp("Shortest block of lines detected as moved (0 uses the default of 3)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"skip-annotations",
+				fields.TypeBool,
+				fields.WithHelp("Skip line pairing, word diffs and move detection for very large diffs"),
+				fields.WithDefault(false),
 			),
 			fields.New(
 				"sources-dir",
@@ -86,23 +127,32 @@ func (c *IngestDiffCommand) RunIntoGlazeProcessor(
 		FromRef:    settings.FromRef,
 		ToRef:      settings.ToRef,
 		SourcesDir: settings.SourcesDir,
+		Mode:       settings.Mode,
+		PatchPath:  settings.PatchPath,
+
+		ContextLines:    settings.Context,
+		MinMovedLines:   settings.MinMoved,
+		SkipAnnotations: settings.SkipAnnotations,
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := gp.AddRow(ctx, ingestDiffRow(result.RunID, result.Files, result.Hunks, result.Lines)); err != nil {
+	if err := gp.AddRow(ctx, ingestDiffRow(result)); err != nil {
 		return errors.Wrap(err, "add ingest diff row")
 	}
 
 	return nil
 }
 
-func ingestDiffRow(runID int64, files int, hunks int, lines int) types.Row {
+func ingestDiffRow(result *refactorindex.IngestDiffResult) types.Row {
 	return types.NewRow(
-		types.MRP("run_id", runID),
-		types.MRP("files", files),
-		types.MRP("hunks", hunks),
-		types.MRP("lines", lines),
+		types.MRP("run_id", result.RunID),
+		types.MRP("files", result.Files),
+		types.MRP("hunks", result.Hunks),
+		types.MRP("lines", result.Lines),
+		types.MRP("line_pairs", result.LinePairs),
+		types.MRP("word_edits", result.WordEdits),
+		types.MRP("moved_blocks", result.MovedBlocks),
 	)
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestDirectivesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestDirectivesSettings struct {
+	DBPath     string `glazed:"db"`
+	RootDir    string `glazed:"root"`
+	SourcesDir string `glazed:"sources-dir"`
+	Tolerant   bool   `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestDirectivesCommand{}
+
+func NewIngestDirectivesCommand() (*IngestDirectivesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"directives",
+		cmds.WithShort("Ingest compiler and tool directives"),
+		cmds.WithLong("Record every directive comment (//go:build, //go:generate, //go:embed, //go:linkname, //nolint and other //tool:name directives) with its arguments, position and the declaration it is attached to. Every Go file is parsed regardless of build constraints. //go:embed patterns are resolved to the files they embed and //go:linkname names are checked, so directives referencing missing files or symbols are flagged."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory to scan for Go files"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip files that fail to parse and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+	)
+
+	return &IngestDirectivesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestDirectivesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestDirectivesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestDirectives(ctx, refactorindex.IngestDirectivesConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("files", result.Files),
+		types.MRP("directives", result.Directives),
+		types.MRP("embed_files", result.EmbedFiles),
+		types.MRP("broken", result.Broken),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest directives row")
+	}
+
+	return nil
+}
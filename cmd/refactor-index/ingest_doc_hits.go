@@ -65,6 +65,7 @@ func NewIngestDocHitsCommand() (*IngestDocHitsCommand, error) {
 				fields.WithDefault("sources"),
 			),
 		),
+		cmds.WithFlags(fileScopeFlags()...),
 	)
 
 	return &IngestDocHitsCommand{CommandDescription: cmdDesc}, nil
@@ -79,6 +80,10 @@ func (c *IngestDocHitsCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
 
 	var commitID *int64
 	if settings.CommitID > 0 {
@@ -91,6 +96,7 @@ func (c *IngestDocHitsCommand) RunIntoGlazeProcessor(
 		TermsFile:  settings.TermsFile,
 		CommitID:   commitID,
 		SourcesDir: settings.SourcesDir,
+		FileScope:  scopeSettings.scope(),
 	})
 	if err != nil {
 		return err
@@ -115,6 +121,7 @@ func ingestDocHitsRow(result *refactorindex.IngestDocHitsResult) types.Row {
 		types.MRP("hits", result.Hits),
 		types.MRP("files", result.Files),
 		types.MRP("skipped", result.Skipped),
+		types.MRP("excluded", result.Excluded),
 		types.MRP("terms_file", result.TermsFile),
 		types.MRP("commit_id", commitID),
 	)
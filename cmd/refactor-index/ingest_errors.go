@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestErrorsCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestErrorsSettings struct {
+	DBPath     string `glazed:"db"`
+	RootDir    string `glazed:"root"`
+	SourcesDir string `glazed:"sources-dir"`
+	Tolerant   bool   `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestErrorsCommand{}
+
+func NewIngestErrorsCommand() (*IngestErrorsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"errors",
+		cmds.WithShort("Ingest an error-handling inventory"),
+		cmds.WithLong("Record error-creating and wrapping calls (errors.New, fmt.Errorf, github.com/pkg/errors New/Errorf/Wrap/Wrapf/WithMessage/WithStack) with their message literals, package-level sentinel errors, and calls whose error result is discarded with _ or left unchecked (including defer and go statements), each with its enclosing code unit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory to scan for Go packages"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+		cmds.WithFlags(goLoadFlags()...),
+	)
+
+	return &IngestErrorsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestErrorsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestErrorsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestErrors(ctx, refactorindex.IngestErrorsConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Load:       loadSettings.options(),
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("packages", result.Packages),
+		types.MRP("sites", result.Sites),
+		types.MRP("sentinels", result.Sentinels),
+		types.MRP("ignored", result.Ignored),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest errors row")
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestExternalUsesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestExternalUsesSettings struct {
+	DBPath     string `glazed:"db"`
+	RootDir    string `glazed:"root"`
+	SourcesDir string `glazed:"sources-dir"`
+	IncludeStd bool   `glazed:"include-std"`
+	Tolerant   bool   `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestExternalUsesCommand{}
+
+func NewIngestExternalUsesCommand() (*IngestExternalUsesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"external-uses",
+		cmds.WithShort("Ingest uses of external package identifiers"),
+		cmds.WithLong("Record every use of an identifier declared outside the main modules (package, name, kind, position and enclosing code unit), to measure how much of a dependency's API the code relies on."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory to scan for Go packages"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"include-std",
+				fields.TypeBool,
+				fields.WithHelp("Also record uses of the standard library"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+		cmds.WithFlags(goLoadFlags()...),
+	)
+
+	return &IngestExternalUsesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestExternalUsesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestExternalUsesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestExternalUses(ctx, refactorindex.IngestExternalUsesConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Load:       loadSettings.options(),
+		IncludeStd: settings.IncludeStd,
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("packages", result.Packages),
+		types.MRP("uses", result.Uses),
+		types.MRP("external_packages", result.ExternalPkgs),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest external uses row")
+	}
+
+	return nil
+}
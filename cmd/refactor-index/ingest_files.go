@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestFilesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestFilesSettings struct {
+	DBPath  string `glazed:"db"`
+	RootDir string `glazed:"root"`
+}
+
+var _ cmds.GlazeCommand = &IngestFilesCommand{}
+
+func NewIngestFilesCommand() (*IngestFilesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"files",
+		cmds.WithShort("Classify indexed files"),
+		cmds.WithLong("Fill the extension, language, size, existence, binary, generated (\"Code generated ... DO NOT EDIT.\" header), test and vendored columns of every indexed file from the tree at --root. Each path has one classification, so the tree classified last applies to every commit. List commands, reports and doc-hits ingestion then leave generated and vendored files out unless --include-generated or --include-vendored is set."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory the indexed paths are resolved against"),
+				fields.WithRequired(true),
+			),
+		),
+	)
+
+	return &IngestFilesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestFilesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestFilesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.ClassifyFiles(ctx, refactorindex.ClassifyFilesConfig{
+		DBPath:  settings.DBPath,
+		RootDir: settings.RootDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("files", result.Files),
+		types.MRP("missing", result.Missing),
+		types.MRP("generated", result.Generated),
+		types.MRP("test", result.Test),
+		types.MRP("vendored", result.Vendored),
+		types.MRP("binary", result.Binary),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest files row")
+	}
+
+	return nil
+}
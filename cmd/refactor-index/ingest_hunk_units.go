@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestHunkUnitsCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestHunkUnitsSettings struct {
+	DBPath            string `glazed:"db"`
+	DiffRunID         int64  `glazed:"diff-run-id"`
+	OldCodeUnitsRunID int64  `glazed:"old-code-units-run-id"`
+	NewCodeUnitsRunID int64  `glazed:"new-code-units-run-id"`
+}
+
+var _ cmds.GlazeCommand = &IngestHunkUnitsCommand{}
+
+func NewIngestHunkUnitsCommand() (*IngestHunkUnitsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"hunk-units",
+		cmds.WithShort("Link diff hunks to the code units they touch"),
+		cmds.WithLong("Record which code units each hunk of a diff run added, removed or modified, using code-unit runs taken at the diff's from and to refs."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"diff-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the diff ingestion"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"old-code-units-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the code-unit ingestion at the from ref"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"new-code-units-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the code-unit ingestion at the to ref"),
+				fields.WithRequired(true),
+			),
+		),
+	)
+
+	return &IngestHunkUnitsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestHunkUnitsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestHunkUnitsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestHunkCodeUnits(ctx, refactorindex.IngestHunkCodeUnitsConfig{
+		DBPath:            settings.DBPath,
+		DiffRunID:         settings.DiffRunID,
+		OldCodeUnitsRunID: settings.OldCodeUnitsRunID,
+		NewCodeUnitsRunID: settings.NewCodeUnitsRunID,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("hunks", result.Hunks),
+		types.MRP("links", result.Links),
+		types.MRP("code_units", result.CodeUnits),
+		types.MRP("added", result.Added),
+		types.MRP("removed", result.Removed),
+		types.MRP("modified", result.Modified),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest hunk-units row")
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestModulesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestModulesSettings struct {
+	DBPath     string `glazed:"db"`
+	RootDir    string `glazed:"root"`
+	SourcesDir string `glazed:"sources-dir"`
+	BuildList  bool   `glazed:"build-list"`
+	Tolerant   bool   `glazed:"tolerant"`
+}
+
+var _ cmds.GlazeCommand = &IngestModulesCommand{}
+
+func NewIngestModulesCommand() (*IngestModulesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"modules",
+		cmds.WithShort("Ingest go.mod dependencies"),
+		cmds.WithLong("Parse every go.mod below the root (require, replace, exclude, retract, go and toolchain lines) and optionally record each module's go list -m all build list, resolved offline from the module cache."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Root directory to scan for go.mod files"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"build-list",
+				fields.TypeBool,
+				fields.WithHelp("Also record go list -m -json all (offline; failures are recorded as load errors)"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Record go.mod files that fail to parse instead of failing"),
+				fields.WithDefault(false),
+			),
+		),
+	)
+
+	return &IngestModulesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestModulesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestModulesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestModules(ctx, refactorindex.IngestModulesConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		BuildList:  settings.BuildList,
+		Tolerant:   settings.Tolerant,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("mod_files", result.ModFiles),
+		types.MRP("requires", result.Requires),
+		types.MRP("replaces", result.Replaces),
+		types.MRP("excludes", result.Excludes),
+		types.MRP("retracts", result.Retracts),
+		types.MRP("build_list", result.BuildList),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest modules row")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestOwnersCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestOwnersSettings struct {
+	DBPath         string `glazed:"db"`
+	RepoPath       string `glazed:"repo"`
+	Commit         string `glazed:"commit"`
+	CodeownersPath string `glazed:"codeowners"`
+	SourcesDir     string `glazed:"sources-dir"`
+}
+
+var _ cmds.GlazeCommand = &IngestOwnersCommand{}
+
+func NewIngestOwnersCommand() (*IngestOwnersCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"owners",
+		cmds.WithShort("Ingest CODEOWNERS rules and file owners"),
+		cmds.WithLong("Parse CODEOWNERS (GitHub or GitLab syntax) at a commit and compute the owners of every indexed file. The latest owners run backs the --owner filters of list and report commands."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"repo",
+				fields.TypeString,
+				fields.WithHelp("Path to the git repository"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"commit",
+				fields.TypeString,
+				fields.WithHelp("Commit to read CODEOWNERS and the file tree from"),
+				fields.WithDefault("HEAD"),
+			),
+			fields.New(
+				"codeowners",
+				fields.TypeString,
+				fields.WithHelp("CODEOWNERS path in the repository (default: .github/, root, docs/, .gitlab/)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+		),
+	)
+
+	return &IngestOwnersCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestOwnersCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestOwnersSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestOwners(ctx, refactorindex.IngestOwnersConfig{
+		DBPath:         settings.DBPath,
+		RepoPath:       settings.RepoPath,
+		Commit:         settings.Commit,
+		CodeownersPath: settings.CodeownersPath,
+		SourcesDir:     settings.SourcesDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("commit", result.Commit),
+		types.MRP("codeowners", result.SourcePath),
+		types.MRP("rules", result.Rules),
+		types.MRP("files", result.Files),
+		types.MRP("owned_files", result.OwnedFiles),
+		types.MRP("owner_entries", result.OwnerEntries),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest owners row")
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestPatchesCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestPatchesSettings struct {
+	DBPath     string   `glazed:"db"`
+	Patches    []string `glazed:"patch"`
+	SourcesDir string   `glazed:"sources-dir"`
+
+	IssuePatterns []string `glazed:"issue-pattern"`
+}
+
+var _ cmds.GlazeCommand = &IngestPatchesCommand{}
+
+func NewIngestPatchesCommand() (*IngestPatchesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"patches",
+		cmds.WithShort("Ingest mbox or git format-patch series into the refactor index"),
+		cmds.WithLong("Store each patch email as a commit (author, date and subject from the headers) and its diff as a separate diff run."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"patch",
+				fields.TypeStringList,
+				fields.WithHelp("Patch or mbox file to ingest (repeatable)"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"issue-pattern",
+				fields.TypeStringList,
+				fields.WithHelp("Regular expression for issue references; the first capture group is stored (defaults to #123 and ABC-456)"),
+				fields.WithDefault([]string{}),
+			),
+		),
+	)
+
+	return &IngestPatchesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestPatchesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestPatchesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestPatches(ctx, refactorindex.IngestPatchesConfig{
+		DBPath:        settings.DBPath,
+		Paths:         settings.Patches,
+		SourcesDir:    settings.SourcesDir,
+		IssuePatterns: settings.IssuePatterns,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, patch := range result.Patches {
+		row := types.NewRow(
+			types.MRP("run_id", result.RunID),
+			types.MRP("patch_file", patch.PatchFile),
+			types.MRP("commit_hash", patch.CommitHash),
+			types.MRP("subject", patch.Subject),
+			types.MRP("diff_run_id", patch.DiffRunID),
+			types.MRP("files", patch.Files),
+			types.MRP("hunks", patch.Hunks),
+			types.MRP("lines", patch.Lines),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add ingest patches row")
+		}
+	}
+
+	return nil
+}
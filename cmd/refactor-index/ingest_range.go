@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/go-go-golems/glazed/pkg/cmds"
@@ -26,12 +28,22 @@ type IngestRangeSettings struct {
 	ToRef      string `glazed:"to"`
 	SourcesDir string `glazed:"sources-dir"`
 
-	IncludeDiff       bool `glazed:"include-diff"`
-	IncludeSymbols    bool `glazed:"include-symbols"`
-	IncludeCodeUnits  bool `glazed:"include-code-units"`
-	IncludeDocHits    bool `glazed:"include-doc-hits"`
-	IncludeTreeSitter bool `glazed:"include-tree-sitter"`
-	IncludeGopls      bool `glazed:"include-gopls"`
+	IncludeDiff        bool `glazed:"include-diff"`
+	IncludeSymbols     bool `glazed:"include-symbols"`
+	IncludeCodeUnits   bool `glazed:"include-code-units"`
+	IncludeDocHits     bool `glazed:"include-doc-hits"`
+	IncludeTreeSitter  bool `glazed:"include-tree-sitter"`
+	IncludeGopls       bool `glazed:"include-gopls"`
+	IncludeTests       bool `glazed:"include-tests"`
+	IncludeBenchmarks  bool `glazed:"include-benchmarks"`
+	IncludeDiagnostics bool `glazed:"include-diagnostics"`
+	IncludeModules     bool `glazed:"include-modules"`
+	IncludeExternal    bool `glazed:"include-external-uses"`
+	IncludeCallSites   bool `glazed:"include-call-sites"`
+	IncludeErrors      bool `glazed:"include-errors"`
+	IncludeDirectives  bool `glazed:"include-directives"`
+	SkipClassifyFiles  bool `glazed:"skip-classify-files"`
+	Tolerant           bool `glazed:"tolerant"`
 
 	TermsFile          string   `glazed:"terms"`
 	TreeSitterLanguage string   `glazed:"ts-language"`
@@ -40,6 +52,17 @@ type IngestRangeSettings struct {
 	GoplsTargets       []string `glazed:"gopls-target"`
 	GoplsTargetsFile   string   `glazed:"gopls-targets-file"`
 	GoplsTargetsJSON   string   `glazed:"gopls-targets-json"`
+	IssuePatterns      []string `glazed:"issue-pattern"`
+	TestPackages       []string `glazed:"test-package"`
+	TestTags           []string `glazed:"test-tags"`
+	TestTimeout        string   `glazed:"test-timeout"`
+	BenchPattern       string   `glazed:"bench"`
+	BenchCount         int      `glazed:"bench-count"`
+	BenchTime          string   `glazed:"bench-time"`
+	Analyzers          []string `glazed:"analyzer"`
+	Matrix             []string `glazed:"matrix"`
+	ModuleBuildList    bool     `glazed:"module-build-list"`
+	CallTargets        []string `glazed:"call-target"`
 }
 
 var _ cmds.GlazeCommand = &IngestRangeCommand{}
@@ -48,7 +71,7 @@ func NewIngestRangeCommand() (*IngestRangeCommand, error) {
 	cmdDesc := cmds.NewCommandDescription(
 		"range",
 		cmds.WithShort("Ingest multiple passes across a commit range"),
-		cmds.WithLong("Orchestrate commit lineage plus optional diff/symbols/code units/doc hits/tree-sitter/gopls ingestion."),
+		cmds.WithLong("Orchestrate commit lineage plus optional diff/symbols/code units/doc hits/tree-sitter/gopls/go test/benchmark/analyzer ingestion."),
 		cmds.WithFlags(
 			fields.New(
 				"db",
@@ -116,6 +139,66 @@ func NewIngestRangeCommand() (*IngestRangeCommand, error) {
 				fields.WithHelp("Include gopls references ingestion per commit"),
 				fields.WithDefault(false),
 			),
+			fields.New(
+				"include-tests",
+				fields.TypeBool,
+				fields.WithHelp("Include go test -json results per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-benchmarks",
+				fields.TypeBool,
+				fields.WithHelp("Include go test -bench results per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-diagnostics",
+				fields.TypeBool,
+				fields.WithHelp("Include go vet and --analyzer diagnostics per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-modules",
+				fields.TypeBool,
+				fields.WithHelp("Include go.mod dependencies per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-external-uses",
+				fields.TypeBool,
+				fields.WithHelp("Include uses of external package identifiers per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-call-sites",
+				fields.TypeBool,
+				fields.WithHelp("Include calls of the --call-target functions with their arguments per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-errors",
+				fields.TypeBool,
+				fields.WithHelp("Include the error-handling inventory per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"include-directives",
+				fields.TypeBool,
+				fields.WithHelp("Include compiler and tool directives per commit"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"skip-classify-files",
+				fields.TypeBool,
+				fields.WithHelp("Skip classifying indexed files (generated, test, vendored, language, size) against each commit's tree; by default the last commit's classification scopes list commands and reports"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Continue past commits whose packages fail to load, recording their errors"),
+				fields.WithDefault(false),
+			),
 			fields.New(
 				"terms",
 				fields.TypeString,
@@ -158,7 +241,70 @@ func NewIngestRangeCommand() (*IngestRangeCommand, error) {
 				fields.WithHelp("JSON file containing gopls target specs"),
 				fields.WithDefault(""),
 			),
+			fields.New(
+				"issue-pattern",
+				fields.TypeStringList,
+				fields.WithHelp("Regular expression for issue references in commit messages"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"test-package",
+				fields.TypeStringList,
+				fields.WithHelp("Package patterns for go test (default ./...)"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"test-tags",
+				fields.TypeStringList,
+				fields.WithHelp("Build tags for go test"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"test-timeout",
+				fields.TypeString,
+				fields.WithHelp("go test -timeout value per commit, e.g. 5m"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"bench",
+				fields.TypeString,
+				fields.WithHelp("Benchmark regular expression for go test -bench"),
+				fields.WithDefault("."),
+			),
+			fields.New(
+				"bench-count",
+				fields.TypeInteger,
+				fields.WithHelp("Benchmark samples per commit (go test -count)"),
+				fields.WithDefault(1),
+			),
+			fields.New(
+				"bench-time",
+				fields.TypeString,
+				fields.WithHelp("go test -benchtime value, e.g. 100x or 1s (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"analyzer",
+				fields.TypeStringList,
+				fields.WithHelp("Extra analyzers to run with --include-diagnostics, e.g. nilness"),
+				fields.WithDefault([]string{}),
+			),
+			matrixFlag(),
+			fields.New(
+				"module-build-list",
+				fields.TypeBool,
+				fields.WithHelp("With --include-modules, also record the offline go list -m all build list"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"call-target",
+				fields.TypeStringList,
+				fields.WithHelp("Fully qualified function or method for --include-call-sites, e.g. github.com/pkg/errors.Wrap (repeatable)"),
+				fields.WithDefault([]string{}),
+			),
 		),
+		cmds.WithFlags(goLoadFlags()...),
+		cmds.WithFlags(fileScopeFlags()...),
 	)
 
 	return &IngestRangeCommand{CommandDescription: cmdDesc}, nil
@@ -173,12 +319,30 @@ func (c *IngestRangeCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
 
 	goplsTargets, err := loadGoplsTargets(settings.GoplsTargets, settings.GoplsTargetsFile, settings.GoplsTargetsJSON)
 	if err != nil {
 		return err
 	}
 
+	testTimeout, err := parseTestTimeout(settings.TestTimeout)
+	if err != nil {
+		return err
+	}
+
+	matrix, err := parseMatrix(settings.Matrix)
+	if err != nil {
+		return err
+	}
+
 	result, err := refactorindex.IngestCommitRange(ctx, refactorindex.RangeIngestConfig{
 		DBPath:             settings.DBPath,
 		RepoPath:           settings.RepoPath,
@@ -191,11 +355,34 @@ func (c *IngestRangeCommand) RunIntoGlazeProcessor(
 		IncludeDocHits:     settings.IncludeDocHits,
 		IncludeTreeSitter:  settings.IncludeTreeSitter,
 		IncludeGopls:       settings.IncludeGopls,
+		IncludeTests:       settings.IncludeTests,
+		IncludeBenchmarks:  settings.IncludeBenchmarks,
+		IncludeDiagnostics: settings.IncludeDiagnostics,
+		IncludeModules:     settings.IncludeModules,
+		IncludeExternal:    settings.IncludeExternal,
+		IncludeCallSites:   settings.IncludeCallSites,
+		IncludeErrors:      settings.IncludeErrors,
+		IncludeDirectives:  settings.IncludeDirectives,
+		Tolerant:           settings.Tolerant,
+		Load:               loadSettings.options(),
+		Matrix:             matrix,
+		SkipClassifyFiles:  settings.SkipClassifyFiles,
+		FileScope:          scopeSettings.scope(),
 		TermsFile:          settings.TermsFile,
 		TreeSitterLanguage: settings.TreeSitterLanguage,
 		TreeSitterQueries:  settings.TreeSitterQueries,
 		TreeSitterGlob:     settings.TreeSitterGlob,
 		GoplsTargets:       goplsTargets,
+		IssuePatterns:      settings.IssuePatterns,
+		TestPackages:       settings.TestPackages,
+		TestTags:           settings.TestTags,
+		TestTimeout:        testTimeout,
+		BenchPattern:       settings.BenchPattern,
+		BenchCount:         settings.BenchCount,
+		BenchTime:          settings.BenchTime,
+		Analyzers:          settings.Analyzers,
+		ModuleBuildList:    settings.ModuleBuildList,
+		CallTargets:        settings.CallTargets,
 	})
 	if err != nil {
 		return err
@@ -216,12 +403,29 @@ func (c *IngestRangeCommand) RunIntoGlazeProcessor(
 			types.MRP("doc_hits_run_id", commit.DocHitsRunID),
 			types.MRP("tree_sitter_run_id", commit.TreeSitterRunID),
 			types.MRP("gopls_run_id", commit.GoplsRunID),
+			types.MRP("hunk_units_run_id", commit.HunkUnitsRunID),
+			types.MRP("tests_run_id", commit.TestsRunID),
+			types.MRP("tests_status", commit.TestsStatus),
+			types.MRP("bench_run_id", commit.BenchRunID),
+			types.MRP("diagnostics_run_id", commit.DiagnosticsRunID),
+			types.MRP("modules_run_id", commit.ModulesRunID),
+			types.MRP("external_uses_run_id", commit.ExternalRunID),
+			types.MRP("call_sites_run_id", commit.CallSitesRunID),
+			types.MRP("errors_run_id", commit.ErrorsRunID),
+			types.MRP("directives_run_id", commit.DirectivesRunID),
+			types.MRP("load_errors", commit.LoadErrors),
+			types.MRP("partial", commit.Partial),
 		)
 		if err := gp.AddRow(ctx, row); err != nil {
 			return errors.Wrap(err, "add ingest range row")
 		}
 	}
 
+	if len(result.PartialCommits) > 0 {
+		fmt.Fprintf(os.Stderr, "%d commit(s) had package load errors (see list load-errors): %s\n",
+			len(result.PartialCommits), strings.Join(result.PartialCommits, ", "))
+	}
+
 	return nil
 }
 
@@ -236,6 +440,18 @@ func ingestRangeSummaryRow(runID int64, commitCount int) types.Row {
 		types.MRP("doc_hits_run_id", 0),
 		types.MRP("tree_sitter_run_id", 0),
 		types.MRP("gopls_run_id", 0),
+		types.MRP("hunk_units_run_id", 0),
+		types.MRP("tests_run_id", 0),
+		types.MRP("tests_status", ""),
+		types.MRP("bench_run_id", 0),
+		types.MRP("diagnostics_run_id", 0),
+		types.MRP("modules_run_id", 0),
+		types.MRP("external_uses_run_id", 0),
+		types.MRP("call_sites_run_id", 0),
+		types.MRP("errors_run_id", 0),
+		types.MRP("directives_run_id", 0),
+		types.MRP("load_errors", 0),
+		types.MRP("partial", false),
 	)
 }
 
@@ -251,5 +467,8 @@ func (c *IngestRangeCommand) Validate(values *values.Values) error {
 	if settings.IncludeTreeSitter && (strings.TrimSpace(settings.TreeSitterLanguage) == "" || strings.TrimSpace(settings.TreeSitterQueries) == "") {
 		return errors.New("ts-language and ts-queries are required when include-tree-sitter is set")
 	}
+	if settings.IncludeCallSites && len(settings.CallTargets) == 0 {
+		return errors.New("at least one call-target is required when include-call-sites is set")
+	}
 	return nil
 }
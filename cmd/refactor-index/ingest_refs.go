@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestRefsCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestRefsSettings struct {
+	DBPath   string `glazed:"db"`
+	RepoPath string `glazed:"repo"`
+}
+
+var _ cmds.GlazeCommand = &IngestRefsCommand{}
+
+func NewIngestRefsCommand() (*IngestRefsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"refs",
+		cmds.WithShort("Ingest tags and branch heads into the refactor index"),
+		cmds.WithLong("Capture tags (annotated and lightweight), branch heads, semver versions, and the first tag containing each ingested commit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"repo",
+				fields.TypeString,
+				fields.WithHelp("Path to the git repository"),
+				fields.WithRequired(true),
+			),
+		),
+	)
+
+	return &IngestRefsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestRefsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestRefsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestRefs(ctx, refactorindex.IngestRefsConfig{
+		DBPath:   settings.DBPath,
+		RepoPath: settings.RepoPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := gp.AddRow(ctx, ingestRefsRow(result)); err != nil {
+		return errors.Wrap(err, "add ingest refs row")
+	}
+
+	return nil
+}
+
+func ingestRefsRow(result *refactorindex.IngestRefsResult) types.Row {
+	return types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("tags", result.Tags),
+		types.MRP("branches", result.Branches),
+		types.MRP("linked_commits", result.LinkedCommits),
+		types.MRP("first_tags", result.FirstTags),
+	)
+}
@@ -19,9 +19,11 @@ type IngestSymbolsCommand struct {
 }
 
 type IngestSymbolsSettings struct {
-	DBPath     string `glazed:"db"`
-	RootDir    string `glazed:"root"`
-	SourcesDir string `glazed:"sources-dir"`
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Tolerant   bool     `glazed:"tolerant"`
+	Matrix     []string `glazed:"matrix"`
 }
 
 var _ cmds.GlazeCommand = &IngestSymbolsCommand{}
@@ -50,7 +52,15 @@ func NewIngestSymbolsCommand() (*IngestSymbolsCommand, error) {
 				fields.WithHelp("Directory to write raw tool outputs"),
 				fields.WithDefault("sources"),
 			),
+			fields.New(
+				"tolerant",
+				fields.TypeBool,
+				fields.WithHelp("Skip packages that fail to load and record their errors instead of failing"),
+				fields.WithDefault(false),
+			),
+			matrixFlag(),
 		),
+		cmds.WithFlags(goLoadFlags()...),
 	)
 
 	return &IngestSymbolsCommand{CommandDescription: cmdDesc}, nil
@@ -65,29 +75,42 @@ func (c *IngestSymbolsCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	loadSettings := &GoLoadSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, loadSettings); err != nil {
+		return err
+	}
+	matrix, err := parseMatrix(settings.Matrix)
+	if err != nil {
+		return err
+	}
 
 	result, err := refactorindex.IngestSymbols(ctx, refactorindex.IngestSymbolsConfig{
 		DBPath:     settings.DBPath,
 		RootDir:    settings.RootDir,
 		SourcesDir: settings.SourcesDir,
+		Load:       loadSettings.options(),
+		Matrix:     matrix,
+		Tolerant:   settings.Tolerant,
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := gp.AddRow(ctx, ingestSymbolsRow(result.RunID, result.Symbols, result.Occurrences, result.Packages, result.Files)); err != nil {
+	if err := gp.AddRow(ctx, ingestSymbolsRow(result)); err != nil {
 		return errors.Wrap(err, "add ingest symbols row")
 	}
 
 	return nil
 }
 
-func ingestSymbolsRow(runID int64, symbols int, occurrences int, packages int, files int) types.Row {
+func ingestSymbolsRow(result *refactorindex.IngestSymbolsResult) types.Row {
 	return types.NewRow(
-		types.MRP("run_id", runID),
-		types.MRP("symbols", symbols),
-		types.MRP("occurrences", occurrences),
-		types.MRP("packages", packages),
-		types.MRP("files", files),
+		types.MRP("run_id", result.RunID),
+		types.MRP("symbols", result.Symbols),
+		types.MRP("occurrences", result.Occurrences),
+		types.MRP("packages", result.Packages),
+		types.MRP("files", result.Files),
+		types.MRP("load_errors", result.LoadErrors),
+		types.MRP("partial", result.Partial),
 	)
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type IngestTestsCommand struct {
+	*cmds.CommandDescription
+}
+
+type IngestTestsSettings struct {
+	DBPath     string   `glazed:"db"`
+	RootDir    string   `glazed:"root"`
+	SourcesDir string   `glazed:"sources-dir"`
+	Packages   []string `glazed:"package"`
+	Tags       []string `glazed:"tags"`
+	Timeout    string   `glazed:"timeout"`
+}
+
+var _ cmds.GlazeCommand = &IngestTestsCommand{}
+
+func NewIngestTestsCommand() (*IngestTestsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"tests",
+		cmds.WithShort("Ingest go test -json results"),
+		cmds.WithLong("Run go test -json in a module root and store package, test and subtest results. Failing tests are recorded, not reported as errors."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"root",
+				fields.TypeString,
+				fields.WithHelp("Module root to run go test in"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"sources-dir",
+				fields.TypeString,
+				fields.WithHelp("Directory to write raw tool outputs"),
+				fields.WithDefault("sources"),
+			),
+			fields.New(
+				"package",
+				fields.TypeStringList,
+				fields.WithHelp("Package patterns to test (default ./...)"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"tags",
+				fields.TypeStringList,
+				fields.WithHelp("Build tags passed to go test"),
+				fields.WithDefault([]string{}),
+			),
+			fields.New(
+				"timeout",
+				fields.TypeString,
+				fields.WithHelp("go test -timeout value, e.g. 5m (default: go's own)"),
+				fields.WithDefault(""),
+			),
+		),
+	)
+
+	return &IngestTestsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *IngestTestsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &IngestTestsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	timeout, err := parseTestTimeout(settings.Timeout)
+	if err != nil {
+		return err
+	}
+
+	result, err := refactorindex.IngestTests(ctx, refactorindex.IngestTestsConfig{
+		DBPath:     settings.DBPath,
+		RootDir:    settings.RootDir,
+		SourcesDir: settings.SourcesDir,
+		Packages:   settings.Packages,
+		Tags:       settings.Tags,
+		Timeout:    timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	row := types.NewRow(
+		types.MRP("run_id", result.RunID),
+		types.MRP("test_run_id", result.TestRunID),
+		types.MRP("status", result.Status),
+		types.MRP("exit_code", result.ExitCode),
+		types.MRP("packages", result.Packages),
+		types.MRP("tests", result.Tests),
+		types.MRP("passed", result.Passed),
+		types.MRP("failed", result.Failed),
+		types.MRP("skipped", result.Skipped),
+		types.MRP("elapsed", result.ElapsedSec),
+	)
+	if err := gp.AddRow(ctx, row); err != nil {
+		return errors.Wrap(err, "add ingest tests row")
+	}
+
+	return nil
+}
+
+func parseTestTimeout(value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "parse test timeout")
+	}
+	return timeout, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListAPIChangesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListAPIChangesSettings struct {
+	DBPath  string `glazed:"db"`
+	RunID   int64  `glazed:"run-id"`
+	Package string `glazed:"package"`
+	Change  string `glazed:"change"`
+	Limit   int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListAPIChangesCommand{}
+
+func NewListAPIChangesCommand() (*ListAPIChangesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"api-changes",
+		cmds.WithShort("List exported symbols removed or changed between dependency versions"),
+		cmds.WithLong("List the removed, changed and unknown exported symbols recorded by ingest api-diff, with their old and new signatures."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by api-diff run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"change",
+				fields.TypeString,
+				fields.WithHelp("Filter by change: removed, changed or unknown (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListAPIChangesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListAPIChangesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListAPIChangesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListAPIChanges(ctx, refactorindex.APIChangeFilter{
+		RunID:   settings.RunID,
+		Package: settings.Package,
+		Change:  settings.Change,
+		Limit:   settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("module", record.Module),
+			types.MRP("old_version", record.OldVersion),
+			types.MRP("new_version", record.NewVersion),
+			types.MRP("package", record.Package),
+			types.MRP("recv", record.Recv),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("change", record.Change),
+			types.MRP("old_signature", record.OldSignature),
+			types.MRP("new_signature", record.NewSignature),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add api change row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListBenchResultsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListBenchResultsSettings struct {
+	DBPath  string `glazed:"db"`
+	RunID   int64  `glazed:"run-id"`
+	Commit  string `glazed:"commit"`
+	Package string `glazed:"package"`
+	Name    string `glazed:"name"`
+	Metric  string `glazed:"metric"`
+	Limit   int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListBenchResultsCommand{}
+
+func NewListBenchResultsCommand() (*ListBenchResultsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"bench-results",
+		cmds.WithShort("List benchmark samples"),
+		cmds.WithLong("Query ingested benchmark samples, one row per metric, with the commit they ran at."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by benchmarks run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"commit",
+				fields.TypeString,
+				fields.WithHelp("Filter by commit hash prefix (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by benchmark name without the -procs suffix (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"metric",
+				fields.TypeString,
+				fields.WithHelp("Filter by metric unit, e.g. ns/op (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListBenchResultsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListBenchResultsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListBenchResultsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListBenchResults(ctx, refactorindex.BenchResultFilter{
+		RunID:   settings.RunID,
+		Commit:  settings.Commit,
+		Package: settings.Package,
+		Name:    settings.Name,
+		Metric:  settings.Metric,
+		Limit:   settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("name", record.Name),
+			types.MRP("procs", record.Procs),
+			types.MRP("sample", record.Sample),
+			types.MRP("iterations", record.Iterations),
+			types.MRP("metric", record.Metric),
+			types.MRP("value", record.Value),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add bench result row")
+		}
+	}
+
+	return nil
+}
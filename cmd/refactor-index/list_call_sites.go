@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListCallSitesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListCallSitesSettings struct {
+	DBPath string `glazed:"db"`
+	RunID  int64  `glazed:"run-id"`
+	Callee string `glazed:"callee"`
+	Path   string `glazed:"path"`
+	Limit  int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListCallSitesCommand{}
+
+func NewListCallSitesCommand() (*ListCallSitesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"call-sites",
+		cmds.WithShort("List recorded calls with their arguments"),
+		cmds.WithLong("List each recorded call with its span and enclosing code unit, and per argument its source text (arg_N) and constant value (arg_N_value, when known)."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by call-sites run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"callee",
+				fields.TypeString,
+				fields.WithHelp("Filter by fully qualified callee (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListCallSitesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListCallSitesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListCallSitesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListCallSites(ctx, refactorindex.CallSiteFilter{
+		RunID:     settings.RunID,
+		Callee:    settings.Callee,
+		Path:      settings.Path,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("callee", record.Callee),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("end_line", record.EndLine),
+			types.MRP("end_col", record.EndCol),
+			types.MRP("code_unit", record.CodeUnit),
+		)
+		for i, arg := range record.Args {
+			key := "arg_" + strconv.Itoa(i)
+			row.Set(key, arg.Source)
+			if arg.Const {
+				row.Set(key+"_value", arg.Value)
+			}
+		}
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add call site row")
+		}
+	}
+
+	return nil
+}
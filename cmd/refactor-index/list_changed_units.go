@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListChangedUnitsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListChangedUnitsSettings struct {
+	DBPath     string `glazed:"db"`
+	RunID      int64  `glazed:"run-id"`
+	DiffRunID  int64  `glazed:"diff-run-id"`
+	ChangeKind string `glazed:"change"`
+	Kind       string `glazed:"kind"`
+	Pkg        string `glazed:"pkg"`
+	Limit      int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListChangedUnitsCommand{}
+
+func NewListChangedUnitsCommand() (*ListChangedUnitsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"changed-units",
+		cmds.WithShort("List code units changed by a diff"),
+		cmds.WithLong("Query functions, methods and types touched by the hunks of a diff run (see 'ingest hunk-units')."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by hunk-units run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"diff-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by diff run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"change",
+				fields.TypeString,
+				fields.WithHelp("Filter by change: added, removed or modified (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"kind",
+				fields.TypeString,
+				fields.WithHelp("Filter by code unit kind: func, method or type (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"pkg",
+				fields.TypeString,
+				fields.WithHelp("Filter by package path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListChangedUnitsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListChangedUnitsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListChangedUnitsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListChangedUnits(ctx, refactorindex.ChangedUnitFilter{
+		RunID:      settings.RunID,
+		DiffRunID:  settings.DiffRunID,
+		ChangeKind: settings.ChangeKind,
+		Kind:       settings.Kind,
+		Pkg:        settings.Pkg,
+		Limit:      settings.Limit,
+		FileScope:  scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := gp.AddRow(ctx, changedUnitRow(record)); err != nil {
+			return errors.Wrap(err, "add changed unit row")
+		}
+	}
+
+	return nil
+}
+
+func changedUnitRow(record refactorindex.ChangedUnitRecord) types.Row {
+	return types.NewRow(
+		types.MRP("run_id", record.RunID),
+		types.MRP("diff_run_id", record.DiffRunID),
+		types.MRP("change", record.ChangeKind),
+		types.MRP("kind", record.Kind),
+		types.MRP("pkg", record.Pkg),
+		types.MRP("recv", record.Recv),
+		types.MRP("name", record.Name),
+		types.MRP("path", record.Path),
+		types.MRP("hunks", record.Hunks),
+		types.MRP("added_lines", record.AddedLines),
+		types.MRP("removed_lines", record.RemovedLines),
+		types.MRP("unit_hash", record.UnitHash),
+	)
+}
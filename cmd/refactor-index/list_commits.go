@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListCommitsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListCommitsSettings struct {
+	DBPath       string `glazed:"db"`
+	RunID        int64  `glazed:"run-id"`
+	Type         string `glazed:"type"`
+	Scope        string `glazed:"scope"`
+	Issue        string `glazed:"issue"`
+	BreakingOnly bool   `glazed:"breaking-only"`
+	Limit        int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListCommitsCommand{}
+
+func NewListCommitsCommand() (*ListCommitsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"commits",
+		cmds.WithShort("List commits with parsed message fields"),
+		cmds.WithLong("Query commits together with conventional-commit type/scope, trailers, and issue references."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by a specific run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"type",
+				fields.TypeString,
+				fields.WithHelp("Filter by conventional-commit type (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"scope",
+				fields.TypeString,
+				fields.WithHelp("Filter by conventional-commit scope (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"issue",
+				fields.TypeString,
+				fields.WithHelp("Filter by referenced issue, e.g. #123 or ABC-456 (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"breaking-only",
+				fields.TypeBool,
+				fields.WithHelp("Only include breaking changes"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListCommitsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListCommitsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListCommitsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListCommitMessages(ctx, refactorindex.CommitMessageFilter{
+		RunID:        settings.RunID,
+		Type:         settings.Type,
+		Scope:        settings.Scope,
+		Issue:        settings.Issue,
+		BreakingOnly: settings.BreakingOnly,
+		Limit:        settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := gp.AddRow(ctx, commitMessageRow(record)); err != nil {
+			return errors.Wrap(err, "add commit row")
+		}
+	}
+
+	return nil
+}
+
+func commitMessageRow(record refactorindex.CommitMessageRecord) types.Row {
+	return types.NewRow(
+		types.MRP("run_id", record.RunID),
+		types.MRP("hash", record.Hash),
+		types.MRP("author", record.AuthorName),
+		types.MRP("author_date", record.AuthorDate),
+		types.MRP("subject", record.Subject),
+		types.MRP("type", record.Type),
+		types.MRP("scope", record.Scope),
+		types.MRP("is_breaking", record.IsBreaking),
+		types.MRP("description", record.Description),
+		types.MRP("issues", record.Issues),
+		types.MRP("trailers", record.Trailers),
+	)
+}
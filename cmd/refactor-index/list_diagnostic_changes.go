@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListDiagnosticChangesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListDiagnosticChangesSettings struct {
+	DBPath    string `glazed:"db"`
+	BaseRunID int64  `glazed:"base-run-id"`
+	HeadRunID int64  `glazed:"head-run-id"`
+	Analyzer  string `glazed:"analyzer"`
+}
+
+var _ cmds.GlazeCommand = &ListDiagnosticChangesCommand{}
+
+func NewListDiagnosticChangesCommand() (*ListDiagnosticChangesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"diagnostic-changes",
+		cmds.WithShort("List diagnostics introduced or fixed between two runs"),
+		cmds.WithLong("Compare two diagnostics runs and list the diagnostics only present in the head run (introduced) or only in the base run (fixed). Diagnostics are matched by analyzer, file, enclosing code unit and message, not by line."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"base-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Diagnostics run before the change"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"head-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Diagnostics run after the change"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"analyzer",
+				fields.TypeString,
+				fields.WithHelp("Filter by analyzer name (optional)"),
+				fields.WithDefault(""),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListDiagnosticChangesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListDiagnosticChangesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListDiagnosticChangesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListDiagnosticChanges(ctx, refactorindex.DiagnosticChangeFilter{
+		BaseRunID: settings.BaseRunID,
+		HeadRunID: settings.HeadRunID,
+		Analyzer:  settings.Analyzer,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("change", record.Change),
+			types.MRP("analyzer", record.Analyzer),
+			types.MRP("path", record.Path),
+			types.MRP("code_unit", record.CodeUnit),
+			types.MRP("message", record.Message),
+			types.MRP("count", record.Count),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add diagnostic change row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListDiagnosticCountsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListDiagnosticCountsSettings struct {
+	DBPath   string `glazed:"db"`
+	RunIDs   []int  `glazed:"run-id"`
+	Analyzer string `glazed:"analyzer"`
+}
+
+var _ cmds.GlazeCommand = &ListDiagnosticCountsCommand{}
+
+func NewListDiagnosticCountsCommand() (*ListDiagnosticCountsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"diagnostic-counts",
+		cmds.WithShort("Count diagnostics per run and analyzer"),
+		cmds.WithLong("Count ingested diagnostics per diagnostics run and analyzer, with the commit each run analyzed, to track warnings across a range."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeIntegerList,
+				fields.WithHelp("Diagnostics run ids to include (default: all)"),
+				fields.WithDefault([]int{}),
+			),
+			fields.New(
+				"analyzer",
+				fields.TypeString,
+				fields.WithHelp("Filter by analyzer name (optional)"),
+				fields.WithDefault(""),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListDiagnosticCountsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListDiagnosticCountsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListDiagnosticCountsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	runIDs := make([]int64, 0, len(settings.RunIDs))
+	for _, id := range settings.RunIDs {
+		runIDs = append(runIDs, int64(id))
+	}
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListDiagnosticCounts(ctx, refactorindex.DiagnosticCountFilter{
+		RunIDs:    runIDs,
+		Analyzer:  settings.Analyzer,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("analyzer", record.Analyzer),
+			types.MRP("count", record.Count),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add diagnostic count row")
+		}
+	}
+
+	return nil
+}
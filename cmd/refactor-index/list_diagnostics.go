@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListDiagnosticsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListDiagnosticsSettings struct {
+	DBPath   string `glazed:"db"`
+	RunID    int64  `glazed:"run-id"`
+	Analyzer string `glazed:"analyzer"`
+	Path     string `glazed:"path"`
+	Limit    int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListDiagnosticsCommand{}
+
+func NewListDiagnosticsCommand() (*ListDiagnosticsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"diagnostics",
+		cmds.WithShort("List analyzer diagnostics"),
+		cmds.WithLong("Query ingested go/analysis diagnostics with their span, enclosing code unit and number of suggested fixes."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by diagnostics run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"analyzer",
+				fields.TypeString,
+				fields.WithHelp("Filter by analyzer name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListDiagnosticsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListDiagnosticsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListDiagnosticsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListDiagnostics(ctx, refactorindex.DiagnosticFilter{
+		RunID:     settings.RunID,
+		Analyzer:  settings.Analyzer,
+		Path:      settings.Path,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("analyzer", record.Analyzer),
+			types.MRP("category", record.Category),
+			types.MRP("path", record.Path),
+			types.MRP("start_line", record.StartLine),
+			types.MRP("start_col", record.StartCol),
+			types.MRP("end_line", record.EndLine),
+			types.MRP("end_col", record.EndCol),
+			types.MRP("code_unit", record.CodeUnit),
+			types.MRP("message", record.Message),
+			types.MRP("fixes", record.Fixes),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add diagnostic row")
+		}
+	}
+
+	return nil
+}
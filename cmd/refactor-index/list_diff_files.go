@@ -19,8 +19,11 @@ type ListDiffFilesCommand struct {
 }
 
 type ListDiffFilesSettings struct {
-	DBPath string `glazed:"db"`
-	RunID  int64  `glazed:"run-id"`
+	DBPath         string   `glazed:"db"`
+	RunID          int64    `glazed:"run-id"`
+	ChangeClasses  []string `glazed:"change-class"`
+	ExcludeClasses []string `glazed:"exclude-class"`
+	Owner          string   `glazed:"owner"`
 }
 
 var _ cmds.GlazeCommand = &ListDiffFilesCommand{}
@@ -43,7 +46,24 @@ func NewListDiffFilesCommand() (*ListDiffFilesCommand, error) {
 				fields.WithHelp("Filter by a specific run id (optional)"),
 				fields.WithDefault(0),
 			),
+			fields.New(
+				"change-class",
+				fields.TypeStringList,
+				fields.WithHelp("Only include these change classes (none, binary, whitespace, comment, imports, code)"),
+			),
+			fields.New(
+				"exclude-class",
+				fields.TypeStringList,
+				fields.WithHelp("Exclude these change classes, e.g. whitespace,comment,imports"),
+			),
+			fields.New(
+				"owner",
+				fields.TypeString,
+				fields.WithHelp("Only include files owned by this CODEOWNERS owner, e.g. @org/team (optional)"),
+				fields.WithDefault(""),
+			),
 		),
+		cmds.WithFlags(fileScopeFlags()...),
 	)
 
 	return &ListDiffFilesCommand{CommandDescription: cmdDesc}, nil
@@ -58,6 +78,10 @@ func (c *ListDiffFilesCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
 
 	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
 	if err != nil {
@@ -68,13 +92,19 @@ func (c *ListDiffFilesCommand) RunIntoGlazeProcessor(
 	}()
 
 	store := refactorindex.NewStore(db)
-	records, err := store.ListDiffFiles(ctx, settings.RunID)
+	records, err := store.ListDiffFiles(ctx, refactorindex.DiffFileFilter{
+		RunID:          settings.RunID,
+		ChangeClasses:  settings.ChangeClasses,
+		ExcludeClasses: settings.ExcludeClasses,
+		Owner:          settings.Owner,
+		FileScope:      scopeSettings.scope(),
+	})
 	if err != nil {
 		return err
 	}
 
 	for _, record := range records {
-		if err := gp.AddRow(ctx, diffFileRow(record.RunID, record.Status, record.Path, record.OldPath, record.NewPath)); err != nil {
+		if err := gp.AddRow(ctx, diffFileRow(record)); err != nil {
 			return errors.Wrap(err, "add diff file row")
 		}
 	}
@@ -82,12 +112,19 @@ func (c *ListDiffFilesCommand) RunIntoGlazeProcessor(
 	return nil
 }
 
-func diffFileRow(runID int64, status string, path string, oldPath string, newPath string) types.Row {
+func diffFileRow(record refactorindex.DiffFileRecord) types.Row {
 	return types.NewRow(
-		types.MRP("run_id", runID),
-		types.MRP("status", status),
-		types.MRP("path", path),
-		types.MRP("old_path", oldPath),
-		types.MRP("new_path", newPath),
+		types.MRP("run_id", record.RunID),
+		types.MRP("status", record.Status),
+		types.MRP("path", record.Path),
+		types.MRP("old_path", record.OldPath),
+		types.MRP("new_path", record.NewPath),
+		types.MRP("insertions", record.Insertions),
+		types.MRP("deletions", record.Deletions),
+		types.MRP("similarity", record.Similarity),
+		types.MRP("is_binary", record.IsBinary),
+		types.MRP("change_class", record.ChangeClass),
+		types.MRP("moved_insertions", record.MovedInsertions),
+		types.MRP("moved_deletions", record.MovedDeletions),
 	)
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListDirectivesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListDirectivesSettings struct {
+	DBPath string `glazed:"db"`
+	RunID  int64  `glazed:"run-id"`
+	Name   string `glazed:"name"`
+	Path   string `glazed:"path"`
+	Broken bool   `glazed:"broken"`
+	Limit  int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListDirectivesCommand{}
+
+func NewListDirectivesCommand() (*ListDirectivesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"directives",
+		cmds.WithShort("List compiler and tool directives"),
+		cmds.WithLong("List the directives recorded by ingest directives with their arguments, attached declaration, the files matched by //go:embed patterns, and the problem for directives that reference missing files or symbols."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by directives run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by directive name, e.g. go:embed or nolint (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"broken",
+				fields.TypeBool,
+				fields.WithHelp("Only list directives that reference missing files or symbols"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListDirectivesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListDirectivesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListDirectivesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListDirectives(ctx, refactorindex.DirectiveFilter{
+		RunID:     settings.RunID,
+		Name:      settings.Name,
+		Path:      settings.Path,
+		Broken:    settings.Broken,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		var embeds []string
+		for _, embed := range record.Embeds {
+			if embed.Path != "" {
+				embeds = append(embeds, embed.Path)
+			}
+		}
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("name", record.Name),
+			types.MRP("args", record.Args),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("decl", record.Decl),
+			types.MRP("decl_kind", record.DeclKind),
+			types.MRP("embeds", strings.Join(embeds, " ")),
+			types.MRP("problem", record.Problem),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add directive row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListErrorMessagesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListErrorMessagesSettings struct {
+	DBPath   string `glazed:"db"`
+	RunID    int64  `glazed:"run-id"`
+	MinCount int    `glazed:"min-count"`
+	Limit    int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListErrorMessagesCommand{}
+
+func NewListErrorMessagesCommand() (*ListErrorMessagesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"error-messages",
+		cmds.WithShort("Count error messages to spot duplicates"),
+		cmds.WithLong("Count the error-creating and wrapping sites per constant message, most used first. The default --min-count 2 lists duplicated messages only."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by errors run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"min-count",
+				fields.TypeInteger,
+				fields.WithHelp("Only list messages used at least this many times"),
+				fields.WithDefault(2),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListErrorMessagesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListErrorMessagesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListErrorMessagesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListErrorMessages(ctx, refactorindex.ErrorMessageFilter{
+		RunID:     settings.RunID,
+		MinCount:  settings.MinCount,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("message", record.Message),
+			types.MRP("sites", record.Sites),
+			types.MRP("files", record.Files),
+			types.MRP("code_units", record.CodeUnits),
+			types.MRP("callees", record.Callees),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add error message row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListErrorSitesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListErrorSitesSettings struct {
+	DBPath  string `glazed:"db"`
+	RunID   int64  `glazed:"run-id"`
+	Kind    string `glazed:"kind"`
+	Callee  string `glazed:"callee"`
+	Message string `glazed:"message"`
+	Path    string `glazed:"path"`
+	Limit   int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListErrorSitesCommand{}
+
+func NewListErrorSitesCommand() (*ListErrorSitesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"error-sites",
+		cmds.WithShort("List error-creating, sentinel and ignored-error sites"),
+		cmds.WithLong("List the sites recorded by ingest errors with their kind (new, wrap, errorf, sentinel, discarded, unchecked), callee, message and enclosing code unit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by errors run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"kind",
+				fields.TypeString,
+				fields.WithHelp("Filter by kind: new, wrap, errorf, sentinel, discarded or unchecked (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"callee",
+				fields.TypeString,
+				fields.WithHelp("Filter by fully qualified callee, e.g. github.com/pkg/errors.Wrap (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"message",
+				fields.TypeString,
+				fields.WithHelp("Filter by exact message or format (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListErrorSitesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListErrorSitesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListErrorSitesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListErrorSites(ctx, refactorindex.ErrorSiteFilter{
+		RunID:     settings.RunID,
+		Kind:      settings.Kind,
+		Callee:    settings.Callee,
+		Message:   settings.Message,
+		Path:      settings.Path,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("kind", record.Kind),
+			types.MRP("callee", record.Callee),
+			types.MRP("name", record.Name),
+			types.MRP("message", record.Message),
+			types.MRP("wraps", record.Wraps),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("code_unit", record.CodeUnit),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add error site row")
+		}
+	}
+
+	return nil
+}
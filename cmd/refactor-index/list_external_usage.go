@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListExternalUsageCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListExternalUsageSettings struct {
+	DBPath    string `glazed:"db"`
+	RunIDs    []int  `glazed:"run-id"`
+	Package   string `glazed:"package"`
+	Module    string `glazed:"module"`
+	ByPackage bool   `glazed:"by-package"`
+}
+
+var _ cmds.GlazeCommand = &ListExternalUsageCommand{}
+
+func NewListExternalUsageCommand() (*ListExternalUsageCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"external-usage",
+		cmds.WithShort("Count external API uses per package and symbol"),
+		cmds.WithLong("Count recorded external uses per run and external package and symbol (or per package with --by-package), with the number of files and code units using each, to estimate upgrade impact and track it across a range."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeIntegerList,
+				fields.WithHelp("External-uses run ids to include (default: all)"),
+				fields.WithDefault([]int{}),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by external package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"module",
+				fields.TypeString,
+				fields.WithHelp("Filter by required module path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"by-package",
+				fields.TypeBool,
+				fields.WithHelp("Count per package instead of per symbol"),
+				fields.WithDefault(false),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListExternalUsageCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListExternalUsageCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListExternalUsageSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	runIDs := make([]int64, 0, len(settings.RunIDs))
+	for _, id := range settings.RunIDs {
+		runIDs = append(runIDs, int64(id))
+	}
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListExternalUsage(ctx, refactorindex.ExternalUsageFilter{
+		RunIDs:    runIDs,
+		Package:   settings.Package,
+		Module:    settings.Module,
+		ByPackage: settings.ByPackage,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("module", record.Module),
+			types.MRP("recv", record.Recv),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("uses", record.Uses),
+			types.MRP("files", record.Files),
+			types.MRP("code_units", record.CodeUnits),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add external usage row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListExternalUsesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListExternalUsesSettings struct {
+	DBPath  string `glazed:"db"`
+	RunID   int64  `glazed:"run-id"`
+	Package string `glazed:"package"`
+	Name    string `glazed:"name"`
+	Path    string `glazed:"path"`
+	Limit   int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListExternalUsesCommand{}
+
+func NewListExternalUsesCommand() (*ListExternalUsesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"external-uses",
+		cmds.WithShort("List uses of external package identifiers"),
+		cmds.WithLong("List each recorded use of an external identifier with its position and enclosing code unit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by external-uses run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by external package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by identifier name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListExternalUsesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListExternalUsesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListExternalUsesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListExternalUses(ctx, refactorindex.ExternalUseFilter{
+		RunID:     settings.RunID,
+		Package:   settings.Package,
+		Name:      settings.Name,
+		Path:      settings.Path,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("module", record.Module),
+			types.MRP("recv", record.Recv),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("code_unit", record.CodeUnit),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add external use row")
+		}
+	}
+
+	return nil
+}
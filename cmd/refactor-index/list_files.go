@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListFilesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListFilesSettings struct {
+	DBPath   string `glazed:"db"`
+	Path     string `glazed:"path"`
+	Language string `glazed:"language"`
+	TestOnly bool   `glazed:"test-only"`
+	Limit    int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListFilesCommand{}
+
+func NewListFilesCommand() (*ListFilesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"files",
+		cmds.WithShort("List indexed files and their classification"),
+		cmds.WithLong("List indexed files with the extension, language, size and generated, test, vendored and binary flags recorded by ingest files."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"language",
+				fields.TypeString,
+				fields.WithHelp("Filter by language, e.g. Go or Markdown (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"test-only",
+				fields.TypeBool,
+				fields.WithHelp("Only list test files and test data"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListFilesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListFilesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListFilesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListFiles(ctx, refactorindex.FileFilter{
+		Path:      settings.Path,
+		Language:  settings.Language,
+		TestOnly:  settings.TestOnly,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("path", record.Path),
+			types.MRP("classified", record.Classified),
+			types.MRP("ext", record.Ext),
+			types.MRP("language", record.Language),
+			types.MRP("exists", record.Exists),
+			types.MRP("size", record.Size),
+			types.MRP("binary", record.Binary),
+			types.MRP("generated", record.Generated),
+			types.MRP("test", record.Test),
+			types.MRP("vendored", record.Vendored),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add file row")
+		}
+	}
+
+	return nil
+}
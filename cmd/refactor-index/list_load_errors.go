@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListLoadErrorsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListLoadErrorsSettings struct {
+	DBPath string `glazed:"db"`
+	RunID  int64  `glazed:"run-id"`
+	Kind   string `glazed:"kind"`
+	Limit  int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListLoadErrorsCommand{}
+
+func NewListLoadErrorsCommand() (*ListLoadErrorsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"load-errors",
+		cmds.WithShort("List package load errors"),
+		cmds.WithLong("Query the go/packages list, parse and type errors recorded by tolerant symbol, code unit and diagnostics runs."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"kind",
+				fields.TypeString,
+				fields.WithHelp("Filter by error kind: list, parse, type or unknown (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListLoadErrorsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListLoadErrorsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListLoadErrorsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListLoadErrors(ctx, refactorindex.LoadErrorFilter{
+		RunID: settings.RunID,
+		Kind:  settings.Kind,
+		Limit: settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("kind", record.Kind),
+			types.MRP("message", record.Message),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add load error row")
+		}
+	}
+
+	return nil
+}
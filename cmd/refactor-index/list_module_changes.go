@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListModuleChangesCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListModuleChangesSettings struct {
+	DBPath    string `glazed:"db"`
+	BaseRunID int64  `glazed:"base-run-id"`
+	HeadRunID int64  `glazed:"head-run-id"`
+	Module    string `glazed:"module"`
+}
+
+var _ cmds.GlazeCommand = &ListModuleChangesCommand{}
+
+func NewListModuleChangesCommand() (*ListModuleChangesCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"module-changes",
+		cmds.WithShort("List dependency changes between two modules runs"),
+		cmds.WithLong("Compare the go.mod requirements of two modules runs and list the modules added, removed, upgraded or downgraded, per go.mod file."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"base-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Modules run before the change"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"head-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Modules run after the change"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"module",
+				fields.TypeString,
+				fields.WithHelp("Filter by required module path (optional)"),
+				fields.WithDefault(""),
+			),
+		),
+	)
+
+	return &ListModuleChangesCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListModuleChangesCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListModuleChangesSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListModuleChanges(ctx, refactorindex.ModuleChangeFilter{
+		BaseRunID: settings.BaseRunID,
+		HeadRunID: settings.HeadRunID,
+		Module:    settings.Module,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("change", record.Change),
+			types.MRP("mod_file", record.ModFile),
+			types.MRP("module", record.Module),
+			types.MRP("old_version", record.OldVersion),
+			types.MRP("new_version", record.NewVersion),
+			types.MRP("indirect", record.Indirect),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add module change row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListModuleRequiresCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListModuleRequiresSettings struct {
+	DBPath     string `glazed:"db"`
+	RunID      int64  `glazed:"run-id"`
+	Module     string `glazed:"module"`
+	DirectOnly bool   `glazed:"direct-only"`
+	Limit      int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListModuleRequiresCommand{}
+
+func NewListModuleRequiresCommand() (*ListModuleRequiresCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"module-requires",
+		cmds.WithShort("List go.mod requirements"),
+		cmds.WithLong("List the require lines of ingested go.mod files, with the replace directive that applies to each."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by modules run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"module",
+				fields.TypeString,
+				fields.WithHelp("Filter by required module path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"direct-only",
+				fields.TypeBool,
+				fields.WithHelp("Skip // indirect requirements"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListModuleRequiresCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListModuleRequiresCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListModuleRequiresSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListModuleRequires(ctx, refactorindex.ModuleRequireFilter{
+		RunID:      settings.RunID,
+		Module:     settings.Module,
+		DirectOnly: settings.DirectOnly,
+		Limit:      settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("mod_file", record.ModFile),
+			types.MRP("main_module", record.MainModule),
+			types.MRP("module", record.Module),
+			types.MRP("version", record.Version),
+			types.MRP("indirect", record.Indirect),
+			types.MRP("replacement", record.Replacement),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add module require row")
+		}
+	}
+
+	return nil
+}
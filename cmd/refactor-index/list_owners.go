@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListOwnersCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListOwnersSettings struct {
+	DBPath string `glazed:"db"`
+	RunID  int64  `glazed:"run-id"`
+	Owner  string `glazed:"owner"`
+	Path   string `glazed:"path"`
+	Limit  int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListOwnersCommand{}
+
+func NewListOwnersCommand() (*ListOwnersCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"owners",
+		cmds.WithShort("List file owners from CODEOWNERS"),
+		cmds.WithLong("Query the owners computed for each file, with the CODEOWNERS rule that assigned them."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Owners run id (default: latest)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"owner",
+				fields.TypeString,
+				fields.WithHelp("Filter by owner (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListOwnersCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListOwnersCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListOwnersSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListFileOwners(ctx, refactorindex.FileOwnerFilter{
+		RunID:     settings.RunID,
+		Owner:     settings.Owner,
+		Path:      settings.Path,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("path", record.Path),
+			types.MRP("owner", record.Owner),
+			types.MRP("pattern", record.Pattern),
+			types.MRP("line", record.Line),
+			types.MRP("section", record.Section),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add file owner row")
+		}
+	}
+
+	return nil
+}
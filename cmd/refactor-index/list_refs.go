@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListRefsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListRefsSettings struct {
+	DBPath string `glazed:"db"`
+	RunID  int64  `glazed:"run-id"`
+	Kind   string `glazed:"kind"`
+	Name   string `glazed:"name"`
+	Commit string `glazed:"commit"`
+	Limit  int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListRefsCommand{}
+
+func NewListRefsCommand() (*ListRefsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"refs",
+		cmds.WithShort("List tags and branches stored in the index"),
+		cmds.WithLong("Query ingested refs, or look up the first tag containing a commit with --commit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by a specific run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"kind",
+				fields.TypeString,
+				fields.WithHelp("Filter by ref kind: tag or branch (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by ref name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"commit",
+				fields.TypeString,
+				fields.WithHelp("Return the first tag containing this commit hash (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListRefsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListRefsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListRefsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	if settings.Commit != "" {
+		record, err := store.GetFirstTagContainingCommit(ctx, settings.Commit)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return nil
+		}
+		if err := gp.AddRow(ctx, refRow(*record)); err != nil {
+			return errors.Wrap(err, "add ref row")
+		}
+		return nil
+	}
+
+	records, err := store.ListRefs(ctx, refactorindex.RefFilter{
+		RunID: settings.RunID,
+		Kind:  settings.Kind,
+		Name:  settings.Name,
+		Limit: settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := gp.AddRow(ctx, refRow(record)); err != nil {
+			return errors.Wrap(err, "add ref row")
+		}
+	}
+
+	return nil
+}
+
+func refRow(record refactorindex.RefRecord) types.Row {
+	return types.NewRow(
+		types.MRP("run_id", record.RunID),
+		types.MRP("name", record.ShortName),
+		types.MRP("ref", record.Name),
+		types.MRP("kind", record.Kind),
+		types.MRP("target_hash", record.TargetHash),
+		types.MRP("is_annotated", record.IsAnnotated),
+		types.MRP("tagger_name", record.TaggerName),
+		types.MRP("tagger_email", record.TaggerEmail),
+		types.MRP("tagger_date", record.TaggerDate),
+		types.MRP("ref_date", record.RefDate),
+		types.MRP("semver", record.Semver),
+		types.MRP("message", record.Message),
+		types.MRP("commit_id", record.CommitID),
+	)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListRemovedAuthorsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListRemovedAuthorsSettings struct {
+	DBPath     string `glazed:"db"`
+	BlameRunID int64  `glazed:"blame-run-id"`
+	DiffRunID  int64  `glazed:"diff-run-id"`
+	Path       string `glazed:"path"`
+	Limit      int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListRemovedAuthorsCommand{}
+
+func NewListRemovedAuthorsCommand() (*ListRemovedAuthorsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"removed-authors",
+		cmds.WithShort("List whose lines a diff removes"),
+		cmds.WithLong("Join the removed lines of a diff run with a blame run taken at the diff's from commit, grouped by file and author."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"blame-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the blame ingestion at the diff's from commit"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"diff-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the diff ingestion"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"path",
+				fields.TypeString,
+				fields.WithHelp("Filter by file path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListRemovedAuthorsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListRemovedAuthorsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListRemovedAuthorsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListRemovedLineAuthors(ctx, refactorindex.RemovedLineAuthorFilter{
+		BlameRunID: settings.BlameRunID,
+		DiffRunID:  settings.DiffRunID,
+		Path:       settings.Path,
+		Limit:      settings.Limit,
+		FileScope:  scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("path", record.Path),
+			types.MRP("author_name", record.AuthorName),
+			types.MRP("author_email", record.AuthorEmail),
+			types.MRP("removed_lines", record.RemovedLines),
+			types.MRP("commits", record.Commits),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add removed author row")
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/fields"
@@ -20,14 +21,18 @@ type ListSymbolsCommand struct {
 }
 
 type ListSymbolsSettings struct {
-	DBPath       string `glazed:"db"`
-	RunID        int64  `glazed:"run-id"`
-	ExportedOnly bool   `glazed:"exported-only"`
-	Kind         string `glazed:"kind"`
-	Name         string `glazed:"name"`
-	Pkg          string `glazed:"pkg"`
-	Path         string `glazed:"path"`
-	Limit        int    `glazed:"limit"`
+	DBPath           string `glazed:"db"`
+	RunID            int64  `glazed:"run-id"`
+	ExportedOnly     bool   `glazed:"exported-only"`
+	Kind             string `glazed:"kind"`
+	Name             string `glazed:"name"`
+	Pkg              string `glazed:"pkg"`
+	Path             string `glazed:"path"`
+	Owner            string `glazed:"owner"`
+	Module           string `glazed:"module"`
+	BuildConfig      string `glazed:"build-config"`
+	PlatformSpecific bool   `glazed:"platform-specific"`
+	Limit            int    `glazed:"limit"`
 }
 
 var _ cmds.GlazeCommand = &ListSymbolsCommand{}
@@ -80,6 +85,30 @@ func NewListSymbolsCommand() (*ListSymbolsCommand, error) {
 				fields.WithHelp("Filter by file path (optional)"),
 				fields.WithDefault(""),
 			),
+			fields.New(
+				"owner",
+				fields.TypeString,
+				fields.WithHelp("Only include files owned by this CODEOWNERS owner, e.g. @org/team (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"module",
+				fields.TypeString,
+				fields.WithHelp("Filter by Go module path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"build-config",
+				fields.TypeString,
+				fields.WithHelp("Only include occurrences seen under this matrix configuration, e.g. windows/amd64 (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"platform-specific",
+				fields.TypeBool,
+				fields.WithHelp("Only include occurrences missing from some of the run's matrix configurations"),
+				fields.WithDefault(false),
+			),
 			fields.New(
 				"limit",
 				fields.TypeInteger,
@@ -87,6 +116,7 @@ func NewListSymbolsCommand() (*ListSymbolsCommand, error) {
 				fields.WithDefault(0),
 			),
 		),
+		cmds.WithFlags(fileScopeFlags()...),
 	)
 
 	return &ListSymbolsCommand{CommandDescription: cmdDesc}, nil
@@ -101,6 +131,10 @@ func (c *ListSymbolsCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
 
 	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
 	if err != nil {
@@ -112,13 +146,18 @@ func (c *ListSymbolsCommand) RunIntoGlazeProcessor(
 
 	store := refactorindex.NewStore(db)
 	records, err := store.ListSymbolInventory(ctx, refactorindex.SymbolInventoryFilter{
-		RunID:        settings.RunID,
-		ExportedOnly: settings.ExportedOnly,
-		Kind:         settings.Kind,
-		Name:         settings.Name,
-		Pkg:          settings.Pkg,
-		Path:         settings.Path,
-		Limit:        settings.Limit,
+		RunID:            settings.RunID,
+		ExportedOnly:     settings.ExportedOnly,
+		Kind:             settings.Kind,
+		Name:             settings.Name,
+		Pkg:              settings.Pkg,
+		Path:             settings.Path,
+		Owner:            settings.Owner,
+		Module:           settings.Module,
+		BuildConfig:      settings.BuildConfig,
+		PlatformSpecific: settings.PlatformSpecific,
+		Limit:            settings.Limit,
+		FileScope:        scopeSettings.scope(),
 	})
 	if err != nil {
 		return err
@@ -141,12 +180,14 @@ func symbolInventoryRow(record refactorindex.SymbolInventoryRecord) types.Row {
 		types.MRP("name", record.Name),
 		types.MRP("kind", record.Kind),
 		types.MRP("pkg", record.Pkg),
+		types.MRP("module", record.Module),
 		types.MRP("recv", record.Recv),
 		types.MRP("signature", record.Signature),
 		types.MRP("file", record.FilePath),
 		types.MRP("line", record.Line),
 		types.MRP("col", record.Col),
 		types.MRP("is_exported", record.IsExported),
+		types.MRP("build_configs", strings.Join(record.BuildConfigs, ",")),
 		types.MRP("target_spec", targetSpec),
 	)
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListTestResultsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListTestResultsSettings struct {
+	DBPath          string `glazed:"db"`
+	RunID           int64  `glazed:"run-id"`
+	Package         string `glazed:"package"`
+	Test            string `glazed:"test"`
+	Status          string `glazed:"status"`
+	IncludePackages bool   `glazed:"include-packages"`
+	WithOutput      bool   `glazed:"with-output"`
+	Limit           int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListTestResultsCommand{}
+
+func NewListTestResultsCommand() (*ListTestResultsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"test-results",
+		cmds.WithShort("List go test results"),
+		cmds.WithLong("Query ingested go test results with the commit they ran at, e.g. --status fail to find regressions across a range."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by tests run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"test",
+				fields.TypeString,
+				fields.WithHelp("Filter by top-level test name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"status",
+				fields.TypeString,
+				fields.WithHelp("Filter by status: pass, fail, skip or unknown (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"include-packages",
+				fields.TypeBool,
+				fields.WithHelp("Include package-level results"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"with-output",
+				fields.TypeBool,
+				fields.WithHelp("Include the output excerpt"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+	)
+
+	return &ListTestResultsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListTestResultsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListTestResultsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListTestResults(ctx, refactorindex.TestResultFilter{
+		RunID:           settings.RunID,
+		Package:         settings.Package,
+		Test:            settings.Test,
+		Status:          settings.Status,
+		IncludePackages: settings.IncludePackages,
+		Limit:           settings.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("test", record.Test),
+			types.MRP("subtest", record.Subtest),
+			types.MRP("status", record.Status),
+			types.MRP("elapsed", record.Elapsed),
+		)
+		if settings.WithOutput {
+			row.Set("output", record.Output)
+		}
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add test result row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListUnitAuthorsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListUnitAuthorsSettings struct {
+	DBPath         string `glazed:"db"`
+	BlameRunID     int64  `glazed:"blame-run-id"`
+	CodeUnitsRunID int64  `glazed:"code-units-run-id"`
+	Name           string `glazed:"name"`
+	Pkg            string `glazed:"pkg"`
+	Limit          int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListUnitAuthorsCommand{}
+
+func NewListUnitAuthorsCommand() (*ListUnitAuthorsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"unit-authors",
+		cmds.WithShort("List authorship shares per code unit"),
+		cmds.WithLong("Join a blame run with a code-unit run taken at the same commit and report how many lines of each code unit every author last changed."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"blame-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the blame ingestion"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"code-units-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Run id of the code-unit ingestion"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by code unit name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"pkg",
+				fields.TypeString,
+				fields.WithHelp("Filter by package path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListUnitAuthorsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListUnitAuthorsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListUnitAuthorsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListCodeUnitAuthors(ctx, refactorindex.CodeUnitAuthorFilter{
+		BlameRunID:     settings.BlameRunID,
+		CodeUnitsRunID: settings.CodeUnitsRunID,
+		Name:           settings.Name,
+		Pkg:            settings.Pkg,
+		Limit:          settings.Limit,
+		FileScope:      scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("pkg", record.Pkg),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("path", record.Path),
+			types.MRP("author_name", record.AuthorName),
+			types.MRP("author_email", record.AuthorEmail),
+			types.MRP("lines", record.Lines),
+			types.MRP("total_lines", record.TotalLines),
+			types.MRP("share", record.Share),
+			types.MRP("last_changed", record.LastChanged),
+			types.MRP("unit_hash", record.UnitHash),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add unit author row")
+		}
+	}
+
+	return nil
+}
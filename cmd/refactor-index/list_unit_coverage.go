@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListUnitCoverageCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListUnitCoverageSettings struct {
+	DBPath string  `glazed:"db"`
+	RunID  int64   `glazed:"run-id"`
+	Pkg    string  `glazed:"pkg"`
+	Name   string  `glazed:"name"`
+	Below  float64 `glazed:"below"`
+	Limit  int     `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListUnitCoverageCommand{}
+
+func NewListUnitCoverageCommand() (*ListUnitCoverageCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"unit-coverage",
+		cmds.WithShort("List statement coverage per code unit"),
+		cmds.WithLong("Query covered and total statements per code unit from a coverage run."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Coverage run id (default: latest)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"pkg",
+				fields.TypeString,
+				fields.WithHelp("Filter by package path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"name",
+				fields.TypeString,
+				fields.WithHelp("Filter by code unit name (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"below",
+				fields.TypeFloat,
+				fields.WithHelp("Only units with a covered fraction below this value, e.g. 0.5 (optional)"),
+				fields.WithDefault(0.0),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListUnitCoverageCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListUnitCoverageCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListUnitCoverageSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListCodeUnitCoverage(ctx, refactorindex.CodeUnitCoverageFilter{
+		RunID:     settings.RunID,
+		Pkg:       settings.Pkg,
+		Name:      settings.Name,
+		Below:     settings.Below,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		coverage := 0.0
+		if record.TotalStatements > 0 {
+			coverage = float64(record.CoveredStatements) / float64(record.TotalStatements)
+		}
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("pkg", record.Pkg),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("recv", record.Recv),
+			types.MRP("path", record.Path),
+			types.MRP("start_line", record.StartLine),
+			types.MRP("end_line", record.EndLine),
+			types.MRP("covered_statements", record.CoveredStatements),
+			types.MRP("total_statements", record.TotalStatements),
+			types.MRP("coverage", coverage),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add unit coverage row")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListUpgradeImpactCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListUpgradeImpactSettings struct {
+	DBPath    string `glazed:"db"`
+	APIRunID  int64  `glazed:"api-run-id"`
+	UsesRunID int64  `glazed:"uses-run-id"`
+	Package   string `glazed:"package"`
+	Limit     int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListUpgradeImpactCommand{}
+
+func NewListUpgradeImpactCommand() (*ListUpgradeImpactCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"upgrade-impact",
+		cmds.WithShort("List external uses that break after a dependency upgrade"),
+		cmds.WithLong("Join the removed and changed symbols of an api-diff run against recorded external uses and list each affected call site with its enclosing code unit."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"api-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("api-diff run id"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"uses-run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by external-uses run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"package",
+				fields.TypeString,
+				fields.WithHelp("Filter by package import path (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListUpgradeImpactCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListUpgradeImpactCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListUpgradeImpactSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListUpgradeImpact(ctx, refactorindex.UpgradeImpactFilter{
+		APIRunID:  settings.APIRunID,
+		UsesRunID: settings.UsesRunID,
+		Package:   settings.Package,
+		Limit:     settings.Limit,
+		FileScope: scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("uses_run_id", record.UsesRunID),
+			types.MRP("commit_hash", record.CommitHash),
+			types.MRP("package", record.Package),
+			types.MRP("recv", record.Recv),
+			types.MRP("name", record.Name),
+			types.MRP("kind", record.Kind),
+			types.MRP("change", record.Change),
+			types.MRP("old_signature", record.OldSignature),
+			types.MRP("new_signature", record.NewSignature),
+			types.MRP("path", record.Path),
+			types.MRP("line", record.Line),
+			types.MRP("col", record.Col),
+			types.MRP("code_unit", record.CodeUnit),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add upgrade impact row")
+		}
+	}
+
+	return nil
+}
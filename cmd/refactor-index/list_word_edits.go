@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/refactorio/pkg/refactorindex"
+)
+
+type ListWordEditsCommand struct {
+	*cmds.CommandDescription
+}
+
+type ListWordEditsSettings struct {
+	DBPath     string `glazed:"db"`
+	RunID      int64  `glazed:"run-id"`
+	OldText    string `glazed:"old"`
+	NewText    string `glazed:"new"`
+	SingleEdit bool   `glazed:"only"`
+	Limit      int    `glazed:"limit"`
+}
+
+var _ cmds.GlazeCommand = &ListWordEditsCommand{}
+
+func NewListWordEditsCommand() (*ListWordEditsCommand, error) {
+	cmdDesc := cmds.NewCommandDescription(
+		"word-edits",
+		cmds.WithShort("List word-level edits between paired diff lines"),
+		cmds.WithLong("Query the token spans that changed between removed and added lines paired within a hunk. Use --old/--new with --only to find lines where only one identifier was renamed."),
+		cmds.WithFlags(
+			fields.New(
+				"db",
+				fields.TypeString,
+				fields.WithHelp("Path to the SQLite database"),
+				fields.WithRequired(true),
+			),
+			fields.New(
+				"run-id",
+				fields.TypeInteger,
+				fields.WithHelp("Filter by diff run id (optional)"),
+				fields.WithDefault(0),
+			),
+			fields.New(
+				"old",
+				fields.TypeString,
+				fields.WithHelp("Filter by replaced text (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"new",
+				fields.TypeString,
+				fields.WithHelp("Filter by replacement text (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"only",
+				fields.TypeBool,
+				fields.WithHelp("Only lines where this edit is the sole change"),
+				fields.WithDefault(false),
+			),
+			fields.New(
+				"limit",
+				fields.TypeInteger,
+				fields.WithHelp("Limit number of rows (optional)"),
+				fields.WithDefault(0),
+			),
+		),
+		cmds.WithFlags(fileScopeFlags()...),
+	)
+
+	return &ListWordEditsCommand{CommandDescription: cmdDesc}, nil
+}
+
+func (c *ListWordEditsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	vals *values.Values,
+	gp middlewares.Processor,
+) error {
+	settings := &ListWordEditsSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
+		return err
+	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
+
+	db, err := refactorindex.OpenDB(ctx, settings.DBPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := refactorindex.NewStore(db)
+	records, err := store.ListWordEdits(ctx, refactorindex.WordEditFilter{
+		RunID:      settings.RunID,
+		OldText:    settings.OldText,
+		NewText:    settings.NewText,
+		SingleEdit: settings.SingleEdit,
+		Limit:      settings.Limit,
+		FileScope:  scopeSettings.scope(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := types.NewRow(
+			types.MRP("run_id", record.RunID),
+			types.MRP("path", record.Path),
+			types.MRP("old_line", record.OldLine),
+			types.MRP("new_line", record.NewLine),
+			types.MRP("kind", record.Kind),
+			types.MRP("old_text", record.OldText),
+			types.MRP("new_text", record.NewText),
+			types.MRP("edit_count", record.EditCount),
+			types.MRP("similarity", record.Similarity),
+			types.MRP("old_source", record.OldSource),
+			types.MRP("new_source", record.NewSource),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return errors.Wrap(err, "add word edit row")
+		}
+	}
+
+	return nil
+}
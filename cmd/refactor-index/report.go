@@ -22,6 +22,13 @@ type ReportSettings struct {
 	DBPath    string `glazed:"db"`
 	RunID     int64  `glazed:"run-id"`
 	OutputDir string `glazed:"out"`
+	Owner     string `glazed:"owner"`
+
+	CoverageThreshold float64 `glazed:"coverage-threshold"`
+	BenchBase         string  `glazed:"bench-base"`
+	BenchHead         string  `glazed:"bench-head"`
+	RepoPath          string  `glazed:"repo"`
+	BenchThreshold    float64 `glazed:"bench-threshold"`
 }
 
 var _ cmds.GlazeCommand = &ReportCommand{}
@@ -50,7 +57,44 @@ func NewReportCommand() (*ReportCommand, error) {
 				fields.WithHelp("Directory to write reports"),
 				fields.WithDefault("reports"),
 			),
+			fields.New(
+				"owner",
+				fields.TypeString,
+				fields.WithHelp("Only include files owned by this CODEOWNERS owner, e.g. @org/team (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"coverage-threshold",
+				fields.TypeFloat,
+				fields.WithHelp("Covered-statement fraction below which touched units count as low coverage"),
+				fields.WithDefault(refactorindex.DefaultCoverageThreshold),
+			),
+			fields.New(
+				"bench-base",
+				fields.TypeString,
+				fields.WithHelp("Base commit, tag or branch for the benchmark deltas report (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"bench-head",
+				fields.TypeString,
+				fields.WithHelp("Head commit, tag or branch for the benchmark deltas report (optional)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"repo",
+				fields.TypeString,
+				fields.WithHelp("Git repository used to resolve --bench-base and --bench-head (default: ingested refs and commit hash prefixes)"),
+				fields.WithDefault(""),
+			),
+			fields.New(
+				"bench-threshold",
+				fields.TypeFloat,
+				fields.WithHelp("Relative change beyond which benchmark deltas are flagged, e.g. 0.05"),
+				fields.WithDefault(refactorindex.DefaultBenchThreshold),
+			),
 		),
+		cmds.WithFlags(fileScopeFlags()...),
 	)
 
 	return &ReportCommand{CommandDescription: cmdDesc}, nil
@@ -65,11 +109,23 @@ func (c *ReportCommand) RunIntoGlazeProcessor(
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, settings); err != nil {
 		return err
 	}
+	scopeSettings := &FileScopeSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, scopeSettings); err != nil {
+		return err
+	}
 
 	results, err := refactorindex.GenerateReports(ctx, refactorindex.ReportConfig{
 		DBPath:    settings.DBPath,
 		RunID:     settings.RunID,
 		OutputDir: settings.OutputDir,
+		Owner:     settings.Owner,
+
+		CoverageThreshold: settings.CoverageThreshold,
+		BenchBase:         settings.BenchBase,
+		BenchHead:         settings.BenchHead,
+		RepoPath:          settings.RepoPath,
+		BenchThreshold:    settings.BenchThreshold,
+		FileScope:         scopeSettings.scope(),
 	})
 	if err != nil {
 		return err
@@ -96,6 +96,166 @@ func NewRootCommand() (*cobra.Command, error) {
 	}
 	ingestCmd.AddCommand(cobraIngestGoplsRefsCmd)
 
+	ingestRefsCmd, err := NewIngestRefsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest refs command")
+	}
+	cobraIngestRefsCmd, err := cli.BuildCobraCommand(ingestRefsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest refs command")
+	}
+	ingestCmd.AddCommand(cobraIngestRefsCmd)
+
+	ingestPatchesCmd, err := NewIngestPatchesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest patches command")
+	}
+	cobraIngestPatchesCmd, err := cli.BuildCobraCommand(ingestPatchesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest patches command")
+	}
+	ingestCmd.AddCommand(cobraIngestPatchesCmd)
+
+	ingestHunkUnitsCmd, err := NewIngestHunkUnitsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest hunk-units command")
+	}
+	cobraIngestHunkUnitsCmd, err := cli.BuildCobraCommand(ingestHunkUnitsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest hunk-units command")
+	}
+	ingestCmd.AddCommand(cobraIngestHunkUnitsCmd)
+
+	ingestBlameCmd, err := NewIngestBlameCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest blame command")
+	}
+	cobraIngestBlameCmd, err := cli.BuildCobraCommand(ingestBlameCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest blame command")
+	}
+	ingestCmd.AddCommand(cobraIngestBlameCmd)
+
+	ingestOwnersCmd, err := NewIngestOwnersCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest owners command")
+	}
+	cobraIngestOwnersCmd, err := cli.BuildCobraCommand(ingestOwnersCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest owners command")
+	}
+	ingestCmd.AddCommand(cobraIngestOwnersCmd)
+
+	ingestTestsCmd, err := NewIngestTestsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest tests command")
+	}
+	cobraIngestTestsCmd, err := cli.BuildCobraCommand(ingestTestsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest tests command")
+	}
+	ingestCmd.AddCommand(cobraIngestTestsCmd)
+
+	ingestCoverageCmd, err := NewIngestCoverageCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest coverage command")
+	}
+	cobraIngestCoverageCmd, err := cli.BuildCobraCommand(ingestCoverageCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest coverage command")
+	}
+	ingestCmd.AddCommand(cobraIngestCoverageCmd)
+
+	ingestBenchmarksCmd, err := NewIngestBenchmarksCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest benchmarks command")
+	}
+	cobraIngestBenchmarksCmd, err := cli.BuildCobraCommand(ingestBenchmarksCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest benchmarks command")
+	}
+	ingestCmd.AddCommand(cobraIngestBenchmarksCmd)
+
+	ingestDiagnosticsCmd, err := NewIngestDiagnosticsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest diagnostics command")
+	}
+	cobraIngestDiagnosticsCmd, err := cli.BuildCobraCommand(ingestDiagnosticsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest diagnostics command")
+	}
+	ingestCmd.AddCommand(cobraIngestDiagnosticsCmd)
+
+	ingestModulesCmd, err := NewIngestModulesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest modules command")
+	}
+	cobraIngestModulesCmd, err := cli.BuildCobraCommand(ingestModulesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest modules command")
+	}
+	ingestCmd.AddCommand(cobraIngestModulesCmd)
+
+	ingestExternalUsesCmd, err := NewIngestExternalUsesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest external-uses command")
+	}
+	cobraIngestExternalUsesCmd, err := cli.BuildCobraCommand(ingestExternalUsesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest external-uses command")
+	}
+	ingestCmd.AddCommand(cobraIngestExternalUsesCmd)
+
+	ingestAPIDiffCmd, err := NewIngestAPIDiffCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest api-diff command")
+	}
+	cobraIngestAPIDiffCmd, err := cli.BuildCobraCommand(ingestAPIDiffCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest api-diff command")
+	}
+	ingestCmd.AddCommand(cobraIngestAPIDiffCmd)
+
+	ingestCallSitesCmd, err := NewIngestCallSitesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest call-sites command")
+	}
+	cobraIngestCallSitesCmd, err := cli.BuildCobraCommand(ingestCallSitesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest call-sites command")
+	}
+	ingestCmd.AddCommand(cobraIngestCallSitesCmd)
+
+	ingestErrorsCmd, err := NewIngestErrorsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest errors command")
+	}
+	cobraIngestErrorsCmd, err := cli.BuildCobraCommand(ingestErrorsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest errors command")
+	}
+	ingestCmd.AddCommand(cobraIngestErrorsCmd)
+
+	ingestDirectivesCmd, err := NewIngestDirectivesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest directives command")
+	}
+	cobraIngestDirectivesCmd, err := cli.BuildCobraCommand(ingestDirectivesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest directives command")
+	}
+	ingestCmd.AddCommand(cobraIngestDirectivesCmd)
+
+	ingestFilesCmd, err := NewIngestFilesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build ingest files command")
+	}
+	cobraIngestFilesCmd, err := cli.BuildCobraCommand(ingestFilesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire ingest files command")
+	}
+	ingestCmd.AddCommand(cobraIngestFilesCmd)
+
 	ingestRangeCmd, err := NewIngestRangeCommand()
 	if err != nil {
 		return nil, errors.Wrap(err, "build ingest range command")
@@ -130,6 +290,256 @@ func NewRootCommand() (*cobra.Command, error) {
 		return nil, errors.Wrap(err, "wire list symbols command")
 	}
 	listCmd.AddCommand(cobraListSymbolsCmd)
+
+	listCommitsCmd, err := NewListCommitsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list commits command")
+	}
+	cobraListCommitsCmd, err := cli.BuildCobraCommand(listCommitsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list commits command")
+	}
+	listCmd.AddCommand(cobraListCommitsCmd)
+
+	listRefsCmd, err := NewListRefsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list refs command")
+	}
+	cobraListRefsCmd, err := cli.BuildCobraCommand(listRefsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list refs command")
+	}
+	listCmd.AddCommand(cobraListRefsCmd)
+
+	listChangedUnitsCmd, err := NewListChangedUnitsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list changed-units command")
+	}
+	cobraListChangedUnitsCmd, err := cli.BuildCobraCommand(listChangedUnitsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list changed-units command")
+	}
+	listCmd.AddCommand(cobraListChangedUnitsCmd)
+
+	listWordEditsCmd, err := NewListWordEditsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list word-edits command")
+	}
+	cobraListWordEditsCmd, err := cli.BuildCobraCommand(listWordEditsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list word-edits command")
+	}
+	listCmd.AddCommand(cobraListWordEditsCmd)
+
+	listUnitAuthorsCmd, err := NewListUnitAuthorsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list unit-authors command")
+	}
+	cobraListUnitAuthorsCmd, err := cli.BuildCobraCommand(listUnitAuthorsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list unit-authors command")
+	}
+	listCmd.AddCommand(cobraListUnitAuthorsCmd)
+
+	listRemovedAuthorsCmd, err := NewListRemovedAuthorsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list removed-authors command")
+	}
+	cobraListRemovedAuthorsCmd, err := cli.BuildCobraCommand(listRemovedAuthorsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list removed-authors command")
+	}
+	listCmd.AddCommand(cobraListRemovedAuthorsCmd)
+
+	listOwnersCmd, err := NewListOwnersCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list owners command")
+	}
+	cobraListOwnersCmd, err := cli.BuildCobraCommand(listOwnersCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list owners command")
+	}
+	listCmd.AddCommand(cobraListOwnersCmd)
+
+	listTestResultsCmd, err := NewListTestResultsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list test-results command")
+	}
+	cobraListTestResultsCmd, err := cli.BuildCobraCommand(listTestResultsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list test-results command")
+	}
+	listCmd.AddCommand(cobraListTestResultsCmd)
+
+	listUnitCoverageCmd, err := NewListUnitCoverageCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list unit-coverage command")
+	}
+	cobraListUnitCoverageCmd, err := cli.BuildCobraCommand(listUnitCoverageCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list unit-coverage command")
+	}
+	listCmd.AddCommand(cobraListUnitCoverageCmd)
+
+	listBenchResultsCmd, err := NewListBenchResultsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list bench-results command")
+	}
+	cobraListBenchResultsCmd, err := cli.BuildCobraCommand(listBenchResultsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list bench-results command")
+	}
+	listCmd.AddCommand(cobraListBenchResultsCmd)
+
+	listDiagnosticsCmd, err := NewListDiagnosticsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list diagnostics command")
+	}
+	cobraListDiagnosticsCmd, err := cli.BuildCobraCommand(listDiagnosticsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list diagnostics command")
+	}
+	listCmd.AddCommand(cobraListDiagnosticsCmd)
+
+	listDiagnosticCountsCmd, err := NewListDiagnosticCountsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list diagnostic-counts command")
+	}
+	cobraListDiagnosticCountsCmd, err := cli.BuildCobraCommand(listDiagnosticCountsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list diagnostic-counts command")
+	}
+	listCmd.AddCommand(cobraListDiagnosticCountsCmd)
+
+	listDiagnosticChangesCmd, err := NewListDiagnosticChangesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list diagnostic-changes command")
+	}
+	cobraListDiagnosticChangesCmd, err := cli.BuildCobraCommand(listDiagnosticChangesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list diagnostic-changes command")
+	}
+	listCmd.AddCommand(cobraListDiagnosticChangesCmd)
+
+	listLoadErrorsCmd, err := NewListLoadErrorsCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list load-errors command")
+	}
+	cobraListLoadErrorsCmd, err := cli.BuildCobraCommand(listLoadErrorsCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list load-errors command")
+	}
+	listCmd.AddCommand(cobraListLoadErrorsCmd)
+
+	listModuleRequiresCmd, err := NewListModuleRequiresCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list module-requires command")
+	}
+	cobraListModuleRequiresCmd, err := cli.BuildCobraCommand(listModuleRequiresCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list module-requires command")
+	}
+	listCmd.AddCommand(cobraListModuleRequiresCmd)
+
+	listModuleChangesCmd, err := NewListModuleChangesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list module-changes command")
+	}
+	cobraListModuleChangesCmd, err := cli.BuildCobraCommand(listModuleChangesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list module-changes command")
+	}
+	listCmd.AddCommand(cobraListModuleChangesCmd)
+
+	listExternalUsesCmd, err := NewListExternalUsesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list external-uses command")
+	}
+	cobraListExternalUsesCmd, err := cli.BuildCobraCommand(listExternalUsesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list external-uses command")
+	}
+	listCmd.AddCommand(cobraListExternalUsesCmd)
+
+	listExternalUsageCmd, err := NewListExternalUsageCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list external-usage command")
+	}
+	cobraListExternalUsageCmd, err := cli.BuildCobraCommand(listExternalUsageCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list external-usage command")
+	}
+	listCmd.AddCommand(cobraListExternalUsageCmd)
+
+	listAPIChangesCmd, err := NewListAPIChangesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list api-changes command")
+	}
+	cobraListAPIChangesCmd, err := cli.BuildCobraCommand(listAPIChangesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list api-changes command")
+	}
+	listCmd.AddCommand(cobraListAPIChangesCmd)
+
+	listUpgradeImpactCmd, err := NewListUpgradeImpactCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list upgrade-impact command")
+	}
+	cobraListUpgradeImpactCmd, err := cli.BuildCobraCommand(listUpgradeImpactCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list upgrade-impact command")
+	}
+	listCmd.AddCommand(cobraListUpgradeImpactCmd)
+
+	listCallSitesCmd, err := NewListCallSitesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list call-sites command")
+	}
+	cobraListCallSitesCmd, err := cli.BuildCobraCommand(listCallSitesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list call-sites command")
+	}
+	listCmd.AddCommand(cobraListCallSitesCmd)
+
+	listErrorSitesCmd, err := NewListErrorSitesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list error-sites command")
+	}
+	cobraListErrorSitesCmd, err := cli.BuildCobraCommand(listErrorSitesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list error-sites command")
+	}
+	listCmd.AddCommand(cobraListErrorSitesCmd)
+
+	listErrorMessagesCmd, err := NewListErrorMessagesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list error-messages command")
+	}
+	cobraListErrorMessagesCmd, err := cli.BuildCobraCommand(listErrorMessagesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list error-messages command")
+	}
+	listCmd.AddCommand(cobraListErrorMessagesCmd)
+
+	listDirectivesCmd, err := NewListDirectivesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list directives command")
+	}
+	cobraListDirectivesCmd, err := cli.BuildCobraCommand(listDirectivesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list directives command")
+	}
+	listCmd.AddCommand(cobraListDirectivesCmd)
+
+	listFilesCmd, err := NewListFilesCommand()
+	if err != nil {
+		return nil, errors.Wrap(err, "build list files command")
+	}
+	cobraListFilesCmd, err := cli.BuildCobraCommand(listFilesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "wire list files command")
+	}
+	listCmd.AddCommand(cobraListFilesCmd)
 	rootCmd.AddCommand(listCmd)
 
 	reportCmd, err := NewReportCommand()
@@ -7,6 +7,7 @@ require (
 	github.com/go-go-golems/oak v0.0.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/mod v0.31.0
 	golang.org/x/tools v0.40.0
 	modernc.org/sqlite v1.44.3
 )
@@ -68,7 +69,6 @@ require (
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
 	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546 // indirect
-	golang.org/x/mod v0.31.0 // indirect
 	golang.org/x/net v0.49.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
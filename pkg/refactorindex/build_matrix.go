@@ -0,0 +1,103 @@
+package refactorindex
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// BuildConfig is one GOOS/GOARCH/tags combination of a build matrix.
+type BuildConfig struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// ParseBuildConfig parses "goos/goarch" with optional "+"-separated tags
+// after a colon, e.g. "windows/amd64" or "linux/arm64:integration+e2e".
+func ParseBuildConfig(spec string) (BuildConfig, error) {
+	spec = strings.TrimSpace(spec)
+	platform, tags, _ := strings.Cut(spec, ":")
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" {
+		return BuildConfig{}, errors.Errorf("invalid build config %q (want goos/goarch[:tag+tag])", spec)
+	}
+	config := BuildConfig{GOOS: goos, GOARCH: goarch}
+	for _, tag := range strings.Split(tags, "+") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			config.Tags = append(config.Tags, tag)
+		}
+	}
+	return config, nil
+}
+
+// String returns the configuration in the form accepted by
+// ParseBuildConfig; it is the name stored per occurrence.
+func (c BuildConfig) String() string {
+	name := c.GOOS + "/" + c.GOARCH
+	if len(c.Tags) > 0 {
+		name += ":" + strings.Join(c.Tags, "+")
+	}
+	return name
+}
+
+func buildMatrixNames(matrix []BuildConfig) []string {
+	names := make([]string, 0, len(matrix))
+	for _, config := range matrix {
+		names = append(names, config.String())
+	}
+	return names
+}
+
+func buildMatrixArg(matrix []BuildConfig) string {
+	return strings.Join(buildMatrixNames(matrix), " ")
+}
+
+// configLoad is the result of loading packages under one build
+// configuration; Config is empty when no matrix was requested.
+type configLoad struct {
+	Config string
+	Pkgs   []*packages.Package
+}
+
+// loadBuildMatrix loads the packages once per build configuration, on top
+// of the shared load options. Without a matrix it is a single plain load.
+// Load errors are prefixed with their configuration.
+func loadBuildMatrix(pkgConfig *packages.Config, opts GoLoadOptions, matrix []BuildConfig, rootDir string, tolerant bool) ([]configLoad, []LoadError, error) {
+	if len(matrix) == 0 {
+		pkgs, loadErrors, err := loadGoPackages(pkgConfig, opts, rootDir, tolerant)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []configLoad{{Pkgs: pkgs}}, loadErrors, nil
+	}
+
+	var loads []configLoad
+	var loadErrors []LoadError
+	for _, config := range matrix {
+		configOpts := opts
+		configOpts.GOOS = config.GOOS
+		configOpts.GOARCH = config.GOARCH
+		configOpts.Tags = append(append([]string{}, opts.Tags...), config.Tags...)
+		configPkgConfig := configOpts.packagesConfig(pkgConfig.Context, rootDir, pkgConfig.Mode)
+		pkgs, configErrors, err := loadGoPackages(configPkgConfig, configOpts, rootDir, tolerant)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "load %s", config)
+		}
+		for _, loadErr := range configErrors {
+			loadErr.Message = "[" + config.String() + "] " + loadErr.Message
+			loadErrors = append(loadErrors, loadErr)
+		}
+		loads = append(loads, configLoad{Config: config.String(), Pkgs: pkgs})
+	}
+	return loads, loadErrors, nil
+}
+
+func configLoadPackages(loads []configLoad) []*packages.Package {
+	var pkgs []*packages.Package
+	for _, load := range loads {
+		pkgs = append(pkgs, load.Pkgs...)
+	}
+	return pkgs
+}
@@ -0,0 +1,104 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIngestSymbolsBuildMatrix(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/matrix\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "common.go"), "package matrix\n\nfunc Common() string { return sep() }\n")
+	writeFile(t, filepath.Join(repoPath, "sep_unix.go"), "//go:build !windows\n\npackage matrix\n\nfunc sep() string { return \"/\" }\n")
+	writeFile(t, filepath.Join(repoPath, "sep_windows.go"), "package matrix\n\nfunc sep() string { return \"\\\\\" }\n")
+	writeFile(t, filepath.Join(repoPath, "extra.go"), "//go:build extra\n\npackage matrix\n\nfunc Extra() {}\n")
+
+	var matrix []BuildConfig
+	for _, spec := range []string{"linux/amd64", "windows/amd64", "linux/amd64:extra"} {
+		config, err := ParseBuildConfig(spec)
+		if err != nil {
+			t.Fatalf("parse %s: %v", spec, err)
+		}
+		if config.String() != spec {
+			t.Fatalf("expected %s to round-trip, got %s", spec, config)
+		}
+		matrix = append(matrix, config)
+	}
+	if _, err := ParseBuildConfig("linux"); err == nil {
+		t.Fatalf("expected error for config without arch")
+	}
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: repoPath, Matrix: matrix})
+	if err != nil {
+		t.Fatalf("ingest symbols: %v", err)
+	}
+	if result.Occurrences != 4 || result.Packages != 1 {
+		t.Fatalf("expected 4 deduplicated occurrences in 1 package, got %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	records, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: result.RunID})
+	if err != nil {
+		t.Fatalf("list symbols: %v", err)
+	}
+	configs := make(map[string][]string)
+	for _, record := range records {
+		configs[record.FilePath+":"+record.Name] = record.BuildConfigs
+	}
+	expected := map[string][]string{
+		"common.go:Common":   {"linux/amd64", "linux/amd64:extra", "windows/amd64"},
+		"extra.go:Extra":     {"linux/amd64:extra"},
+		"sep_unix.go:sep":    {"linux/amd64", "linux/amd64:extra"},
+		"sep_windows.go:sep": {"windows/amd64"},
+	}
+	if !reflect.DeepEqual(configs, expected) {
+		t.Fatalf("unexpected build configs: %+v", configs)
+	}
+
+	specific, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: result.RunID, PlatformSpecific: true})
+	if err != nil {
+		t.Fatalf("list platform-specific symbols: %v", err)
+	}
+	if len(specific) != 3 {
+		t.Fatalf("expected 3 platform-specific occurrences, got %+v", specific)
+	}
+	windows, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: result.RunID, BuildConfig: "windows/amd64", Name: "sep"})
+	if err != nil {
+		t.Fatalf("list windows symbols: %v", err)
+	}
+	if len(windows) != 1 || windows[0].FilePath != "sep_windows.go" {
+		t.Fatalf("unexpected windows sep occurrences: %+v", windows)
+	}
+
+	units, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath, Matrix: matrix})
+	if err != nil {
+		t.Fatalf("ingest code units: %v", err)
+	}
+	if units.Snapshots != 4 {
+		t.Fatalf("expected 4 deduplicated snapshots, got %+v", units)
+	}
+	var snapshotConfigs int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM code_unit_snapshot_configs c JOIN code_unit_snapshots s ON s.id = c.snapshot_id WHERE s.run_id = ?", units.RunID).Scan(&snapshotConfigs); err != nil {
+		t.Fatalf("count snapshot configs: %v", err)
+	}
+	if snapshotConfigs != 7 {
+		t.Fatalf("expected 7 snapshot config rows, got %d", snapshotConfigs)
+	}
+}
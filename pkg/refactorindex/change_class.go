@@ -0,0 +1,190 @@
+package refactorindex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	ChangeClassNone       = "none"
+	ChangeClassBinary     = "binary"
+	ChangeClassWhitespace = "whitespace"
+	ChangeClassComment    = "comment"
+	ChangeClassImports    = "imports"
+	ChangeClassCode       = "code"
+)
+
+// FileChangeStats carries numstat and classification data for a changed file.
+type FileChangeStats struct {
+	Insertions  *int
+	Deletions   *int
+	Similarity  *int
+	IsBinary    bool
+	ChangeClass string
+}
+
+var goImportLinePattern = regexp.MustCompile(`^(import\s*\(|import\s+([\w.]+\s+)?"[^"]*"|\)|([\w.]+\s+)?"[^"]*")$`)
+
+var cStyleComments = []string{"//", "/*", "*/", "* "}
+
+var lineCommentPrefixes = map[string][]string{
+	"go":    cStyleComments,
+	"js":    cStyleComments,
+	"ts":    cStyleComments,
+	"tsx":   cStyleComments,
+	"jsx":   cStyleComments,
+	"java":  cStyleComments,
+	"c":     cStyleComments,
+	"h":     cStyleComments,
+	"cc":    cStyleComments,
+	"cpp":   cStyleComments,
+	"rs":    cStyleComments,
+	"proto": cStyleComments,
+	"py":    {"#"},
+	"sh":    {"#"},
+	"bash":  {"#"},
+	"rb":    {"#"},
+	"yaml":  {"#"},
+	"yml":   {"#"},
+	"toml":  {"#"},
+	"sql":   {"--"},
+	"lua":   {"--"},
+}
+
+// ClassifyChange labels a file change by the kind of lines it touches, so
+// noise (formatting, comments, import shuffles) can be filtered from large
+// refactor diffs. Only added and removed lines are considered.
+func ClassifyChange(path string, binary bool, lines []DiffLine) string {
+	classifier := newChangeClassifier(path)
+	for _, line := range lines {
+		classifier.Add(line)
+	}
+	return classifier.Class(binary)
+}
+
+// changeClassifier computes ClassifyChange incrementally, so streamed diffs
+// can be classified without keeping their lines. The whitespace-only check
+// compares hashes of the removed and added text with all whitespace dropped.
+type changeClassifier struct {
+	commentPrefixes []string
+	removed         hash.Hash
+	added           hash.Hash
+	changed         bool
+	allComments     bool
+	allImports      bool
+}
+
+func newChangeClassifier(path string) *changeClassifier {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	prefixes, hasComments := lineCommentPrefixes[ext]
+	return &changeClassifier{
+		commentPrefixes: prefixes,
+		removed:         sha256.New(),
+		added:           sha256.New(),
+		allComments:     hasComments,
+		allImports:      ext == "go",
+	}
+}
+
+func (c *changeClassifier) Add(line DiffLine) {
+	switch line.Kind {
+	case "-":
+		_, _ = io.WriteString(c.removed, stripWhitespace(line.Text))
+	case "+":
+		_, _ = io.WriteString(c.added, stripWhitespace(line.Text))
+	default:
+		return
+	}
+	c.changed = true
+
+	trimmed := strings.TrimSpace(line.Text)
+	if trimmed == "" {
+		return
+	}
+	if c.allComments && !hasAnyPrefix(trimmed, c.commentPrefixes) {
+		c.allComments = false
+	}
+	if c.allImports && !goImportLinePattern.MatchString(trimmed) {
+		c.allImports = false
+	}
+}
+
+func (c *changeClassifier) Class(binary bool) string {
+	switch {
+	case binary:
+		return ChangeClassBinary
+	case !c.changed:
+		return ChangeClassNone
+	case bytes.Equal(c.removed.Sum(nil), c.added.Sum(nil)):
+		return ChangeClassWhitespace
+	case c.allComments:
+		return ChangeClassComment
+	case c.allImports:
+		return ChangeClassImports
+	default:
+		return ChangeClassCode
+	}
+}
+
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) || text == strings.TrimSpace(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripWhitespace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// collectChangeStats joins name-status entries with numstat output and parsed
+// patches, keyed by the entry's primary path.
+func collectChangeStats(entries []DiffFileEntry, numstat []NumstatEntry, patches []FilePatch) map[string]FileChangeStats {
+	numstatByPath := make(map[string]NumstatEntry, len(numstat))
+	for _, entry := range numstat {
+		numstatByPath[entry.PrimaryPath()] = entry
+	}
+	patchByPath := make(map[string]*FilePatch, len(patches))
+	for i := range patches {
+		patch := &patches[i]
+		if patch.OldPath != "" {
+			patchByPath[patch.OldPath] = patch
+		}
+		if patch.NewPath != "" {
+			patchByPath[patch.NewPath] = patch
+		}
+	}
+
+	stats := make(map[string]FileChangeStats, len(entries))
+	for _, entry := range entries {
+		path := entry.PrimaryPath()
+		stat := FileChangeStats{Similarity: entry.Similarity()}
+		if ns, ok := numstatByPath[path]; ok {
+			stat.Insertions = ns.Insertions
+			stat.Deletions = ns.Deletions
+			stat.IsBinary = ns.Binary
+		}
+		var lines []DiffLine
+		if patch, ok := patchByPath[path]; ok {
+			for _, hunk := range patch.Hunks {
+				lines = append(lines, hunk.Lines...)
+			}
+		}
+		stat.ChangeClass = ClassifyChange(path, stat.IsBinary, lines)
+		stats[path] = stat
+	}
+	return stats
+}
@@ -0,0 +1,212 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CodeownersLocations are the paths GitHub and GitLab look for, in order.
+var CodeownersLocations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+}
+
+var codeownersSectionPattern = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*(.*)$`)
+
+// OwnerRule is one pattern line of a CODEOWNERS file. Rules without owners
+// explicitly leave matching paths unowned (GitHub) or fall back to the
+// section's default owners (GitLab).
+type OwnerRule struct {
+	Line     int
+	Section  string
+	Optional bool
+	Pattern  string
+	Owners   []string
+
+	matcher *regexp.Regexp
+}
+
+// Codeowners is a parsed CODEOWNERS file.
+type Codeowners struct {
+	Rules []OwnerRule
+}
+
+// ParseCodeowners parses GitHub or GitLab CODEOWNERS syntax, including
+// GitLab sections with default owners.
+func ParseCodeowners(data []byte) (*Codeowners, error) {
+	result := &Codeowners{}
+	section := ""
+	optional := false
+	var defaults []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := codeownersSectionPattern.FindStringSubmatch(line); match != nil {
+			section = strings.TrimSpace(match[1])
+			optional = strings.HasPrefix(line, "^")
+			defaults = splitCodeownersFields(match[2])
+			continue
+		}
+
+		fields := splitCodeownersFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		owners := fields[1:]
+		if len(owners) == 0 && section != "" {
+			owners = defaults
+		}
+		matcher, err := compileOwnerPattern(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "CODEOWNERS line %d", lineNo)
+		}
+		result.Rules = append(result.Rules, OwnerRule{
+			Line:     lineNo,
+			Section:  section,
+			Optional: optional,
+			Pattern:  fields[0],
+			Owners:   append([]string(nil), owners...),
+			matcher:  matcher,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read CODEOWNERS")
+	}
+	return result, nil
+}
+
+// splitCodeownersFields splits on unescaped whitespace and drops a trailing
+// "#" comment.
+func splitCodeownersFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		case r == '#' && current.Len() == 0:
+			return fields
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// Match returns the owners of a repository-relative path and the rules that
+// selected them. The last matching rule wins within each section; owners of
+// all sections are combined.
+func (c *Codeowners) Match(path string) ([]string, []*OwnerRule) {
+	path = strings.TrimPrefix(path, "/")
+	lastBySection := make(map[string]*OwnerRule)
+	var sections []string
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if !rule.matcher.MatchString(path) {
+			continue
+		}
+		if _, ok := lastBySection[rule.Section]; !ok {
+			sections = append(sections, rule.Section)
+		}
+		lastBySection[rule.Section] = rule
+	}
+
+	var owners []string
+	var rules []*OwnerRule
+	seen := make(map[string]struct{})
+	for _, section := range sections {
+		rule := lastBySection[section]
+		rules = append(rules, rule)
+		for _, owner := range rule.Owners {
+			if _, ok := seen[owner]; ok {
+				continue
+			}
+			seen[owner] = struct{}{}
+			owners = append(owners, owner)
+		}
+	}
+	return owners, rules
+}
+
+// compileOwnerPattern turns a gitignore-style CODEOWNERS pattern into a
+// regular expression over slash-separated paths. Patterns containing a slash
+// are anchored at the repository root; a pattern naming a directory matches
+// everything below it, but "dir/*" only matches direct children.
+func compileOwnerPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.HasPrefix(trimmed, "/") || strings.Contains(strings.TrimPrefix(trimmed, "/"), "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored && !strings.HasPrefix(trimmed, "**") {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		ch := trimmed[i]
+		switch ch {
+		case '*':
+			if i+1 < len(trimmed) && trimmed[i+1] == '*' {
+				i++
+				if i+1 < len(trimmed) && trimmed[i+1] == '/' {
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(trimmed) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(trimmed[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	lastSegment := trimmed[strings.LastIndex(trimmed, "/")+1:]
+	switch {
+	case trimmed == "":
+		expr.WriteString(".*")
+	case dirOnly:
+		expr.WriteString("/.*")
+	case !strings.ContainsAny(lastSegment, "*?"):
+		expr.WriteString("(?:/.*)?")
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
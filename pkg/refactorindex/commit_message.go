@@ -0,0 +1,173 @@
+package refactorindex
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultIssuePatterns match GitHub-style (#123) and Jira-style (ABC-456)
+// issue references. The first capture group, when present, is stored.
+// Jira keys need two leading letters so that tokens like X-1 do not count.
+var DefaultIssuePatterns = []string{
+	`(?:^|[^\w&])(#[0-9]+)\b`,
+	`\b([A-Z]{2}[A-Z0-9]*-[0-9]+)\b`,
+}
+
+// nonIssuePrefixes are standards and encodings that read like Jira keys,
+// such as UTF-8, SHA-256 and ISO-8601. Matches with these prefixes are
+// never stored as issue references.
+var nonIssuePrefixes = map[string]struct{}{
+	"AES":  {},
+	"CRC":  {},
+	"CVE":  {},
+	"CWE":  {},
+	"ECMA": {},
+	"HTTP": {},
+	"IEEE": {},
+	"ISO":  {},
+	"MD":   {},
+	"PEP":  {},
+	"RFC":  {},
+	"RSA":  {},
+	"SHA":  {},
+	"TLS":  {},
+	"UCS":  {},
+	"UTF":  {},
+	"WCAG": {},
+}
+
+var (
+	conventionalSubjectPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?:\s+(.+)$`)
+	trailerPattern             = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*|BREAKING CHANGE):\s*(.*)$`)
+)
+
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+type CommitTrailer struct {
+	Key   string
+	Value string
+}
+
+type CommitIssueRef struct {
+	Issue  string
+	Source string
+}
+
+// CommitMessageInfo is the structured form of a commit message.
+type CommitMessageInfo struct {
+	Conventional *ConventionalCommit
+	Trailers     []CommitTrailer
+	IssueRefs    []CommitIssueRef
+}
+
+// CompileIssuePatterns compiles issue reference patterns, falling back to
+// DefaultIssuePatterns when none are given.
+func CompileIssuePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultIssuePatterns
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile issue pattern %q", pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func ParseCommitMessage(subject string, body string, issuePatterns []*regexp.Regexp) CommitMessageInfo {
+	info := CommitMessageInfo{}
+
+	if match := conventionalSubjectPattern.FindStringSubmatch(strings.TrimSpace(subject)); match != nil {
+		info.Conventional = &ConventionalCommit{
+			Type:        strings.ToLower(match[1]),
+			Scope:       strings.TrimSpace(match[2]),
+			Breaking:    match[3] == "!",
+			Description: strings.TrimSpace(match[4]),
+		}
+	}
+
+	info.Trailers = parseTrailers(body)
+	for _, trailer := range info.Trailers {
+		if info.Conventional != nil && (trailer.Key == "BREAKING CHANGE" || trailer.Key == "BREAKING-CHANGE") {
+			info.Conventional.Breaking = true
+		}
+	}
+
+	seen := make(map[string]struct{})
+	addIssues := func(text string, source string) {
+		for _, re := range issuePatterns {
+			for _, match := range re.FindAllStringSubmatch(text, -1) {
+				issue := match[0]
+				if len(match) > 1 && match[1] != "" {
+					issue = match[1]
+				}
+				if isNonIssueToken(issue) {
+					continue
+				}
+				if _, ok := seen[issue]; ok {
+					continue
+				}
+				seen[issue] = struct{}{}
+				info.IssueRefs = append(info.IssueRefs, CommitIssueRef{Issue: issue, Source: source})
+			}
+		}
+	}
+	addIssues(subject, "subject")
+	for _, trailer := range info.Trailers {
+		addIssues(trailer.Value, "trailer")
+	}
+	addIssues(body, "body")
+
+	return info
+}
+
+func isNonIssueToken(issue string) bool {
+	prefix, _, ok := strings.Cut(issue, "-")
+	if !ok {
+		return false
+	}
+	_, skip := nonIssuePrefixes[prefix]
+	return skip
+}
+
+// parseTrailers reads the trailer block from the last paragraph of a commit
+// body. The paragraph only counts as trailers when every line is either a
+// "Key: value" pair or an indented continuation of the previous value.
+func parseTrailers(body string) []CommitTrailer {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	if body == "" {
+		return nil
+	}
+	paragraphs := strings.Split(body, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+
+	var trailers []CommitTrailer
+	for _, line := range strings.Split(last, "\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(trailers) > 0 {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		match := trailerPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil
+		}
+		trailers = append(trailers, CommitTrailer{Key: match[1], Value: strings.TrimSpace(match[2])})
+	}
+	return trailers
+}
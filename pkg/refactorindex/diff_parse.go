@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
@@ -34,7 +35,21 @@ type DiffHunk struct {
 type FilePatch struct {
 	OldPath string
 	NewPath string
-	Hunks   []DiffHunk
+	// Status is derived from git extended headers (A, D, R<score>, C<score>)
+	// and defaults to M.
+	Status string
+	Binary bool
+	Hunks  []DiffHunk
+}
+
+// NumstatEntry is one file from git diff --numstat -z. Insertions and
+// Deletions are nil for binary files.
+type NumstatEntry struct {
+	Insertions *int
+	Deletions  *int
+	Binary     bool
+	OldPath    string
+	NewPath    string
 }
 
 func (d DiffFileEntry) PrimaryPath() string {
@@ -44,6 +59,69 @@ func (d DiffFileEntry) PrimaryPath() string {
 	return d.OldPath
 }
 
+// Similarity returns the rename/copy score encoded in statuses such as R087.
+func (d DiffFileEntry) Similarity() *int {
+	if len(d.Status) < 2 || (d.Status[0] != 'R' && d.Status[0] != 'C') {
+		return nil
+	}
+	score, err := strconv.Atoi(d.Status[1:])
+	if err != nil {
+		return nil
+	}
+	return &score
+}
+
+func (n NumstatEntry) PrimaryPath() string {
+	if n.NewPath != "" {
+		return n.NewPath
+	}
+	return n.OldPath
+}
+
+func ParseNumstat(data []byte) ([]NumstatEntry, error) {
+	fields := bytes.Split(data, []byte{0})
+	entries := make([]NumstatEntry, 0)
+	for i := 0; i < len(fields); {
+		field := strings.TrimLeft(string(fields[i]), "\n")
+		i++
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "\t", 3)
+		if len(parts) != 3 {
+			return nil, errors.New("invalid numstat output")
+		}
+		entry := NumstatEntry{}
+		if parts[0] == "-" && parts[1] == "-" {
+			entry.Binary = true
+		} else {
+			insertions, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return nil, errors.Wrap(err, "parse numstat insertions")
+			}
+			deletions, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, errors.Wrap(err, "parse numstat deletions")
+			}
+			entry.Insertions = &insertions
+			entry.Deletions = &deletions
+		}
+		if parts[2] == "" {
+			if i+1 >= len(fields) {
+				return nil, errors.New("invalid numstat output for rename/copy")
+			}
+			entry.OldPath = string(fields[i])
+			entry.NewPath = string(fields[i+1])
+			i += 2
+		} else {
+			entry.OldPath = parts[2]
+			entry.NewPath = parts[2]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 func ParseNameStatus(data []byte) ([]DiffFileEntry, error) {
 	fields := bytes.Split(data, []byte{0})
 	entries := make([]DiffFileEntry, 0)
@@ -90,89 +168,327 @@ func ParseNameStatus(data []byte) ([]DiffFileEntry, error) {
 	return entries, nil
 }
 
-func ParseUnifiedDiff(data []byte) ([]FilePatch, error) {
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024)
+// DiffCallbacks receives events from StreamUnifiedDiff. Any callback may be
+// nil. The *FilePatch passed to the callbacks carries the file header only;
+// its Hunks are not accumulated.
+type DiffCallbacks struct {
+	// OnFile is called once the file header (paths, status, binary marker) is
+	// complete, before its first hunk.
+	OnFile func(patch *FilePatch) error
+	// OnHunk is called for each hunk header, before the hunk's lines.
+	OnHunk func(patch *FilePatch, hunk DiffHunk) error
+	// OnLine is called for each context, added or removed line.
+	OnLine func(patch *FilePatch, line DiffLine) error
+	// OnFileEnd is called after the last line of a file.
+	OnFileEnd func(patch *FilePatch) error
+}
 
+// ParseUnifiedDiff parses a complete diff into memory. Large diffs should use
+// StreamUnifiedDiff instead.
+func ParseUnifiedDiff(data []byte) ([]FilePatch, error) {
 	var patches []FilePatch
+	err := StreamUnifiedDiff(bytes.NewReader(data), DiffCallbacks{
+		OnFile: func(patch *FilePatch) error {
+			patches = append(patches, *patch)
+			return nil
+		},
+		OnHunk: func(_ *FilePatch, hunk DiffHunk) error {
+			current := &patches[len(patches)-1]
+			current.Hunks = append(current.Hunks, hunk)
+			return nil
+		},
+		OnLine: func(_ *FilePatch, line DiffLine) error {
+			current := &patches[len(patches)-1]
+			hunk := &current.Hunks[len(current.Hunks)-1]
+			hunk.Lines = append(hunk.Lines, line)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patches, nil
+}
+
+// StreamUnifiedDiff reads a unified diff (git or plain diff -u output, with
+// any amount of context) and reports files, hunks and lines as they are
+// read. Hunk bodies are delimited by the line counts of their headers, so
+// removed lines starting with "--" are not mistaken for file headers.
+func StreamUnifiedDiff(r io.Reader, callbacks DiffCallbacks) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+
 	var current *FilePatch
-	var currentHunk *DiffHunk
-	var oldLine int
-	var newLine int
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "diff --git ") {
-			parts := strings.Fields(line)
-			if len(parts) >= 4 {
-				oldPath := normalizeDiffPath(parts[2])
-				newPath := normalizeDiffPath(parts[3])
-				patches = append(patches, FilePatch{OldPath: oldPath, NewPath: newPath})
-				current = &patches[len(patches)-1]
-				currentHunk = nil
+	headerDone := false
+	sawHunk := false
+	sawOldHeader := false
+	oldRemaining := 0
+	newRemaining := 0
+	oldLine := 0
+	newLine := 0
+
+	finishHeader := func() error {
+		if current == nil || headerDone {
+			return nil
+		}
+		headerDone = true
+		if callbacks.OnFile != nil {
+			return callbacks.OnFile(current)
+		}
+		return nil
+	}
+	finishFile := func() error {
+		if current == nil {
+			return nil
+		}
+		if err := finishHeader(); err != nil {
+			return err
+		}
+		if callbacks.OnFileEnd != nil {
+			if err := callbacks.OnFileEnd(current); err != nil {
+				return err
+			}
+		}
+		current = nil
+		return nil
+	}
+	startFile := func(patch FilePatch) error {
+		if err := finishFile(); err != nil {
+			return err
+		}
+		current = &patch
+		headerDone = false
+		sawHunk = false
+		sawOldHeader = false
+		oldRemaining = 0
+		newRemaining = 0
+		return nil
+	}
+
+	for {
+		raw, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return errors.Wrap(readErr, "read unified diff")
+		}
+		if raw == "" && readErr == io.EOF {
+			break
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+		if strings.HasPrefix(line, "\\") {
+			// "\ No newline at end of file" may appear inside or after a hunk.
+			if readErr == io.EOF {
+				break
 			}
 			continue
 		}
-		if strings.HasPrefix(line, "@@") {
-			if current == nil {
+
+		if current != nil && (oldRemaining > 0 || newRemaining > 0) {
+			kind := " "
+			text := line
+			if line != "" {
+				kind = line[:1]
+				text = line[1:]
+			}
+			var diffLine DiffLine
+			switch kind {
+			case "+":
+				lineNo := newLine
+				newLine++
+				newRemaining--
+				diffLine = DiffLine{Kind: "+", NewLine: &lineNo, Text: text}
+			case "-":
+				lineNo := oldLine
+				oldLine++
+				oldRemaining--
+				diffLine = DiffLine{Kind: "-", OldLine: &lineNo, Text: text}
+			case " ":
+				oldNo := oldLine
+				newNo := newLine
+				oldLine++
+				newLine++
+				oldRemaining--
+				newRemaining--
+				diffLine = DiffLine{Kind: " ", OldLine: &oldNo, NewLine: &newNo, Text: text}
+			default:
+				// Truncated hunk; fall through to header handling.
+				oldRemaining = 0
+				newRemaining = 0
+			}
+			if diffLine.Kind != "" {
+				if callbacks.OnLine != nil {
+					if err := callbacks.OnLine(current, diffLine); err != nil {
+						return err
+					}
+				}
+				if readErr == io.EOF {
+					break
+				}
 				continue
 			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			oldPath, newPath := parseGitDiffPaths(strings.TrimPrefix(line, "diff --git "))
+			if err := startFile(FilePatch{OldPath: oldPath, NewPath: newPath, Status: "M"}); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				break
+			}
 			oldStart, oldLines, newStart, newLines, err := parseHunkHeader(line)
 			if err != nil {
-				return nil, err
-			}
-			current.Hunks = append(current.Hunks, DiffHunk{
-				OldStart: oldStart,
-				OldLines: oldLines,
-				NewStart: newStart,
-				NewLines: newLines,
-			})
-			currentHunk = &current.Hunks[len(current.Hunks)-1]
+				return err
+			}
+			if err := finishHeader(); err != nil {
+				return err
+			}
+			sawHunk = true
+			oldRemaining = oldLines
+			newRemaining = newLines
 			oldLine = oldStart
 			newLine = newStart
-			continue
+			if callbacks.OnHunk != nil {
+				hunk := DiffHunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines}
+				if err := callbacks.OnHunk(current, hunk); err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(line, "--- "):
+			// Plain diff -u output has no "diff --git" line; "---" starts a file.
+			if current == nil || sawHunk || sawOldHeader {
+				if err := startFile(FilePatch{Status: "M"}); err != nil {
+					return err
+				}
+				current.OldPath = parseHeaderPath(strings.TrimPrefix(line, "--- "))
+				if current.OldPath == "" {
+					current.Status = "A"
+				}
+			} else if path := parseHeaderPath(strings.TrimPrefix(line, "--- ")); path != "" {
+				current.OldPath = path
+			}
+			sawOldHeader = true
+		case strings.HasPrefix(line, "+++ "):
+			if current != nil && !sawHunk {
+				path := parseHeaderPath(strings.TrimPrefix(line, "+++ "))
+				current.NewPath = path
+				if path == "" {
+					current.Status = "D"
+				}
+			}
+		default:
+			if current != nil && !sawHunk {
+				parseExtendedHeader(current, line)
+			}
 		}
-		if currentHunk == nil {
-			continue
+
+		if readErr == io.EOF {
+			break
 		}
-		if line == "" {
-			continue
+	}
+	return finishFile()
+}
+
+// parseGitDiffPaths splits the "a/<old> b/<new>" part of a diff --git line.
+// Paths containing special characters are C-quoted by git; paths with spaces
+// are not, so an unquoted line is split where both halves name the same file.
+// Renames with spaces are corrected later by the rename/---/+++ headers.
+func parseGitDiffPaths(rest string) (string, string) {
+	if strings.HasPrefix(rest, "\"") {
+		oldPath, remainder, ok := cutQuoted(rest)
+		if ok {
+			return normalizeDiffPath(oldPath), normalizeDiffPath(unquoteDiffPath(strings.TrimSpace(remainder)))
 		}
-		switch line[0] {
-		case '+':
-			if strings.HasPrefix(line, "+++") {
-				continue
-			}
-			text := strings.TrimPrefix(line, "+")
-			lineNo := newLine
-			newLine++
-			currentHunk.Lines = append(currentHunk.Lines, DiffLine{Kind: "+", NewLine: &lineNo, Text: text})
-		case '-':
-			if strings.HasPrefix(line, "---") {
-				continue
-			}
-			text := strings.TrimPrefix(line, "-")
-			lineNo := oldLine
-			oldLine++
-			currentHunk.Lines = append(currentHunk.Lines, DiffLine{Kind: "-", OldLine: &lineNo, Text: text})
-		case ' ':
-			text := strings.TrimPrefix(line, " ")
-			oldNo := oldLine
-			newNo := newLine
-			oldLine++
-			newLine++
-			currentHunk.Lines = append(currentHunk.Lines, DiffLine{Kind: " ", OldLine: &oldNo, NewLine: &newNo, Text: text})
+	}
+	if strings.HasSuffix(rest, "\"") {
+		if idx := strings.LastIndex(rest[:len(rest)-1], " \""); idx >= 0 {
+			return normalizeDiffPath(rest[:idx]), normalizeDiffPath(unquoteDiffPath(rest[idx+1:]))
+		}
+	}
+	if len(rest)%2 == 1 {
+		mid := len(rest) / 2
+		left, right := rest[:mid], rest[mid+1:]
+		if rest[mid] == ' ' && len(left) > 2 && len(right) > 2 && left[2:] == right[2:] {
+			return normalizeDiffPath(left), normalizeDiffPath(right)
+		}
+	}
+	if idx := strings.Index(rest, " b/"); idx >= 0 {
+		return normalizeDiffPath(rest[:idx]), normalizeDiffPath(rest[idx+1:])
+	}
+	parts := strings.Fields(rest)
+	if len(parts) >= 2 {
+		return normalizeDiffPath(parts[0]), normalizeDiffPath(parts[1])
+	}
+	return normalizeDiffPath(rest), normalizeDiffPath(rest)
+}
+
+// parseHeaderPath reads the path of a ---/+++ line, which may be quoted and
+// may carry a tab-separated timestamp (plain diff -u).
+func parseHeaderPath(value string) string {
+	if strings.HasPrefix(value, "\"") {
+		if path, _, ok := cutQuoted(value); ok {
+			return normalizeDiffPath(path)
+		}
+	}
+	if idx := strings.Index(value, "\t"); idx >= 0 {
+		value = value[:idx]
+	}
+	return normalizeDiffPath(value)
+}
+
+// cutQuoted unquotes the C-style quoted string at the start of value and
+// returns the rest of the input.
+func cutQuoted(value string) (string, string, bool) {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
 		case '\\':
-			continue
-		default:
-			continue
+			i++
+		case '"':
+			return unquoteDiffPath(value[:i+1]), value[i+1:], true
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, errors.Wrap(err, "scan unified diff")
+	return "", value, false
+}
+
+func unquoteDiffPath(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return value[1 : len(value)-1]
+	}
+	return unquoted
+}
+
+// parseExtendedHeader applies git's extended header lines (between
+// "diff --git" and the first hunk) to the current file patch.
+func parseExtendedHeader(patch *FilePatch, line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode"):
+		patch.Status = "A"
+		patch.OldPath = ""
+	case strings.HasPrefix(line, "deleted file mode"):
+		patch.Status = "D"
+		patch.NewPath = ""
+	case strings.HasPrefix(line, "similarity index "):
+		score := strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%")
+		if len(score) < 3 {
+			score = strings.Repeat("0", 3-len(score)) + score
+		}
+		patch.Status = "R" + score
+	case strings.HasPrefix(line, "rename from "):
+		patch.OldPath = unquoteDiffPath(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		patch.NewPath = unquoteDiffPath(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy from "):
+		patch.Status = "C" + strings.TrimPrefix(patch.Status, "R")
+		patch.OldPath = unquoteDiffPath(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		patch.NewPath = unquoteDiffPath(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+		patch.Binary = true
 	}
-	return patches, nil
 }
 
 func parseHunkHeader(line string) (int, int, int, int, error) {
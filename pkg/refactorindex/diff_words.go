@@ -0,0 +1,322 @@
+package refactorindex
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMinMovedLines is the shortest run of lines treated as a move.
+	DefaultMinMovedLines = 3
+
+	// minPairSimilarity is the token similarity required to pair a removed
+	// line with an added line.
+	minPairSimilarity = 0.5
+	// maxPairLookahead bounds how many added lines are tried per removed line.
+	maxPairLookahead = 16
+	// maxWordTokens skips word diffs for very long lines.
+	maxWordTokens = 400
+)
+
+const (
+	WordEditReplace = "replace"
+	WordEditInsert  = "insert"
+	WordEditDelete  = "delete"
+)
+
+// diffAnnotationCounts reports the line pairs, word edits and moved blocks
+// stored for a run.
+type diffAnnotationCounts struct {
+	LinePairs   int
+	WordEdits   int
+	MovedBlocks int
+}
+
+// changeLine is a "+" or "-" line of the change block being streamed.
+// lineNo is the old line for removals and the new line for additions.
+type changeLine struct {
+	id     int64
+	kind   string
+	lineNo int
+	text   string
+}
+
+// movedLine is what move detection keeps of a changed line: a hash of its
+// text and its position, so the index stays small for large diffs.
+type movedLine struct {
+	id         int64
+	hunkID     int64
+	diffFileID int64
+	lineNo     int
+	hash       uint64
+	blank      bool
+	moved      bool
+}
+
+type wordToken struct {
+	start int
+	end   int
+	text  string
+	space bool
+}
+
+func newMovedLine(hunkID int64, diffFileID int64, line changeLine) *movedLine {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(line.text))
+	return &movedLine{
+		id:         line.id,
+		hunkID:     hunkID,
+		diffFileID: diffFileID,
+		lineNo:     line.lineNo,
+		hash:       hash.Sum64(),
+		blank:      strings.TrimSpace(line.text) == "",
+	}
+}
+
+// detectMovedBlocks finds runs of at least minLines removed lines that match
+// consecutive added lines in a different hunk, preferring the longest match
+// for each starting line. Lines match when their text hashes are equal.
+func detectMovedBlocks(removedRuns [][]*movedLine, addedRuns [][]*movedLine, minLines int) []DiffMovedBlock {
+	type position struct{ run, offset int }
+	addedByHash := make(map[uint64][]position)
+	for r, run := range addedRuns {
+		for o, line := range run {
+			if line.blank {
+				continue
+			}
+			addedByHash[line.hash] = append(addedByHash[line.hash], position{r, o})
+		}
+	}
+
+	var blocks []DiffMovedBlock
+	for _, removed := range removedRuns {
+		for i := 0; i < len(removed); {
+			best := 0
+			var bestPos position
+			for _, pos := range addedByHash[removed[i].hash] {
+				added := addedRuns[pos.run]
+				if added[pos.offset].hunkID == removed[i].hunkID {
+					continue
+				}
+				length := 0
+				for i+length < len(removed) && pos.offset+length < len(added) {
+					oldLine := removed[i+length]
+					newLine := added[pos.offset+length]
+					if oldLine.moved || newLine.moved || oldLine.hash != newLine.hash {
+						break
+					}
+					length++
+				}
+				if length > best {
+					best = length
+					bestPos = pos
+				}
+			}
+			if best < minLines {
+				i++
+				continue
+			}
+			added := addedRuns[bestPos.run][bestPos.offset : bestPos.offset+best]
+			block := DiffMovedBlock{
+				FromDiffFileID: removed[i].diffFileID,
+				ToDiffFileID:   added[0].diffFileID,
+				OldStartLine:   removed[i].lineNo,
+				NewStartLine:   added[0].lineNo,
+				LineCount:      best,
+			}
+			for k := 0; k < best; k++ {
+				removed[i+k].moved = true
+				added[k].moved = true
+				block.OldLineIDs = append(block.OldLineIDs, removed[i+k].id)
+				block.NewLineIDs = append(block.NewLineIDs, added[k].id)
+			}
+			blocks = append(blocks, block)
+			i += best
+		}
+	}
+	return blocks
+}
+
+// pairChangedLines matches removed lines with added lines in order, taking
+// the first added line that is similar enough.
+func pairChangedLines(removed []changeLine, added []changeLine) [][2]changeLine {
+	var pairs [][2]changeLine
+	next := 0
+	for _, oldLine := range removed {
+		oldTokens := tokenizeWords(oldLine.text)
+		for k := next; k < len(added) && k < next+maxPairLookahead; k++ {
+			similarity, ok := tokenSimilarity(oldTokens, tokenizeWords(added[k].text))
+			if ok && similarity >= minPairSimilarity {
+				pairs = append(pairs, [2]changeLine{oldLine, added[k]})
+				next = k + 1
+				break
+			}
+		}
+		if next >= len(added) {
+			break
+		}
+	}
+	return pairs
+}
+
+// tokenizeWords splits a line into identifier/number runs, whitespace runs
+// and single punctuation characters.
+func tokenizeWords(text string) []wordToken {
+	var tokens []wordToken
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		start := i
+		space := unicode.IsSpace(r)
+		switch {
+		case isWordRune(r):
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+		case space:
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				i += size
+			}
+		default:
+			i += size
+		}
+		tokens = append(tokens, wordToken{start: start, end: i, text: text[start:i], space: space})
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenMatches returns the LCS alignment of two token lists as a per-token
+// "matched" flag for each side. It reports false when the lines are too long.
+func tokenMatches(oldTokens []wordToken, newTokens []wordToken) ([]bool, []bool, bool) {
+	n, m := len(oldTokens), len(newTokens)
+	if n > maxWordTokens || m > maxWordTokens {
+		return nil, nil, false
+	}
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldTokens[i].text == newTokens[j].text {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+	oldMatched := make([]bool, n)
+	newMatched := make([]bool, m)
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case oldTokens[i].text == newTokens[j].text:
+			oldMatched[i] = true
+			newMatched[j] = true
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return oldMatched, newMatched, true
+}
+
+// tokenSimilarity is the share of non-whitespace tokens the two lines have
+// in common, so renaming one long identifier still pairs the lines.
+func tokenSimilarity(oldTokens []wordToken, newTokens []wordToken) (float64, bool) {
+	oldMatched, newMatched, ok := tokenMatches(oldTokens, newTokens)
+	if !ok {
+		return 0, false
+	}
+	return matchedShare(oldTokens, oldMatched, newTokens, newMatched), true
+}
+
+func matchedShare(oldTokens []wordToken, oldMatched []bool, newTokens []wordToken, newMatched []bool) float64 {
+	total, matched := 0, 0
+	count := func(tokens []wordToken, flags []bool) {
+		for i, token := range tokens {
+			if token.space {
+				continue
+			}
+			total++
+			if flags[i] {
+				matched++
+			}
+		}
+	}
+	count(oldTokens, oldMatched)
+	count(newTokens, newMatched)
+	if total == 0 {
+		return 1
+	}
+	return float64(matched) / float64(total)
+}
+
+// wordDiff returns the similarity of two lines and the token spans that
+// differ between them. Adjacent unmatched tokens form a single edit.
+func wordDiff(oldText string, newText string) (float64, []DiffWordEdit) {
+	oldTokens := tokenizeWords(oldText)
+	newTokens := tokenizeWords(newText)
+	oldMatched, newMatched, ok := tokenMatches(oldTokens, newTokens)
+	if !ok {
+		return 0, nil
+	}
+
+	var edits []DiffWordEdit
+	i, j := 0, 0
+	for i < len(oldTokens) || j < len(newTokens) {
+		if i < len(oldTokens) && j < len(newTokens) && oldMatched[i] && newMatched[j] {
+			i++
+			j++
+			continue
+		}
+		edit := DiffWordEdit{
+			OldStart: tokenOffset(oldTokens, i, len(oldText)),
+			NewStart: tokenOffset(newTokens, j, len(newText)),
+		}
+		for i < len(oldTokens) && !oldMatched[i] {
+			i++
+		}
+		for j < len(newTokens) && !newMatched[j] {
+			j++
+		}
+		edit.OldEnd = tokenOffset(oldTokens, i, len(oldText))
+		edit.NewEnd = tokenOffset(newTokens, j, len(newText))
+		edit.OldText = oldText[edit.OldStart:edit.OldEnd]
+		edit.NewText = newText[edit.NewStart:edit.NewEnd]
+		switch {
+		case edit.OldText == "":
+			edit.Kind = WordEditInsert
+		case edit.NewText == "":
+			edit.Kind = WordEditDelete
+		default:
+			edit.Kind = WordEditReplace
+		}
+		edits = append(edits, edit)
+	}
+	return matchedShare(oldTokens, oldMatched, newTokens, newMatched), edits
+}
+
+func tokenOffset(tokens []wordToken, index int, length int) int {
+	if index < len(tokens) {
+		return tokens[index].start
+	}
+	return length
+}
@@ -0,0 +1,403 @@
+package refactorindex
+
+import (
+	"context"
+	"database/sql"
+)
+
+// diffLineBatchSize bounds the number of diff lines buffered between inserts.
+const diffLineBatchSize = 500
+
+type diffCounts struct {
+	Files int
+	Hunks int
+	Lines int
+	diffAnnotationCounts
+}
+
+type diffWriterFile struct {
+	id         int64
+	fileID     int64
+	path       string
+	stats      FileChangeStats
+	classifier *changeClassifier
+	insertions int
+	deletions  int
+}
+
+// diffLinePairRow is a line pair waiting for its lines to be flushed.
+type diffLinePairRow struct {
+	hunkID     int64
+	oldLineID  int64
+	newLineID  int64
+	similarity float64
+	edits      []DiffWordEdit
+}
+
+// diffWriter stores a streamed diff for one run. Files known up front (from
+// name-status) are inserted by AddEntries; without entries, diff_files rows
+// are created from the patch headers as they are read.
+//
+// Lines are numbered as they arrive so that each change block can be paired
+// and word-diffed while the hunk is streamed. Move detection needs the whole
+// diff and runs in Finish over a hash index of the changed lines.
+type diffWriter struct {
+	ctx   context.Context
+	store *Store
+	tx    *sql.Tx
+	runID int64
+	// minMovedLines is the shortest moved block; 0 uses the default.
+	minMovedLines int
+	// skipAnnotations turns off line pairing, word diffs and move detection.
+	skipAnnotations bool
+
+	files   []*diffWriterFile
+	byPath  map[string]*diffWriterFile
+	fromAdd bool
+
+	current *diffWriterFile
+	hunkID  int64
+	lastID  int64
+	pending []DiffLineRow
+	pairs   []diffLinePairRow
+	block   []changeLine
+	counts  diffCounts
+
+	removedRuns [][]*movedLine
+	addedRuns   [][]*movedLine
+}
+
+func newDiffWriter(ctx context.Context, store *Store, tx *sql.Tx, runID int64) *diffWriter {
+	return &diffWriter{
+		ctx:    ctx,
+		store:  store,
+		tx:     tx,
+		runID:  runID,
+		byPath: make(map[string]*diffWriterFile),
+	}
+}
+
+// AddEntries inserts diff_files rows for name-status entries, with numstat
+// data attached. Change classes are filled in by Finish.
+func (w *diffWriter) AddEntries(entries []DiffFileEntry, numstat []NumstatEntry) error {
+	w.fromAdd = true
+	numstatByPath := make(map[string]NumstatEntry, len(numstat))
+	for _, entry := range numstat {
+		numstatByPath[entry.PrimaryPath()] = entry
+	}
+	for _, entry := range entries {
+		stats := FileChangeStats{Similarity: entry.Similarity()}
+		if ns, ok := numstatByPath[entry.PrimaryPath()]; ok {
+			stats.Insertions = ns.Insertions
+			stats.Deletions = ns.Deletions
+			stats.IsBinary = ns.Binary
+		}
+		if _, err := w.insertFile(entry, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *diffWriter) insertFile(entry DiffFileEntry, stats FileChangeStats) (*diffWriterFile, error) {
+	primaryPath := entry.PrimaryPath()
+	fileID, err := w.store.GetOrCreateFile(w.ctx, w.tx, primaryPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry.OldPath != "" {
+		if _, err := w.store.GetOrCreateFile(w.ctx, w.tx, entry.OldPath); err != nil {
+			return nil, err
+		}
+	}
+	if entry.NewPath != "" {
+		if _, err := w.store.GetOrCreateFile(w.ctx, w.tx, entry.NewPath); err != nil {
+			return nil, err
+		}
+	}
+	diffFileID, err := w.store.InsertDiffFile(w.ctx, w.tx, w.runID, fileID, entry.Status, entry.OldPath, entry.NewPath, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &diffWriterFile{id: diffFileID, fileID: fileID, path: primaryPath, stats: stats}
+	w.files = append(w.files, file)
+	w.counts.Files++
+	w.byPath[primaryPath] = file
+	if entry.OldPath != "" {
+		w.byPath[entry.OldPath] = file
+	}
+	if entry.NewPath != "" {
+		w.byPath[entry.NewPath] = file
+	}
+	return file, nil
+}
+
+func (w *diffWriter) lookup(oldPath string, newPath string) *diffWriterFile {
+	if newPath != "" {
+		if file, ok := w.byPath[newPath]; ok {
+			return file
+		}
+	}
+	if oldPath != "" {
+		if file, ok := w.byPath[oldPath]; ok {
+			return file
+		}
+	}
+	return nil
+}
+
+// Callbacks returns the StreamUnifiedDiff callbacks that feed the writer.
+func (w *diffWriter) Callbacks() DiffCallbacks {
+	return DiffCallbacks{
+		OnFile: w.onFile,
+		OnHunk: w.onHunk,
+		OnLine: w.onLine,
+	}
+}
+
+func (w *diffWriter) onFile(patch *FilePatch) error {
+	if err := w.endBlock(); err != nil {
+		return err
+	}
+	file := w.lookup(patch.OldPath, patch.NewPath)
+	if file == nil && !w.fromAdd {
+		entry := DiffFileEntry{Status: patch.Status, OldPath: patch.OldPath, NewPath: patch.NewPath}
+		inserted, err := w.insertFile(entry, FileChangeStats{Similarity: entry.Similarity()})
+		if err != nil {
+			return err
+		}
+		file = inserted
+	}
+	w.current = file
+	w.hunkID = 0
+	if file == nil {
+		return nil
+	}
+	if patch.Binary {
+		file.stats.IsBinary = true
+	}
+	if file.classifier == nil {
+		file.classifier = newChangeClassifier(file.path)
+	}
+	return nil
+}
+
+func (w *diffWriter) onHunk(_ *FilePatch, hunk DiffHunk) error {
+	if err := w.endBlock(); err != nil {
+		return err
+	}
+	if w.current == nil {
+		return nil
+	}
+	hunkID, err := w.store.InsertDiffHunk(w.ctx, w.tx, w.current.id, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	if err != nil {
+		return err
+	}
+	w.hunkID = hunkID
+	w.counts.Hunks++
+	return nil
+}
+
+func (w *diffWriter) onLine(_ *FilePatch, line DiffLine) error {
+	if w.current == nil || w.hunkID == 0 {
+		return nil
+	}
+	w.current.classifier.Add(line)
+	switch line.Kind {
+	case "+":
+		w.current.insertions++
+	case "-":
+		w.current.deletions++
+	}
+	if w.lastID == 0 {
+		lastID, err := w.store.MaxDiffLineID(w.ctx, w.tx)
+		if err != nil {
+			return err
+		}
+		w.lastID = lastID
+	}
+	w.lastID++
+	w.pending = append(w.pending, DiffLineRow{ID: w.lastID, HunkID: w.hunkID, Line: line})
+	w.counts.Lines++
+
+	switch line.Kind {
+	case "-":
+		w.block = append(w.block, changeLine{id: w.lastID, kind: line.Kind, lineNo: lineNumber(line.OldLine), text: line.Text})
+	case "+":
+		w.block = append(w.block, changeLine{id: w.lastID, kind: line.Kind, lineNo: lineNumber(line.NewLine), text: line.Text})
+	default:
+		if err := w.endBlock(); err != nil {
+			return err
+		}
+	}
+	if len(w.pending) >= diffLineBatchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// endBlock pairs the removed and added lines of the change block that just
+// ended and adds its runs to the move index.
+func (w *diffWriter) endBlock() error {
+	block := w.block
+	w.block = nil
+	if len(block) == 0 || w.skipAnnotations {
+		return nil
+	}
+	var removed, added []changeLine
+	for _, line := range block {
+		if line.kind == "-" {
+			removed = append(removed, line)
+		} else {
+			added = append(added, line)
+		}
+	}
+	if run := w.moveRun(removed); run != nil {
+		w.removedRuns = append(w.removedRuns, run)
+	}
+	if run := w.moveRun(added); run != nil {
+		w.addedRuns = append(w.addedRuns, run)
+	}
+
+	for _, pair := range pairChangedLines(removed, added) {
+		similarity, edits := wordDiff(pair[0].text, pair[1].text)
+		w.pairs = append(w.pairs, diffLinePairRow{
+			hunkID:     w.hunkID,
+			oldLineID:  pair[0].id,
+			newLineID:  pair[1].id,
+			similarity: similarity,
+			edits:      edits,
+		})
+		w.counts.LinePairs++
+		w.counts.WordEdits += len(edits)
+	}
+	if len(w.pairs) >= diffLineBatchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *diffWriter) moveRun(lines []changeLine) []*movedLine {
+	minLines := w.minMovedLines
+	if minLines <= 0 {
+		minLines = DefaultMinMovedLines
+	}
+	if len(lines) < minLines {
+		return nil
+	}
+	run := make([]*movedLine, 0, len(lines))
+	for _, line := range lines {
+		run = append(run, newMovedLine(w.hunkID, w.current.id, line))
+	}
+	return run
+}
+
+// flush inserts buffered lines, then the pairs that reference them.
+func (w *diffWriter) flush() error {
+	if err := w.store.InsertDiffLines(w.ctx, w.tx, w.pending); err != nil {
+		return err
+	}
+	w.pending = w.pending[:0]
+	for _, pair := range w.pairs {
+		if _, err := w.store.InsertDiffLinePair(w.ctx, w.tx, w.runID, pair.hunkID, pair.oldLineID, pair.newLineID, pair.similarity, pair.edits); err != nil {
+			return err
+		}
+	}
+	w.pairs = w.pairs[:0]
+	return nil
+}
+
+// Finish flushes buffered lines, stores per-file stats and change classes,
+// and records moved blocks. Numstat from git takes precedence over counted
+// lines.
+func (w *diffWriter) Finish() (diffCounts, error) {
+	if err := w.endBlock(); err != nil {
+		return diffCounts{}, err
+	}
+	if err := w.flush(); err != nil {
+		return diffCounts{}, err
+	}
+	for _, file := range w.files {
+		stats := file.stats
+		if stats.Insertions == nil && !stats.IsBinary {
+			insertions := file.insertions
+			deletions := file.deletions
+			stats.Insertions = &insertions
+			stats.Deletions = &deletions
+		}
+		classifier := file.classifier
+		if classifier == nil {
+			classifier = newChangeClassifier(file.path)
+		}
+		stats.ChangeClass = classifier.Class(stats.IsBinary)
+		if err := w.store.UpdateDiffFileStats(w.ctx, w.tx, file.id, stats); err != nil {
+			return diffCounts{}, err
+		}
+		if stats.Insertions != nil || stats.IsBinary {
+			if err := w.store.SetFileBinary(w.ctx, w.tx, file.fileID, stats.IsBinary); err != nil {
+				return diffCounts{}, err
+			}
+		}
+	}
+	if err := w.storeMovedBlocks(); err != nil {
+		return diffCounts{}, err
+	}
+	return w.counts, nil
+}
+
+// storeMovedBlocks records blocks of removed lines that reappear as added
+// lines in another hunk. Moved lines are not edits, so their pairs go.
+func (w *diffWriter) storeMovedBlocks() error {
+	minLines := w.minMovedLines
+	if minLines <= 0 {
+		minLines = DefaultMinMovedLines
+	}
+	blocks := detectMovedBlocks(w.removedRuns, w.addedRuns, minLines)
+	w.removedRuns, w.addedRuns = nil, nil
+
+	type movedCount struct{ insertions, deletions int }
+	movedByFile := make(map[int64]*movedCount)
+	var fileOrder []int64
+	fileCount := func(id int64) *movedCount {
+		count, ok := movedByFile[id]
+		if !ok {
+			count = &movedCount{}
+			movedByFile[id] = count
+			fileOrder = append(fileOrder, id)
+		}
+		return count
+	}
+	var movedLineIDs []int64
+	for _, block := range blocks {
+		if _, err := w.store.InsertDiffMovedBlock(w.ctx, w.tx, w.runID, block); err != nil {
+			return err
+		}
+		fileCount(block.FromDiffFileID).deletions += block.LineCount
+		fileCount(block.ToDiffFileID).insertions += block.LineCount
+		movedLineIDs = append(movedLineIDs, block.OldLineIDs...)
+		movedLineIDs = append(movedLineIDs, block.NewLineIDs...)
+		w.counts.MovedBlocks++
+	}
+	for _, id := range fileOrder {
+		count := movedByFile[id]
+		if err := w.store.UpdateDiffFileMoved(w.ctx, w.tx, id, count.insertions, count.deletions); err != nil {
+			return err
+		}
+	}
+
+	pairs, edits, err := w.store.DeleteDiffLinePairs(w.ctx, w.tx, w.runID, movedLineIDs)
+	if err != nil {
+		return err
+	}
+	w.counts.LinePairs -= pairs
+	w.counts.WordEdits -= edits
+	return nil
+}
+
+func lineNumber(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
@@ -0,0 +1,302 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FileClass describes a file as found in one tree. Ext, Language, Test and
+// Vendored follow from the path; Binary, Generated and Size are only known
+// when the file exists.
+type FileClass struct {
+	Ext       string
+	Language  string
+	Exists    bool
+	Binary    bool
+	Generated bool
+	Test      bool
+	Vendored  bool
+	Size      int64
+}
+
+// Excluded reports whether a FileScope leaves the file out.
+func (c FileClass) Excluded(scope FileScope) bool {
+	return (c.Generated && !scope.IncludeGenerated) || (c.Vendored && !scope.IncludeVendored)
+}
+
+type ClassifyFilesConfig struct {
+	DBPath string
+	// RootDir is the tree the indexed paths are resolved against.
+	RootDir string
+}
+
+type ClassifyFilesResult struct {
+	Files     int
+	Missing   int
+	Generated int
+	Test      int
+	Vendored  int
+	Binary    int
+}
+
+// ClassifyFiles fills the classification columns of every row in files
+// from the tree at RootDir. Files missing from the tree keep what an earlier
+// classification learned from their content. The files table holds one
+// classification per path, so the last tree classified wins and applies to
+// every commit the path appears in.
+func ClassifyFiles(ctx context.Context, cfg ClassifyFilesConfig) (*ClassifyFilesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	files, err := store.filePaths(ctx, tx, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ClassifyFilesResult{}
+	for _, file := range files {
+		class, err := ClassifyFile(rootDir, file.path)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.SetFileClass(ctx, tx, file.id, class); err != nil {
+			return nil, err
+		}
+		result.Files++
+		if !class.Exists {
+			result.Missing++
+		}
+		if class.Generated {
+			result.Generated++
+		}
+		if class.Test {
+			result.Test++
+		}
+		if class.Vendored {
+			result.Vendored++
+		}
+		if class.Binary {
+			result.Binary++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit file classification")
+	}
+	return result, nil
+}
+
+// classifyNewFiles classifies the files whose content no tree has
+// classified yet against rootDir, so rows created by an ingestion are scoped
+// by default without a separate ingest files pass. Files missing from
+// rootDir are retried by the next tree.
+func classifyNewFiles(ctx context.Context, store *Store, tx *sql.Tx, rootDir string) error {
+	files, err := store.filePaths(ctx, tx, true)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		class, err := ClassifyFile(rootDir, file.path)
+		if err != nil {
+			return err
+		}
+		if err := store.SetFileClass(ctx, tx, file.id, class); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClassifyFile classifies a root-relative, slash-separated path.
+func ClassifyFile(rootDir string, relPath string) (FileClass, error) {
+	class := FileClass{
+		Ext:      strings.TrimPrefix(path.Ext(relPath), "."),
+		Language: fileLanguage(relPath),
+		Test:     isTestPath(relPath),
+		Vendored: isVendoredPath(relPath),
+	}
+
+	f, err := os.Open(filepath.Join(rootDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return class, nil
+		}
+		return class, errors.Wrap(err, "open file")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return class, errors.Wrap(err, "stat file")
+	}
+	if info.IsDir() {
+		return class, nil
+	}
+	class.Exists = true
+	class.Size = info.Size()
+
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return class, errors.Wrap(err, "read file")
+	}
+	head = head[:n]
+	class.Binary = bytes.IndexByte(head, 0) >= 0
+	if !class.Binary {
+		class.Generated = hasGeneratedHeader(head)
+	}
+	return class, nil
+}
+
+// generatedHeader matches the "Code generated ... DO NOT EDIT." marker of
+// https://go.dev/s/generatedcode in the comment syntax of common languages.
+var generatedHeader = regexp.MustCompile(`^(?://|#|--|;+|/\*+|\*|<!--)\s*Code generated .* DO NOT EDIT\.`)
+
+// hasGeneratedHeader looks for the marker in the leading comments of a
+// file, stopping at the first line that is neither blank nor a comment, the
+// way Go requires it to appear before the package clause.
+func hasGeneratedHeader(head []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(head))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if generatedHeader.MatchString(line) {
+			return true
+		}
+		if !isCommentLine(line) {
+			return false
+		}
+	}
+	return false
+}
+
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "#", "--", ";", "/*", "*", "<!--"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTestPath(relPath string) bool {
+	base := path.Base(relPath)
+	for _, segment := range strings.Split(path.Dir(relPath), "/") {
+		switch segment {
+		case "testdata", "__tests__":
+			return true
+		}
+	}
+	switch {
+	case strings.HasSuffix(base, "_test.go"):
+		return true
+	case strings.HasSuffix(base, ".py") && (strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py")):
+		return true
+	case strings.Contains(base, ".test.") || strings.Contains(base, ".spec."):
+		return true
+	}
+	return false
+}
+
+func isVendoredPath(relPath string) bool {
+	for _, segment := range strings.Split(path.Dir(relPath), "/") {
+		switch segment {
+		case "vendor", "node_modules", "third_party":
+			return true
+		}
+	}
+	return false
+}
+
+var languagesByName = map[string]string{
+	"go.mod":     "Go Module",
+	"go.sum":     "Go Checksums",
+	"go.work":    "Go Workspace",
+	"Makefile":   "Makefile",
+	"Dockerfile": "Dockerfile",
+	"CODEOWNERS": "CODEOWNERS",
+}
+
+var languagesByExt = map[string]string{
+	"go":    "Go",
+	"c":     "C",
+	"h":     "C",
+	"cc":    "C++",
+	"cpp":   "C++",
+	"hpp":   "C++",
+	"s":     "Assembly",
+	"rs":    "Rust",
+	"java":  "Java",
+	"kt":    "Kotlin",
+	"py":    "Python",
+	"rb":    "Ruby",
+	"js":    "JavaScript",
+	"jsx":   "JavaScript",
+	"mjs":   "JavaScript",
+	"ts":    "TypeScript",
+	"tsx":   "TypeScript",
+	"sh":    "Shell",
+	"bash":  "Shell",
+	"sql":   "SQL",
+	"proto": "Protocol Buffers",
+	"html":  "HTML",
+	"tmpl":  "Template",
+	"css":   "CSS",
+	"md":    "Markdown",
+	"txt":   "Text",
+	"json":  "JSON",
+	"yaml":  "YAML",
+	"yml":   "YAML",
+	"toml":  "TOML",
+	"xml":   "XML",
+}
+
+func fileLanguage(relPath string) string {
+	base := path.Base(relPath)
+	if language, ok := languagesByName[base]; ok {
+		return language
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(base), "."))
+	return languagesByExt[ext]
+}
@@ -0,0 +1,174 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyFiles(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{filepath.Join(repoPath, "vendor", "example.com", "dep"), filepath.Join(repoPath, "testdata")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), "package app\n\n//go:generate stringer -type=Mode\ntype Mode int\n")
+	writeFile(t, filepath.Join(repoPath, "mode_string.go"), `// Code generated by "stringer -type=Mode"; DO NOT EDIT.
+
+package app
+
+func (i Mode) String() string { return "" } //nolint:gocritic
+`)
+	writeFile(t, filepath.Join(repoPath, "app_test.go"), "package app\n")
+	writeFile(t, filepath.Join(repoPath, "vendor", "example.com", "dep", "dep.go"), "package dep\n")
+	writeFile(t, filepath.Join(repoPath, "testdata", "logo.png"), "\x89PNG\x00\x01")
+	writeFile(t, filepath.Join(repoPath, "late.go"), "package app\n\nvar x = 1\n\n// Code generated by hand. DO NOT EDIT.\n")
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	directivesResult, err := IngestDirectives(ctx, IngestDirectivesConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest directives: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	// Ingestion classifies the files it adds without an ingest files pass.
+	ingested, err := store.ListDirectives(ctx, DirectiveFilter{RunID: directivesResult.RunID})
+	if err != nil {
+		t.Fatalf("list ingested directives: %v", err)
+	}
+	if len(ingested) != 1 || ingested[0].Name != "go:generate" {
+		t.Fatalf("expected the generated file to be classified on ingestion, got %+v", ingested)
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("begin tx: %v", err)
+	}
+	for _, path := range []string{"app_test.go", "vendor/example.com/dep/dep.go", "testdata/logo.png", "late.go", "gone.go"} {
+		if _, err := store.GetOrCreateFile(ctx, tx, path); err != nil {
+			t.Fatalf("create file %s: %v", path, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit files: %v", err)
+	}
+
+	result, err := ClassifyFiles(ctx, ClassifyFilesConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("classify files: %v", err)
+	}
+	if result.Files != 7 || result.Missing != 1 || result.Generated != 1 || result.Test != 2 || result.Vendored != 1 || result.Binary != 1 {
+		t.Fatalf("unexpected classification: %+v", result)
+	}
+
+	files, err := store.ListFiles(ctx, FileFilter{FileScope: FileScope{IncludeGenerated: true, IncludeVendored: true}})
+	if err != nil {
+		t.Fatalf("list files: %v", err)
+	}
+	byPath := make(map[string]FileRecord)
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+	if file := byPath["mode_string.go"]; !file.Classified || !file.Generated || file.Language != "Go" || !file.Exists || file.Size == 0 {
+		t.Fatalf("unexpected generated file: %+v", file)
+	}
+	if file := byPath["late.go"]; file.Generated {
+		t.Fatalf("marker after the package clause should not count: %+v", file)
+	}
+	if file := byPath["testdata/logo.png"]; !file.Binary || !file.Test || file.Ext != "png" {
+		t.Fatalf("unexpected binary file: %+v", file)
+	}
+	if file := byPath["gone.go"]; file.Exists || !file.Classified {
+		t.Fatalf("unexpected missing file: %+v", file)
+	}
+
+	scoped, err := store.ListFiles(ctx, FileFilter{})
+	if err != nil {
+		t.Fatalf("list scoped files: %v", err)
+	}
+	if len(scoped) != 5 {
+		t.Fatalf("expected generated and vendored files to be excluded, got %+v", scoped)
+	}
+
+	directives, err := store.ListDirectives(ctx, DirectiveFilter{RunID: directivesResult.RunID})
+	if err != nil {
+		t.Fatalf("list directives: %v", err)
+	}
+	if len(directives) != 1 || directives[0].Name != "go:generate" {
+		t.Fatalf("expected the generated file's directive to be excluded, got %+v", directives)
+	}
+	all, err := store.ListDirectives(ctx, DirectiveFilter{RunID: directivesResult.RunID, FileScope: FileScope{IncludeGenerated: true}})
+	if err != nil {
+		t.Fatalf("list all directives: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected directives of generated files when included, got %+v", all)
+	}
+}
+
+func TestIngestCommitRangeClassifiesFiles(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repoPath, "vendor", "example.com", "dep"), 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "classes\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "app.go"), "package app\n")
+	writeFile(t, filepath.Join(repoPath, "app_gen.go"), "// Code generated by gen. DO NOT EDIT.\n\npackage app\n")
+	writeFile(t, filepath.Join(repoPath, "vendor", "example.com", "dep", "dep.go"), "package dep\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "add app")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:      dbPath,
+		RepoPath:    repoPath,
+		FromRef:     fromRef,
+		ToRef:       toRef,
+		SourcesDir:  filepath.Join(root, "sources"),
+		IncludeDiff: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	files, err := NewStore(db).ListDiffFiles(ctx, DiffFileFilter{RunID: rangeResult.Commits[0].DiffRunID})
+	if err != nil {
+		t.Fatalf("list diff files: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "app.go" {
+		t.Fatalf("expected generated and vendored files to be excluded by default, got %+v", files)
+	}
+}
@@ -0,0 +1,236 @@
+package refactorindex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// goModuleDirs returns the module directories to load under rootDir. A
+// go.work file at the root is honored; otherwise every go.mod below the root
+// is a module, skipping hidden, vendor and testdata directories. Explicit
+// package patterns are loaded from the root only, as given.
+func goModuleDirs(ctx context.Context, rootDir string, opts GoLoadOptions, env []string) ([]string, bool, error) {
+	if len(opts.Patterns) > 0 {
+		return []string{rootDir}, false, nil
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "go.work")); err == nil {
+		dirs, err := goWorkModuleDirs(ctx, rootDir, env)
+		if err != nil {
+			return nil, false, err
+		}
+		return dirs, true, nil
+	}
+
+	dirs, err := findGoModDirs(rootDir)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(dirs) == 0 {
+		// Let go list report the missing module as before.
+		return []string{rootDir}, false, nil
+	}
+	return dirs, false, nil
+}
+
+// findGoModDirs returns the sorted directories below rootDir that contain a
+// go.mod, skipping hidden, "_", vendor and testdata directories.
+func findGoModDirs(rootDir string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != rootDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "find go.mod files")
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func goWorkModuleDirs(ctx context.Context, rootDir string, env []string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "go", "work", "edit", "-json")
+	cmd.Dir = rootDir
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "read go.work")
+	}
+	var work struct {
+		Use []struct {
+			DiskPath string
+		}
+	}
+	if err := json.Unmarshal(out, &work); err != nil {
+		return nil, errors.Wrap(err, "parse go.work")
+	}
+	dirs := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		dir := use.DiskPath
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rootDir, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("go.work has no use directives")
+	}
+	return dirs, nil
+}
+
+// workspaceEnv drops -mod flags from GOFLAGS, which the go command rejects
+// in workspace mode.
+func workspaceEnv(env []string) []string {
+	if env == nil {
+		env = os.Environ()
+	}
+	result := make([]string, 0, len(env))
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GOFLAGS="); ok {
+			var flags []string
+			for _, flag := range strings.Fields(value) {
+				if !strings.HasPrefix(flag, "-mod=") {
+					flags = append(flags, flag)
+				}
+			}
+			kv = "GOFLAGS=" + strings.Join(flags, " ")
+		}
+		result = append(result, kv)
+	}
+	return result
+}
+
+// symbolPackagePaths maps packages to the package path used in symbol
+// and code unit hashes. Import paths already include the module path, so
+// they only need qualifying when two loaded modules share a module path
+// (e.g. several examples declaring "module example"); those get the
+// root-relative module directory appended.
+func symbolPackagePaths(rootDir string, pkgs []*packages.Package) map[*packages.Package]string {
+	moduleDirs := make(map[string]map[string]struct{})
+	for _, pkg := range pkgs {
+		if pkg.Module == nil {
+			continue
+		}
+		if moduleDirs[pkg.Module.Path] == nil {
+			moduleDirs[pkg.Module.Path] = make(map[string]struct{})
+		}
+		moduleDirs[pkg.Module.Path][pkg.Module.Dir] = struct{}{}
+	}
+	paths := make(map[*packages.Package]string, len(pkgs))
+	for _, pkg := range pkgs {
+		path := pkg.PkgPath
+		if pkg.Module != nil && len(moduleDirs[pkg.Module.Path]) > 1 {
+			dir, ok := rootRelativePath(rootDir, pkg.Module.Dir)
+			if !ok {
+				dir = pkg.Module.Dir
+			}
+			path += " [" + dir + "]"
+		}
+		paths[pkg] = path
+	}
+	return paths
+}
+
+// insertGoPackages records the module, package and files of every loaded
+// package for a run. A package loaded several times (test variants, build
+// matrix configurations) is recorded once with the union of its files.
+func insertGoPackages(ctx context.Context, store *Store, tx *sql.Tx, runID int64, rootDir string, pkgs []*packages.Package, pkgPaths map[*packages.Package]string) error {
+	type goPackage struct {
+		first *packages.Package
+		files []string
+		seen  map[string]struct{}
+	}
+	var order []string
+	byPath := make(map[string]*goPackage)
+	for _, pkg := range pkgs {
+		pkgPath := pkgPaths[pkg]
+		entry, ok := byPath[pkgPath]
+		if !ok {
+			entry = &goPackage{first: pkg, seen: make(map[string]struct{})}
+			byPath[pkgPath] = entry
+			order = append(order, pkgPath)
+		}
+		for _, file := range pkg.CompiledGoFiles {
+			rel, ok := rootRelativePath(rootDir, file)
+			if !ok {
+				continue
+			}
+			if _, dup := entry.seen[rel]; dup {
+				continue
+			}
+			entry.seen[rel] = struct{}{}
+			entry.files = append(entry.files, rel)
+		}
+	}
+
+	moduleIDs := make(map[string]int64)
+	for _, pkgPath := range order {
+		entry := byPath[pkgPath]
+		pkg := entry.first
+
+		var moduleID *int64
+		if pkg.Module != nil {
+			key := pkg.Module.Path + "\x00" + pkg.Module.Dir
+			id, ok := moduleIDs[key]
+			if !ok {
+				dir, _ := rootRelativePath(rootDir, pkg.Module.Dir)
+				var err error
+				id, err = store.InsertGoModule(ctx, tx, runID, pkg.Module.Path, dir, pkg.Module.GoVersion)
+				if err != nil {
+					return err
+				}
+				moduleIDs[key] = id
+			}
+			moduleID = &id
+		}
+
+		pkgDir := ""
+		if len(entry.files) > 0 {
+			pkgDir = filepath.ToSlash(filepath.Dir(filepath.FromSlash(entry.files[0])))
+		}
+		packageID, err := store.InsertGoPackage(ctx, tx, runID, moduleID, pkgPath, pkg.Name, pkgDir)
+		if err != nil {
+			return err
+		}
+		for _, rel := range entry.files {
+			fileID, err := store.GetOrCreateFile(ctx, tx, rel)
+			if err != nil {
+				return err
+			}
+			if err := store.InsertGoPackageFile(ctx, tx, packageID, fileID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countPackagePaths counts distinct packages across repeated loads.
+func countPackagePaths(pkgPaths map[*packages.Package]string) int {
+	distinct := make(map[string]struct{}, len(pkgPaths))
+	for _, path := range pkgPaths {
+		distinct[path] = struct{}{}
+	}
+	return len(distinct)
+}
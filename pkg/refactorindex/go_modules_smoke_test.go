@@ -0,0 +1,89 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestSymbolsNestedModules(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{"tools", "examples/a", "examples/b", "testdata/skip"} {
+		if err := os.MkdirAll(filepath.Join(repoPath, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/root\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "root.go"), "package root\n\nfunc Root() {}\n")
+	writeFile(t, filepath.Join(repoPath, "tools", "go.mod"), "module example.com/tools\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "tools", "tools.go"), "package tools\n\nfunc Tool() {}\n")
+	for _, dir := range []string{"a", "b"} {
+		writeFile(t, filepath.Join(repoPath, "examples", dir, "go.mod"), "module example\n\ngo 1.21\n")
+		writeFile(t, filepath.Join(repoPath, "examples", dir, "main.go"), "package main\n\nfunc Run() {}\n\nfunc main() { Run() }\n")
+	}
+	writeFile(t, filepath.Join(repoPath, "testdata", "skip", "go.mod"), "module example.com/skip\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "testdata", "skip", "skip.go"), "package skip\n\nfunc Skipped() {}\n")
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest symbols: %v", err)
+	}
+	if result.Packages != 4 {
+		t.Fatalf("expected 4 packages across modules, got %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	tools, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: result.RunID, Module: "example.com/tools"})
+	if err != nil {
+		t.Fatalf("list tools symbols: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "Tool" || tools[0].FilePath != "tools/tools.go" || tools[0].Module != "example.com/tools" {
+		t.Fatalf("unexpected tools symbols: %+v", tools)
+	}
+
+	runs, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: result.RunID, Name: "Run"})
+	if err != nil {
+		t.Fatalf("list Run symbols: %v", err)
+	}
+	if len(runs) != 2 || runs[0].SymbolHash == runs[1].SymbolHash {
+		t.Fatalf("expected distinct Run symbols per example module, got %+v", runs)
+	}
+	if runs[0].Pkg != "example [examples/a]" || runs[1].Pkg != "example [examples/b]" {
+		t.Fatalf("unexpected example package paths: %+v", runs)
+	}
+
+	var modules int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM go_modules WHERE run_id = ?", result.RunID).Scan(&modules); err != nil {
+		t.Fatalf("count modules: %v", err)
+	}
+	if modules != 4 {
+		t.Fatalf("expected 4 modules, got %d", modules)
+	}
+
+	// A go.work at the root selects the modules to load.
+	writeFile(t, filepath.Join(repoPath, "go.work"), "go 1.21\n\nuse (\n\t.\n\t./tools\n)\n")
+	workResult, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest workspace symbols: %v", err)
+	}
+	workSymbols, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: workResult.RunID})
+	if err != nil {
+		t.Fatalf("list workspace symbols: %v", err)
+	}
+	if len(workSymbols) != 2 || workSymbols[0].Name != "Root" || workSymbols[1].Name != "Tool" {
+		t.Fatalf("unexpected workspace symbols: %+v", workSymbols)
+	}
+}
@@ -0,0 +1,626 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	APIRemoved = "removed"
+	APIChanged = "changed"
+	// APIUnknown marks symbols whose signature mentions a type that could
+	// not be resolved in either version, so whether it changed is unknown.
+	APIUnknown = "unknown"
+)
+
+// IngestAPIDiffConfig controls the comparison of two versions of a
+// dependency's exported API.
+type IngestAPIDiffConfig struct {
+	DBPath     string
+	SourcesDir string
+	// Module is the import path prefix both versions are compared under. It
+	// defaults to the module path of each directory's go.mod and is required
+	// for directories without one, such as vendored copies. Setting it also
+	// compares a new major version against the import paths of the old one:
+	// a version whose go.mod declares another path, such as a /v2 suffix,
+	// has its imports of that path read as imports of Module.
+	Module string
+	// OldDir and NewDir hold the two versions. When a directory is empty it
+	// is resolved in the module cache from Module and the version.
+	OldDir     string
+	NewDir     string
+	OldVersion string
+	NewVersion string
+}
+
+// IngestAPIDiffResult reports counts for an API comparison.
+type IngestAPIDiffResult struct {
+	RunID       int64
+	OldPackages int
+	NewPackages int
+	Removed     int
+	Changed     int
+	Unknown     int
+	LoadErrors  int
+	Partial     bool
+}
+
+// APIChange is an exported symbol that was removed, whose signature
+// changed, or whose signature could not be compared between two versions.
+// Pkg, Name and Recv follow the conventions of ExternalUse so changes can be
+// joined against recorded uses; Recv is the old receiver.
+type APIChange struct {
+	Module       string
+	OldVersion   string
+	NewVersion   string
+	Pkg          string
+	Name         string
+	Recv         string
+	Kind         string
+	Change       string
+	OldSignature string
+	NewSignature string
+}
+
+// apiSymbol is one exported symbol of a loaded API version.
+type apiSymbol struct {
+	Pkg       string
+	Name      string
+	Recv      string
+	Kind      string
+	Signature string
+}
+
+func IngestAPIDiff(ctx context.Context, cfg IngestAPIDiffConfig) (*IngestAPIDiffResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	oldDir, oldVersion, err := resolveAPIDir(ctx, cfg.Module, cfg.OldDir, cfg.OldVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "old version")
+	}
+	newDir, newVersion, err := resolveAPIDir(ctx, cfg.Module, cfg.NewDir, cfg.NewVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "new version")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"module":      cfg.Module,
+		"old_dir":     oldDir,
+		"new_dir":     newDir,
+		"old_version": oldVersion,
+		"new_version": newVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    oldDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oldModule, oldSymbols, oldPackages, oldErrors, err := loadAPI(ctx, oldDir, cfg.Module)
+	if err != nil {
+		return nil, errors.Wrap(err, "load old version")
+	}
+	_, newSymbols, newPackages, newErrors, err := loadAPI(ctx, newDir, cfg.Module)
+	if err != nil {
+		return nil, errors.Wrap(err, "load new version")
+	}
+	loadErrors := append(oldErrors, newErrors...)
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, nil, loadErrors); err != nil {
+		return nil, err
+	}
+
+	result := &IngestAPIDiffResult{
+		RunID:       runID,
+		OldPackages: oldPackages,
+		NewPackages: newPackages,
+		LoadErrors:  len(loadErrors),
+		Partial:     len(loadErrors) > 0,
+	}
+	for _, change := range diffAPI(oldSymbols, newSymbols) {
+		change.Module = oldModule
+		change.OldVersion = oldVersion
+		change.NewVersion = newVersion
+		if err := store.InsertAPIChange(ctx, tx, runID, change); err != nil {
+			return nil, err
+		}
+		switch change.Change {
+		case APIRemoved:
+			result.Removed++
+		case APIUnknown:
+			result.Unknown++
+		default:
+			result.Changed++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit api diff ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// resolveAPIDir returns the directory of one version and its label. Without
+// a directory the version is looked up in the module cache; without a
+// version the label is taken from a module cache style "path@version"
+// directory name, or is the directory itself.
+func resolveAPIDir(ctx context.Context, modulePath string, dir string, version string) (string, string, error) {
+	if strings.TrimSpace(dir) == "" {
+		if modulePath == "" || version == "" {
+			return "", "", errors.New("either a directory or a module and version is required")
+		}
+		cacheDir, err := moduleCacheDir(ctx, modulePath, version)
+		if err != nil {
+			return "", "", err
+		}
+		dir = cacheDir
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", errors.Wrap(err, "resolve dir")
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return "", "", errors.Errorf("%s is not a directory", absDir)
+	}
+	if version == "" {
+		if _, v, ok := strings.Cut(filepath.Base(absDir), "@"); ok {
+			version = v
+		} else {
+			version = absDir
+		}
+	}
+	return absDir, version, nil
+}
+
+// moduleCacheDir returns the extracted module cache directory of a module
+// version, as the go command lays it out.
+func moduleCacheDir(ctx context.Context, modulePath string, version string) (string, error) {
+	out, err := exec.CommandContext(ctx, "go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", errors.Wrap(err, "go env GOMODCACHE")
+	}
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return "", errors.Wrap(err, "escape module path")
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", errors.Wrap(err, "escape module version")
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), filepath.FromSlash(escapedPath)+"@"+escapedVersion), nil
+}
+
+// loadAPI type-checks the packages of one version from source and returns
+// its exported symbols. The directory may be a module cache entry or a
+// vendored copy, neither of which go/packages can load on its own, so
+// packages are parsed with go/build's file selection for the host platform
+// and checked with go/types. Imports inside the module resolve to each
+// other, other dependencies to the module graph of the directory's go.mod
+// (see loadAPIDeps) and the standard library to its export data.
+// Dependencies that cannot be resolved are left empty, so types from them
+// print as invalid and diffAPI reports the symbols using them as unknown.
+func loadAPI(ctx context.Context, dir string, modulePath string) (string, []apiSymbol, int, []LoadError, error) {
+	modFile, modErr := ParseModFile(filepath.Join(dir, "go.mod"))
+	if modulePath == "" {
+		if modErr != nil {
+			return "", nil, 0, nil, errors.Wrapf(modErr, "module path of %s (set a module for directories without go.mod)", dir)
+		}
+		modulePath = modFile.ModulePath
+	}
+	// A new major version imports its own packages under its go.mod path,
+	// such as example.com/lib/v2/util. They resolve to the packages checked
+	// under modulePath so sibling types print alike in both versions.
+	localPath := func(importPath string) string { return importPath }
+	if modErr == nil && modFile.ModulePath != modulePath {
+		ownPath := modFile.ModulePath
+		localPath = func(importPath string) string {
+			if rest, ok := strings.CutPrefix(importPath, ownPath); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+				return modulePath + rest
+			}
+			return importPath
+		}
+	}
+
+	pkgDirs := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if p != dir {
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		pkgDirs[path.Join(modulePath, filepath.ToSlash(rel))] = p
+		return nil
+	})
+	if err != nil {
+		return "", nil, 0, nil, errors.Wrap(err, "walk packages")
+	}
+
+	deps, depErrors := loadAPIDeps(ctx, dir, pkgDirs, localPath)
+	fset := token.NewFileSet()
+	api := &apiImporter{
+		fset:       fset,
+		dirs:       pkgDirs,
+		localPath:  localPath,
+		deps:       deps,
+		checked:    make(map[string]*types.Package),
+		std:        importer.Default(),
+		loadErrors: depErrors,
+	}
+	importPaths := make([]string, 0, len(pkgDirs))
+	for importPath := range pkgDirs {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	var symbols []apiSymbol
+	packageCount := 0
+	for _, importPath := range importPaths {
+		pkg, err := api.check(importPath)
+		if err != nil {
+			api.loadErrors = append(api.loadErrors, LoadError{Package: importPath, Kind: LoadErrorParse, Message: err.Error()})
+			continue
+		}
+		if pkg == nil {
+			continue
+		}
+		packageCount++
+		symbols = append(symbols, exportedAPI(pkg)...)
+	}
+	return modulePath, symbols, packageCount, api.loadErrors, nil
+}
+
+// loadAPIDeps type-checks the packages that the packages of dir import from
+// outside the module, with the module graph of dir's go.mod. The returned
+// map also holds the standard library packages of that graph so the types
+// shared with the dependencies keep one identity. Directories without
+// go.mod, such as vendored copies, have no module graph to resolve against.
+func loadAPIDeps(ctx context.Context, dir string, pkgDirs map[string]string, localPath func(string) string) (map[string]*types.Package, []LoadError) {
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, pkgDir := range pkgDirs {
+		buildPkg, err := build.Default.ImportDir(pkgDir, 0)
+		if err != nil {
+			continue
+		}
+		for _, importPath := range buildPkg.Imports {
+			if _, ok := pkgDirs[localPath(importPath)]; ok || seen[importPath] || importPath == "C" || isStdPackage(importPath) {
+				continue
+			}
+			seen[importPath] = true
+			patterns = append(patterns, importPath)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	sort.Strings(patterns)
+
+	pkgs, err := packages.Load(&packages.Config{
+		Context: ctx,
+		// With NeedDeps the whole graph is checked from source, which does
+		// not depend on export data matching the toolchain.
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+		Env:  append(os.Environ(), "GOWORK=off"),
+	}, patterns...)
+	if err != nil {
+		return nil, []LoadError{{Kind: LoadErrorList, Message: err.Error()}}
+	}
+	deps := make(map[string]*types.Package)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil {
+			deps[pkg.PkgPath] = pkg.Types
+		}
+	})
+	var loadErrors []LoadError
+	for _, pkg := range pkgs {
+		for _, pkgErr := range pkg.Errors {
+			loadErrors = append(loadErrors, buildLoadError(dir, pkg.PkgPath, pkgErr))
+		}
+	}
+	return deps, loadErrors
+}
+
+// apiImporter checks the packages of one version on demand, resolving
+// their imports of each other and of the dependencies from loadAPIDeps.
+type apiImporter struct {
+	fset       *token.FileSet
+	dirs       map[string]string
+	localPath  func(string) string
+	deps       map[string]*types.Package
+	checked    map[string]*types.Package
+	std        types.Importer
+	loadErrors []LoadError
+}
+
+func (a *apiImporter) Import(importPath string) (*types.Package, error) {
+	importPath = a.localPath(importPath)
+	if _, ok := a.dirs[importPath]; ok {
+		pkg, err := a.check(importPath)
+		if err == nil && pkg != nil {
+			return pkg, nil
+		}
+	} else if pkg, ok := a.deps[importPath]; ok {
+		return pkg, nil
+	} else if isStdPackage(importPath) && importPath != "C" {
+		if pkg, err := a.std.Import(importPath); err == nil {
+			return pkg, nil
+		}
+	}
+	pkg := types.NewPackage(importPath, path.Base(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// check type-checks the package at importPath once. It returns nil for
+// directories without Go files.
+func (a *apiImporter) check(importPath string) (*types.Package, error) {
+	if pkg, ok := a.checked[importPath]; ok {
+		return pkg, nil
+	}
+	// Guards against import cycles, which the type checker reports.
+	a.checked[importPath] = nil
+
+	buildPkg, err := build.Default.ImportDir(a.dirs[importPath], 0)
+	if err != nil {
+		if _, ok := err.(*build.NoGoError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range append(append([]string{}, buildPkg.GoFiles...), buildPkg.CgoFiles...) {
+		file, err := parser.ParseFile(a.fset, filepath.Join(buildPkg.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	config := types.Config{
+		Importer: a,
+		// Errors from unresolved dependencies are expected; the API of the
+		// package itself is still complete, with their types left invalid.
+		Error: func(error) {},
+	}
+	pkg, _ := config.Check(importPath, a.fset, files, nil)
+	a.checked[importPath] = pkg
+	return pkg, nil
+}
+
+// exportedAPI lists the exported package-level symbols of a package, the
+// exported methods of its named types and interfaces, and the exported
+// fields of its structs.
+func exportedAPI(pkg *types.Package) []apiSymbol {
+	qualifier := types.RelativeTo(pkg)
+	var symbols []apiSymbol
+	add := func(obj types.Object, recv string, kind string) {
+		symbols = append(symbols, apiSymbol{
+			Pkg:       pkg.Path(),
+			Name:      obj.Name(),
+			Recv:      recv,
+			Kind:      kind,
+			Signature: apiSignature(obj, qualifier),
+		})
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		add(obj, "", symbolKind(obj))
+
+		typeName, ok := obj.(*types.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+		named, ok := typeName.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+			if method.Exported() {
+				add(method, receiverString(method, qualifier), "method")
+			}
+		}
+		switch underlying := named.Underlying().(type) {
+		case *types.Struct:
+			recv := types.TypeString(named, qualifier)
+			for i := 0; i < underlying.NumFields(); i++ {
+				field := underlying.Field(i)
+				if field.Exported() {
+					add(field, recv, "field")
+				}
+			}
+		case *types.Interface:
+			for i := 0; i < underlying.NumExplicitMethods(); i++ {
+				method := underlying.ExplicitMethod(i)
+				if method.Exported() {
+					add(method, receiverString(method, qualifier), "method")
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// apiSignature renders the part of a symbol that callers depend on.
+// Constant values are left out, and struct and interface types only record
+// their kind because their fields and methods are compared one by one.
+func apiSignature(obj types.Object, qualifier types.Qualifier) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		// Parameter and result names are not part of the API, so the
+		// signature is rendered from a copy with the types only.
+		sig := o.Type().(*types.Signature)
+		name := o.Name()
+		if recv := sig.Recv(); recv != nil {
+			name = "(" + types.TypeString(recv.Type(), qualifier) + ")." + name
+		}
+		unnamed := func(tuple *types.Tuple) *types.Tuple {
+			vars := make([]*types.Var, tuple.Len())
+			for i := range vars {
+				vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+			}
+			return types.NewTuple(vars...)
+		}
+		typesOnly := types.NewSignatureType(nil, nil, nil, unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic())
+		return "func " + name + typeParamList(sig.TypeParams(), qualifier) + strings.TrimPrefix(types.TypeString(typesOnly, qualifier), "func")
+	case *types.Const:
+		return "const " + o.Name() + " " + types.TypeString(o.Type(), qualifier)
+	case *types.Var:
+		if o.IsField() {
+			return "field " + o.Name() + " " + types.TypeString(o.Type(), qualifier)
+		}
+		return "var " + o.Name() + " " + types.TypeString(o.Type(), qualifier)
+	case *types.TypeName:
+		if o.IsAlias() {
+			return "type " + o.Name() + " = " + types.TypeString(o.Type(), qualifier)
+		}
+		name := types.TypeString(o.Type(), qualifier)
+		if named, ok := o.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			name = o.Name() + typeParamList(named.TypeParams(), qualifier)
+		}
+		switch underlying := o.Type().Underlying().(type) {
+		case *types.Struct:
+			return "type " + name + " struct"
+		case *types.Interface:
+			return "type " + name + " interface"
+		default:
+			return "type " + name + " " + types.TypeString(underlying, qualifier)
+		}
+	default:
+		return types.ObjectString(obj, qualifier)
+	}
+}
+
+// typeParamList renders type parameters with their constraints, or nothing
+// for a non-generic declaration.
+func typeParamList(params *types.TypeParamList, qualifier types.Qualifier) string {
+	if params.Len() == 0 {
+		return ""
+	}
+	parts := make([]string, 0, params.Len())
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		parts = append(parts, param.Obj().Name()+" "+types.TypeString(param.Constraint(), qualifier))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// diffAPI returns the symbols of the old API that are missing from the new
+// one or whose signature changed, in the old API's order. Receivers are
+// compared without their pointer so a method moving between value and
+// pointer receivers is reported as changed rather than removed. A
+// signature with an unresolved type cannot be compared, even when both
+// sides render alike, and is reported as unknown.
+func diffAPI(oldSymbols []apiSymbol, newSymbols []apiSymbol) []APIChange {
+	key := func(symbol apiSymbol) string {
+		return symbol.Pkg + "\x00" + strings.TrimPrefix(symbol.Recv, "*") + "\x00" + symbol.Name
+	}
+	byKey := make(map[string]apiSymbol, len(newSymbols))
+	for _, symbol := range newSymbols {
+		byKey[key(symbol)] = symbol
+	}
+
+	var changes []APIChange
+	for _, symbol := range oldSymbols {
+		change := APIChange{
+			Pkg:          symbol.Pkg,
+			Name:         symbol.Name,
+			Recv:         symbol.Recv,
+			Kind:         symbol.Kind,
+			OldSignature: symbol.Signature,
+		}
+		newSymbol, ok := byKey[key(symbol)]
+		switch {
+		case !ok:
+			change.Change = APIRemoved
+		case strings.Contains(symbol.Signature, "invalid type") || strings.Contains(newSymbol.Signature, "invalid type"):
+			change.Change = APIUnknown
+			change.NewSignature = newSymbol.Signature
+		case newSymbol.Signature != symbol.Signature:
+			change.Change = APIChanged
+			change.NewSignature = newSymbol.Signature
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -0,0 +1,276 @@
+package refactorindex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestAPIDiffUpgradeImpact(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "lib")
+	// The new version is a vendored copy without go.mod.
+	newPath := filepath.Join(root, "vendor", "example.com", "lib")
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{filepath.Join(oldPath, "util"), filepath.Join(newPath, "util"), repoPath} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(oldPath, "go.mod"), "module example.com/lib\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(oldPath, "util", "util.go"), "package util\n\ntype Options struct{}\n\nfunc Help() string { return \"\" }\n")
+	writeFile(t, filepath.Join(oldPath, "lib.go"), `package lib
+
+import (
+	"time"
+
+	"example.com/lib/util"
+)
+
+const Version = "1"
+
+type Client struct {
+	Name string
+	Addr string
+	Wait time.Duration
+}
+
+func New() *Client { return &Client{} }
+
+func (c *Client) Do(opts util.Options) error { return nil }
+
+func Old() int { return 0 }
+`)
+
+	writeFile(t, filepath.Join(newPath, "util", "util.go"), "package util\n\ntype Options struct{ Retries int }\n\nfunc Help() string { return \"\" }\n\nfunc Extra() {}\n")
+	writeFile(t, filepath.Join(newPath, "lib.go"), `package lib
+
+import (
+	"time"
+
+	"example.com/lib/util"
+)
+
+const Version = "2"
+
+type Client struct {
+	Name    string
+	Wait    time.Duration
+	Timeout int
+}
+
+func New(addr string) *Client { return &Client{} }
+
+func (c *Client) Do(opts util.Options) error { return nil }
+`)
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n\nrequire example.com/lib v0.0.0\n\nreplace example.com/lib => ../lib\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import (
+	"example.com/lib"
+	"example.com/lib/util"
+)
+
+func Run() error {
+	c := lib.New()
+	c.Name = lib.Version + c.Addr + util.Help()
+	_ = lib.Old()
+	return c.Do(util.Options{})
+}
+`)
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	usesResult, err := IngestExternalUses(ctx, IngestExternalUsesConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest external uses: %v", err)
+	}
+
+	if _, err := IngestAPIDiff(ctx, IngestAPIDiffConfig{DBPath: dbPath, OldDir: oldPath, NewDir: newPath}); err == nil {
+		t.Fatalf("expected an error for a new version without go.mod and no module")
+	}
+	diff, err := IngestAPIDiff(ctx, IngestAPIDiffConfig{
+		DBPath:     dbPath,
+		Module:     "example.com/lib",
+		OldDir:     oldPath,
+		NewDir:     newPath,
+		NewVersion: "v2-vendored",
+	})
+	if err != nil {
+		t.Fatalf("ingest api diff: %v", err)
+	}
+	if diff.OldPackages != 2 || diff.NewPackages != 2 || diff.Removed != 2 || diff.Changed != 1 || diff.Partial {
+		t.Fatalf("unexpected api diff result: %+v", diff)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	changes, err := store.ListAPIChanges(ctx, APIChangeFilter{RunID: diff.RunID, Change: APIChanged})
+	if err != nil {
+		t.Fatalf("list api changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "New" || changes[0].OldSignature != "func New() *Client" ||
+		changes[0].NewSignature != "func New(string) *Client" || changes[0].NewVersion != "v2-vendored" {
+		t.Fatalf("unexpected changed symbols: %+v", changes)
+	}
+
+	impact, err := store.ListUpgradeImpact(ctx, UpgradeImpactFilter{APIRunID: diff.RunID, UsesRunID: usesResult.RunID})
+	if err != nil {
+		t.Fatalf("list upgrade impact: %v", err)
+	}
+	got := make([]string, 0, len(impact))
+	for _, record := range impact {
+		got = append(got, record.Change+" "+record.Recv+"."+record.Name+" "+record.CodeUnit)
+	}
+	want := []string{"changed .New Run", "removed Client.Addr Run", "removed .Old Run"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestIngestAPIDiffParamNamesAndDependencyTypes(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	depPath := filepath.Join(root, "dep")
+	oldPath := filepath.Join(root, "old")
+	newPath := filepath.Join(root, "new")
+	// The vendored copy has no go.mod, so its dependency cannot be resolved.
+	vendoredPath := filepath.Join(root, "vendor", "example.com", "lib")
+	for _, dir := range []string{depPath, oldPath, newPath, vendoredPath} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(depPath, "go.mod"), "module example.com/dep\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(depPath, "dep.go"), "package dep\n\ntype Token struct{ ID int }\n")
+	libMod := "module example.com/lib\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ../dep\n"
+	writeFile(t, filepath.Join(oldPath, "go.mod"), libMod)
+	writeFile(t, filepath.Join(newPath, "go.mod"), libMod)
+	lib := `package lib
+
+import "example.com/dep"
+
+type Client struct{}
+
+func Join(%s) (%s) { return "", nil }
+
+func (c *Client) Send(%s) error { return nil }
+
+func Use(t dep.Token) int { return t.ID }
+`
+	writeFile(t, filepath.Join(oldPath, "lib.go"), fmt.Sprintf(lib, "sep string, parts ...string", "string, error", "msg string"))
+	writeFile(t, filepath.Join(newPath, "lib.go"), fmt.Sprintf(lib, "separator string, elems ...string", "joined string, err error", "message string"))
+	writeFile(t, filepath.Join(vendoredPath, "lib.go"), fmt.Sprintf(lib, "sep string, parts ...string", "string, error", "msg string"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	renamed, err := IngestAPIDiff(ctx, IngestAPIDiffConfig{DBPath: dbPath, OldDir: oldPath, NewDir: newPath})
+	if err != nil {
+		t.Fatalf("ingest api diff: %v", err)
+	}
+	if renamed.Removed != 0 || renamed.Changed != 0 || renamed.Unknown != 0 || renamed.Partial {
+		t.Fatalf("expected renamed parameters and a resolved dependency to be unchanged: %+v", renamed)
+	}
+
+	vendored, err := IngestAPIDiff(ctx, IngestAPIDiffConfig{
+		DBPath: dbPath,
+		Module: "example.com/lib",
+		OldDir: oldPath,
+		NewDir: vendoredPath,
+	})
+	if err != nil {
+		t.Fatalf("ingest vendored api diff: %v", err)
+	}
+	if vendored.Removed != 0 || vendored.Changed != 0 || vendored.Unknown != 1 {
+		t.Fatalf("expected only the unresolved dependency type to be unknown: %+v", vendored)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	changes, err := NewStore(db).ListAPIChanges(ctx, APIChangeFilter{RunID: vendored.RunID, Change: APIUnknown})
+	if err != nil {
+		t.Fatalf("list api changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "Use" || changes[0].OldSignature != "func Use(example.com/dep.Token) int" ||
+		changes[0].NewSignature != "func Use(invalid type) int" {
+		t.Fatalf("unexpected unknown symbols: %+v", changes)
+	}
+}
+
+func TestIngestAPIDiffMajorVersion(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "lib")
+	newPath := filepath.Join(root, "lib-v2")
+	for _, dir := range []string{filepath.Join(oldPath, "util"), filepath.Join(newPath, "util")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	util := "package util\n\ntype Options struct{ Retries int }\n"
+	lib := `package lib
+
+import "%s/util"
+
+func Do(opts util.Options) error { return nil }
+
+func Count(%s) int { return 0 }
+`
+	writeFile(t, filepath.Join(oldPath, "go.mod"), "module example.com/lib\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(oldPath, "util", "util.go"), util)
+	writeFile(t, filepath.Join(oldPath, "lib.go"), fmt.Sprintf(lib, "example.com/lib", ""))
+	writeFile(t, filepath.Join(newPath, "go.mod"), "module example.com/lib/v2\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(newPath, "util", "util.go"), util)
+	writeFile(t, filepath.Join(newPath, "lib.go"), fmt.Sprintf(lib, "example.com/lib/v2", "opts util.Options"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	diff, err := IngestAPIDiff(ctx, IngestAPIDiffConfig{
+		DBPath: dbPath,
+		Module: "example.com/lib",
+		OldDir: oldPath,
+		NewDir: newPath,
+	})
+	if err != nil {
+		t.Fatalf("ingest api diff: %v", err)
+	}
+	if diff.Removed != 0 || diff.Changed != 1 || diff.Unknown != 0 || diff.Partial {
+		t.Fatalf("expected only Count to change across the major version: %+v", diff)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	changes, err := NewStore(db).ListAPIChanges(ctx, APIChangeFilter{RunID: diff.RunID})
+	if err != nil {
+		t.Fatalf("list api changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "Count" || changes[0].NewSignature != "func Count(example.com/lib/util.Options) int" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+}
@@ -0,0 +1,285 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IngestBenchmarksConfig controls go test -bench ingestion.
+type IngestBenchmarksConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	// CommitID links results to a commit; when nil the HEAD of RootDir is
+	// looked up in the commits table.
+	CommitID *int64
+
+	// OutputPath reads existing benchmark output instead of running go test.
+	OutputPath string
+	// Packages defaults to ./...
+	Packages []string
+	// Pattern is the -bench regular expression; defaults to ".".
+	Pattern string
+	// Count is the -count value; defaults to 1.
+	Count     int
+	Benchtime string
+	Tags      []string
+	Timeout   time.Duration
+}
+
+// IngestBenchmarksResult reports counts for benchmark ingestion.
+type IngestBenchmarksResult struct {
+	RunID      int64
+	CommitID   int64
+	ExitCode   int
+	Benchmarks int
+	Samples    int
+	Metrics    int
+	RunDir     string
+}
+
+// BenchResult is one line of benchmark output: a single sample of a
+// benchmark with all its metrics.
+type BenchResult struct {
+	Package    string
+	Name       string
+	Procs      int
+	Sample     int
+	Iterations int64
+	Metrics    []BenchMetric
+}
+
+// BenchMetric is a value/unit pair such as 120 ns/op or 3.5 MB/s.
+type BenchMetric struct {
+	Unit  string
+	Value float64
+}
+
+func IngestBenchmarks(ctx context.Context, cfg IngestBenchmarksConfig) (*IngestBenchmarksResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" && strings.TrimSpace(cfg.OutputPath) == "" {
+		return nil, errors.New("root dir or benchmark output is required")
+	}
+	rootDir := ""
+	if strings.TrimSpace(cfg.RootDir) != "" {
+		var err error
+		rootDir, err = filepath.Abs(cfg.RootDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve root dir")
+		}
+	}
+	packages := cfg.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	pattern := cfg.Pattern
+	if strings.TrimSpace(pattern) == "" {
+		pattern = "."
+	}
+	count := cfg.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err := filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	commitID := cfg.CommitID
+	if commitID == nil && rootDir != "" {
+		if out, err := runGit(ctx, rootDir, "rev-parse", "HEAD"); err == nil {
+			commitID, err = store.LatestCommitIDByHash(ctx, strings.TrimSpace(string(out)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"root":        rootDir,
+		"output":      cfg.OutputPath,
+		"packages":    strings.Join(packages, " "),
+		"bench":       pattern,
+		"count":       strconv.Itoa(count),
+		"benchtime":   cfg.Benchtime,
+		"tags":        strings.Join(cfg.Tags, ","),
+		"sources_dir": sourcesDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	result := &IngestBenchmarksResult{RunID: runID, RunDir: runDir}
+	if commitID != nil {
+		result.CommitID = *commitID
+	}
+
+	var output []byte
+	var stderr []byte
+	if strings.TrimSpace(cfg.OutputPath) != "" {
+		output, err = os.ReadFile(cfg.OutputPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read benchmark output")
+		}
+	} else {
+		args := []string{"test", "-run", "^$", "-bench", pattern, "-benchmem", "-count", strconv.Itoa(count)}
+		if strings.TrimSpace(cfg.Benchtime) != "" {
+			args = append(args, "-benchtime", cfg.Benchtime)
+		}
+		if len(cfg.Tags) > 0 {
+			args = append(args, "-tags", strings.Join(cfg.Tags, ","))
+		}
+		if cfg.Timeout > 0 {
+			args = append(args, "-timeout", cfg.Timeout.String())
+		}
+		args = append(args, packages...)
+		output, stderr, result.ExitCode, err = runGoTest(ctx, rootDir, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	benchmarks, err := ParseBenchmarkOutput(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "go-test-bench", "go-test-bench.txt", output); err != nil {
+		return nil, err
+	}
+	if len(stderr) > 0 {
+		if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "go-test-bench-stderr", "go-test-bench-stderr.txt", stderr); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make(map[string]struct{})
+	for _, bench := range benchmarks {
+		if err := store.InsertBenchResult(ctx, tx, runID, commitID, bench); err != nil {
+			return nil, err
+		}
+		names[bench.Package+"\x00"+bench.Name] = struct{}{}
+		result.Samples++
+		result.Metrics += len(bench.Metrics)
+	}
+	result.Benchmarks = len(names)
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit benchmark ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ParseBenchmarkOutput parses the standard Go benchmark format. "pkg:"
+// configuration lines set the package of the benchmark lines that follow;
+// other lines (PASS, ok, test output) are ignored. Repeated lines of the same
+// benchmark, e.g. from -count, get increasing Sample numbers.
+func ParseBenchmarkOutput(r io.Reader) ([]BenchResult, error) {
+	var results []BenchResult
+	samples := make(map[string]int)
+	pkg := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "pkg:"); ok {
+			pkg = strings.TrimSpace(value)
+			continue
+		}
+		if !strings.HasPrefix(line, "Benchmark") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			continue
+		}
+		iterations, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		name, procs := splitBenchmarkProcs(fields[0])
+		bench := BenchResult{Package: pkg, Name: name, Procs: procs, Iterations: iterations}
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse benchmark value in %q", line)
+			}
+			bench.Metrics = append(bench.Metrics, BenchMetric{Unit: fields[i+1], Value: value})
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%d", pkg, name, procs)
+		bench.Sample = samples[key]
+		samples[key]++
+		results = append(results, bench)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read benchmark output")
+	}
+	return results, nil
+}
+
+// splitBenchmarkProcs splits the GOMAXPROCS suffix ("-8") from a benchmark
+// name. Names without a suffix ran with GOMAXPROCS=1.
+func splitBenchmarkProcs(name string) (string, int) {
+	idx := strings.LastIndex(name, "-")
+	if idx <= 0 {
+		return name, 1
+	}
+	procs, err := strconv.Atoi(name[idx+1:])
+	if err != nil || procs <= 0 {
+		return name, 1
+	}
+	return name[:idx], procs
+}
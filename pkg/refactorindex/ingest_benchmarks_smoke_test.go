@@ -0,0 +1,205 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBenchmarkOutput(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+pkg: example.com/calc
+cpu: Example CPU
+BenchmarkAdd-8           	1000000000	         0.2500 ns/op	       0 B/op	       0 allocs/op
+BenchmarkAdd-8           	1000000000	         0.2600 ns/op	       0 B/op	       0 allocs/op
+BenchmarkEncode/size=10-8	  500000	      2400 ns/op	  41.67 MB/s	     128 B/op	       2 allocs/op
+BenchmarkSerial          	     100	  10000000 ns/op
+--- BENCH: BenchmarkSerial
+    calc_test.go:20: some log
+PASS
+ok  	example.com/calc	3.210s
+`
+	results, err := ParseBenchmarkOutput(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("parse benchmark output: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 samples, got %d: %+v", len(results), results)
+	}
+	if results[1].Name != "BenchmarkAdd" || results[1].Procs != 8 || results[1].Sample != 1 || results[1].Package != "example.com/calc" {
+		t.Fatalf("unexpected second sample: %+v", results[1])
+	}
+	encode := results[2]
+	if encode.Name != "BenchmarkEncode/size=10" || len(encode.Metrics) != 4 || encode.Metrics[1] != (BenchMetric{Unit: "MB/s", Value: 41.67}) {
+		t.Fatalf("unexpected sub-benchmark sample: %+v", encode)
+	}
+	if results[3].Name != "BenchmarkSerial" || results[3].Procs != 1 || results[3].Iterations != 100 {
+		t.Fatalf("unexpected serial sample: %+v", results[3])
+	}
+}
+
+func TestBenchDeltasReport(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "calc\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	readmeRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/calc\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int { return a + b }\n")
+	writeFile(t, filepath.Join(repoPath, "calc_test.go"), `package calc
+
+import "testing"
+
+func BenchmarkAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = Add(i, i)
+	}
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	baseRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int { return b + a }\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "swap operands")
+	headRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	sourcesDir := filepath.Join(root, "sources")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:            dbPath,
+		RepoPath:          repoPath,
+		FromRef:           readmeRef,
+		ToRef:             headRef,
+		SourcesDir:        sourcesDir,
+		IncludeBenchmarks: true,
+		BenchTime:         "10x",
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(rangeResult.Commits) != 2 || rangeResult.Commits[1].BenchRunID == 0 {
+		t.Fatalf("expected two commits with benchmarks runs, got %+v", rangeResult.Commits)
+	}
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+	headID, err := store.LatestCommitIDByHash(ctx, headRef)
+	if err != nil || headID == nil {
+		t.Fatalf("expected head commit to be indexed: %v", err)
+	}
+
+	records, err := store.ListBenchResults(ctx, BenchResultFilter{Commit: headRef[:10], Metric: "ns/op"})
+	if err != nil {
+		t.Fatalf("list bench results: %v", err)
+	}
+	if len(records) != 1 || records[0].Name != "BenchmarkAdd" || records[0].Iterations != 10 {
+		t.Fatalf("expected one BenchmarkAdd ns/op sample, got %+v", records)
+	}
+
+	basePath := filepath.Join(root, "base.txt")
+	headPath := filepath.Join(root, "head.txt")
+	writeFile(t, basePath, `pkg: example.com/calc
+BenchmarkParse-8   1000   1000 ns/op   100 B/op   2 allocs/op   50.0 MB/s
+BenchmarkParse-8   1000   1100 ns/op   100 B/op   2 allocs/op   50.0 MB/s
+BenchmarkNoisy-8   1000   1000 ns/op
+BenchmarkNoisy-8   1000   1000 ns/op
+BenchmarkNoisy-8   1000   3000 ns/op
+`)
+	writeFile(t, headPath, `pkg: example.com/calc
+BenchmarkParse-8   1000   1500 ns/op   100 B/op   1 allocs/op   40.0 MB/s
+BenchmarkParse-8   1000   1500 ns/op   100 B/op   1 allocs/op   40.0 MB/s
+BenchmarkNoisy-8   1000   2000 ns/op
+BenchmarkNoisy-8   1000   2000 ns/op
+`)
+	if _, err := IngestBenchmarks(ctx, IngestBenchmarksConfig{DBPath: dbPath, SourcesDir: sourcesDir, OutputPath: headPath, CommitID: headID}); err != nil {
+		t.Fatalf("ingest head benchmarks: %v", err)
+	}
+	baseID, err := store.LatestCommitIDByHash(ctx, baseRef)
+	if err != nil || baseID == nil {
+		t.Fatalf("expected base commit to be indexed: %v", err)
+	}
+	if _, err := IngestBenchmarks(ctx, IngestBenchmarksConfig{DBPath: dbPath, SourcesDir: sourcesDir, OutputPath: basePath, CommitID: baseID}); err != nil {
+		t.Fatalf("ingest base benchmarks: %v", err)
+	}
+
+	reportDir := filepath.Join(root, "reports")
+	if _, err := GenerateReports(ctx, ReportConfig{
+		DBPath:         dbPath,
+		RunID:          rangeResult.CommitLineageRunID,
+		OutputDir:      reportDir,
+		BenchBase:      baseRef[:10],
+		BenchHead:      headRef[:10],
+		BenchThreshold: 0.1,
+	}); err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(reportDir, "bench-deltas.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	for _, expected := range []string{
+		"| example.com/calc | BenchmarkParse | 8 | ns/op | 1050 | ±5% | 2 | 1500 | ±0% | 2 | +42.86% | regression |",
+		"| example.com/calc | BenchmarkParse | 8 | MB/s | 50 | ±0% | 2 | 40 | ±0% | 2 | -20.00% | regression |",
+		"| example.com/calc | BenchmarkParse | 8 | B/op | 100 | ±0% | 2 | 100 | ±0% | 2 | +0.00% | ~ |",
+		"| example.com/calc | BenchmarkParse | 8 | allocs/op | 2 | ±0% | 2 | 1 | ±0% | 2 | -50.00% | improvement |",
+		// The medians double, but the base range reaches the head samples.
+		"| example.com/calc | BenchmarkNoisy | 8 | ns/op | 1000 | ±100% | 3 | 2000 | ±0% | 2 | +100.00% | ~ |",
+	} {
+		if !strings.Contains(string(report), expected) {
+			t.Fatalf("expected report row %q, got:\n%s", expected, report)
+		}
+	}
+	if !strings.Contains(string(report), "| example.com/calc | BenchmarkAdd | ") {
+		t.Fatalf("expected BenchmarkAdd from the range run in the report, got:\n%s", report)
+	}
+	for _, line := range strings.Split(string(report), "\n") {
+		if strings.Contains(line, "| BenchmarkAdd |") && !strings.HasSuffix(line, "| ~ |") {
+			t.Fatalf("expected single-sample BenchmarkAdd to be inconclusive, got %q", line)
+		}
+	}
+
+	git(t, repoPath, "tag", "v1.0", baseRef)
+	if _, err := GenerateReports(ctx, ReportConfig{
+		DBPath:         dbPath,
+		RunID:          rangeResult.CommitLineageRunID,
+		OutputDir:      reportDir,
+		RepoPath:       repoPath,
+		BenchBase:      "v1.0",
+		BenchHead:      "HEAD",
+		BenchThreshold: 0.1,
+	}); err != nil {
+		t.Fatalf("generate reports with refs: %v", err)
+	}
+	report, err = os.ReadFile(filepath.Join(reportDir, "bench-deltas.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if !strings.Contains(string(report), "Base: v1.0 ("+baseRef+")") ||
+		!strings.Contains(string(report), "| example.com/calc | BenchmarkParse | 8 | ns/op | 1050 | ±5% | 2 | 1500 | ±0% | 2 | +42.86% | regression |") {
+		t.Fatalf("expected refs resolved through the repository, got:\n%s", report)
+	}
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: rangeResult.CommitLineageRunID, OutputDir: reportDir, BenchBase: "v1.0", BenchHead: "HEAD"}); err == nil {
+		t.Fatalf("expected an unknown bench ref to fail without a repository")
+	}
+}
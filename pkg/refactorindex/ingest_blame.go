@@ -0,0 +1,298 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IngestBlameConfig controls git blame ingestion for a file set at a commit.
+type IngestBlameConfig struct {
+	DBPath     string
+	RepoPath   string
+	Commit     string
+	SourcesDir string
+	// Paths restricts blame to these pathspecs; all text files of the commit
+	// are blamed when empty.
+	Paths []string
+}
+
+// IngestBlameResult reports counts for blame ingestion.
+type IngestBlameResult struct {
+	RunID   int64
+	Commit  string
+	Files   int
+	Lines   int
+	Commits int
+	RunDir  string
+}
+
+// BlameCommit is the commit metadata git blame reports for a line.
+type BlameCommit struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	AuthorTime  int64
+	Summary     string
+	Boundary    bool
+}
+
+// BlameLine attributes one line of the blamed file to the commit that last
+// changed it, with the line's path and number in that commit.
+type BlameLine struct {
+	Line     int
+	Commit   string
+	OrigPath string
+	OrigLine int
+}
+
+func IngestBlame(ctx context.Context, cfg IngestBlameConfig) (*IngestBlameResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RepoPath) == "" {
+		return nil, errors.New("repo path is required")
+	}
+	repoPath, err := filepath.Abs(cfg.RepoPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve repo path")
+	}
+	commitRef := cfg.Commit
+	if strings.TrimSpace(commitRef) == "" {
+		commitRef = "HEAD"
+	}
+	out, err := runGit(ctx, repoPath, "rev-parse", "--verify", commitRef+"^{commit}")
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve blame commit")
+	}
+	commitHash := strings.TrimSpace(string(out))
+
+	paths, err := blameTextFiles(ctx, repoPath, commitHash, cfg.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err = filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"repo":        repoPath,
+		"commit":      commitRef,
+		"paths":       strings.Join(cfg.Paths, ","),
+		"sources_dir": sourcesDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		GitTo:       commitHash,
+		RootPath:    repoPath,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+		BaseCommit:  commitHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	raw, err := store.CreateRawOutput(ctx, tx, runDir, runID, "git-blame", "git-blame.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = raw.Close()
+	}()
+
+	result := &IngestBlameResult{RunID: runID, Commit: commitHash, RunDir: runDir}
+	commitIDs := make(map[string]int64)
+	for _, path := range paths {
+		output, err := runGit(ctx, repoPath, "blame", "--porcelain", commitHash, "--", path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "git blame %s", path)
+		}
+		if _, err := fmt.Fprintf(raw, "# %s\n", path); err != nil {
+			return nil, errors.Wrap(err, "write raw blame output")
+		}
+		if _, err := raw.Write(output); err != nil {
+			return nil, errors.Wrap(err, "write raw blame output")
+		}
+
+		lines, commits, err := ParseBlamePorcelain(output)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse blame for %s", path)
+		}
+		for _, commit := range commits {
+			if _, ok := commitIDs[commit.Hash]; ok {
+				continue
+			}
+			id, err := store.InsertBlameCommit(ctx, tx, runID, commit)
+			if err != nil {
+				return nil, err
+			}
+			commitIDs[commit.Hash] = id
+		}
+		fileID, err := store.GetOrCreateFile(ctx, tx, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.InsertBlameLines(ctx, tx, runID, fileID, lines, commitIDs); err != nil {
+			return nil, err
+		}
+		result.Files++
+		result.Lines += len(lines)
+	}
+	result.Commits = len(commitIDs)
+
+	if err := raw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close raw blame output")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit blame ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// blameTextFiles lists the non-binary files of a commit matching pathspecs,
+// using numstat against the empty tree to detect binaries.
+func blameTextFiles(ctx context.Context, repoPath string, commitHash string, pathspecs []string) ([]string, error) {
+	out, err := runGit(ctx, repoPath, "hash-object", "-t", "tree", "--stdin")
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve empty tree")
+	}
+	emptyTree := strings.TrimSpace(string(out))
+	args := []string{"diff", "--numstat", "-z", "--no-renames", emptyTree, commitHash}
+	if len(pathspecs) > 0 {
+		args = append(append(args, "--"), pathspecs...)
+	}
+	numstatOutput, err := runGit(ctx, repoPath, args...)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ParseNumstat(numstatOutput)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Binary {
+			continue
+		}
+		paths = append(paths, entry.PrimaryPath())
+	}
+	return paths, nil
+}
+
+// ParseBlamePorcelain parses git blame --porcelain output. Commits are
+// returned in order of first appearance.
+func ParseBlamePorcelain(data []byte) ([]BlameLine, []BlameCommit, error) {
+	var lines []BlameLine
+	var commits []BlameCommit
+	commitIndex := make(map[string]int)
+	filenames := make(map[string]string)
+
+	reader := bufio.NewReader(bytes.NewReader(data))
+	var current *BlameLine
+	for {
+		raw, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, nil, errors.Wrap(readErr, "read blame output")
+		}
+		line := strings.TrimSuffix(raw, "\n")
+		switch {
+		case raw == "":
+		case strings.HasPrefix(line, "\t"):
+			if current == nil {
+				return nil, nil, errors.New("blame content line without header")
+			}
+			current.OrigPath = filenames[current.Commit]
+			lines = append(lines, *current)
+			current = nil
+		case current == nil:
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, nil, errors.Errorf("invalid blame header %q", line)
+			}
+			origLine, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "parse blame orig line %q", line)
+			}
+			finalLine, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "parse blame final line %q", line)
+			}
+			hash := fields[0]
+			if _, ok := commitIndex[hash]; !ok {
+				commitIndex[hash] = len(commits)
+				commits = append(commits, BlameCommit{Hash: hash})
+			}
+			current = &BlameLine{Line: finalLine, Commit: hash, OrigLine: origLine}
+		default:
+			commit := &commits[commitIndex[current.Commit]]
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "author":
+				commit.AuthorName = value
+			case "author-mail":
+				commit.AuthorEmail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+			case "author-time":
+				if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+					commit.AuthorTime = seconds
+				}
+			case "summary":
+				commit.Summary = value
+			case "boundary":
+				commit.Boundary = true
+			case "filename":
+				filenames[current.Commit] = value
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if current != nil {
+		return nil, nil, errors.New("truncated blame output")
+	}
+	return lines, commits, nil
+}
@@ -0,0 +1,174 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestBlameAuthorship(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	commitAs := func(name string, message string) string {
+		git(t, repoPath, "add", "-A")
+		git(t, repoPath, "-c", "user.name="+name, "-c", "user.email="+strings.ToLower(name)+"@example.com", "commit", "-m", message)
+		return strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+	}
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/test\n\ngo 1.25\n")
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+`)
+	writeFile(t, filepath.Join(repoPath, "logo.bin"), "\x00\x01\x02")
+	commitAs("Alice", "initial")
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	return b + a
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+`)
+	second := commitAs("Bob", "swap operands")
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	blame, err := IngestBlame(ctx, IngestBlameConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		Commit:     second,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest blame: %v", err)
+	}
+	if blame.Files != 2 || blame.Lines != 12 || blame.Commits != 2 {
+		t.Fatalf("unexpected blame counts: %+v", blame)
+	}
+	if _, err := os.Stat(filepath.Join(blame.RunDir, "git-blame.txt")); err != nil {
+		t.Fatalf("missing git-blame.txt: %v", err)
+	}
+
+	units, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest code units: %v", err)
+	}
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	return b + a
+}
+`)
+	third := commitAs("Carol", "drop mul")
+	diff, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    second,
+		ToRef:      third,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	authors, err := store.ListCodeUnitAuthors(ctx, CodeUnitAuthorFilter{
+		BlameRunID:     blame.RunID,
+		CodeUnitsRunID: units.RunID,
+		Name:           "Add",
+	})
+	if err != nil {
+		t.Fatalf("list code unit authors: %v", err)
+	}
+	if len(authors) != 2 {
+		t.Fatalf("expected 2 authors for Add, got %+v", authors)
+	}
+	if authors[0].AuthorName != "Alice" || authors[0].Lines != 2 || authors[0].TotalLines != 3 {
+		t.Fatalf("unexpected first author: %+v", authors[0])
+	}
+	if authors[1].AuthorEmail != "bob@example.com" || authors[1].Lines != 1 {
+		t.Fatalf("unexpected second author: %+v", authors[1])
+	}
+
+	removed, err := store.ListRemovedLineAuthors(ctx, RemovedLineAuthorFilter{
+		BlameRunID: blame.RunID,
+		DiffRunID:  diff.RunID,
+	})
+	if err != nil {
+		t.Fatalf("list removed line authors: %v", err)
+	}
+	if len(removed) != 1 || removed[0].AuthorName != "Alice" || removed[0].RemovedLines != 4 || removed[0].Path != "calc.go" {
+		t.Fatalf("unexpected removed line authors: %+v", removed)
+	}
+
+	lateBlame, err := IngestBlame(ctx, IngestBlameConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		Commit:     third,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest blame at third commit: %v", err)
+	}
+	if _, err := store.ListRemovedLineAuthors(ctx, RemovedLineAuthorFilter{
+		BlameRunID: lateBlame.RunID,
+		DiffRunID:  diff.RunID,
+	}); err == nil || !strings.Contains(err.Error(), "blame the diff's from commit") {
+		t.Fatalf("expected a mismatched blame run to be rejected, got %v", err)
+	}
+
+	if _, err := IngestCommits(ctx, IngestCommitsConfig{DBPath: dbPath, RepoPath: repoPath, FromRef: second, ToRef: third}); err != nil {
+		t.Fatalf("ingest commits: %v", err)
+	}
+	thirdID, err := store.LatestCommitIDByHash(ctx, third)
+	if err != nil || thirdID == nil {
+		t.Fatalf("commit id for third: %v", err)
+	}
+	thirdUnits, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath, CommitID: thirdID})
+	if err != nil {
+		t.Fatalf("ingest code units at third commit: %v", err)
+	}
+	if _, err := store.ListCodeUnitAuthors(ctx, CodeUnitAuthorFilter{
+		BlameRunID:     blame.RunID,
+		CodeUnitsRunID: thirdUnits.RunID,
+	}); err == nil || !strings.Contains(err.Error(), "blame the code units' commit") {
+		t.Fatalf("expected code units at another commit to be rejected, got %v", err)
+	}
+	lateAuthors, err := store.ListCodeUnitAuthors(ctx, CodeUnitAuthorFilter{
+		BlameRunID:     lateBlame.RunID,
+		CodeUnitsRunID: thirdUnits.RunID,
+		Name:           "Add",
+	})
+	if err != nil {
+		t.Fatalf("list code unit authors at third commit: %v", err)
+	}
+	if len(lateAuthors) != 2 || lateAuthors[0].TotalLines != 3 {
+		t.Fatalf("unexpected authors at third commit: %+v", lateAuthors)
+	}
+}
@@ -0,0 +1,338 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// IngestCallSitesConfig controls call-site ingestion.
+type IngestCallSitesConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	// Targets lists the fully qualified functions and methods whose calls
+	// are recorded, e.g. "github.com/pkg/errors.Wrap" or
+	// "(*example.com/lib.Client).Do". Receivers may also be written without
+	// parentheses and pointer, as in "example.com/lib.Client.Do".
+	Targets  []string
+	Load     GoLoadOptions
+	Tolerant bool
+}
+
+// IngestCallSitesResult reports counts for call-site ingestion.
+type IngestCallSitesResult struct {
+	RunID      int64
+	Packages   int
+	CallSites  int
+	Args       int
+	LoadErrors int
+	Partial    bool
+}
+
+// CallSite is one call of a target function or method.
+type CallSite struct {
+	// Callee is the types.Func full name of the called function.
+	Callee   string
+	Path     string
+	Line     int
+	Col      int
+	EndLine  int
+	EndCol   int
+	CodeUnit *CodeUnitDef
+	Args     []CallArg
+}
+
+// CallArg is one argument expression of a call. Value holds the constant
+// value when the type checker knows it; strings are stored unquoted.
+type CallArg struct {
+	Source string
+	Type   string
+	Value  string
+	Const  bool
+}
+
+func IngestCallSites(ctx context.Context, cfg IngestCallSitesConfig) (*IngestCallSitesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("at least one target function is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	args := map[string]string{
+		"root":     rootDir,
+		"targets":  strings.Join(cfg.Targets, " "),
+		"tolerant": strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	argsJSON, err := EncodeArgsJSON(args)
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callees are mostly declared in dependencies, which are type-checked
+	// from source as in IngestExternalUses.
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo|packages.NeedFiles|packages.NeedCompiledGoFiles|packages.NeedImports|packages.NeedDeps)
+	pkgs, loadErrors, err := loadGoPackages(pkgConfig, cfg.Load, rootDir, cfg.Tolerant)
+	if err != nil {
+		return nil, err
+	}
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
+
+	calls, err := collectCallSites(rootDir, pkgs, pkgPaths, cfg.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+
+	result := &IngestCallSitesResult{
+		RunID:      runID,
+		Packages:   countPackagePaths(pkgPaths),
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	fileIDs := make(map[string]int64)
+	for _, call := range calls {
+		fileID, ok := fileIDs[call.Path]
+		if !ok {
+			id, err := store.GetOrCreateFile(ctx, tx, call.Path)
+			if err != nil {
+				return nil, err
+			}
+			fileID = id
+			fileIDs[call.Path] = id
+		}
+		var codeUnitID *int64
+		if call.CodeUnit != nil {
+			id, err := store.GetOrCreateCodeUnit(ctx, tx, *call.CodeUnit)
+			if err != nil {
+				return nil, err
+			}
+			codeUnitID = &id
+		}
+		callSiteID, err := store.InsertCallSite(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, call)
+		if err != nil {
+			return nil, err
+		}
+		for i, arg := range call.Args {
+			if err := store.InsertCallSiteArg(ctx, tx, callSiteID, i, arg); err != nil {
+				return nil, err
+			}
+			result.Args++
+		}
+		result.CallSites++
+	}
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit call site ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// collectCallSites returns the calls of the target functions in the loaded
+// packages, in source order, with the source text of each argument.
+func collectCallSites(rootDir string, pkgs []*packages.Package, pkgPaths map[*packages.Package]string, targets []string) ([]CallSite, error) {
+	wanted := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		wanted[callTargetKey(strings.TrimSpace(target))] = struct{}{}
+	}
+	sources := make(map[string][]byte)
+
+	var calls []CallSite
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
+			continue
+		}
+		qualifier := types.RelativeTo(pkg.Types)
+		for _, file := range pkg.Syntax {
+			filename := pkg.Fset.PositionFor(file.Pos(), false).Filename
+			path, ok := rootRelativePath(rootDir, filename)
+			if !ok {
+				continue
+			}
+			src, ok := sources[filename]
+			if !ok {
+				data, err := os.ReadFile(filename)
+				if err != nil {
+					return nil, errors.Wrap(err, "read source file")
+				}
+				src = data
+				sources[filename] = data
+			}
+			tokenFile := pkg.Fset.File(file.Pos())
+			text := func(node ast.Node) string {
+				start, end := tokenFile.Offset(node.Pos()), tokenFile.Offset(node.End())
+				if start < 0 || end > len(src) || start > end {
+					return ""
+				}
+				return string(src[start:end])
+			}
+
+			ast.Inspect(file, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fn := calledFunc(pkg.TypesInfo, call)
+				if fn == nil {
+					return true
+				}
+				callee := fn.Origin().FullName()
+				if _, ok := wanted[callTargetKey(callee)]; !ok {
+					return true
+				}
+
+				start := pkg.Fset.PositionFor(call.Pos(), false)
+				end := pkg.Fset.PositionFor(call.End(), false)
+				site := CallSite{
+					Callee:   callee,
+					Path:     path,
+					Line:     start.Line,
+					Col:      start.Column,
+					EndLine:  end.Line,
+					EndCol:   end.Column,
+					CodeUnit: enclosingCodeUnit(pkg, pkgPaths[pkg], call.Pos()),
+				}
+				for i, argExpr := range call.Args {
+					arg := CallArg{Source: text(argExpr)}
+					if i == len(call.Args)-1 && call.Ellipsis.IsValid() {
+						arg.Source += "..."
+					}
+					if tv, ok := pkg.TypesInfo.Types[argExpr]; ok {
+						if tv.Type != nil {
+							arg.Type = types.TypeString(tv.Type, qualifier)
+						}
+						if tv.Value != nil {
+							arg.Const = true
+							arg.Value = constantString(tv.Value)
+						}
+					}
+					site.Args = append(site.Args, arg)
+				}
+				calls = append(calls, site)
+				return true
+			})
+		}
+	}
+	return calls, nil
+}
+
+// calledFunc returns the function or method a call expression calls, or nil
+// for conversions, builtins and calls of function values.
+func calledFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	fun := ast.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+	var ident *ast.Ident
+	switch f := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		ident = f
+	case *ast.SelectorExpr:
+		ident = f.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[ident].(*types.Func)
+	return fn
+}
+
+// callTargetKey normalizes a function's full name so that
+// "(*example.com/lib.Client).Do", "(example.com/lib.Client).Do" and
+// "example.com/lib.Client.Do" all match; type parameter lists are dropped.
+func callTargetKey(name string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth > 0, r == '(', r == ')', r == '*':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func constantString(value constant.Value) string {
+	switch value.Kind() {
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Float, constant.Complex:
+		return value.String()
+	default:
+		return value.ExactString()
+	}
+}
@@ -0,0 +1,104 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestCallSites(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repoPath, "fields"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "fields", "fields.go"), `package fields
+
+type Field struct{}
+
+type Option func()
+
+func New(name string, opts ...Option) Field { return Field{} }
+
+type Registry struct{}
+
+func (r *Registry) Add(name string, n int) {}
+`)
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import (
+	"errors"
+
+	"example.com/app/fields"
+)
+
+const prefix = "app."
+
+func Build(r *fields.Registry, opts []fields.Option) error {
+	fields.New("name")
+	fields.New(prefix+"id", opts...)
+	r.Add("count", 3)
+	name := "dyn"
+	fields.New(name)
+	return errors.New("boom")
+}
+`)
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestCallSites(ctx, IngestCallSitesConfig{
+		DBPath:  dbPath,
+		RootDir: repoPath,
+		Targets: []string{"example.com/app/fields.New", "example.com/app/fields.Registry.Add"},
+	})
+	if err != nil {
+		t.Fatalf("ingest call sites: %v", err)
+	}
+	if result.CallSites != 4 || result.Args != 6 {
+		t.Fatalf("expected 4 call sites with 6 args, got %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	calls, err := store.ListCallSites(ctx, CallSiteFilter{RunID: result.RunID, Callee: "example.com/app/fields.New"})
+	if err != nil {
+		t.Fatalf("list call sites: %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 fields.New calls, got %+v", calls)
+	}
+	first := calls[0]
+	if first.Callee != "example.com/app/fields.New" || first.CodeUnit != "Build" || first.Line != 12 || first.Col != 2 ||
+		first.EndLine != 12 || first.EndCol != 20 || len(first.Args) != 1 {
+		t.Fatalf("unexpected first call: %+v", first)
+	}
+	if arg := first.Args[0]; arg.Source != `"name"` || !arg.Const || arg.Value != "name" || arg.Type != "string" {
+		t.Fatalf("unexpected first argument: %+v", arg)
+	}
+	second := calls[1]
+	if len(second.Args) != 2 || second.Args[0].Value != "app.id" || second.Args[0].Source != `prefix+"id"` ||
+		second.Args[1].Source != "opts..." || second.Args[1].Const {
+		t.Fatalf("unexpected second call: %+v", second)
+	}
+	if arg := calls[2].Args[0]; arg.Source != "name" || arg.Const {
+		t.Fatalf("expected a non-constant argument, got %+v", arg)
+	}
+
+	adds, err := store.ListCallSites(ctx, CallSiteFilter{RunID: result.RunID, Callee: "(*example.com/app/fields.Registry).Add"})
+	if err != nil {
+		t.Fatalf("list method call sites: %v", err)
+	}
+	if len(adds) != 1 || adds[0].Callee != "(*example.com/app/fields.Registry).Add" || len(adds[0].Args) != 2 ||
+		adds[0].Args[1].Value != "3" || adds[0].Args[1].Type != "int" {
+		t.Fatalf("unexpected method call sites: %+v", adds)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -21,6 +22,13 @@ type IngestCodeUnitsConfig struct {
 	RootDir    string
 	SourcesDir string
 	CommitID   *int64
+	Load       GoLoadOptions
+	// Matrix loads the packages once per build configuration and records
+	// the configurations each snapshot was seen in.
+	Matrix []BuildConfig
+	// Tolerant ingests the packages that loaded cleanly and records the
+	// errors of the others in load_errors instead of failing the run.
+	Tolerant bool
 }
 
 // IngestCodeUnitsResult reports counts for code unit ingestion.
@@ -32,6 +40,8 @@ type IngestCodeUnitsResult struct {
 	Files      int
 	BodyBytes  int
 	DocEntries int
+	LoadErrors int
+	Partial    bool
 }
 
 func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCodeUnitsResult, error) {
@@ -59,9 +69,15 @@ func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCod
 		return nil, err
 	}
 
-	argsJSON, err := EncodeArgsJSON(map[string]string{
-		"root": rootDir,
-	})
+	args := map[string]string{
+		"root":     rootDir,
+		"tolerant": strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	if len(cfg.Matrix) > 0 {
+		args["matrix"] = buildMatrixArg(cfg.Matrix)
+	}
+	argsJSON, err := EncodeArgsJSON(args)
 	if err != nil {
 		return nil, err
 	}
@@ -76,17 +92,13 @@ func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCod
 		return nil, err
 	}
 
-	pkgConfig := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedFiles | packages.NeedCompiledGoFiles,
-		Dir:  rootDir,
-	}
-	pkgs, err := packages.Load(pkgConfig, "./...")
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo|packages.NeedFiles|packages.NeedCompiledGoFiles)
+	loads, loadErrors, err := loadBuildMatrix(pkgConfig, cfg.Load, cfg.Matrix, rootDir, cfg.Tolerant)
 	if err != nil {
-		return nil, errors.Wrap(err, "load packages")
-	}
-	if packages.PrintErrors(pkgs) > 0 {
-		return nil, errors.New("package load errors")
+		return nil, err
 	}
+	pkgs := configLoadPackages(loads)
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
 
 	tx, err := store.BeginTx(ctx)
 	if err != nil {
@@ -96,6 +108,16 @@ func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCod
 		_ = tx.Rollback()
 	}()
 
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+	if err := store.InsertRunBuildConfigs(ctx, tx, runID, buildMatrixNames(cfg.Matrix)); err != nil {
+		return nil, err
+	}
+
 	fileIDs := make(map[string]int64)
 	seenCodeUnits := make(map[string]struct{})
 	codeUnitCount := 0
@@ -104,87 +126,71 @@ func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCod
 	docCount := 0
 	bodyBytes := 0
 
-	for _, pkg := range pkgs {
-		if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
-			continue
-		}
-		qualifier := types.RelativeTo(pkg.Types)
-		pkgPath := pkg.PkgPath
-
-		for _, file := range pkg.Syntax {
-			filePath := pkg.Fset.Position(file.Pos()).Filename
-			if filePath == "" {
-				continue
-			}
-			relPath, err := filepath.Rel(rootDir, filePath)
+	// With a matrix the same declaration is seen once per configuration
+	// that compiles it; it is stored once with one config row each.
+	snapshotIDs := make(map[string]int64)
+	config := ""
+	insertSnapshot := func(fileID int64, codeUnitID int64, startLine, startCol, endLine, endCol int, bodyHash, bodyText, docText string) error {
+		key := strconv.FormatInt(fileID, 10) + "|" + strconv.FormatInt(codeUnitID, 10) + "|" + strconv.Itoa(startLine) + "|" + strconv.Itoa(startCol) + "|" + bodyHash
+		snapshotID, ok := snapshotIDs[key]
+		if !ok {
+			id, err := store.InsertCodeUnitSnapshot(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, startLine, startCol, endLine, endCol, bodyHash, bodyText, docText)
 			if err != nil {
-				return nil, errors.Wrap(err, "relativize file path")
+				return err
 			}
-			relPath = filepath.ToSlash(relPath)
-			fileID, ok := fileIDs[relPath]
-			if !ok {
-				id, err := store.GetOrCreateFile(ctx, tx, relPath)
-				if err != nil {
-					return nil, err
-				}
-				fileID = id
-				fileIDs[relPath] = id
-				fileCount++
+			snapshotID = id
+			snapshotIDs[key] = id
+			snapshotCount++
+			bodyBytes += len(bodyText)
+			if docText != "" {
+				docCount++
 			}
+		}
+		if config == "" {
+			return nil
+		}
+		return store.InsertCodeUnitSnapshotConfig(ctx, tx, snapshotID, config)
+	}
 
-			fileBytes, err := os.ReadFile(filePath)
-			if err != nil {
-				return nil, errors.Wrap(err, "read file")
+	for _, load := range loads {
+		config = load.Config
+		for _, pkg := range load.Pkgs {
+			if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
+				continue
 			}
+			qualifier := types.RelativeTo(pkg.Types)
+			pkgPath := pkgPaths[pkg]
 
-			for _, decl := range file.Decls {
-				switch d := decl.(type) {
-				case *ast.FuncDecl:
-					obj := pkg.TypesInfo.Defs[d.Name]
-					if obj == nil {
-						continue
-					}
-					def, err := buildCodeUnitDef(pkgPath, qualifier, obj)
-					if err != nil {
-						return nil, err
-					}
-					codeUnitID, err := store.GetOrCreateCodeUnit(ctx, tx, def)
-					if err != nil {
-						return nil, err
-					}
-					if _, seen := seenCodeUnits[def.Hash]; !seen {
-						seenCodeUnits[def.Hash] = struct{}{}
-						codeUnitCount++
-					}
-
-					bodyText, err := extractNodeText(pkg.Fset, fileBytes, d)
+			for _, file := range pkg.Syntax {
+				filePath := pkg.Fset.Position(file.Pos()).Filename
+				if filePath == "" {
+					continue
+				}
+				relPath, err := filepath.Rel(rootDir, filePath)
+				if err != nil {
+					return nil, errors.Wrap(err, "relativize file path")
+				}
+				relPath = filepath.ToSlash(relPath)
+				fileID, ok := fileIDs[relPath]
+				if !ok {
+					id, err := store.GetOrCreateFile(ctx, tx, relPath)
 					if err != nil {
 						return nil, err
 					}
-					normalized := normalizeBodyText(bodyText)
-					bodyHash := hashText(normalized)
-					bodyBytes += len(bodyText)
+					fileID = id
+					fileIDs[relPath] = id
+					fileCount++
+				}
 
-					docText := commentText(d.Doc)
-					if docText != "" {
-						docCount++
-					}
+				fileBytes, err := os.ReadFile(filePath)
+				if err != nil {
+					return nil, errors.Wrap(err, "read file")
+				}
 
-					startLine, startCol, endLine, endCol, err := nodeSpan(pkg.Fset, d)
-					if err != nil {
-						return nil, err
-					}
-					if err := store.InsertCodeUnitSnapshot(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, startLine, startCol, endLine, endCol, bodyHash, bodyText, docText); err != nil {
-						return nil, err
-					}
-					snapshotCount++
-				case *ast.GenDecl:
-					for _, spec := range d.Specs {
-						s, ok := spec.(*ast.TypeSpec)
-						if !ok {
-							continue
-						}
-						obj := pkg.TypesInfo.Defs[s.Name]
+				for _, decl := range file.Decls {
+					switch d := decl.(type) {
+					case *ast.FuncDecl:
+						obj := pkg.TypesInfo.Defs[d.Name]
 						if obj == nil {
 							continue
 						}
@@ -201,55 +207,99 @@ func IngestCodeUnits(ctx context.Context, cfg IngestCodeUnitsConfig) (*IngestCod
 							codeUnitCount++
 						}
 
-						node := ast.Node(s)
-						if len(d.Specs) == 1 {
-							node = d
-						}
-						bodyText, err := extractNodeText(pkg.Fset, fileBytes, node)
+						bodyText, err := extractNodeText(pkg.Fset, fileBytes, d)
 						if err != nil {
 							return nil, err
 						}
 						normalized := normalizeBodyText(bodyText)
 						bodyHash := hashText(normalized)
-						bodyBytes += len(bodyText)
-
-						docText := commentText(s.Doc)
-						if docText == "" {
-							docText = commentText(d.Doc)
-						}
-						if docText != "" {
-							docCount++
-						}
+						docText := commentText(d.Doc)
 
-						startLine, startCol, endLine, endCol, err := nodeSpan(pkg.Fset, node)
+						startLine, startCol, endLine, endCol, err := nodeSpan(pkg.Fset, d)
 						if err != nil {
 							return nil, err
 						}
-						if err := store.InsertCodeUnitSnapshot(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, startLine, startCol, endLine, endCol, bodyHash, bodyText, docText); err != nil {
+						if err := insertSnapshot(fileID, codeUnitID, startLine, startCol, endLine, endCol, bodyHash, bodyText, docText); err != nil {
 							return nil, err
 						}
-						snapshotCount++
+					case *ast.GenDecl:
+						for _, spec := range d.Specs {
+							s, ok := spec.(*ast.TypeSpec)
+							if !ok {
+								continue
+							}
+							obj := pkg.TypesInfo.Defs[s.Name]
+							if obj == nil {
+								continue
+							}
+							def, err := buildCodeUnitDef(pkgPath, qualifier, obj)
+							if err != nil {
+								return nil, err
+							}
+							codeUnitID, err := store.GetOrCreateCodeUnit(ctx, tx, def)
+							if err != nil {
+								return nil, err
+							}
+							if _, seen := seenCodeUnits[def.Hash]; !seen {
+								seenCodeUnits[def.Hash] = struct{}{}
+								codeUnitCount++
+							}
+
+							node := ast.Node(s)
+							if len(d.Specs) == 1 {
+								node = d
+							}
+							bodyText, err := extractNodeText(pkg.Fset, fileBytes, node)
+							if err != nil {
+								return nil, err
+							}
+							normalized := normalizeBodyText(bodyText)
+							bodyHash := hashText(normalized)
+							docText := commentText(s.Doc)
+							if docText == "" {
+								docText = commentText(d.Doc)
+							}
+
+							startLine, startCol, endLine, endCol, err := nodeSpan(pkg.Fset, node)
+							if err != nil {
+								return nil, err
+							}
+							if err := insertSnapshot(fileID, codeUnitID, startLine, startCol, endLine, endCol, bodyHash, bodyText, docText); err != nil {
+								return nil, err
+							}
+						}
 					}
 				}
 			}
 		}
+
 	}
 
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, errors.Wrap(err, "commit code unit ingestion")
 	}
 	if err := store.FinishRun(ctx, runID); err != nil {
 		return nil, err
 	}
+	if len(loadErrors) > 0 {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
 
 	return &IngestCodeUnitsResult{
 		RunID:      runID,
 		CodeUnits:  codeUnitCount,
 		Snapshots:  snapshotCount,
-		Packages:   len(pkgs),
+		Packages:   countPackagePaths(pkgPaths),
 		Files:      fileCount,
 		BodyBytes:  bodyBytes,
 		DocEntries: docCount,
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
 	}, nil
 }
 
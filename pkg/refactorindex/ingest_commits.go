@@ -16,6 +16,10 @@ type IngestCommitsConfig struct {
 	RepoPath string
 	FromRef  string
 	ToRef    string
+
+	// IssuePatterns are regular expressions for issue references in commit
+	// messages. DefaultIssuePatterns is used when empty.
+	IssuePatterns []string
 }
 
 // IngestCommitsResult reports commit ingestion counts.
@@ -25,6 +29,10 @@ type IngestCommitsResult struct {
 	FileCount    int
 	BlobCount    int
 	CommitHashes []string
+
+	ConventionalCount int
+	TrailerCount      int
+	IssueRefCount     int
 }
 
 func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommitsResult, error) {
@@ -37,6 +45,10 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 	if strings.TrimSpace(cfg.FromRef) == "" || strings.TrimSpace(cfg.ToRef) == "" {
 		return nil, errors.New("from/to refs are required")
 	}
+	issuePatterns, err := CompileIssuePatterns(cfg.IssuePatterns)
+	if err != nil {
+		return nil, err
+	}
 
 	db, err := OpenDB(ctx, cfg.DBPath)
 	if err != nil {
@@ -52,9 +64,10 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 	}
 
 	argsJSON, err := EncodeArgsJSON(map[string]string{
-		"from": cfg.FromRef,
-		"to":   cfg.ToRef,
-		"repo": cfg.RepoPath,
+		"from":           cfg.FromRef,
+		"to":             cfg.ToRef,
+		"repo":           cfg.RepoPath,
+		"issue_patterns": strings.Join(cfg.IssuePatterns, "\n"),
 	})
 	if err != nil {
 		return nil, err
@@ -96,6 +109,9 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 
 	fileCount := 0
 	blobCount := 0
+	conventionalCount := 0
+	trailerCount := 0
+	issueRefCount := 0
 	for _, hash := range commits {
 		info, err := loadCommitInfo(ctx, cfg.RepoPath, hash)
 		if err != nil {
@@ -105,8 +121,17 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 		if err != nil {
 			return nil, err
 		}
+		message := ParseCommitMessage(info.Subject, info.Body, issuePatterns)
+		if err := store.InsertCommitMessage(ctx, tx, commitID, message); err != nil {
+			return nil, err
+		}
+		if message.Conventional != nil {
+			conventionalCount++
+		}
+		trailerCount += len(message.Trailers)
+		issueRefCount += len(message.IssueRefs)
 
-		nameStatus, err := runGit(ctx, cfg.RepoPath, "diff-tree", "--no-commit-id", "-r", "--name-status", "-z", hash)
+		nameStatus, err := runGit(ctx, cfg.RepoPath, "diff-tree", "--no-commit-id", "-r", "-M", "--name-status", "-z", hash)
 		if err != nil {
 			return nil, err
 		}
@@ -114,6 +139,23 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 		if err != nil {
 			return nil, err
 		}
+		numstatOutput, err := runGit(ctx, cfg.RepoPath, "diff-tree", "--no-commit-id", "-r", "-M", "--numstat", "-z", hash)
+		if err != nil {
+			return nil, err
+		}
+		numstat, err := ParseNumstat(numstatOutput)
+		if err != nil {
+			return nil, err
+		}
+		patchOutput, err := runGit(ctx, cfg.RepoPath, "diff-tree", "--no-commit-id", "-r", "-M", "-p", "-U0", "--no-color", hash)
+		if err != nil {
+			return nil, err
+		}
+		patches, err := ParseUnifiedDiff(patchOutput)
+		if err != nil {
+			return nil, err
+		}
+		changeStats := collectChangeStats(entries, numstat, patches)
 
 		for _, entry := range entries {
 			primaryPath := entry.PrimaryPath()
@@ -142,9 +184,15 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 				blobOld, _ = gitBlobSHA(ctx, cfg.RepoPath, parent, entry.OldPath)
 			}
 
-			if err := store.InsertCommitFile(ctx, tx, commitID, fileID, entry.Status, entry.OldPath, entry.NewPath, blobOld, blobNew); err != nil {
+			stats := changeStats[primaryPath]
+			if err := store.InsertCommitFile(ctx, tx, commitID, fileID, entry.Status, entry.OldPath, entry.NewPath, blobOld, blobNew, stats); err != nil {
 				return nil, err
 			}
+			if stats.Insertions != nil || stats.IsBinary {
+				if err := store.SetFileBinary(ctx, tx, fileID, stats.IsBinary); err != nil {
+					return nil, err
+				}
+			}
 			fileCount++
 
 			if blobNew != "" {
@@ -170,6 +218,10 @@ func IngestCommits(ctx context.Context, cfg IngestCommitsConfig) (*IngestCommits
 		FileCount:    fileCount,
 		BlobCount:    blobCount,
 		CommitHashes: commits,
+
+		ConventionalCount: conventionalCount,
+		TrailerCount:      trailerCount,
+		IssueRefCount:     issueRefCount,
 	}, nil
 }
 
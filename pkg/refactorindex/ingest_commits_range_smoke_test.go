@@ -52,8 +52,8 @@ func TestIngestCommitsGolden(t *testing.T) {
 	if result.CommitCount != 1 {
 		t.Fatalf("expected 1 commit, got %d", result.CommitCount)
 	}
-	if result.FileCount != 5 {
-		t.Fatalf("expected 5 commit files, got %d", result.FileCount)
+	if result.FileCount != 4 {
+		t.Fatalf("expected 4 commit files, got %d", result.FileCount)
 	}
 	if result.BlobCount != 3 {
 		t.Fatalf("expected 3 blobs, got %d", result.BlobCount)
@@ -67,7 +67,16 @@ func TestIngestCommitsGolden(t *testing.T) {
 		_ = db.Close()
 	}()
 
-	assertCommitCounts(t, db, result.RunID, 1, 5, 3)
+	assertCommitCounts(t, db, result.RunID, 1, 4, 3)
+
+	var status, oldPath string
+	var similarity, insertions int
+	if err := db.QueryRow("SELECT status, old_path, similarity, insertions FROM commit_files WHERE new_path = ?", "fileB_renamed.txt").Scan(&status, &oldPath, &similarity, &insertions); err != nil {
+		t.Fatalf("query renamed file: %v", err)
+	}
+	if status != "R100" || oldPath != "fileB.txt" || similarity != 100 || insertions != 0 {
+		t.Fatalf("expected a pure rename of fileB.txt, got %s %s %d +%d", status, oldPath, similarity, insertions)
+	}
 }
 
 func TestIngestCommitRangeDiffAndSymbols(t *testing.T) {
@@ -138,6 +147,21 @@ func TestIngestCommitRangeDiffAndSymbols(t *testing.T) {
 
 	assertCommitCounts(t, db, result.CommitLineageRunID, 1, 1, 1)
 	assertSymbol(t, db, "Sub", "func")
+
+	// The only commit's parent is FromRef, which is outside the range.
+	if commit.HunkUnitsRunID == 0 {
+		t.Fatalf("expected hunk code units for the first commit of the range")
+	}
+	var changeKind string
+	if err := db.QueryRow(`
+		SELECT hcu.change_kind FROM hunk_code_units hcu
+		JOIN code_units cu ON cu.id = hcu.code_unit_id
+		WHERE hcu.run_id = ? AND cu.name = 'Sub'`, commit.HunkUnitsRunID).Scan(&changeKind); err != nil {
+		t.Fatalf("query hunk code unit for Sub: %v", err)
+	}
+	if changeKind != "added" {
+		t.Fatalf("expected Sub to be added, got %q", changeKind)
+	}
 	assertSymbolOccurrenceCommitID(t, db, commit.CommitHash)
 	assertCodeUnitSnapshotCommitID(t, db, commit.CommitHash)
 }
@@ -199,3 +223,84 @@ func assertCodeUnitSnapshotCommitID(t *testing.T, db *sql.DB, commitHash string)
 		t.Fatalf("expected code_unit_snapshots with commit_id for %s", commitHash)
 	}
 }
+
+func TestIngestCommitsMessageFields(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\nbeta\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "feat(api)!: rename Load to Open (#12)\n\nCallers need to migrate.\n\nRefs: ABC-7\nSigned-off-by: Refactor Index <test@example.com>\nCo-authored-by: Other Dev <other@example.com>")
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\nbeta\ngamma\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update docs for ticket ABC-8\n\nFiles are UTF-8, digests are SHA-256 and dates ISO-8601.")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestCommits(ctx, IngestCommitsConfig{
+		DBPath:   dbPath,
+		RepoPath: repoPath,
+		FromRef:  fromRef,
+		ToRef:    toRef,
+	})
+	if err != nil {
+		t.Fatalf("ingest commits: %v", err)
+	}
+	if result.ConventionalCount != 1 || result.TrailerCount != 3 || result.IssueRefCount != 3 {
+		t.Fatalf("expected 1 conventional/3 trailers/3 issues, got %d/%d/%d", result.ConventionalCount, result.TrailerCount, result.IssueRefCount)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	records, err := store.ListCommitMessages(ctx, CommitMessageFilter{RunID: result.RunID, Type: "feat"})
+	if err != nil {
+		t.Fatalf("list commit messages: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 feat commit, got %d", len(records))
+	}
+	record := records[0]
+	if record.Scope != "api" || !record.IsBreaking || record.Description != "rename Load to Open (#12)" {
+		t.Fatalf("unexpected conventional fields: %+v", record)
+	}
+	if record.Issues != "#12,ABC-7" {
+		t.Fatalf("expected issues #12,ABC-7, got %q", record.Issues)
+	}
+
+	byIssue, err := store.ListCommitMessages(ctx, CommitMessageFilter{RunID: result.RunID, Issue: "ABC-8"})
+	if err != nil {
+		t.Fatalf("list commit messages by issue: %v", err)
+	}
+	if len(byIssue) != 1 || byIssue[0].Type != "" {
+		t.Fatalf("expected one non-conventional commit for ABC-8, got %+v", byIssue)
+	}
+
+	var coAuthors int
+	if err := db.QueryRow("SELECT COUNT(*) FROM commit_trailers WHERE key = 'Co-authored-by'").Scan(&coAuthors); err != nil {
+		t.Fatalf("count trailers: %v", err)
+	}
+	if coAuthors != 1 {
+		t.Fatalf("expected 1 Co-authored-by trailer, got %d", coAuthors)
+	}
+}
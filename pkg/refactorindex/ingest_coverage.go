@@ -0,0 +1,370 @@
+package refactorindex
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/cover"
+)
+
+// IngestCoverageConfig controls mapping a Go coverage profile onto the code
+// units of a code-unit run.
+type IngestCoverageConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	// CommitID attributes the coverage to a commit. When nil, RootDir's HEAD
+	// is used if that commit has been ingested.
+	CommitID *int64
+	// CodeUnitsRunID selects the code-unit run to map onto; 0 uses the latest
+	// run for the commit, and fails when code units of several commits exist
+	// and no commit is known.
+	CodeUnitsRunID int64
+
+	// ProfilePath reads an existing -coverprofile file. When empty, go test
+	// is run in RootDir to produce one.
+	ProfilePath string
+	Packages    []string
+	Tags        []string
+	Timeout     time.Duration
+}
+
+// IngestCoverageResult reports counts for coverage ingestion.
+type IngestCoverageResult struct {
+	RunID             int64
+	CodeUnitsRunID    int64
+	Mode              string
+	Files             int
+	Blocks            int
+	MatchedBlocks     int
+	CodeUnits         int
+	CoveredStatements int
+	TotalStatements   int
+	TestExitCode      int
+	RunDir            string
+}
+
+// CodeUnitCoverage is the statement coverage of one code unit snapshot.
+type CodeUnitCoverage struct {
+	SnapshotID        int64
+	CodeUnitID        int64
+	FileID            int64
+	CoveredStatements int
+	TotalStatements   int
+}
+
+type coverageSpan struct {
+	unitSnapshotSpan
+	FileID int64
+}
+
+func IngestCoverage(ctx context.Context, cfg IngestCoverageConfig) (*IngestCoverageResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" && strings.TrimSpace(cfg.ProfilePath) == "" {
+		return nil, errors.New("root dir or coverage profile is required")
+	}
+	rootDir := ""
+	if strings.TrimSpace(cfg.RootDir) != "" {
+		var err error
+		rootDir, err = filepath.Abs(cfg.RootDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve root dir")
+		}
+	}
+	packages := cfg.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err := filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	commitID := cfg.CommitID
+	if commitID == nil && rootDir != "" {
+		commitID, err = headCommitID(ctx, store, rootDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	codeUnitsRunID := cfg.CodeUnitsRunID
+	if codeUnitsRunID == 0 {
+		codeUnitsRunID, err = coverageCodeUnitsRunID(ctx, store, commitID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"root":              rootDir,
+		"profile":           cfg.ProfilePath,
+		"packages":          strings.Join(packages, " "),
+		"tags":              strings.Join(cfg.Tags, ","),
+		"code_units_run_id": strconv.FormatInt(codeUnitsRunID, 10),
+		"sources_dir":       sourcesDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	result := &IngestCoverageResult{RunID: runID, CodeUnitsRunID: codeUnitsRunID, RunDir: runDir}
+
+	var profile []byte
+	var testOutput []byte
+	if strings.TrimSpace(cfg.ProfilePath) != "" {
+		profile, err = os.ReadFile(cfg.ProfilePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read coverage profile")
+		}
+	} else {
+		profile, testOutput, result.TestExitCode, err = runCoverageTests(ctx, rootDir, runDir, packages, cfg.Tags, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profiles, err := cover.ParseProfilesFromReader(bytes.NewReader(profile))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse coverage profile")
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "coverprofile", "coverage.out", profile); err != nil {
+		return nil, err
+	}
+	if len(testOutput) > 0 {
+		if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "go-test-cover", "go-test-cover.txt", testOutput); err != nil {
+			return nil, err
+		}
+	}
+
+	spans, err := loadCoverageSpans(ctx, tx, codeUnitsRunID)
+	if err != nil {
+		return nil, err
+	}
+	coverage, matched := mapCoverageProfiles(profiles, spans)
+	for _, p := range profiles {
+		result.Files++
+		result.Blocks += len(p.Blocks)
+		if result.Mode == "" {
+			result.Mode = p.Mode
+		}
+	}
+	result.MatchedBlocks = matched
+
+	for _, unit := range coverage {
+		if err := store.InsertCodeUnitCoverage(ctx, tx, runID, commitID, unit); err != nil {
+			return nil, err
+		}
+		result.CodeUnits++
+		result.CoveredStatements += unit.CoveredStatements
+		result.TotalStatements += unit.TotalStatements
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit coverage ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// headCommitID returns the commits row for rootDir's HEAD, or nil when
+// rootDir is not a git checkout or its HEAD has not been ingested.
+func headCommitID(ctx context.Context, store *Store, rootDir string) (*int64, error) {
+	out, err := runGit(ctx, rootDir, "rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return nil, nil
+	}
+	return store.LatestCommitIDByHash(ctx, strings.TrimSpace(string(out)))
+}
+
+// coverageCodeUnitsRunID picks the code units run for a commit, which must
+// have one so the profile is not mapped onto another commit's spans.
+// Without a commit the latest run is only used when every code units run
+// belongs to the same commit.
+func coverageCodeUnitsRunID(ctx context.Context, store *Store, commitID *int64) (int64, error) {
+	if commitID != nil {
+		runID, hash, err := store.CodeUnitsRunIDForCommit(ctx, *commitID)
+		if err != nil {
+			return 0, err
+		}
+		if runID == 0 {
+			return 0, errors.Errorf("no code units were ingested for %s; run ingest code-units for %s or pass --code-units-run-id", hash, hash)
+		}
+		return runID, nil
+	}
+	commits, err := store.CodeUnitsCommitCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if commits > 1 {
+		return 0, errors.New("code units were ingested for several commits; pass a code units run id or run from a checkout of an ingested commit")
+	}
+	return store.LatestCodeUnitsRunID(ctx, nil)
+}
+
+// runCoverageTests runs go test -coverprofile and returns the profile and the
+// combined test output. Failing tests still produce a profile for the
+// packages that built, so only a missing profile is an error.
+func runCoverageTests(ctx context.Context, rootDir string, runDir string, packages []string, tags []string, timeout time.Duration) ([]byte, []byte, int, error) {
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		return nil, nil, 0, errors.Wrap(err, "create sources dir")
+	}
+	profilePath := filepath.Join(runDir, "coverage.tmp")
+	defer func() {
+		_ = os.Remove(profilePath)
+	}()
+
+	args := []string{"test", "-coverprofile", profilePath}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+	if timeout > 0 {
+		args = append(args, "-timeout", timeout.String())
+	}
+	args = append(args, packages...)
+
+	stdout, stderr, exitCode, err := runGoTest(ctx, rootDir, args)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	output := make([]byte, 0, len(stdout)+len(stderr))
+	output = append(append(output, stdout...), stderr...)
+	profile, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, nil, 0, errors.Wrapf(err, "go test produced no coverage profile: %s", strings.TrimSpace(string(stderr)))
+	}
+	return profile, output, exitCode, nil
+}
+
+// mapCoverageProfiles assigns each profile block to the innermost code unit
+// span containing its start line. Profile file names are import paths, so
+// they are matched to indexed files by the longest path suffix.
+func mapCoverageProfiles(profiles []*cover.Profile, spans map[string][]coverageSpan) ([]CodeUnitCoverage, int) {
+	byID := make(map[int64]*CodeUnitCoverage)
+	var order []int64
+	matched := 0
+	for _, p := range profiles {
+		path := coverageFilePath(p.FileName, spans)
+		if path == "" {
+			continue
+		}
+		for _, block := range p.Blocks {
+			var best *coverageSpan
+			for i := range spans[path] {
+				span := &spans[path][i]
+				if block.StartLine < span.StartLine || block.StartLine > span.EndLine {
+					continue
+				}
+				if best == nil || span.EndLine-span.StartLine < best.EndLine-best.StartLine {
+					best = span
+				}
+			}
+			if best == nil {
+				continue
+			}
+			matched++
+			unit, ok := byID[best.ID]
+			if !ok {
+				unit = &CodeUnitCoverage{SnapshotID: best.ID, CodeUnitID: best.CodeUnitID, FileID: best.FileID}
+				byID[best.ID] = unit
+				order = append(order, best.ID)
+			}
+			unit.TotalStatements += block.NumStmt
+			if block.Count > 0 {
+				unit.CoveredStatements += block.NumStmt
+			}
+		}
+	}
+
+	results := make([]CodeUnitCoverage, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byID[id])
+	}
+	return results, matched
+}
+
+func coverageFilePath(fileName string, spans map[string][]coverageSpan) string {
+	fileName = filepath.ToSlash(fileName)
+	best := ""
+	for path := range spans {
+		if (fileName == path || strings.HasSuffix(fileName, "/"+path)) && len(path) > len(best) {
+			best = path
+		}
+	}
+	return best
+}
+
+func loadCoverageSpans(ctx context.Context, tx *sql.Tx, codeUnitsRunID int64) (map[string][]coverageSpan, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.id, s.code_unit_id, s.file_id, f.path, s.start_line, s.end_line
+		FROM code_unit_snapshots s
+		JOIN files f ON f.id = s.file_id
+		WHERE s.run_id = ?`, codeUnitsRunID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query code unit snapshots")
+	}
+	defer rows.Close()
+
+	spans := make(map[string][]coverageSpan)
+	for rows.Next() {
+		var span coverageSpan
+		var path string
+		if err := rows.Scan(&span.ID, &span.CodeUnitID, &span.FileID, &path, &span.StartLine, &span.EndLine); err != nil {
+			return nil, errors.Wrap(err, "scan code unit snapshot")
+		}
+		spans[path] = append(spans[path], span)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate code unit snapshots")
+	}
+	return spans, nil
+}
@@ -0,0 +1,273 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestCoverageLowCoverageReport(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/calc\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+`)
+	writeFile(t, filepath.Join(repoPath, "calc_test.go"), `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(2, 3) != 5 {
+		t.Fatal("Add(2, 3) != 5")
+	}
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	oldUnits, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest old code units: %v", err)
+	}
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	sum := a + b
+	return sum
+}
+
+func Sub(a, b int) int {
+	if a < b {
+		return -(b - a)
+	}
+	return a - b
+}
+`)
+	// An exported name with a non-ASCII capital initial.
+	writeFile(t, filepath.Join(repoPath, "delta.go"), "package calc\n\nfunc Δ(a, b int) int {\n\treturn b - a\n}\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	newUnits, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest new code units: %v", err)
+	}
+	coverage, err := IngestCoverage(ctx, IngestCoverageConfig{
+		DBPath:     dbPath,
+		RootDir:    repoPath,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest coverage: %v", err)
+	}
+	if coverage.CodeUnitsRunID != newUnits.RunID {
+		t.Fatalf("expected latest code units run %d, got %d", newUnits.RunID, coverage.CodeUnitsRunID)
+	}
+	if coverage.CodeUnits != 3 || coverage.MatchedBlocks != coverage.Blocks {
+		t.Fatalf("unexpected coverage mapping: %+v", coverage)
+	}
+
+	diffResult, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+	if _, err := IngestHunkCodeUnits(ctx, IngestHunkCodeUnitsConfig{
+		DBPath:            dbPath,
+		DiffRunID:         diffResult.RunID,
+		OldCodeUnitsRunID: oldUnits.RunID,
+		NewCodeUnitsRunID: newUnits.RunID,
+	}); err != nil {
+		t.Fatalf("ingest hunk code units: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	records, err := store.ListCodeUnitCoverage(ctx, CodeUnitCoverageFilter{})
+	if err != nil {
+		t.Fatalf("list unit coverage: %v", err)
+	}
+	byName := make(map[string]CodeUnitCoverageRecord)
+	for _, record := range records {
+		byName[record.Name] = record
+	}
+	if add := byName["Add"]; add.TotalStatements != 2 || add.CoveredStatements != 2 {
+		t.Fatalf("expected Add fully covered, got %+v", add)
+	}
+	if sub := byName["Sub"]; sub.TotalStatements != 3 || sub.CoveredStatements != 0 {
+		t.Fatalf("expected Sub uncovered, got %+v", sub)
+	}
+	low, err := store.ListCodeUnitCoverage(ctx, CodeUnitCoverageFilter{Below: 0.5})
+	if err != nil {
+		t.Fatalf("list low unit coverage: %v", err)
+	}
+	if len(low) != 2 || low[0].Name != "Sub" || low[1].Name != "Δ" {
+		t.Fatalf("expected Sub and Δ below 50%%, got %+v", low)
+	}
+
+	reportDir := filepath.Join(root, "reports")
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: diffResult.RunID, OutputDir: reportDir}); err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(reportDir, "low-coverage-units.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if !strings.Contains(string(report), "| example.com/calc | Sub | func |  | 0 | 3 | 0.0 |") {
+		t.Fatalf("expected Sub in low coverage report, got:\n%s", report)
+	}
+	if !strings.Contains(string(report), "| example.com/calc | Δ | func |  | 0 | 1 | 0.0 |") {
+		t.Fatalf("expected Δ in low coverage report, got:\n%s", report)
+	}
+	if strings.Contains(string(report), "| Add |") {
+		t.Fatalf("expected Add to be excluded from low coverage report, got:\n%s", report)
+	}
+
+	// Coverage of a later tree must not stand in for the diff's to-commit.
+	writeFile(t, filepath.Join(repoPath, "sub_test.go"), `package calc
+
+import "testing"
+
+func TestSub(t *testing.T) {
+	if Sub(1, 3) != -2 || Sub(3, 1) != 2 {
+		t.Fatal("Sub")
+	}
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "test sub")
+	if _, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath}); err != nil {
+		t.Fatalf("ingest later code units: %v", err)
+	}
+	if _, err := IngestCoverage(ctx, IngestCoverageConfig{DBPath: dbPath, RootDir: repoPath, SourcesDir: filepath.Join(root, "sources")}); err != nil {
+		t.Fatalf("ingest later coverage: %v", err)
+	}
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: diffResult.RunID, OutputDir: reportDir}); err != nil {
+		t.Fatalf("regenerate reports: %v", err)
+	}
+	report, err = os.ReadFile(filepath.Join(reportDir, "low-coverage-units.md"))
+	if err != nil {
+		t.Fatalf("read regenerated report: %v", err)
+	}
+	if !strings.Contains(string(report), "| example.com/calc | Sub | func |  | 0 | 3 | 0.0 |") {
+		t.Fatalf("expected Sub's coverage at the diff's to-commit, got:\n%s", report)
+	}
+}
+
+func TestIngestCoverageResolvesHeadCommit(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/calc\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "README.md"), "calc\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n")
+	writeFile(t, filepath.Join(repoPath, "calc_test.go"), "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\t_ = Add(1, 2)\n}\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "add")
+	middleRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "sub")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:           dbPath,
+		RepoPath:         repoPath,
+		FromRef:          fromRef,
+		ToRef:            toRef,
+		SourcesDir:       filepath.Join(root, "sources"),
+		IncludeCodeUnits: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(rangeResult.Commits) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", rangeResult.Commits)
+	}
+
+	// HEAD is the older commit, whose code units are not the latest run.
+	git(t, repoPath, "checkout", "-q", middleRef)
+	coverage, err := IngestCoverage(ctx, IngestCoverageConfig{DBPath: dbPath, RootDir: repoPath, SourcesDir: filepath.Join(root, "sources")})
+	if err != nil {
+		t.Fatalf("ingest coverage: %v", err)
+	}
+	if coverage.CodeUnitsRunID != rangeResult.Commits[0].CodeUnitsRunID || coverage.CodeUnits != 1 {
+		t.Fatalf("expected coverage on the code units of %s, got %+v", middleRef, coverage)
+	}
+
+	if _, err := IngestCoverage(ctx, IngestCoverageConfig{
+		DBPath:      dbPath,
+		RootDir:     t.TempDir(),
+		ProfilePath: filepath.Join(coverage.RunDir, "coverage.out"),
+		SourcesDir:  filepath.Join(root, "sources"),
+	}); err == nil || !strings.Contains(err.Error(), "several commits") {
+		t.Fatalf("expected an ambiguous code units run to be rejected, got %v", err)
+	}
+
+	// An ingested HEAD without code units must not borrow another commit's.
+	git(t, repoPath, "checkout", "-q", toRef)
+	writeFile(t, filepath.Join(repoPath, "README.md"), "calc\n\nadds and subtracts\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "docs")
+	docsRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+	if _, err := IngestCommits(ctx, IngestCommitsConfig{DBPath: dbPath, RepoPath: repoPath, FromRef: toRef, ToRef: docsRef}); err != nil {
+		t.Fatalf("ingest commits: %v", err)
+	}
+	if _, err := IngestCoverage(ctx, IngestCoverageConfig{
+		DBPath:      dbPath,
+		RootDir:     repoPath,
+		ProfilePath: filepath.Join(coverage.RunDir, "coverage.out"),
+		SourcesDir:  filepath.Join(root, "sources"),
+	}); err == nil || !strings.Contains(err.Error(), "run ingest code-units for "+docsRef) {
+		t.Fatalf("expected HEAD without code units to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// IngestDiagnosticsConfig controls in-process go/analysis ingestion.
+type IngestDiagnosticsConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	Load       GoLoadOptions
+	// Analyzers adds analyzers by name (see AnalyzerNames) to the vet suite.
+	Analyzers []string
+	// SkipVet runs only the named analyzers.
+	SkipVet bool
+	// Tolerant analyzes the packages that loaded cleanly and records the
+	// errors of the others in load_errors.
+	Tolerant bool
+}
+
+// IngestDiagnosticsResult reports counts for diagnostics ingestion.
+type IngestDiagnosticsResult struct {
+	RunID          int64
+	Analyzers      int
+	Packages       int
+	Diagnostics    int
+	Fixes          int
+	AnalyzerErrors int
+	LoadErrors     int
+	Partial        bool
+}
+
+// Diagnostic is one analyzer finding with its span and enclosing code unit.
+type Diagnostic struct {
+	Analyzer   string
+	Category   string
+	Message    string
+	URL        string
+	Path       string
+	StartLine  int
+	StartCol   int
+	EndLine    int
+	EndCol     int
+	CodeUnit   *CodeUnitDef
+	Fixes      []DiagnosticFix
+	sortOffset int
+}
+
+// DiagnosticFix is one suggested fix; its edits may touch several files.
+type DiagnosticFix struct {
+	Message string
+	Edits   []DiagnosticEdit
+}
+
+// DiagnosticEdit replaces the span with NewText.
+type DiagnosticEdit struct {
+	Path      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NewText   string
+}
+
+func IngestDiagnostics(ctx context.Context, cfg IngestDiagnosticsConfig) (*IngestDiagnosticsResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+	analyzers, err := ResolveAnalyzers(cfg.Analyzers, cfg.SkipVet)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(analyzers))
+	for _, a := range analyzers {
+		names = append(names, a.Name)
+	}
+	args := map[string]string{
+		"root":      rootDir,
+		"analyzers": strings.Join(names, ","),
+		"tolerant":  strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	argsJSON, err := EncodeArgsJSON(args)
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Analyzers that use facts run on dependencies too, so those need
+	// syntax and type information as well.
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.LoadAllSyntax)
+	pkgs, loadErrors, err := loadGoPackages(pkgConfig, cfg.Load, rootDir, cfg.Tolerant)
+	if err != nil {
+		return nil, err
+	}
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
+
+	result := &IngestDiagnosticsResult{
+		RunID:      runID,
+		Analyzers:  len(analyzers),
+		Packages:   len(pkgs),
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	var roots []*checker.Action
+	if len(pkgs) > 0 {
+		graph, err := checker.Analyze(analyzers, pkgs, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "run analyzers")
+		}
+		roots = graph.Roots
+	}
+	var diagnostics []Diagnostic
+	for _, act := range roots {
+		if act.Err != nil {
+			result.AnalyzerErrors++
+			continue
+		}
+		for _, d := range act.Diagnostics {
+			diagnostic, ok := buildDiagnostic(rootDir, act.Package, pkgPaths[act.Package], act.Analyzer, d)
+			if ok {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Path != diagnostics[j].Path {
+			return diagnostics[i].Path < diagnostics[j].Path
+		}
+		if diagnostics[i].sortOffset != diagnostics[j].sortOffset {
+			return diagnostics[i].sortOffset < diagnostics[j].sortOffset
+		}
+		return diagnostics[i].Analyzer < diagnostics[j].Analyzer
+	})
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+
+	fileIDs := make(map[string]int64)
+	fileID := func(path string) (int64, error) {
+		if id, ok := fileIDs[path]; ok {
+			return id, nil
+		}
+		id, err := store.GetOrCreateFile(ctx, tx, path)
+		if err != nil {
+			return 0, err
+		}
+		fileIDs[path] = id
+		return id, nil
+	}
+
+	for _, diagnostic := range diagnostics {
+		diagFileID, err := fileID(diagnostic.Path)
+		if err != nil {
+			return nil, err
+		}
+		var codeUnitID *int64
+		if diagnostic.CodeUnit != nil {
+			id, err := store.GetOrCreateCodeUnit(ctx, tx, *diagnostic.CodeUnit)
+			if err != nil {
+				return nil, err
+			}
+			codeUnitID = &id
+		}
+		diagnosticID, err := store.InsertDiagnostic(ctx, tx, runID, cfg.CommitID, diagFileID, codeUnitID, diagnostic)
+		if err != nil {
+			return nil, err
+		}
+		result.Diagnostics++
+		for fixIndex, fix := range diagnostic.Fixes {
+			for _, edit := range fix.Edits {
+				editFileID, err := fileID(edit.Path)
+				if err != nil {
+					return nil, err
+				}
+				if err := store.InsertDiagnosticFix(ctx, tx, diagnosticID, fixIndex, fix.Message, editFileID, edit); err != nil {
+					return nil, err
+				}
+			}
+			result.Fixes++
+		}
+	}
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit diagnostics ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// buildDiagnostic converts an analyzer diagnostic to root-relative
+// positions. Diagnostics outside the root (e.g. in generated cgo files) are
+// dropped.
+func buildDiagnostic(rootDir string, pkg *packages.Package, pkgPath string, analyzer *analysis.Analyzer, d analysis.Diagnostic) (Diagnostic, bool) {
+	start := pkg.Fset.PositionFor(d.Pos, false)
+	path, ok := rootRelativePath(rootDir, start.Filename)
+	if !ok {
+		return Diagnostic{}, false
+	}
+	end := start
+	if d.End.IsValid() {
+		end = pkg.Fset.PositionFor(d.End, false)
+	}
+	diagnostic := Diagnostic{
+		Analyzer:   analyzer.Name,
+		Category:   d.Category,
+		Message:    d.Message,
+		URL:        d.URL,
+		Path:       path,
+		StartLine:  start.Line,
+		StartCol:   start.Column,
+		EndLine:    end.Line,
+		EndCol:     end.Column,
+		CodeUnit:   enclosingCodeUnit(pkg, pkgPath, d.Pos),
+		sortOffset: start.Offset,
+	}
+	for _, fix := range d.SuggestedFixes {
+		converted := DiagnosticFix{Message: fix.Message}
+		for _, edit := range fix.TextEdits {
+			editStart := pkg.Fset.PositionFor(edit.Pos, false)
+			editEnd := editStart
+			if edit.End.IsValid() {
+				editEnd = pkg.Fset.PositionFor(edit.End, false)
+			}
+			editPath, ok := rootRelativePath(rootDir, editStart.Filename)
+			if !ok {
+				continue
+			}
+			converted.Edits = append(converted.Edits, DiagnosticEdit{
+				Path:      editPath,
+				StartLine: editStart.Line,
+				StartCol:  editStart.Column,
+				EndLine:   editEnd.Line,
+				EndCol:    editEnd.Column,
+				NewText:   string(edit.NewText),
+			})
+		}
+		diagnostic.Fixes = append(diagnostic.Fixes, converted)
+	}
+	return diagnostic, true
+}
+
+func rootRelativePath(rootDir string, filename string) (string, bool) {
+	if filename == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(rootDir, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// enclosingCodeUnit returns the function, method or type declaration that
+// contains pos, matching the code units built by IngestCodeUnits.
+func enclosingCodeUnit(pkg *packages.Package, pkgPath string, pos token.Pos) *CodeUnitDef {
+	if pkg.Types == nil || pkg.TypesInfo == nil {
+		return nil
+	}
+	qualifier := types.RelativeTo(pkg.Types)
+	for _, file := range pkg.Syntax {
+		if pos < file.FileStart || pos > file.FileEnd {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if pos < decl.Pos() || pos > decl.End() {
+				continue
+			}
+			var name *ast.Ident
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				name = d.Name
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					s, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					if len(d.Specs) == 1 || pos >= s.Pos() && pos <= s.End() {
+						name = s.Name
+						break
+					}
+				}
+			}
+			if name == nil {
+				return nil
+			}
+			obj := pkg.TypesInfo.Defs[name]
+			if obj == nil {
+				return nil
+			}
+			def, err := buildCodeUnitDef(pkgPath, qualifier, obj)
+			if err != nil {
+				return nil
+			}
+			return &def
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	modPath := filepath.Join(root, "mod")
+	if err := os.MkdirAll(modPath, 0o755); err != nil {
+		t.Fatalf("mkdir module: %v", err)
+	}
+	dbPath := filepath.Join(root, "index.sqlite")
+
+	writeFile(t, filepath.Join(modPath, "go.mod"), "module example.com/diag\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(modPath, "diag.go"), `package diag
+
+import "fmt"
+
+func Describe(n int) string {
+	return fmt.Sprintf("%s items", n)
+}
+
+func Convert(n int) string {
+	return string(n)
+}
+
+func Early() int {
+	return 1
+	fmt.Println("never")
+	return 2
+}
+`)
+
+	base, err := IngestDiagnostics(ctx, IngestDiagnosticsConfig{DBPath: dbPath, RootDir: modPath, SourcesDir: filepath.Join(root, "sources")})
+	if err != nil {
+		t.Fatalf("ingest diagnostics: %v", err)
+	}
+	if base.Diagnostics != 3 || base.AnalyzerErrors != 0 {
+		t.Fatalf("expected 3 diagnostics, got %+v", base)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	records, err := store.ListDiagnostics(ctx, DiagnosticFilter{RunID: base.RunID})
+	if err != nil {
+		t.Fatalf("list diagnostics: %v", err)
+	}
+	byAnalyzer := make(map[string]DiagnosticRecord)
+	for _, record := range records {
+		byAnalyzer[record.Analyzer] = record
+	}
+	printfRecord, ok := byAnalyzer["printf"]
+	if !ok || printfRecord.Path != "diag.go" || printfRecord.StartLine != 6 || printfRecord.CodeUnit != "Describe" {
+		t.Fatalf("unexpected printf diagnostic: %+v", records)
+	}
+	unreachableRecord, ok := byAnalyzer["unreachable"]
+	if !ok || unreachableRecord.StartLine != 15 || unreachableRecord.CodeUnit != "Early" {
+		t.Fatalf("unexpected unreachable diagnostic: %+v", records)
+	}
+	conversion, ok := byAnalyzer["stringintconv"]
+	if !ok || conversion.CodeUnit != "Convert" || conversion.Fixes == 0 {
+		t.Fatalf("expected stringintconv diagnostic with fixes: %+v", records)
+	}
+
+	// Fix the printf call and enable an extra analyzer; the unreachable
+	// statement moves but is still matched to the base diagnostic.
+	writeFile(t, filepath.Join(modPath, "diag.go"), `package diag
+
+import "fmt"
+
+// Describe formats a count.
+func Describe(n int) string {
+	return fmt.Sprintf("%d items", n)
+}
+
+func Convert(n int) string {
+	return string(n)
+}
+
+func Early() int {
+	return 1
+	fmt.Println("never")
+	return 2
+}
+
+func Shadow(err error) error {
+	if err != nil {
+		err := fmt.Errorf("wrap: %w", err)
+		return err
+	}
+	return err
+}
+`)
+	head, err := IngestDiagnostics(ctx, IngestDiagnosticsConfig{DBPath: dbPath, RootDir: modPath, Analyzers: []string{"shadow"}, SourcesDir: filepath.Join(root, "sources")})
+	if err != nil {
+		t.Fatalf("ingest head diagnostics: %v", err)
+	}
+
+	changes, err := store.ListDiagnosticChanges(ctx, DiagnosticChangeFilter{BaseRunID: base.RunID, HeadRunID: head.RunID})
+	if err != nil {
+		t.Fatalf("list diagnostic changes: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Change != DiagnosticFixed || changes[0].Analyzer != "printf" || changes[0].CodeUnit != "Describe" {
+		t.Fatalf("unexpected fixed diagnostic: %+v", changes[0])
+	}
+	if changes[1].Change != DiagnosticIntroduced || changes[1].Analyzer != "shadow" || changes[1].CodeUnit != "Shadow" {
+		t.Fatalf("unexpected introduced diagnostic: %+v", changes[1])
+	}
+
+	counts, err := store.ListDiagnosticCounts(ctx, DiagnosticCountFilter{RunIDs: []int64{head.RunID}})
+	if err != nil {
+		t.Fatalf("list diagnostic counts: %v", err)
+	}
+	if len(counts) != 3 || counts[0].Analyzer != "shadow" || counts[2].Analyzer != "unreachable" {
+		t.Fatalf("unexpected diagnostic counts: %+v", counts)
+	}
+
+	if _, err := ResolveAnalyzers([]string{"nope"}, false); err == nil {
+		t.Fatalf("expected unknown analyzer error")
+	}
+}
@@ -3,9 +3,13 @@ package refactorindex
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -13,38 +17,133 @@ import (
 
 const ToolVersion = "dev"
 
+const (
+	// DiffModeRefs diffs FromRef against ToRef.
+	DiffModeRefs = "refs"
+	// DiffModeWorktree diffs the working tree against FromRef (default HEAD),
+	// including untracked files that are not ignored.
+	DiffModeWorktree = "worktree"
+	// DiffModeStaged diffs the index against FromRef (default HEAD).
+	DiffModeStaged = "staged"
+	// DiffModePatch reads a unified diff from PatchPath ("-" for stdin).
+	DiffModePatch = "patch"
+)
+
 type IngestDiffConfig struct {
 	DBPath     string
 	RepoPath   string
 	FromRef    string
 	ToRef      string
 	SourcesDir string
+
+	Mode      string
+	PatchPath string
+	// ContextLines is passed to git diff as -U<n>; 0 keeps hunks minimal.
+	ContextLines int
+	// MinMovedLines is the shortest block of removed lines that counts as
+	// moved when it reappears elsewhere; 0 uses DefaultMinMovedLines.
+	MinMovedLines int
+	// SkipAnnotations leaves out line pairs, word edits and moved blocks.
+	SkipAnnotations bool
 }
 
 type IngestDiffResult struct {
-	RunID  int64
-	Files  int
-	Hunks  int
-	Lines  int
-	RunDir string
+	RunID       int64
+	Files       int
+	Hunks       int
+	Lines       int
+	LinePairs   int
+	WordEdits   int
+	MovedBlocks int
+	RunDir      string
 }
 
 func IngestDiff(ctx context.Context, cfg IngestDiffConfig) (*IngestDiffResult, error) {
 	if strings.TrimSpace(cfg.DBPath) == "" {
 		return nil, errors.New("db path is required")
 	}
-	if strings.TrimSpace(cfg.RepoPath) == "" {
+	mode := cfg.Mode
+	if strings.TrimSpace(mode) == "" {
+		mode = DiffModeRefs
+	}
+	var diffArgs []string
+	switch mode {
+	case DiffModeRefs:
+		if strings.TrimSpace(cfg.FromRef) == "" || strings.TrimSpace(cfg.ToRef) == "" {
+			return nil, errors.New("from/to refs are required")
+		}
+		diffArgs = []string{cfg.FromRef, cfg.ToRef}
+	case DiffModeWorktree, DiffModeStaged:
+		if strings.TrimSpace(cfg.FromRef) == "" {
+			cfg.FromRef = "HEAD"
+		}
+		diffArgs = []string{cfg.FromRef}
+		if mode == DiffModeStaged {
+			diffArgs = []string{"--cached", cfg.FromRef}
+		}
+	case DiffModePatch:
+		if strings.TrimSpace(cfg.PatchPath) == "" {
+			return nil, errors.New("patch path is required")
+		}
+	default:
+		return nil, errors.Errorf("unknown diff mode %q", mode)
+	}
+	if strings.TrimSpace(cfg.RepoPath) == "" && mode != DiffModePatch {
 		return nil, errors.New("repo path is required")
 	}
-	repoPath, err := filepath.Abs(cfg.RepoPath)
-	if err != nil {
-		return nil, errors.Wrap(err, "resolve repo path")
+	repoPath := ""
+	if strings.TrimSpace(cfg.RepoPath) != "" {
+		abs, err := filepath.Abs(cfg.RepoPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve repo path")
+		}
+		repoPath = abs
+	}
+
+	// Untracked files are marked intent-to-add in a copy of the index so
+	// the worktree diff reports them as added.
+	var gitEnv []string
+	if mode == DiffModeWorktree {
+		indexFile, cleanup, err := intentToAddIndex(ctx, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		if indexFile != "" {
+			gitEnv = []string{"GIT_INDEX_FILE=" + indexFile}
+		}
+	}
+
+	baseCommit := ""
+	if repoPath != "" && strings.TrimSpace(cfg.FromRef) != "" {
+		out, err := runGit(ctx, repoPath, "rev-parse", "--verify", cfg.FromRef+"^{commit}")
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve base commit")
+		}
+		baseCommit = strings.TrimSpace(string(out))
+	}
+
+	if cfg.ContextLines < 0 {
+		return nil, errors.New("context lines must not be negative")
 	}
+
+	var patchInput io.ReadCloser
+	if mode == DiffModePatch {
+		input, err := openPatchInput(cfg.PatchPath)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = input.Close()
+		}()
+		patchInput = input
+	}
+
 	sourcesDir := cfg.SourcesDir
 	if strings.TrimSpace(sourcesDir) == "" {
 		sourcesDir = "sources"
 	}
-	sourcesDir, err = filepath.Abs(sourcesDir)
+	sourcesDir, err := filepath.Abs(sourcesDir)
 	if err != nil {
 		return nil, errors.Wrap(err, "resolve sources dir")
 	}
@@ -67,6 +166,11 @@ func IngestDiff(ctx context.Context, cfg IngestDiffConfig) (*IngestDiffResult, e
 		"to":          cfg.ToRef,
 		"repo":        repoPath,
 		"sources_dir": sourcesDir,
+		"mode":        mode,
+		"patch":       cfg.PatchPath,
+		"context":     strconv.Itoa(cfg.ContextLines),
+		"min_moved":   strconv.Itoa(cfg.MinMovedLines),
+		"annotate":    strconv.FormatBool(!cfg.SkipAnnotations),
 	})
 	if err != nil {
 		return nil, err
@@ -79,6 +183,8 @@ func IngestDiff(ctx context.Context, cfg IngestDiffConfig) (*IngestDiffResult, e
 		RootPath:    repoPath,
 		SourcesDir:  sourcesDir,
 		ArgsJSON:    argsJSON,
+		Mode:        mode,
+		BaseCommit:  baseCommit,
 	})
 	if err != nil {
 		return nil, err
@@ -94,121 +200,199 @@ func IngestDiff(ctx context.Context, cfg IngestDiffConfig) (*IngestDiffResult, e
 
 	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
 
-	nameStatusOutput, err := runGit(ctx, repoPath, "diff", "--name-status", "-z", cfg.FromRef, cfg.ToRef)
+	writer := newDiffWriter(ctx, store, tx, runID)
+	writer.minMovedLines = cfg.MinMovedLines
+	writer.skipAnnotations = cfg.SkipAnnotations
+	if mode == DiffModePatch {
+		raw, err := store.CreateRawOutput(ctx, tx, runDir, runID, "patch-file", "input.patch")
+		if err != nil {
+			return nil, err
+		}
+		streamErr := StreamUnifiedDiff(io.TeeReader(patchInput, raw), writer.Callbacks())
+		if err := raw.Close(); err != nil && streamErr == nil {
+			streamErr = errors.Wrap(err, "close raw output")
+		}
+		if streamErr != nil {
+			return nil, streamErr
+		}
+	} else {
+		if err := ingestGitDiff(ctx, store, tx, writer, runDir, runID, repoPath, gitEnv, diffArgs, cfg.ContextLines); err != nil {
+			return nil, err
+		}
+	}
+	counts, err := writer.Finish()
 	if err != nil {
 		return nil, err
 	}
-	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "git-name-status", "git-name-status.txt", nameStatusOutput); err != nil {
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit diff ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
 		return nil, err
 	}
 
+	return &IngestDiffResult{
+		RunID:       runID,
+		Files:       counts.Files,
+		Hunks:       counts.Hunks,
+		Lines:       counts.Lines,
+		LinePairs:   counts.LinePairs,
+		WordEdits:   counts.WordEdits,
+		MovedBlocks: counts.MovedBlocks,
+		RunDir:      runDir,
+	}, nil
+}
+
+// ingestGitDiff records name-status and numstat output up front, then
+// streams the patch from git into the writer while copying it to the raw
+// outputs. gitEnv is added to the environment of every git call.
+func ingestGitDiff(ctx context.Context, store *Store, tx *sql.Tx, writer *diffWriter, runDir string, runID int64, repoPath string, gitEnv []string, diffArgs []string, contextLines int) error {
+	nameStatusOutput, err := runGitEnv(ctx, repoPath, gitEnv, append([]string{"diff", "--name-status", "-z"}, diffArgs...)...)
+	if err != nil {
+		return err
+	}
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "git-name-status", "git-name-status.txt", nameStatusOutput); err != nil {
+		return err
+	}
 	entries, err := ParseNameStatus(nameStatusOutput)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	patchOutput, err := runGit(ctx, repoPath, "diff", "-U0", "--no-color", cfg.FromRef, cfg.ToRef)
+	numstatOutput, err := runGitEnv(ctx, repoPath, gitEnv, append([]string{"diff", "--numstat", "-z"}, diffArgs...)...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "git-diff-u0", "git-diff-u0.patch", patchOutput); err != nil {
-		return nil, err
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "git-numstat", "git-numstat.txt", numstatOutput); err != nil {
+		return err
+	}
+	numstat, err := ParseNumstat(numstatOutput)
+	if err != nil {
+		return err
 	}
 
-	pathToDiffFileID := make(map[string]int64)
-	fileCount := 0
-	for _, entry := range entries {
-		primaryPath := entry.PrimaryPath()
-		fileID, err := store.GetOrCreateFile(ctx, tx, primaryPath)
-		if err != nil {
-			return nil, err
-		}
-		if entry.OldPath != "" {
-			_, err := store.GetOrCreateFile(ctx, tx, entry.OldPath)
-			if err != nil {
-				return nil, err
-			}
-		}
-		if entry.NewPath != "" {
-			_, err := store.GetOrCreateFile(ctx, tx, entry.NewPath)
-			if err != nil {
-				return nil, err
-			}
-		}
-		diffFileID, err := store.InsertDiffFile(ctx, tx, runID, fileID, entry.Status, entry.OldPath, entry.NewPath)
-		if err != nil {
-			return nil, err
-		}
-		fileCount++
-		pathToDiffFileID[primaryPath] = diffFileID
-		if entry.OldPath != "" {
-			pathToDiffFileID[entry.OldPath] = diffFileID
-		}
-		if entry.NewPath != "" {
-			pathToDiffFileID[entry.NewPath] = diffFileID
-		}
+	if err := writer.AddEntries(entries, numstat); err != nil {
+		return err
 	}
 
-	patches, err := ParseUnifiedDiff(patchOutput)
+	source := fmt.Sprintf("git-diff-u%d", contextLines)
+	raw, err := store.CreateRawOutput(ctx, tx, runDir, runID, source, source+".patch")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	args := append([]string{"diff", fmt.Sprintf("-U%d", contextLines), "--no-color"}, diffArgs...)
+	streamErr := runGitStream(ctx, repoPath, gitEnv, func(r io.Reader) error {
+		return StreamUnifiedDiff(io.TeeReader(r, raw), writer.Callbacks())
+	}, args...)
+	if err := raw.Close(); err != nil && streamErr == nil {
+		streamErr = errors.Wrap(err, "close raw output")
 	}
+	return streamErr
+}
 
-	hunkCount := 0
-	lineCount := 0
-	for _, patch := range patches {
-		diffFileID := resolveDiffFileID(pathToDiffFileID, patch.OldPath, patch.NewPath)
-		if diffFileID == 0 {
-			continue
-		}
-		for _, hunk := range patch.Hunks {
-			hunkID, err := store.InsertDiffHunk(ctx, tx, diffFileID, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
-			if err != nil {
-				return nil, err
-			}
-			hunkCount++
-			for _, line := range hunk.Lines {
-				if err := store.InsertDiffLine(ctx, tx, hunkID, line.Kind, line.OldLine, line.NewLine, line.Text); err != nil {
-					return nil, err
-				}
-				lineCount++
-			}
-		}
+// intentToAddIndex copies the repository index to a temporary file and
+// marks the untracked, non-ignored files intent-to-add in the copy. It
+// returns an empty path when nothing is untracked; cleanup removes the copy.
+func intentToAddIndex(ctx context.Context, repoPath string) (string, func(), error) {
+	cleanup := func() {}
+	untracked, err := runGit(ctx, repoPath, "ls-files", "-z", "--others", "--exclude-standard", "--", ":/")
+	if err != nil {
+		return "", cleanup, errors.Wrap(err, "list untracked files")
+	}
+	if len(bytes.Trim(untracked, "\x00")) == 0 {
+		return "", cleanup, nil
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, errors.Wrap(err, "commit diff ingestion")
+	out, err := runGit(ctx, repoPath, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", cleanup, errors.Wrap(err, "resolve index path")
 	}
-	if err := store.FinishRun(ctx, runID); err != nil {
-		return nil, err
+	indexPath := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(repoPath, indexPath)
+	}
+	index, err := os.ReadFile(indexPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", cleanup, errors.Wrap(err, "read index")
 	}
 
-	return &IngestDiffResult{
-		RunID:  runID,
-		Files:  fileCount,
-		Hunks:  hunkCount,
-		Lines:  lineCount,
-		RunDir: runDir,
-	}, nil
+	tmp, err := os.CreateTemp("", "refactor-index-*.index")
+	if err != nil {
+		return "", cleanup, errors.Wrap(err, "create temporary index")
+	}
+	cleanup = func() {
+		_ = os.Remove(tmp.Name())
+	}
+	_, writeErr := tmp.Write(index)
+	if err := tmp.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		cleanup()
+		return "", func() {}, errors.Wrap(writeErr, "write temporary index")
+	}
+
+	if _, err := runGitEnv(ctx, repoPath, []string{"GIT_INDEX_FILE=" + tmp.Name()}, "add", "-N", "--", ":/"); err != nil {
+		cleanup()
+		return "", func() {}, errors.Wrap(err, "mark untracked files intent-to-add")
+	}
+	return tmp.Name(), cleanup, nil
 }
 
-func resolveDiffFileID(index map[string]int64, oldPath string, newPath string) int64 {
-	if newPath != "" {
-		if id, ok := index[newPath]; ok {
-			return id
-		}
+func openPatchInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open patch file")
 	}
-	if oldPath != "" {
-		if id, ok := index[oldPath]; ok {
-			return id
+	return file, nil
+}
+
+// runGitStream runs git and hands its stdout to consume while the command is
+// still running, so large outputs are never held in memory.
+func runGitStream(ctx context.Context, repoPath string, env []string, consume func(io.Reader) error, args ...string) error {
+	cmdArgs := append([]string{"-C", repoPath}, args...)
+	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "open git stdout")
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "start git")
+	}
+	consumeErr := consume(stdout)
+	if consumeErr != nil {
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = "git command failed"
 		}
+		return errors.Wrap(err, msg)
 	}
-	return 0
+	return consumeErr
 }
 
 func runGit(ctx context.Context, repoPath string, args ...string) ([]byte, error) {
+	return runGitEnv(ctx, repoPath, nil, args...)
+}
+
+// runGitEnv runs git with env added to the current environment.
+func runGitEnv(ctx context.Context, repoPath string, env []string, args ...string) ([]byte, error) {
 	cmdArgs := append([]string{"-C", repoPath}, args...)
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	stdout, err := cmd.Output()
@@ -70,7 +70,7 @@ func TestIngestDiffGolden(t *testing.T) {
 	}()
 
 	store := NewStore(db)
-	records, err := store.ListDiffFiles(ctx, result.RunID)
+	records, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: result.RunID})
 	if err != nil {
 		t.Fatalf("list diff files: %v", err)
 	}
@@ -92,6 +92,534 @@ func TestIngestDiffGolden(t *testing.T) {
 	assertDiffLinesFTSCount(t, db)
 }
 
+func TestIngestDiffChangeStats(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "ws.go"), "package a\n\nfunc A() int {\n\treturn 1\n}\n")
+	writeFile(t, filepath.Join(repoPath, "comment.go"), "package a\n\n// B does b.\nfunc B() {}\n")
+	writeFile(t, filepath.Join(repoPath, "imports.go"), "package a\n\nimport (\n\t\"fmt\"\n)\n\nfunc C() { fmt.Println() }\n")
+	writeFile(t, filepath.Join(repoPath, "code.go"), "package a\n\nfunc D() int {\n\treturn 1\n}\n")
+	writeFile(t, filepath.Join(repoPath, "blob.bin"), "\x00\x01\x02")
+	writeFile(t, filepath.Join(repoPath, "moved.txt"), "one\ntwo\nthree\nfour\nfive\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "ws.go"), "package a\n\nfunc A() int {\n    return 1\n}\n")
+	writeFile(t, filepath.Join(repoPath, "comment.go"), "package a\n\n// B does b, now documented.\nfunc B() {}\n")
+	writeFile(t, filepath.Join(repoPath, "imports.go"), "package a\n\nimport (\n\tlog \"fmt\"\n)\n\nfunc C() { fmt.Println() }\n")
+	writeFile(t, filepath.Join(repoPath, "code.go"), "package a\n\nfunc D() int {\n\treturn 2\n}\n")
+	writeFile(t, filepath.Join(repoPath, "blob.bin"), "\x00\x01\x03")
+	git(t, repoPath, "mv", "moved.txt", "renamed.txt")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	records, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: result.RunID})
+	if err != nil {
+		t.Fatalf("list diff files: %v", err)
+	}
+	byPath := make(map[string]DiffFileRecord)
+	for _, record := range records {
+		byPath[record.Path] = record
+	}
+
+	expected := map[string]string{
+		"ws.go":       ChangeClassWhitespace,
+		"comment.go":  ChangeClassComment,
+		"imports.go":  ChangeClassImports,
+		"code.go":     ChangeClassCode,
+		"blob.bin":    ChangeClassBinary,
+		"renamed.txt": ChangeClassNone,
+	}
+	for path, class := range expected {
+		record, ok := byPath[path]
+		if !ok {
+			t.Fatalf("expected diff file %s", path)
+		}
+		if record.ChangeClass != class {
+			t.Fatalf("expected change class %s for %s, got %s", class, path, record.ChangeClass)
+		}
+	}
+	if byPath["code.go"].Insertions != 1 || byPath["code.go"].Deletions != 1 {
+		t.Fatalf("expected 1/1 numstat for code.go, got %d/%d", byPath["code.go"].Insertions, byPath["code.go"].Deletions)
+	}
+	if !byPath["blob.bin"].IsBinary {
+		t.Fatalf("expected blob.bin to be binary")
+	}
+	if byPath["renamed.txt"].Similarity != 100 {
+		t.Fatalf("expected rename similarity 100, got %d", byPath["renamed.txt"].Similarity)
+	}
+
+	var binaryFlag int
+	if err := db.QueryRow("SELECT is_binary FROM files WHERE path = 'blob.bin'").Scan(&binaryFlag); err != nil {
+		t.Fatalf("query files.is_binary: %v", err)
+	}
+	if binaryFlag != 1 {
+		t.Fatalf("expected files.is_binary = 1 for blob.bin")
+	}
+
+	code, err := store.ListDiffFiles(ctx, DiffFileFilter{
+		RunID:          result.RunID,
+		ExcludeClasses: []string{ChangeClassWhitespace, ChangeClassComment, ChangeClassImports, ChangeClassNone},
+	})
+	if err != nil {
+		t.Fatalf("list filtered diff files: %v", err)
+	}
+	if len(code) != 2 {
+		t.Fatalf("expected 2 non-noise diff files, got %d", len(code))
+	}
+}
+
+func TestIngestDiffUncommittedModes(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "staged.txt"), "one\n")
+	writeFile(t, filepath.Join(repoPath, "unstaged.txt"), "one\n")
+	writeFile(t, filepath.Join(repoPath, "gone.txt"), "one\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	head := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "staged.txt"), "one\ntwo\n")
+	writeFile(t, filepath.Join(repoPath, "added.txt"), "new\n")
+	git(t, repoPath, "add", "staged.txt", "added.txt")
+	git(t, repoPath, "rm", "-q", "gone.txt")
+	writeFile(t, filepath.Join(repoPath, "unstaged.txt"), "one\nthree\n")
+	writeFile(t, filepath.Join(repoPath, "untracked.txt"), "fresh\n")
+	writeFile(t, filepath.Join(repoPath, "ignored.log"), "noise\n")
+	writeFile(t, filepath.Join(repoPath, ".git", "info", "exclude"), "*.log\n")
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	sourcesDir := filepath.Join(root, "sources")
+	worktree, err := IngestDiff(ctx, IngestDiffConfig{DBPath: dbPath, RepoPath: repoPath, SourcesDir: sourcesDir, Mode: DiffModeWorktree})
+	if err != nil {
+		t.Fatalf("ingest worktree diff: %v", err)
+	}
+	if worktree.Files != 5 {
+		t.Fatalf("expected 5 worktree files, got %d", worktree.Files)
+	}
+	if status := gitOut(t, repoPath, "status", "--porcelain", "untracked.txt"); status != "?? untracked.txt\n" {
+		t.Fatalf("expected the real index to be untouched, got %q", status)
+	}
+	staged, err := IngestDiff(ctx, IngestDiffConfig{DBPath: dbPath, RepoPath: repoPath, SourcesDir: sourcesDir, Mode: DiffModeStaged})
+	if err != nil {
+		t.Fatalf("ingest staged diff: %v", err)
+	}
+	if staged.Files != 3 {
+		t.Fatalf("expected 3 staged files, got %d", staged.Files)
+	}
+
+	patchPath := filepath.Join(root, "change.patch")
+	writeFile(t, patchPath, gitOut(t, repoPath, "diff", "HEAD"))
+	patch, err := IngestDiff(ctx, IngestDiffConfig{DBPath: dbPath, SourcesDir: sourcesDir, Mode: DiffModePatch, PatchPath: patchPath})
+	if err != nil {
+		t.Fatalf("ingest patch diff: %v", err)
+	}
+	// git diff HEAD leaves out the untracked file the worktree run includes.
+	if patch.Files != 4 || patch.Hunks != worktree.Hunks-1 {
+		t.Fatalf("expected patch to match worktree diff without untracked.txt, got %d files/%d hunks", patch.Files, patch.Hunks)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	for runID, expected := range map[int64][2]string{
+		worktree.RunID: {DiffModeWorktree, head},
+		staged.RunID:   {DiffModeStaged, head},
+		patch.RunID:    {DiffModePatch, ""},
+	} {
+		var mode string
+		var base sql.NullString
+		if err := db.QueryRow("SELECT mode, base_commit FROM meta_runs WHERE id = ?", runID).Scan(&mode, &base); err != nil {
+			t.Fatalf("query meta run: %v", err)
+		}
+		if mode != expected[0] || base.String != expected[1] {
+			t.Fatalf("expected mode %s base %q for run %d, got %s %q", expected[0], expected[1], runID, mode, base.String)
+		}
+	}
+
+	store := NewStore(db)
+	worktreeRecords, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: worktree.RunID})
+	if err != nil {
+		t.Fatalf("list worktree diff files: %v", err)
+	}
+	worktreeStatuses := make(map[string]string)
+	for _, record := range worktreeRecords {
+		worktreeStatuses[record.Path] = record.Status
+	}
+	if worktreeStatuses["untracked.txt"] != "A" || worktreeStatuses["ignored.log"] != "" {
+		t.Fatalf("expected untracked.txt added and ignored.log skipped, got %v", worktreeStatuses)
+	}
+
+	records, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: patch.RunID})
+	if err != nil {
+		t.Fatalf("list patch diff files: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, record := range records {
+		statuses[record.Path] = record.Status
+	}
+	expectedStatuses := map[string]string{"added.txt": "A", "gone.txt": "D", "staged.txt": "M", "unstaged.txt": "M"}
+	for path, status := range expectedStatuses {
+		if statuses[path] != status {
+			t.Fatalf("expected %s status %s, got %q", path, status, statuses[path])
+		}
+	}
+}
+
+func TestIngestDiffContextAndSpacedPaths(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repoPath, "my dir"), 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "my dir", "file one.txt"), "a\nb\nc\nd\ne\n")
+	writeFile(t, filepath.Join(repoPath, "caf\u00e9.txt"), "x\n-- y\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "my dir", "file one.txt"), "a\nb\nC\nd\ne\n")
+	writeFile(t, filepath.Join(repoPath, "caf\u00e9.txt"), "x\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:       dbPath,
+		RepoPath:     repoPath,
+		FromRef:      fromRef,
+		ToRef:        toRef,
+		SourcesDir:   filepath.Join(root, "sources"),
+		ContextLines: 2,
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+	if result.Files != 2 || result.Hunks != 2 {
+		t.Fatalf("expected 2 files and 2 hunks, got %d/%d", result.Files, result.Hunks)
+	}
+	// 4 context + 2 changed lines in "file one.txt", 1 context + 1 removed in café.txt.
+	if result.Lines != 8 {
+		t.Fatalf("expected 8 diff lines, got %d", result.Lines)
+	}
+	if _, err := os.Stat(filepath.Join(result.RunDir, "git-diff-u2.patch")); err != nil {
+		t.Fatalf("missing git-diff-u2.patch: %v", err)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var removed string
+	if err := db.QueryRow(
+		`SELECT dl.text FROM diff_lines dl
+		 JOIN diff_hunks dh ON dh.id = dl.hunk_id
+		 JOIN diff_files df ON df.id = dh.diff_file_id
+		 WHERE df.run_id = ? AND df.new_path = ? AND dl.kind = '-'`,
+		result.RunID, "caf\u00e9.txt",
+	).Scan(&removed); err != nil {
+		t.Fatalf("query removed line: %v", err)
+	}
+	if removed != "-- y" {
+		t.Fatalf("expected removed line %q, got %q", "-- y", removed)
+	}
+
+	var hunkCount int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM diff_hunks dh
+		 JOIN diff_files df ON df.id = dh.diff_file_id
+		 WHERE df.run_id = ? AND df.new_path = ?`,
+		result.RunID, "my dir/file one.txt",
+	).Scan(&hunkCount); err != nil {
+		t.Fatalf("count hunks: %v", err)
+	}
+	if hunkCount != 1 {
+		t.Fatalf("expected hunks linked to the spaced path, got %d", hunkCount)
+	}
+}
+
+func TestIngestDiffWordEditsAndMoves(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	helper := "func helper() int {\n\ttotal := 0\n\ttotal += compute(1)\n\treturn total\n}\n"
+	writeFile(t, filepath.Join(repoPath, "a.go"), "package p\n\nfunc run() {\n\tx := OldName(1)\n\t_ = x\n}\n\n"+helper)
+	writeFile(t, filepath.Join(repoPath, "b.go"), "package p\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "a.go"), "package p\n\nfunc run() {\n\tx := NewName(1)\n\t_ = x\n}\n")
+	writeFile(t, filepath.Join(repoPath, "b.go"), "package p\n\n"+helper)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "rename and move")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+	if result.MovedBlocks != 1 {
+		t.Fatalf("expected 1 moved block, got %d", result.MovedBlocks)
+	}
+	if result.LinePairs != 1 || result.WordEdits != 1 {
+		t.Fatalf("expected 1 line pair with 1 edit, got %d/%d", result.LinePairs, result.WordEdits)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	edits, err := store.ListWordEdits(ctx, WordEditFilter{RunID: result.RunID, OldText: "OldName", NewText: "NewName", SingleEdit: true})
+	if err != nil {
+		t.Fatalf("list word edits: %v", err)
+	}
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 word edit, got %d", len(edits))
+	}
+	edit := edits[0]
+	if edit.Path != "a.go" || edit.OldLine != 4 || edit.Kind != WordEditReplace || edit.OldStart != 6 || edit.NewEnd != 13 {
+		t.Fatalf("unexpected word edit: %+v", edit)
+	}
+
+	files, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: result.RunID})
+	if err != nil {
+		t.Fatalf("list diff files: %v", err)
+	}
+	moved := map[string][2]int{}
+	for _, file := range files {
+		moved[file.Path] = [2]int{file.MovedInsertions, file.MovedDeletions}
+	}
+	// The blank separator line is not part of the moved block.
+	if moved["a.go"] != [2]int{0, 5} || moved["b.go"] != [2]int{5, 0} {
+		t.Fatalf("unexpected moved counts: %+v", moved)
+	}
+
+	var movedLines int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM diff_lines dl
+		 JOIN diff_moved_blocks mb ON mb.id = dl.moved_block_id
+		 WHERE mb.run_id = ?`, result.RunID,
+	).Scan(&movedLines); err != nil {
+		t.Fatalf("count moved lines: %v", err)
+	}
+	if movedLines != 10 {
+		t.Fatalf("expected 10 moved lines, got %d", movedLines)
+	}
+}
+
+func TestIngestDiffMovedLinesAreNotPaired(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	helper := "func helper() int {\n\ttotal := 0\n\ttotal += compute(1)\n\treturn total\n}\n"
+	writeFile(t, filepath.Join(repoPath, "a.go"), "package p\n\n"+helper)
+	writeFile(t, filepath.Join(repoPath, "b.go"), "package p\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	// helper moves to b.go while a look-alike takes its place, so the
+	// removed lines are paired in their hunk before the move is found.
+	writeFile(t, filepath.Join(repoPath, "a.go"), "package p\n\n"+strings.NewReplacer("helper", "other", "total", "sum", "compute", "measure").Replace(helper))
+	writeFile(t, filepath.Join(repoPath, "b.go"), "package p\n\n"+helper)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "move helper")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	cfg := IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	}
+	result, err := IngestDiff(ctx, cfg)
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+	if result.MovedBlocks != 1 || result.LinePairs != 0 || result.WordEdits != 0 {
+		t.Fatalf("expected 1 moved block and no pairs, got %d/%d/%d", result.MovedBlocks, result.LinePairs, result.WordEdits)
+	}
+
+	cfg.SkipAnnotations = true
+	skipped, err := IngestDiff(ctx, cfg)
+	if err != nil {
+		t.Fatalf("ingest diff without annotations: %v", err)
+	}
+	if skipped.Lines != result.Lines || skipped.MovedBlocks != 0 || skipped.LinePairs != 0 {
+		t.Fatalf("expected lines only, got %+v", skipped)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	var pairs, edits int
+	if err := db.QueryRow("SELECT COUNT(*) FROM diff_line_pairs").Scan(&pairs); err != nil {
+		t.Fatalf("count pairs: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM diff_word_edits").Scan(&edits); err != nil {
+		t.Fatalf("count word edits: %v", err)
+	}
+	if pairs != 0 || edits != 0 {
+		t.Fatalf("expected pairs of moved lines to be removed, got %d pairs and %d edits", pairs, edits)
+	}
+}
+
+func TestStreamUnifiedDiffPaths(t *testing.T) {
+	diff := strings.Join([]string{
+		"--- plain.txt\t2024-01-01 00:00:00",
+		"+++ plain.txt\t2024-01-02 00:00:00",
+		`@@ -1 +1 @@`,
+		`-a`,
+		`+b`,
+		`diff --git a/my dir/a b.txt b/my dir/a b.txt`,
+		`index 1111111..2222222 100644`,
+		`--- a/my dir/a b.txt`,
+		`+++ b/my dir/a b.txt`,
+		`@@ -1,2 +1,2 @@`,
+		` keep`,
+		`--- removed`,
+		`+added`,
+		`diff --git "a/tab\there.txt" "b/tab\there.txt"`,
+		`new file mode 100644`,
+		`--- /dev/null`,
+		`+++ "b/tab\there.txt"`,
+		`@@ -0,0 +1 @@`,
+		`+x`,
+		`\ No newline at end of file`,
+		`diff --git a/old name.txt b/new name.txt`,
+		`similarity index 90%`,
+		`rename from old name.txt`,
+		`rename to new name.txt`,
+		``,
+	}, "\n")
+
+	var files []FilePatch
+	lines := 0
+	err := StreamUnifiedDiff(strings.NewReader(diff), DiffCallbacks{
+		OnFile: func(patch *FilePatch) error {
+			files = append(files, *patch)
+			return nil
+		},
+		OnLine: func(_ *FilePatch, _ DiffLine) error {
+			lines++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("stream diff: %v", err)
+	}
+	expected := []FilePatch{
+		{OldPath: "plain.txt", NewPath: "plain.txt", Status: "M"},
+		{OldPath: "my dir/a b.txt", NewPath: "my dir/a b.txt", Status: "M"},
+		{OldPath: "", NewPath: "tab\there.txt", Status: "A"},
+		{OldPath: "old name.txt", NewPath: "new name.txt", Status: "R090"},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %+v", len(expected), files)
+	}
+	for i, want := range expected {
+		got := files[i]
+		if got.OldPath != want.OldPath || got.NewPath != want.NewPath || got.Status != want.Status {
+			t.Fatalf("file %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if lines != 6 {
+		t.Fatalf("expected 6 lines, got %d", lines)
+	}
+}
+
 func assertRawOutputs(t *testing.T, runDir string) {
 	if _, err := os.Stat(filepath.Join(runDir, "git-name-status.txt")); err != nil {
 		t.Fatalf("missing git-name-status.txt: %v", err)
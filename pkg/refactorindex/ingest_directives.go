@@ -0,0 +1,567 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IngestDirectivesConfig controls directive ingestion.
+type IngestDirectivesConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	// Tolerant records files that fail to parse in load_errors instead of
+	// failing the run.
+	Tolerant bool
+}
+
+// IngestDirectivesResult reports counts for directive ingestion.
+type IngestDirectivesResult struct {
+	RunID      int64
+	Files      int
+	Directives int
+	EmbedFiles int
+	Broken     int
+	LoadErrors int
+	Partial    bool
+}
+
+// Directive is one compiler or tool directive comment, e.g. //go:embed,
+// //go:build, //go:generate, //go:linkname or //nolint. Decl names the
+// declaration the directive is attached to, if any. Problem is set when the
+// directive references files or symbols that do not exist.
+type Directive struct {
+	Name     string
+	Args     string
+	Path     string
+	Line     int
+	Col      int
+	Decl     string
+	DeclKind string
+	Problem  string
+	Embeds   []DirectiveEmbed
+}
+
+// DirectiveEmbed is one file matched by a //go:embed pattern; Path is empty
+// when the pattern matches nothing.
+type DirectiveEmbed struct {
+	Pattern string
+	Path    string
+}
+
+func IngestDirectives(ctx context.Context, cfg IngestDirectivesConfig) (*IngestDirectivesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"root":     rootDir,
+		"tolerant": strconv.FormatBool(cfg.Tolerant),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	directives, fileCount, loadErrors, err := collectDirectives(rootDir, cfg.Tolerant)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+
+	result := &IngestDirectivesResult{
+		RunID:      runID,
+		Files:      fileCount,
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	fileIDs := make(map[string]int64)
+	getFileID := func(path string) (int64, error) {
+		if id, ok := fileIDs[path]; ok {
+			return id, nil
+		}
+		id, err := store.GetOrCreateFile(ctx, tx, path)
+		if err != nil {
+			return 0, err
+		}
+		fileIDs[path] = id
+		return id, nil
+	}
+	for _, directive := range directives {
+		fileID, err := getFileID(directive.Path)
+		if err != nil {
+			return nil, err
+		}
+		directiveID, err := store.InsertDirective(ctx, tx, runID, cfg.CommitID, fileID, directive)
+		if err != nil {
+			return nil, err
+		}
+		for _, embed := range directive.Embeds {
+			var embedFileID *int64
+			if embed.Path != "" {
+				id, err := getFileID(embed.Path)
+				if err != nil {
+					return nil, err
+				}
+				embedFileID = &id
+				result.EmbedFiles++
+			}
+			if err := store.InsertDirectiveEmbed(ctx, tx, directiveID, embed.Pattern, embedFileID); err != nil {
+				return nil, err
+			}
+		}
+		if directive.Problem != "" {
+			result.Broken++
+		}
+		result.Directives++
+	}
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit directive ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// directiveFile is one parsed Go file with the import path of its package.
+type directiveFile struct {
+	path       string
+	dir        string
+	importPath string
+	file       *ast.File
+}
+
+// collectDirectives parses every Go file below rootDir, whatever its build
+// constraints, and returns its directives in file and line order. Package
+// level names are indexed by import path to check //go:linkname.
+func collectDirectives(rootDir string, tolerant bool) ([]Directive, int, []LoadError, error) {
+	modDirs, err := findGoModDirs(rootDir)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	modulePaths := make(map[string]string, len(modDirs))
+	for _, dir := range modDirs {
+		if modFile, err := ParseModFile(filepath.Join(dir, "go.mod")); err == nil {
+			modulePaths[dir] = modFile.ModulePath
+		}
+	}
+	importPathOf := func(dir string) string {
+		best := ""
+		for modDir := range modulePaths {
+			if (dir == modDir || strings.HasPrefix(dir, modDir+string(filepath.Separator))) && len(modDir) > len(best) {
+				best = modDir
+			}
+		}
+		if best == "" {
+			return ""
+		}
+		rel, _ := filepath.Rel(best, dir)
+		return path.Join(modulePaths[best], filepath.ToSlash(rel))
+	}
+
+	var paths []string
+	err = filepath.WalkDir(rootDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != rootDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".go") {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "find go files")
+	}
+	sort.Strings(paths)
+
+	fset := token.NewFileSet()
+	var files []directiveFile
+	var loadErrors []LoadError
+	declared := make(map[string]map[string]struct{})
+	for _, p := range paths {
+		relPath, _ := rootRelativePath(rootDir, p)
+		file, err := parser.ParseFile(fset, p, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			if !tolerant {
+				return nil, 0, nil, errors.Wrapf(err, "parse %s", relPath)
+			}
+			loadErrors = append(loadErrors, LoadError{Path: relPath, Kind: LoadErrorParse, Message: err.Error()})
+			if file == nil {
+				continue
+			}
+		}
+		dir := filepath.Dir(p)
+		importPath := importPathOf(dir)
+		files = append(files, directiveFile{path: relPath, dir: dir, importPath: importPath, file: file})
+		key := importPath + "\x00" + dir
+		if declared[key] == nil {
+			declared[key] = make(map[string]struct{})
+		}
+		for name := range packageLevelNames(file) {
+			declared[key][name] = struct{}{}
+		}
+	}
+	dirsByImportPath := make(map[string]string)
+	for _, f := range files {
+		if f.importPath != "" {
+			dirsByImportPath[f.importPath] = f.dir
+		}
+	}
+
+	var directives []Directive
+	for _, f := range files {
+		for _, group := range f.file.Comments {
+			for _, comment := range group.List {
+				name, args, ok := parseDirective(comment.Text)
+				if !ok {
+					continue
+				}
+				pos := fset.PositionFor(comment.Pos(), false)
+				directive := Directive{
+					Name: name,
+					Args: args,
+					Path: f.path,
+					Line: pos.Line,
+					Col:  pos.Column,
+				}
+				directive.Decl, directive.DeclKind = attachedDecl(fset, f.file, comment)
+
+				switch name {
+				case "go:embed":
+					directive.Embeds = resolveEmbeds(rootDir, f.dir, args)
+					var missing []string
+					for _, embed := range directive.Embeds {
+						if embed.Path == "" {
+							missing = append(missing, embed.Pattern)
+						}
+					}
+					if len(missing) > 0 {
+						directive.Problem = "pattern matches no files: " + strings.Join(missing, " ")
+					} else if directive.DeclKind != "var" {
+						directive.Problem = "not attached to a variable"
+					}
+				case "go:linkname":
+					directive.Problem = checkLinkname(args, declared[f.importPath+"\x00"+f.dir], func(importPath string) (map[string]struct{}, bool) {
+						dir, ok := dirsByImportPath[importPath]
+						if !ok {
+							return nil, false
+						}
+						return declared[importPath+"\x00"+dir], true
+					})
+				}
+				directives = append(directives, directive)
+			}
+		}
+	}
+	return directives, len(files), loadErrors, nil
+}
+
+// parseDirective splits a comment into a directive name and arguments. It
+// accepts the //tool:name convention (no space after the slashes), //nolint
+// with or without linters, //export, //line and legacy // +build lines.
+// For //nolint:a,b the linters are the arguments.
+func parseDirective(text string) (string, string, bool) {
+	body, ok := strings.CutPrefix(text, "//")
+	if !ok {
+		return "", "", false
+	}
+	if rest, ok := strings.CutPrefix(body, " +build "); ok {
+		return "+build", strings.TrimSpace(rest), true
+	}
+	name, args, _ := strings.Cut(body, " ")
+	args = strings.TrimSpace(args)
+	if linters, ok := strings.CutPrefix(name, "nolint:"); ok {
+		if args != "" {
+			linters += " " + args
+		}
+		return "nolint", linters, true
+	}
+	switch name {
+	case "nolint", "export", "line":
+		return name, args, true
+	}
+	tool, directive, ok := strings.Cut(name, ":")
+	if !ok || tool == "" || directive == "" || !isDirectiveWord(tool) || !isDirectiveWord(directive) {
+		return "", "", false
+	}
+	return name, args, true
+}
+
+func isDirectiveWord(word string) bool {
+	for _, r := range word {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// attachedDecl returns the name and kind of the declaration a directive
+// belongs to: the one whose doc comment holds it, or, for trailing
+// comments such as //nolint, the one on whose lines it appears.
+func attachedDecl(fset *token.FileSet, file *ast.File, comment *ast.Comment) (string, string) {
+	pos := comment.Pos()
+	within := func(node ast.Node, doc *ast.CommentGroup) bool {
+		if doc != nil && pos >= doc.Pos() && pos <= doc.End() {
+			return true
+		}
+		return pos >= node.Pos() && pos <= node.End()
+	}
+	line := fset.PositionFor(pos, false).Line
+	onLine := func(node ast.Node) bool {
+		return fset.PositionFor(node.End(), false).Line == line
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if within(d, d.Doc) || onLine(d) {
+				name := d.Name.Name
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					return receiverTypeName(d.Recv.List[0].Type) + "." + name, "method"
+				}
+				return name, "func"
+			}
+		case *ast.GenDecl:
+			if !within(d, d.Doc) && !onLine(d) {
+				continue
+			}
+			kind := d.Tok.String()
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if len(d.Specs) == 1 || within(s, s.Doc) || onLine(s) {
+						return s.Name.Name, kind
+					}
+				case *ast.ValueSpec:
+					if len(d.Specs) == 1 || within(s, s.Doc) || onLine(s) {
+						names := make([]string, 0, len(s.Names))
+						for _, ident := range s.Names {
+							names = append(names, ident.Name)
+						}
+						return strings.Join(names, ","), kind
+					}
+				case *ast.ImportSpec:
+					if len(d.Specs) == 1 || within(s, s.Doc) || onLine(s) {
+						importPath, _ := strconv.Unquote(s.Path.Value)
+						return importPath, kind
+					}
+				}
+			}
+			return "", kind
+		}
+	}
+	return "", ""
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverTypeName(e.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(e.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	default:
+		return ""
+	}
+}
+
+// packageLevelNames returns the names a file declares at package level,
+// which is what //go:linkname can refer to.
+func packageLevelNames(file *ast.File) map[string]struct{} {
+	names := make(map[string]struct{})
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				names[d.Name.Name] = struct{}{}
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names[s.Name.Name] = struct{}{}
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						names[ident.Name] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// checkLinkname checks "//go:linkname localname [importpath.name]": the
+// local name must be declared in the package, and the target too when its
+// package was scanned. Targets in other packages are not checked.
+func checkLinkname(args string, local map[string]struct{}, lookup func(string) (map[string]struct{}, bool)) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "missing local name"
+	}
+	if _, ok := local[fields[0]]; !ok {
+		return "local name not declared: " + fields[0]
+	}
+	if len(fields) < 2 {
+		return ""
+	}
+	target := fields[1]
+	dot := strings.LastIndex(target, ".")
+	if dot <= 0 || strings.Contains(target[dot:], "/") {
+		return ""
+	}
+	names, ok := lookup(target[:dot])
+	if !ok {
+		return ""
+	}
+	if _, ok := names[target[dot+1:]]; !ok {
+		return "target not declared: " + target
+	}
+	return ""
+}
+
+// resolveEmbeds expands the patterns of a //go:embed directive relative to
+// the file's directory, following the embed rules: matched directories are
+// embedded recursively, skipping "." and "_" files unless the pattern has
+// the all: prefix. A pattern matching nothing yields one entry without a
+// path.
+func resolveEmbeds(rootDir string, dir string, args string) []DirectiveEmbed {
+	var embeds []DirectiveEmbed
+	for _, pattern := range splitDirectiveArgs(args) {
+		glob, all := strings.CutPrefix(pattern, "all:")
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(glob)))
+		var files []string
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			if !info.IsDir() {
+				files = append(files, match)
+				continue
+			}
+			_ = filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				name := d.Name()
+				if p != match && !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !d.IsDir() {
+					files = append(files, p)
+				}
+				return nil
+			})
+		}
+		sort.Strings(files)
+		added := false
+		for _, file := range files {
+			if relPath, ok := rootRelativePath(rootDir, file); ok {
+				embeds = append(embeds, DirectiveEmbed{Pattern: pattern, Path: relPath})
+				added = true
+			}
+		}
+		if !added {
+			embeds = append(embeds, DirectiveEmbed{Pattern: pattern})
+		}
+	}
+	return embeds
+}
+
+// splitDirectiveArgs splits space-separated arguments, unquoting Go string
+// literals so patterns may contain spaces.
+func splitDirectiveArgs(args string) []string {
+	var result []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if args[0] == '"' || args[0] == '`' {
+			if quoted, err := strconv.QuotedPrefix(args); err == nil {
+				if value, err := strconv.Unquote(quoted); err == nil {
+					result = append(result, value)
+				}
+				args = args[len(quoted):]
+				continue
+			}
+		}
+		field, rest, _ := strings.Cut(args, " ")
+		result = append(result, field)
+		args = rest
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestDirectives(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{filepath.Join(repoPath, "templates"), filepath.Join(repoPath, "internal")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "templates", "a.tmpl"), "a\n")
+	writeFile(t, filepath.Join(repoPath, "templates", "b.tmpl"), "b\n")
+	writeFile(t, filepath.Join(repoPath, "templates", "_skip.tmpl"), "skip\n")
+	writeFile(t, filepath.Join(repoPath, "internal", "clock.go"), "package internal\n\nfunc now() int64 { return 0 }\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `//go:build linux
+
+package app
+
+import (
+	"embed"
+	_ "unsafe"
+)
+
+//go:generate stringer -type=Mode
+type Mode int
+
+// Templates holds the page templates.
+//
+//go:embed templates
+var Templates embed.FS
+
+//go:embed "missing.txt"
+var missing string
+
+//go:linkname clockNow example.com/app/internal.now
+func clockNow() int64
+
+//go:linkname clockGone example.com/app/internal.gone
+func clockGone() int64
+
+// go:noinline is not a directive because of the space.
+func Run() {
+	_ = missing //nolint:staticcheck // kept for compatibility
+}
+`)
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestDirectives(ctx, IngestDirectivesConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest directives: %v", err)
+	}
+	if result.Files != 2 || result.Directives != 7 || result.EmbedFiles != 2 || result.Broken != 2 {
+		t.Fatalf("unexpected directive inventory: %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	directives, err := store.ListDirectives(ctx, DirectiveFilter{RunID: result.RunID})
+	if err != nil {
+		t.Fatalf("list directives: %v", err)
+	}
+	var names []string
+	for _, directive := range directives {
+		names = append(names, directive.Name)
+	}
+	if strings.Join(names, " ") != "go:build go:generate go:embed go:embed go:linkname go:linkname nolint" {
+		t.Fatalf("unexpected directives: %v", names)
+	}
+	if build := directives[0]; build.Args != "linux" || build.Line != 1 || build.Decl != "" {
+		t.Fatalf("unexpected build directive: %+v", build)
+	}
+	if generate := directives[1]; generate.Args != "stringer -type=Mode" || generate.Decl != "Mode" || generate.DeclKind != "type" {
+		t.Fatalf("unexpected generate directive: %+v", generate)
+	}
+	embed := directives[2]
+	if embed.Decl != "Templates" || embed.DeclKind != "var" || embed.Problem != "" || len(embed.Embeds) != 2 ||
+		embed.Embeds[0].Path != "templates/a.tmpl" || embed.Embeds[1].Path != "templates/b.tmpl" {
+		t.Fatalf("unexpected embed directive: %+v", embed)
+	}
+	if nolint := directives[6]; nolint.Args != "staticcheck // kept for compatibility" || nolint.Decl != "Run" || nolint.DeclKind != "func" {
+		t.Fatalf("unexpected nolint directive: %+v", nolint)
+	}
+
+	broken, err := store.ListDirectives(ctx, DirectiveFilter{RunID: result.RunID, Broken: true})
+	if err != nil {
+		t.Fatalf("list broken directives: %v", err)
+	}
+	if len(broken) != 2 ||
+		broken[0].Problem != "pattern matches no files: missing.txt" || len(broken[0].Embeds) != 1 || broken[0].Embeds[0].Path != "" ||
+		broken[1].Problem != "target not declared: example.com/app/internal.gone" || broken[1].Decl != "clockGone" {
+		t.Fatalf("unexpected broken directives: %+v", broken)
+	}
+}
+
+func TestBrokenDirectivesReport(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "directives\n")
+	writeFile(t, filepath.Join(repoPath, "CODEOWNERS"), "*.go @org/app\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	readmeRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "schema.sql"), "CREATE TABLE t (id INTEGER);\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import _ "embed"
+
+//go:embed schema.sql
+var schema string
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "embed schema")
+
+	git(t, repoPath, "rm", "-q", "schema.sql")
+	git(t, repoPath, "commit", "-m", "drop schema file")
+	headRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:            dbPath,
+		RepoPath:          repoPath,
+		FromRef:           readmeRef,
+		ToRef:             headRef,
+		SourcesDir:        filepath.Join(root, "sources"),
+		IncludeDirectives: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(rangeResult.Commits) != 2 || rangeResult.Commits[1].DirectivesRunID == 0 {
+		t.Fatalf("expected directives runs for both commits, got %+v", rangeResult.Commits)
+	}
+
+	reportDir := filepath.Join(root, "reports")
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: rangeResult.CommitLineageRunID, OutputDir: reportDir}); err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(reportDir, "broken-directives.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if strings.Contains(string(report), "| embed schema |") ||
+		!strings.Contains(string(report), "| drop schema file | app.go | 5 | go:embed | schema.sql | pattern matches no files: schema.sql |") {
+		t.Fatalf("unexpected broken directives report:\n%s", report)
+	}
+
+	if _, err := IngestOwners(ctx, IngestOwnersConfig{DBPath: dbPath, RepoPath: repoPath, SourcesDir: filepath.Join(root, "sources")}); err != nil {
+		t.Fatalf("ingest owners: %v", err)
+	}
+	for owner, rows := range map[string]int{"@org/app": 1, "@org/docs": 0} {
+		reports, err := GenerateReports(ctx, ReportConfig{
+			DBPath:    dbPath,
+			RunID:     rangeResult.CommitLineageRunID,
+			OutputDir: filepath.Join(root, "reports-owner"),
+			Owner:     owner,
+		})
+		if err != nil {
+			t.Fatalf("generate reports for %s: %v", owner, err)
+		}
+		for _, report := range reports {
+			if report.Name != "broken-directives" {
+				continue
+			}
+			content, err := os.ReadFile(report.Path)
+			if err != nil {
+				t.Fatalf("read report: %v", err)
+			}
+			if report.RowCount != rows || !strings.Contains(string(content), "Owner: "+owner) {
+				t.Fatalf("expected %d broken-directives rows for %s, got %d:\n%s", rows, owner, report.RowCount, content)
+			}
+		}
+	}
+}
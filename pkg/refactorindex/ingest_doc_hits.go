@@ -19,14 +19,19 @@ type IngestDocHitsConfig struct {
 	TermsFile  string
 	CommitID   *int64
 	SourcesDir string
+	// FileScope decides whether hits in generated and vendored files are
+	// recorded; they are skipped by default.
+	FileScope
 }
 
 type IngestDocHitsResult struct {
-	RunID     int64
-	Terms     int
-	Hits      int
-	Files     int
-	Skipped   int
+	RunID   int64
+	Terms   int
+	Hits    int
+	Files   int
+	Skipped int
+	// Excluded counts files whose hits were left out by the FileScope.
+	Excluded  int
 	CommitID  *int64
 	TermsFile string
 }
@@ -70,8 +75,10 @@ func IngestDocHits(ctx context.Context, cfg IngestDocHitsConfig) (*IngestDocHits
 	}
 
 	argsJSON, err := EncodeArgsJSON(map[string]string{
-		"root":      rootDir,
-		"termsFile": termsPath,
+		"root":              rootDir,
+		"termsFile":         termsPath,
+		"include_generated": strconv.FormatBool(cfg.IncludeGenerated),
+		"include_vendored":  strconv.FormatBool(cfg.IncludeVendored),
 	})
 	if err != nil {
 		return nil, err
@@ -106,6 +113,7 @@ func IngestDocHits(ctx context.Context, cfg IngestDocHitsConfig) (*IngestDocHits
 	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID), "doc-hits")
 
 	fileIDs := make(map[string]int64)
+	excluded := make(map[string]struct{})
 	hitCount := 0
 	skipCount := 0
 
@@ -140,12 +148,26 @@ func IngestDocHits(ctx context.Context, cfg IngestDocHitsConfig) (*IngestDocHits
 					relPath = filepath.ToSlash(rel)
 				}
 			}
+			if _, ok := excluded[relPath]; ok {
+				continue
+			}
 			fileID, ok := fileIDs[relPath]
 			if !ok {
+				class, err := ClassifyFile(rootDir, relPath)
+				if err != nil {
+					return nil, err
+				}
+				if class.Excluded(cfg.FileScope) {
+					excluded[relPath] = struct{}{}
+					continue
+				}
 				id, err := store.GetOrCreateFile(ctx, tx, relPath)
 				if err != nil {
 					return nil, err
 				}
+				if err := store.SetFileClass(ctx, tx, id, class); err != nil {
+					return nil, err
+				}
 				fileID = id
 				fileIDs[relPath] = id
 			}
@@ -169,6 +191,7 @@ func IngestDocHits(ctx context.Context, cfg IngestDocHitsConfig) (*IngestDocHits
 		Hits:      hitCount,
 		Files:     len(fileIDs),
 		Skipped:   skipCount,
+		Excluded:  len(excluded),
 		CommitID:  cfg.CommitID,
 		TermsFile: termsPath,
 	}, nil
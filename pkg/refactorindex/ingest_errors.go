@@ -0,0 +1,399 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	ErrorSiteNew       = "new"
+	ErrorSiteWrap      = "wrap"
+	ErrorSiteErrorf    = "errorf"
+	ErrorSiteSentinel  = "sentinel"
+	ErrorSiteDiscarded = "discarded"
+	ErrorSiteUnchecked = "unchecked"
+)
+
+// IngestErrorsConfig controls error-handling inventory ingestion.
+type IngestErrorsConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	Load       GoLoadOptions
+	Tolerant   bool
+}
+
+// IngestErrorsResult reports counts for error-handling ingestion.
+type IngestErrorsResult struct {
+	RunID      int64
+	Packages   int
+	Sites      int
+	Sentinels  int
+	Ignored    int
+	LoadErrors int
+	Partial    bool
+}
+
+// ErrorSite is one error-creating or wrapping call, package-level sentinel
+// error, or call whose error result is discarded (assigned to _) or
+// unchecked (called as a statement or by defer or go). Message is the
+// constant message or format when known; Name is the sentinel's variable
+// name.
+type ErrorSite struct {
+	Kind       string
+	Callee     string
+	Name       string
+	Message    string
+	HasMessage bool
+	Wraps      bool
+	Path       string
+	Line       int
+	Col        int
+	CodeUnit   *CodeUnitDef
+}
+
+// errorCreator describes a function that creates or wraps an error.
+// MessageArg is the index of its message or format argument, or -1.
+type errorCreator struct {
+	Kind       string
+	MessageArg int
+	Wraps      bool
+}
+
+var errorCreators = map[string]errorCreator{
+	"errors.New":                         {Kind: ErrorSiteNew, MessageArg: 0},
+	"fmt.Errorf":                         {Kind: ErrorSiteErrorf, MessageArg: 0},
+	"github.com/pkg/errors.New":          {Kind: ErrorSiteNew, MessageArg: 0},
+	"github.com/pkg/errors.Errorf":       {Kind: ErrorSiteNew, MessageArg: 0},
+	"github.com/pkg/errors.Wrap":         {Kind: ErrorSiteWrap, MessageArg: 1, Wraps: true},
+	"github.com/pkg/errors.Wrapf":        {Kind: ErrorSiteWrap, MessageArg: 1, Wraps: true},
+	"github.com/pkg/errors.WithMessage":  {Kind: ErrorSiteWrap, MessageArg: 1, Wraps: true},
+	"github.com/pkg/errors.WithMessagef": {Kind: ErrorSiteWrap, MessageArg: 1, Wraps: true},
+	"github.com/pkg/errors.WithStack":    {Kind: ErrorSiteWrap, MessageArg: -1, Wraps: true},
+}
+
+// uncheckedExcludes are calls whose error result is conventionally ignored,
+// as in errcheck's defaults: printing to stdout and writes to in-memory
+// buffers, which never fail.
+var uncheckedExcludes = map[string]struct{}{
+	"fmt.Print":                   {},
+	"fmt.Printf":                  {},
+	"fmt.Println":                 {},
+	"bytes.Buffer.Write":          {},
+	"bytes.Buffer.WriteByte":      {},
+	"bytes.Buffer.WriteRune":      {},
+	"bytes.Buffer.WriteString":    {},
+	"strings.Builder.Write":       {},
+	"strings.Builder.WriteByte":   {},
+	"strings.Builder.WriteRune":   {},
+	"strings.Builder.WriteString": {},
+}
+
+func IngestErrors(ctx context.Context, cfg IngestErrorsConfig) (*IngestErrorsResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	args := map[string]string{
+		"root":     rootDir,
+		"tolerant": strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	argsJSON, err := EncodeArgsJSON(args)
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The error packages are dependencies, type-checked from source as in
+	// IngestExternalUses.
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo|packages.NeedFiles|packages.NeedCompiledGoFiles|packages.NeedImports|packages.NeedDeps)
+	pkgs, loadErrors, err := loadGoPackages(pkgConfig, cfg.Load, rootDir, cfg.Tolerant)
+	if err != nil {
+		return nil, err
+	}
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
+
+	sites := collectErrorSites(rootDir, pkgs, pkgPaths)
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+
+	result := &IngestErrorsResult{
+		RunID:      runID,
+		Packages:   countPackagePaths(pkgPaths),
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	fileIDs := make(map[string]int64)
+	for _, site := range sites {
+		fileID, ok := fileIDs[site.Path]
+		if !ok {
+			id, err := store.GetOrCreateFile(ctx, tx, site.Path)
+			if err != nil {
+				return nil, err
+			}
+			fileID = id
+			fileIDs[site.Path] = id
+		}
+		var codeUnitID *int64
+		if site.CodeUnit != nil {
+			id, err := store.GetOrCreateCodeUnit(ctx, tx, *site.CodeUnit)
+			if err != nil {
+				return nil, err
+			}
+			codeUnitID = &id
+		}
+		if err := store.InsertErrorSite(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, site); err != nil {
+			return nil, err
+		}
+		switch site.Kind {
+		case ErrorSiteSentinel:
+			result.Sentinels++
+		case ErrorSiteDiscarded, ErrorSiteUnchecked:
+			result.Ignored++
+		default:
+			result.Sites++
+		}
+	}
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit error site ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// collectErrorSites walks the loaded packages in source order and returns
+// their error-creating calls, sentinels and ignored error results.
+func collectErrorSites(rootDir string, pkgs []*packages.Package, pkgPaths map[*packages.Package]string) []ErrorSite {
+	var sites []ErrorSite
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
+			continue
+		}
+		info := pkg.TypesInfo
+		newSite := func(kind string, node ast.Node) (ErrorSite, bool) {
+			pos := pkg.Fset.PositionFor(node.Pos(), false)
+			path, ok := rootRelativePath(rootDir, pos.Filename)
+			if !ok {
+				return ErrorSite{}, false
+			}
+			return ErrorSite{
+				Kind:     kind,
+				Path:     path,
+				Line:     pos.Line,
+				Col:      pos.Column,
+				CodeUnit: enclosingCodeUnit(pkg, pkgPaths[pkg], node.Pos()),
+			}, true
+		}
+		callee := func(call *ast.CallExpr) string {
+			if fn := calledFunc(info, call); fn != nil {
+				return fn.Origin().FullName()
+			}
+			return types.ExprString(call.Fun)
+		}
+		ignored := func(kind string, call *ast.CallExpr) {
+			name := callee(call)
+			if _, ok := uncheckedExcludes[callTargetKey(name)]; ok {
+				return
+			}
+			if site, ok := newSite(kind, call); ok {
+				site.Callee = name
+				sites = append(sites, site)
+			}
+		}
+
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					valueSpec := spec.(*ast.ValueSpec)
+					for i, name := range valueSpec.Names {
+						obj := info.Defs[name]
+						if obj == nil || !isErrorType(obj.Type()) {
+							continue
+						}
+						site, ok := newSite(ErrorSiteSentinel, name)
+						if !ok {
+							continue
+						}
+						site.Name = name.Name
+						if i < len(valueSpec.Values) {
+							if call, ok := ast.Unparen(valueSpec.Values[i]).(*ast.CallExpr); ok {
+								site.Callee = callee(call)
+								if creator, ok := errorCreators[site.Callee]; ok {
+									site.Message, site.HasMessage = errorMessage(info, call, creator)
+								}
+							}
+						}
+						sites = append(sites, site)
+					}
+				}
+			}
+
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.CallExpr:
+					name := callee(n)
+					creator, ok := errorCreators[name]
+					if !ok {
+						return true
+					}
+					site, ok := newSite(creator.Kind, n)
+					if !ok {
+						return true
+					}
+					site.Callee = name
+					site.Message, site.HasMessage = errorMessage(info, n, creator)
+					site.Wraps = creator.Wraps
+					if creator.Kind == ErrorSiteErrorf && site.HasMessage {
+						site.Wraps = strings.Contains(site.Message, "%w")
+					}
+					sites = append(sites, site)
+				case *ast.ExprStmt:
+					call, ok := ast.Unparen(n.X).(*ast.CallExpr)
+					if ok && returnsError(info, call, -1) {
+						ignored(ErrorSiteUnchecked, call)
+					}
+				case *ast.DeferStmt:
+					if returnsError(info, n.Call, -1) {
+						ignored(ErrorSiteUnchecked, n.Call)
+					}
+				case *ast.GoStmt:
+					if returnsError(info, n.Call, -1) {
+						ignored(ErrorSiteUnchecked, n.Call)
+					}
+				case *ast.AssignStmt:
+					if len(n.Rhs) == 1 && len(n.Lhs) > 1 {
+						call, ok := ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+						if !ok {
+							return true
+						}
+						for i, lhs := range n.Lhs {
+							if isBlank(lhs) && returnsError(info, call, i) {
+								ignored(ErrorSiteDiscarded, call)
+							}
+						}
+						return true
+					}
+					for i, lhs := range n.Lhs {
+						if i >= len(n.Rhs) || !isBlank(lhs) {
+							continue
+						}
+						if call, ok := ast.Unparen(n.Rhs[i]).(*ast.CallExpr); ok && returnsError(info, call, 0) {
+							ignored(ErrorSiteDiscarded, call)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return sites
+}
+
+// errorMessage returns the constant message or format argument of an
+// error-creating call.
+func errorMessage(info *types.Info, call *ast.CallExpr, creator errorCreator) (string, bool) {
+	if creator.MessageArg < 0 || creator.MessageArg >= len(call.Args) {
+		return "", false
+	}
+	tv, ok := info.Types[call.Args[creator.MessageArg]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// returnsError reports whether result index of the call has type error;
+// index -1 checks every result.
+func returnsError(info *types.Info, call *ast.CallExpr, index int) bool {
+	tv, ok := info.Types[call]
+	if !ok || tv.Type == nil || tv.IsType() || tv.IsBuiltin() {
+		return false
+	}
+	if tuple, ok := tv.Type.(*types.Tuple); ok {
+		for i := 0; i < tuple.Len(); i++ {
+			if (index < 0 || index == i) && isErrorType(tuple.At(i).Type()) {
+				return true
+			}
+		}
+		return false
+	}
+	return index <= 0 && isErrorType(tv.Type)
+}
+
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isBlank(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
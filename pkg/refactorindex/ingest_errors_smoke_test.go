@@ -0,0 +1,239 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestErrors(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	// A stand-in for github.com/pkg/errors so the fixture loads offline.
+	pkgErrorsPath := filepath.Join(root, "pkgerrors")
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{pkgErrorsPath, repoPath} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(pkgErrorsPath, "go.mod"), "module github.com/pkg/errors\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(pkgErrorsPath, "errors.go"), `package errors
+
+import "fmt"
+
+func New(message string) error { return fmt.Errorf("%s", message) }
+
+func Wrap(err error, message string) error { return fmt.Errorf("%s: %w", message, err) }
+`)
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n\nrequire github.com/pkg/errors v0.9.1\n\nreplace github.com/pkg/errors => ../pkgerrors\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNotFound = stderrors.New("not found")
+
+var errInternal = fmt.Errorf("internal")
+
+func Open(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	_ = f.Close()
+	var b strings.Builder
+	b.WriteString(name)
+	fmt.Println(b.String())
+	os.Remove(name)
+	_, _ = f.Stat()
+	defer os.Remove(name + ".lock")
+	go os.Chdir(name)
+	if name == "" {
+		return errors.New("open file")
+	}
+	return fmt.Errorf("open %s: %w", name, ErrNotFound)
+}
+`)
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestErrors(ctx, IngestErrorsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest errors: %v", err)
+	}
+	// Sites: the two sentinel initializers, Wrap, New and Errorf.
+	if result.Sites != 5 || result.Sentinels != 2 || result.Ignored != 5 {
+		t.Fatalf("unexpected error inventory: %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	sentinels, err := store.ListErrorSites(ctx, ErrorSiteFilter{RunID: result.RunID, Kind: ErrorSiteSentinel})
+	if err != nil {
+		t.Fatalf("list sentinels: %v", err)
+	}
+	if len(sentinels) != 2 || sentinels[0].Name != "ErrNotFound" || sentinels[0].Message != "not found" ||
+		sentinels[0].Callee != "errors.New" || sentinels[1].Name != "errInternal" {
+		t.Fatalf("unexpected sentinels: %+v", sentinels)
+	}
+
+	wraps, err := store.ListErrorSites(ctx, ErrorSiteFilter{RunID: result.RunID, Callee: "github.com/pkg/errors.Wrap"})
+	if err != nil {
+		t.Fatalf("list wraps: %v", err)
+	}
+	if len(wraps) != 1 || wraps[0].Kind != ErrorSiteWrap || wraps[0].Message != "open file" || !wraps[0].Wraps ||
+		wraps[0].CodeUnit != "Open" || wraps[0].Line != 19 {
+		t.Fatalf("unexpected wrap sites: %+v", wraps)
+	}
+
+	errorfs, err := store.ListErrorSites(ctx, ErrorSiteFilter{RunID: result.RunID, Kind: ErrorSiteErrorf})
+	if err != nil {
+		t.Fatalf("list errorf sites: %v", err)
+	}
+	if len(errorfs) != 2 || errorfs[0].Wraps || !errorfs[1].Wraps || errorfs[1].Message != "open %s: %w" {
+		t.Fatalf("unexpected errorf sites: %+v", errorfs)
+	}
+
+	discarded, err := store.ListErrorSites(ctx, ErrorSiteFilter{RunID: result.RunID, Kind: ErrorSiteDiscarded})
+	if err != nil {
+		t.Fatalf("list discarded: %v", err)
+	}
+	if len(discarded) != 2 || discarded[0].Callee != "(*os.File).Close" || discarded[1].Callee != "(*os.File).Stat" {
+		t.Fatalf("unexpected discarded errors: %+v", discarded)
+	}
+	unchecked, err := store.ListErrorSites(ctx, ErrorSiteFilter{RunID: result.RunID, Kind: ErrorSiteUnchecked})
+	if err != nil {
+		t.Fatalf("list unchecked: %v", err)
+	}
+	if len(unchecked) != 3 || unchecked[0].Callee != "os.Remove" || unchecked[0].Line != 25 ||
+		unchecked[1].Callee != "os.Remove" || unchecked[1].Line != 27 || unchecked[2].Callee != "os.Chdir" || unchecked[2].Line != 28 {
+		t.Fatalf("unexpected unchecked errors: %+v", unchecked)
+	}
+
+	messages, err := store.ListErrorMessages(ctx, ErrorMessageFilter{RunID: result.RunID, MinCount: 2})
+	if err != nil {
+		t.Fatalf("list error messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Message != "open file" || messages[0].Sites != 2 || messages[0].CodeUnits != 1 {
+		t.Fatalf("unexpected duplicated messages: %+v", messages)
+	}
+}
+
+func TestErrorMigrationReport(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "errors\n")
+	writeFile(t, filepath.Join(repoPath, "CODEOWNERS"), "*.go @org/app\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	readmeRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import "fmt"
+
+func Check(err error) error {
+	return fmt.Errorf("check: %v", err)
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "add check")
+
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import "fmt"
+
+func Check(err error) error {
+	return fmt.Errorf("check: %w", err)
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "wrap with w")
+	headRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:        dbPath,
+		RepoPath:      repoPath,
+		FromRef:       readmeRef,
+		ToRef:         headRef,
+		SourcesDir:    filepath.Join(root, "sources"),
+		IncludeErrors: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(rangeResult.Commits) != 2 || rangeResult.Commits[1].ErrorsRunID == 0 {
+		t.Fatalf("expected errors runs for both commits, got %+v", rangeResult.Commits)
+	}
+
+	reportDir := filepath.Join(root, "reports")
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: rangeResult.CommitLineageRunID, OutputDir: reportDir}); err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(reportDir, "error-migration.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	for _, row := range []string{
+		"| add check | 0 | 0 | 1 | 0 | 0 | 0 | 0 |",
+		"| wrap with w | 0 | 1 | 0 | 0 | 0 | 0 | 0 |",
+	} {
+		if !strings.Contains(string(report), row) {
+			t.Fatalf("expected report row %q, got:\n%s", row, report)
+		}
+	}
+
+	if _, err := IngestOwners(ctx, IngestOwnersConfig{DBPath: dbPath, RepoPath: repoPath, SourcesDir: filepath.Join(root, "sources")}); err != nil {
+		t.Fatalf("ingest owners: %v", err)
+	}
+	for owner, rows := range map[string]int{"@org/app": 2, "@org/docs": 0} {
+		reports, err := GenerateReports(ctx, ReportConfig{
+			DBPath:    dbPath,
+			RunID:     rangeResult.CommitLineageRunID,
+			OutputDir: filepath.Join(root, "reports-owner"),
+			Owner:     owner,
+		})
+		if err != nil {
+			t.Fatalf("generate reports for %s: %v", owner, err)
+		}
+		for _, report := range reports {
+			if report.Name != "error-migration" {
+				continue
+			}
+			content, err := os.ReadFile(report.Path)
+			if err != nil {
+				t.Fatalf("read report: %v", err)
+			}
+			if report.RowCount != rows || !strings.Contains(string(content), "Owner: "+owner) {
+				t.Fatalf("expected %d error-migration rows for %s, got %d:\n%s", rows, owner, report.RowCount, content)
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package refactorindex
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// IngestExternalUsesConfig controls external API usage ingestion.
+type IngestExternalUsesConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	Load       GoLoadOptions
+	// IncludeStd also records uses of the standard library.
+	IncludeStd bool
+	// Tolerant ingests the packages that loaded cleanly and records the
+	// errors of the others in load_errors instead of failing the run.
+	Tolerant bool
+}
+
+// IngestExternalUsesResult reports counts for external API usage ingestion.
+type IngestExternalUsesResult struct {
+	RunID        int64
+	Packages     int
+	Uses         int
+	ExternalPkgs int
+	LoadErrors   int
+	Partial      bool
+}
+
+// ExternalUse is one use of an identifier declared in a package outside the
+// loaded main modules. Recv is the declaring type of methods and fields.
+type ExternalUse struct {
+	Pkg      string
+	Module   string
+	Name     string
+	Recv     string
+	Kind     string
+	Path     string
+	Line     int
+	Col      int
+	CodeUnit *CodeUnitDef
+}
+
+func IngestExternalUses(ctx context.Context, cfg IngestExternalUsesConfig) (*IngestExternalUsesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	args := map[string]string{
+		"root":        rootDir,
+		"include_std": strconv.FormatBool(cfg.IncludeStd),
+		"tolerant":    strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	argsJSON, err := EncodeArgsJSON(args)
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Dependencies are type-checked from source: every use resolves into
+	// one, and their export data is unreadable when the go toolchain is
+	// newer than the golang.org/x/tools it was written for.
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo|packages.NeedFiles|packages.NeedCompiledGoFiles|packages.NeedImports|packages.NeedDeps)
+	pkgs, loadErrors, err := loadGoPackages(pkgConfig, cfg.Load, rootDir, cfg.Tolerant)
+	if err != nil {
+		return nil, err
+	}
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
+
+	uses := collectExternalUses(rootDir, pkgs, pkgPaths, cfg.IncludeStd)
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+
+	result := &IngestExternalUsesResult{
+		RunID:      runID,
+		Packages:   countPackagePaths(pkgPaths),
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	fileIDs := make(map[string]int64)
+	externalPkgs := make(map[string]struct{})
+	for _, use := range uses {
+		fileID, ok := fileIDs[use.Path]
+		if !ok {
+			id, err := store.GetOrCreateFile(ctx, tx, use.Path)
+			if err != nil {
+				return nil, err
+			}
+			fileID = id
+			fileIDs[use.Path] = id
+		}
+		var codeUnitID *int64
+		if use.CodeUnit != nil {
+			id, err := store.GetOrCreateCodeUnit(ctx, tx, *use.CodeUnit)
+			if err != nil {
+				return nil, err
+			}
+			codeUnitID = &id
+		}
+		if err := store.InsertExternalUse(ctx, tx, runID, cfg.CommitID, fileID, codeUnitID, use); err != nil {
+			return nil, err
+		}
+		externalPkgs[use.Pkg] = struct{}{}
+		result.Uses++
+	}
+	result.ExternalPkgs = len(externalPkgs)
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit external use ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// collectExternalUses walks the syntax of the loaded packages in source
+// order and returns the identifiers that resolve to objects declared
+// outside the main modules. Modules are attributed from the requirements of
+// the go.mod the using package belongs to.
+func collectExternalUses(rootDir string, pkgs []*packages.Package, pkgPaths map[*packages.Package]string, includeStd bool) []ExternalUse {
+	var mainModules []string
+	for _, pkg := range pkgs {
+		if pkg.Module != nil {
+			mainModules = append(mainModules, pkg.Module.Path)
+		}
+	}
+	requires := make(map[string][]string)
+	moduleRequires := func(pkg *packages.Package) []string {
+		if pkg.Module == nil || pkg.Module.GoMod == "" {
+			return nil
+		}
+		paths, ok := requires[pkg.Module.GoMod]
+		if !ok {
+			if modFile, err := ParseModFile(pkg.Module.GoMod); err == nil {
+				for _, require := range modFile.Requires {
+					paths = append(paths, require.Path)
+				}
+			}
+			requires[pkg.Module.GoMod] = paths
+		}
+		return paths
+	}
+
+	var uses []ExternalUse
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
+			continue
+		}
+		required := moduleRequires(pkg)
+		for _, file := range pkg.Syntax {
+			selections := make(map[*ast.Ident]*types.Selection)
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.SelectorExpr:
+					if selection, ok := pkg.TypesInfo.Selections[n]; ok {
+						selections[n.Sel] = selection
+					}
+				case *ast.Ident:
+					obj := pkg.TypesInfo.Uses[n]
+					if obj == nil || obj.Pkg() == nil {
+						return true
+					}
+					switch obj.(type) {
+					case *types.PkgName, *types.Label, *types.Builtin, *types.Nil:
+						return true
+					}
+					objPkg := obj.Pkg().Path()
+					if objPkg == "unsafe" || objPkg == "C" {
+						return true
+					}
+					module := longestModuleMatch(objPkg, required)
+					if main := longestModuleMatch(objPkg, mainModules); main != "" && len(main) >= len(module) {
+						return true
+					}
+					if module == "" && isStdPackage(objPkg) && !includeStd {
+						return true
+					}
+					pos := pkg.Fset.PositionFor(n.Pos(), false)
+					path, ok := rootRelativePath(rootDir, pos.Filename)
+					if !ok {
+						return true
+					}
+					use := ExternalUse{
+						Pkg:      objPkg,
+						Module:   module,
+						Name:     obj.Name(),
+						Kind:     externalUseKind(obj),
+						Path:     path,
+						Line:     pos.Line,
+						Col:      pos.Column,
+						CodeUnit: enclosingCodeUnit(pkg, pkgPaths[pkg], n.Pos()),
+					}
+					use.Recv = externalUseRecv(obj, selections[n])
+					uses = append(uses, use)
+				}
+				return true
+			})
+		}
+	}
+	return uses
+}
+
+func externalUseKind(obj types.Object) string {
+	if v, ok := obj.(*types.Var); ok && v.IsField() {
+		return "field"
+	}
+	return symbolKind(obj)
+}
+
+// externalUseRecv names the type a method or field belongs to, relative to
+// the declaring package: the method's receiver, or for fields the type the
+// field was selected from.
+func externalUseRecv(obj types.Object, selection *types.Selection) string {
+	qualifier := types.RelativeTo(obj.Pkg())
+	if fn, ok := obj.(*types.Func); ok {
+		return receiverString(fn.Origin(), qualifier)
+	}
+	if v, ok := obj.(*types.Var); ok && v.IsField() && selection != nil {
+		recv := selection.Recv()
+		if ptr, ok := recv.(*types.Pointer); ok {
+			recv = ptr.Elem()
+		}
+		if named, ok := recv.(*types.Named); ok {
+			return types.TypeString(named.Origin(), qualifier)
+		}
+	}
+	return ""
+}
+
+// longestModuleMatch returns the longest module path that contains the
+// package path, or "".
+func longestModuleMatch(pkgPath string, modules []string) string {
+	best := ""
+	for _, module := range modules {
+		if (pkgPath == module || strings.HasPrefix(pkgPath, module+"/")) && len(module) > len(best) {
+			best = module
+		}
+	}
+	return best
+}
+
+// isStdPackage reports whether the import path belongs to the standard
+// library, whose first path element has no dot.
+func isStdPackage(pkgPath string) bool {
+	first, _, _ := strings.Cut(pkgPath, "/")
+	return !strings.Contains(first, ".")
+}
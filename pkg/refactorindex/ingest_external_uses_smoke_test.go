@@ -0,0 +1,111 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestExternalUses(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	libPath := filepath.Join(root, "lib")
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{libPath, filepath.Join(repoPath, "internal")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(libPath, "go.mod"), "module example.com/lib\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(libPath, "lib.go"), `package lib
+
+const Version = "1"
+
+type Client struct {
+	Name string
+}
+
+func New() *Client { return &Client{} }
+
+func (c *Client) Do() error { return nil }
+`)
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n\nrequire example.com/lib v0.0.0\n\nreplace example.com/lib => ../lib\n")
+	writeFile(t, filepath.Join(repoPath, "internal", "helper.go"), "package internal\n\nfunc Help() {}\n")
+	writeFile(t, filepath.Join(repoPath, "app.go"), `package app
+
+import (
+	"fmt"
+
+	"example.com/app/internal"
+	"example.com/lib"
+)
+
+func Run() error {
+	var c *lib.Client = lib.New()
+	c.Name = lib.Version
+	fmt.Println(c.Name)
+	internal.Help()
+	return c.Do()
+}
+
+func Other() *lib.Client { return lib.New() }
+`)
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestExternalUses(ctx, IngestExternalUsesConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest external uses: %v", err)
+	}
+	if result.Uses != 8 || result.ExternalPkgs != 1 {
+		t.Fatalf("expected 8 uses of one external package, got %+v", result)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	uses, err := store.ListExternalUses(ctx, ExternalUseFilter{RunID: result.RunID, Name: "Do"})
+	if err != nil {
+		t.Fatalf("list external uses: %v", err)
+	}
+	if len(uses) != 1 || uses[0].Kind != "method" || uses[0].Recv != "*Client" || uses[0].CodeUnit != "Run" ||
+		uses[0].Module != "example.com/lib" || uses[0].Path != "app.go" || uses[0].Line != 15 {
+		t.Fatalf("unexpected Do use: %+v", uses)
+	}
+
+	usage, err := store.ListExternalUsage(ctx, ExternalUsageFilter{RunIDs: []int64{result.RunID}})
+	if err != nil {
+		t.Fatalf("list external usage: %v", err)
+	}
+	counts := make(map[string]ExternalUsageRecord)
+	for _, record := range usage {
+		counts[record.Recv+"."+record.Name] = record
+	}
+	if len(counts) != 5 || counts[".New"].Uses != 2 || counts[".New"].CodeUnits != 2 || counts[".Client"].Uses != 2 ||
+		counts["Client.Name"].Kind != "field" || counts["Client.Name"].Uses != 2 || counts[".Version"].Kind != "const" {
+		t.Fatalf("unexpected usage counts: %+v", usage)
+	}
+
+	byPackage, err := store.ListExternalUsage(ctx, ExternalUsageFilter{RunIDs: []int64{result.RunID}, ByPackage: true})
+	if err != nil {
+		t.Fatalf("list usage by package: %v", err)
+	}
+	if len(byPackage) != 1 || byPackage[0].Package != "example.com/lib" || byPackage[0].Uses != 8 || byPackage[0].Files != 1 {
+		t.Fatalf("unexpected usage by package: %+v", byPackage)
+	}
+
+	withStd, err := IngestExternalUses(ctx, IngestExternalUsesConfig{DBPath: dbPath, RootDir: repoPath, IncludeStd: true})
+	if err != nil {
+		t.Fatalf("ingest external uses with std: %v", err)
+	}
+	if withStd.Uses != 9 || withStd.ExternalPkgs != 2 {
+		t.Fatalf("expected fmt.Println to be recorded with IncludeStd, got %+v", withStd)
+	}
+}
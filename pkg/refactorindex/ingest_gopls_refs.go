@@ -156,6 +156,9 @@ func IngestGoplsReferences(ctx context.Context, cfg IngestGoplsRefsConfig) (*Ing
 		}
 	}
 
+	if err := classifyNewFiles(ctx, store, tx, cfg.RepoPath); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, errors.Wrap(err, "commit gopls references")
 	}
@@ -0,0 +1,324 @@
+package refactorindex
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	HunkChangeAdded    = "added"
+	HunkChangeRemoved  = "removed"
+	HunkChangeModified = "modified"
+)
+
+// IngestHunkCodeUnitsConfig links the hunks of a diff run to the code units
+// captured at both sides of the diff.
+type IngestHunkCodeUnitsConfig struct {
+	DBPath string
+	// DiffRunID is the run created by IngestDiff.
+	DiffRunID int64
+	// OldCodeUnitsRunID is the code-unit run for the diff's from ref.
+	OldCodeUnitsRunID int64
+	// NewCodeUnitsRunID is the code-unit run for the diff's to ref.
+	NewCodeUnitsRunID int64
+}
+
+// IngestHunkCodeUnitsResult reports hunk linking counts.
+type IngestHunkCodeUnitsResult struct {
+	RunID     int64
+	Hunks     int
+	Links     int
+	Added     int
+	Removed   int
+	Modified  int
+	CodeUnits int
+}
+
+type diffHunkSide struct {
+	ID       int64
+	FileID   *int64
+	OldPath  string
+	NewPath  string
+	OldLines []int
+	NewLines []int
+}
+
+type unitSnapshotSpan struct {
+	ID         int64
+	CodeUnitID int64
+	StartLine  int
+	EndLine    int
+}
+
+type codeUnitRunIndex struct {
+	byPath map[string][]unitSnapshotSpan
+	units  map[int64]struct{}
+}
+
+func IngestHunkCodeUnits(ctx context.Context, cfg IngestHunkCodeUnitsConfig) (*IngestHunkCodeUnitsResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if cfg.DiffRunID == 0 {
+		return nil, errors.New("diff run id is required")
+	}
+	if cfg.OldCodeUnitsRunID == 0 || cfg.NewCodeUnitsRunID == 0 {
+		return nil, errors.New("old and new code-unit run ids are required")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"diff_run_id":           strconv.FormatInt(cfg.DiffRunID, 10),
+		"old_code_units_run_id": strconv.FormatInt(cfg.OldCodeUnitsRunID, 10),
+		"new_code_units_run_id": strconv.FormatInt(cfg.NewCodeUnitsRunID, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	hunks, err := loadDiffHunkSides(ctx, tx, cfg.DiffRunID)
+	if err != nil {
+		return nil, err
+	}
+	oldIndex, err := loadCodeUnitRunIndex(ctx, tx, cfg.OldCodeUnitsRunID)
+	if err != nil {
+		return nil, err
+	}
+	newIndex, err := loadCodeUnitRunIndex(ctx, tx, cfg.NewCodeUnitsRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IngestHunkCodeUnitsResult{RunID: runID, Hunks: len(hunks)}
+	seenUnits := make(map[int64]struct{})
+	for _, hunk := range hunks {
+		for _, link := range linkHunkToCodeUnits(hunk, oldIndex, newIndex) {
+			if err := store.InsertHunkCodeUnit(ctx, tx, runID, cfg.DiffRunID, link); err != nil {
+				return nil, err
+			}
+			result.Links++
+			switch link.ChangeKind {
+			case HunkChangeAdded:
+				result.Added++
+			case HunkChangeRemoved:
+				result.Removed++
+			default:
+				result.Modified++
+			}
+			seenUnits[link.CodeUnitID] = struct{}{}
+		}
+	}
+	result.CodeUnits = len(seenUnits)
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit hunk code unit ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// linkHunkToCodeUnits finds the code units whose spans contain removed lines
+// (old side) or added lines (new side) of a hunk. A unit touched on only one
+// side is "added" or "removed" when it does not exist at all on the other
+// side, and "modified" otherwise.
+func linkHunkToCodeUnits(hunk *diffHunkSide, oldIndex codeUnitRunIndex, newIndex codeUnitRunIndex) []HunkCodeUnit {
+	links := make(map[int64]*HunkCodeUnit)
+	order := make([]int64, 0)
+	get := func(codeUnitID int64) *HunkCodeUnit {
+		link, ok := links[codeUnitID]
+		if !ok {
+			link = &HunkCodeUnit{HunkID: hunk.ID, FileID: hunk.FileID, CodeUnitID: codeUnitID}
+			links[codeUnitID] = link
+			order = append(order, codeUnitID)
+		}
+		return link
+	}
+
+	for _, span := range oldIndex.byPath[hunk.OldPath] {
+		count := countLinesInSpan(hunk.OldLines, span)
+		if count == 0 {
+			continue
+		}
+		link := get(span.CodeUnitID)
+		id := span.ID
+		link.OldSnapshotID = &id
+		link.RemovedLines += count
+	}
+	for _, span := range newIndex.byPath[hunk.NewPath] {
+		count := countLinesInSpan(hunk.NewLines, span)
+		if count == 0 {
+			continue
+		}
+		link := get(span.CodeUnitID)
+		id := span.ID
+		link.NewSnapshotID = &id
+		link.AddedLines += count
+	}
+
+	results := make([]HunkCodeUnit, 0, len(order))
+	for _, codeUnitID := range order {
+		link := links[codeUnitID]
+		_, inOld := oldIndex.units[codeUnitID]
+		_, inNew := newIndex.units[codeUnitID]
+		switch {
+		case link.OldSnapshotID == nil && !inOld:
+			link.ChangeKind = HunkChangeAdded
+		case link.NewSnapshotID == nil && !inNew:
+			link.ChangeKind = HunkChangeRemoved
+		default:
+			link.ChangeKind = HunkChangeModified
+		}
+		results = append(results, *link)
+	}
+	return results
+}
+
+func countLinesInSpan(lines []int, span unitSnapshotSpan) int {
+	count := 0
+	for _, line := range lines {
+		if line >= span.StartLine && line <= span.EndLine {
+			count++
+		}
+	}
+	return count
+}
+
+func loadDiffHunkSides(ctx context.Context, tx *sql.Tx, diffRunID int64) ([]*diffHunkSide, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT dh.id, df.file_id, df.status, COALESCE(df.old_path, ''), COALESCE(df.new_path, ''), COALESCE(f.path, '')
+		FROM diff_hunks dh
+		JOIN diff_files df ON df.id = dh.diff_file_id
+		LEFT JOIN files f ON f.id = df.file_id
+		WHERE df.run_id = ?
+		ORDER BY dh.id`, diffRunID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query diff hunks")
+	}
+	defer rows.Close()
+
+	var hunks []*diffHunkSide
+	hunksByID := make(map[int64]*diffHunkSide)
+	for rows.Next() {
+		hunk := &diffHunkSide{}
+		var fileID sql.NullInt64
+		var status, path string
+		if err := rows.Scan(&hunk.ID, &fileID, &status, &hunk.OldPath, &hunk.NewPath, &path); err != nil {
+			return nil, errors.Wrap(err, "scan diff hunk")
+		}
+		if fileID.Valid {
+			id := fileID.Int64
+			hunk.FileID = &id
+		}
+		if hunk.OldPath == "" && !strings.HasPrefix(status, "A") {
+			hunk.OldPath = path
+		}
+		if hunk.NewPath == "" && !strings.HasPrefix(status, "D") {
+			hunk.NewPath = path
+		}
+		hunks = append(hunks, hunk)
+		hunksByID[hunk.ID] = hunk
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate diff hunks")
+	}
+
+	lineRows, err := tx.QueryContext(ctx, `
+		SELECT dl.hunk_id, dl.kind, dl.line_no_old, dl.line_no_new
+		FROM diff_lines dl
+		JOIN diff_hunks dh ON dh.id = dl.hunk_id
+		JOIN diff_files df ON df.id = dh.diff_file_id
+		WHERE df.run_id = ? AND dl.kind IN ('+', '-')`, diffRunID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query diff lines")
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var hunkID int64
+		var kind string
+		var lineOld, lineNew sql.NullInt64
+		if err := lineRows.Scan(&hunkID, &kind, &lineOld, &lineNew); err != nil {
+			return nil, errors.Wrap(err, "scan diff line")
+		}
+		hunk, ok := hunksByID[hunkID]
+		if !ok {
+			continue
+		}
+		if kind == "-" && lineOld.Valid {
+			hunk.OldLines = append(hunk.OldLines, int(lineOld.Int64))
+		}
+		if kind == "+" && lineNew.Valid {
+			hunk.NewLines = append(hunk.NewLines, int(lineNew.Int64))
+		}
+	}
+	if err := lineRows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate diff lines")
+	}
+
+	return hunks, nil
+}
+
+func loadCodeUnitRunIndex(ctx context.Context, tx *sql.Tx, runID int64) (codeUnitRunIndex, error) {
+	index := codeUnitRunIndex{
+		byPath: make(map[string][]unitSnapshotSpan),
+		units:  make(map[int64]struct{}),
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.id, s.code_unit_id, f.path, s.start_line, s.end_line
+		FROM code_unit_snapshots s
+		JOIN files f ON f.id = s.file_id
+		WHERE s.run_id = ?`, runID)
+	if err != nil {
+		return index, errors.Wrap(err, "query code unit snapshots")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var span unitSnapshotSpan
+		var path string
+		if err := rows.Scan(&span.ID, &span.CodeUnitID, &path, &span.StartLine, &span.EndLine); err != nil {
+			return index, errors.Wrap(err, "scan code unit snapshot")
+		}
+		index.byPath[path] = append(index.byPath[path], span)
+		index.units[span.CodeUnitID] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return index, errors.Wrap(err, "iterate code unit snapshots")
+	}
+	return index, nil
+}
@@ -0,0 +1,134 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestHunkCodeUnits(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/test\n\ngo 1.25\n")
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+
+func Div(a, b int) int {
+	return a / b
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	oldUnits, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest old code units: %v", err)
+	}
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), `package calc
+
+func Add(a, b int) int {
+	sum := a + b
+	return sum
+}
+
+func Div(a, b int) int {
+	return a / b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	newUnits, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest new code units: %v", err)
+	}
+	diffResult, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+
+	result, err := IngestHunkCodeUnits(ctx, IngestHunkCodeUnitsConfig{
+		DBPath:            dbPath,
+		DiffRunID:         diffResult.RunID,
+		OldCodeUnitsRunID: oldUnits.RunID,
+		NewCodeUnitsRunID: newUnits.RunID,
+	})
+	if err != nil {
+		t.Fatalf("ingest hunk code units: %v", err)
+	}
+	if result.Links == 0 {
+		t.Fatalf("expected hunk code unit links")
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	records, err := store.ListChangedUnits(ctx, ChangedUnitFilter{DiffRunID: diffResult.RunID})
+	if err != nil {
+		t.Fatalf("list changed units: %v", err)
+	}
+	changes := make(map[string]string)
+	for _, record := range records {
+		changes[record.Name] = record.ChangeKind
+	}
+
+	expected := map[string]string{
+		"Add": HunkChangeModified,
+		"Mul": HunkChangeRemoved,
+		"Sub": HunkChangeAdded,
+	}
+	for name, change := range expected {
+		if changes[name] != change {
+			t.Fatalf("expected %s to be %s, got %q (all: %v)", name, change, changes[name], changes)
+		}
+	}
+	if _, ok := changes["Div"]; ok {
+		t.Fatalf("expected Div to be untouched, got %s", changes["Div"])
+	}
+
+	added, err := store.ListChangedUnits(ctx, ChangedUnitFilter{RunID: result.RunID, ChangeKind: HunkChangeAdded})
+	if err != nil {
+		t.Fatalf("list added units: %v", err)
+	}
+	if len(added) != 1 || added[0].Name != "Sub" || added[0].Path != "calc.go" {
+		t.Fatalf("expected only Sub in calc.go to be added, got %+v", added)
+	}
+}
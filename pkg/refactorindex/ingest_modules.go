@@ -0,0 +1,334 @@
+package refactorindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// IngestModulesConfig controls go.mod dependency ingestion.
+type IngestModulesConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+	// BuildList also records the `go list -m -json all` build list of each
+	// module. It runs offline (GOPROXY=off), so it only succeeds when the
+	// module cache already has everything; failures are recorded in
+	// load_errors and mark the run partial.
+	BuildList bool
+	// Tolerant records go.mod files that fail to parse in load_errors
+	// instead of failing the run.
+	Tolerant bool
+}
+
+// IngestModulesResult reports counts for module ingestion.
+type IngestModulesResult struct {
+	RunID      int64
+	ModFiles   int
+	Requires   int
+	Replaces   int
+	Excludes   int
+	Retracts   int
+	BuildList  int
+	LoadErrors int
+	Partial    bool
+}
+
+// ModFile is one parsed go.mod file.
+type ModFile struct {
+	Path       string
+	ModulePath string
+	GoVersion  string
+	Toolchain  string
+	Requires   []ModRequire
+	Replaces   []ModReplace
+	Excludes   []ModRequire
+	Retracts   []ModRetract
+}
+
+type ModRequire struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+type ModReplace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+type ModRetract struct {
+	Low       string
+	High      string
+	Rationale string
+}
+
+// ModBuildListEntry is one module of the `go list -m -json all` output.
+type ModBuildListEntry struct {
+	Path           string
+	Version        string
+	ReplacePath    string
+	ReplaceVersion string
+	Main           bool
+	Indirect       bool
+	GoVersion      string
+}
+
+func IngestModules(ctx context.Context, cfg IngestModulesConfig) (*IngestModulesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"root":       rootDir,
+		"build_list": strconv.FormatBool(cfg.BuildList),
+		"tolerant":   strconv.FormatBool(cfg.Tolerant),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  cfg.SourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := findGoModDirs(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var modFiles []ModFile
+	var buildLists [][]ModBuildListEntry
+	var loadErrors []LoadError
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "go.mod")
+		relPath, _ := rootRelativePath(rootDir, path)
+		modFile, err := ParseModFile(path)
+		if err != nil {
+			if !cfg.Tolerant {
+				return nil, err
+			}
+			loadErrors = append(loadErrors, LoadError{Path: relPath, Kind: LoadErrorParse, Message: errors.Cause(err).Error()})
+			continue
+		}
+		modFile.Path = relPath
+
+		var buildList []ModBuildListEntry
+		if cfg.BuildList {
+			buildList, err = goListModules(ctx, dir)
+			if err != nil {
+				loadErrors = append(loadErrors, LoadError{Package: modFile.ModulePath, Path: relPath, Kind: LoadErrorList, Message: err.Error()})
+			}
+		}
+		modFiles = append(modFiles, modFile)
+		buildLists = append(buildLists, buildList)
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+
+	result := &IngestModulesResult{
+		RunID:      runID,
+		ModFiles:   len(modFiles),
+		LoadErrors: len(loadErrors),
+		Partial:    len(loadErrors) > 0,
+	}
+	for i, modFile := range modFiles {
+		fileID, err := store.GetOrCreateFile(ctx, tx, modFile.Path)
+		if err != nil {
+			return nil, err
+		}
+		modFileID, err := store.InsertModFile(ctx, tx, runID, cfg.CommitID, fileID, modFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, require := range modFile.Requires {
+			if err := store.InsertModRequire(ctx, tx, modFileID, require); err != nil {
+				return nil, err
+			}
+			result.Requires++
+		}
+		for _, replace := range modFile.Replaces {
+			if err := store.InsertModReplace(ctx, tx, modFileID, replace); err != nil {
+				return nil, err
+			}
+			result.Replaces++
+		}
+		for _, exclude := range modFile.Excludes {
+			if err := store.InsertModExclude(ctx, tx, modFileID, exclude.Path, exclude.Version); err != nil {
+				return nil, err
+			}
+			result.Excludes++
+		}
+		for _, retract := range modFile.Retracts {
+			if err := store.InsertModRetract(ctx, tx, modFileID, retract); err != nil {
+				return nil, err
+			}
+			result.Retracts++
+		}
+		for _, entry := range buildLists[i] {
+			if err := store.InsertModBuildListEntry(ctx, tx, modFileID, entry); err != nil {
+				return nil, err
+			}
+			result.BuildList++
+		}
+	}
+
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit module ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if result.Partial {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// ParseModFile parses a go.mod file. Path is left as given.
+func ParseModFile(path string) (ModFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ModFile{}, errors.Wrap(err, "read go.mod")
+	}
+	file, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return ModFile{}, errors.Wrap(err, "parse go.mod")
+	}
+
+	modFile := ModFile{Path: path}
+	if file.Module != nil {
+		modFile.ModulePath = file.Module.Mod.Path
+	}
+	if file.Go != nil {
+		modFile.GoVersion = file.Go.Version
+	}
+	if file.Toolchain != nil {
+		modFile.Toolchain = file.Toolchain.Name
+	}
+	for _, require := range file.Require {
+		modFile.Requires = append(modFile.Requires, ModRequire{Path: require.Mod.Path, Version: require.Mod.Version, Indirect: require.Indirect})
+	}
+	for _, replace := range file.Replace {
+		modFile.Replaces = append(modFile.Replaces, ModReplace{
+			OldPath:    replace.Old.Path,
+			OldVersion: replace.Old.Version,
+			NewPath:    replace.New.Path,
+			NewVersion: replace.New.Version,
+		})
+	}
+	for _, exclude := range file.Exclude {
+		modFile.Excludes = append(modFile.Excludes, ModRequire{Path: exclude.Mod.Path, Version: exclude.Mod.Version})
+	}
+	for _, retract := range file.Retract {
+		modFile.Retracts = append(modFile.Retracts, ModRetract{Low: retract.Low, High: retract.High, Rationale: retract.Rationale})
+	}
+	return modFile, nil
+}
+
+// goListModules returns the build list of the module in dir. It never
+// touches the network or the go.mod file, and ignores any go.work.
+func goListModules(ctx context.Context, dir string) ([]ModBuildListEntry, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-mod=readonly", "-m", "-json", "all")
+	cmd.Dir = dir
+	cmd.Env = append(workspaceEnv(nil), "GOWORK=off", "GOPROXY=off", "GOTOOLCHAIN=local")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, errors.New(msg)
+	}
+
+	var entries []ModBuildListEntry
+	decoder := json.NewDecoder(&stdout)
+	for {
+		var module struct {
+			Path      string
+			Version   string
+			Main      bool
+			Indirect  bool
+			GoVersion string
+			Replace   *struct {
+				Path    string
+				Version string
+			}
+		}
+		if err := decoder.Decode(&module); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "decode go list -m output")
+		}
+		entry := ModBuildListEntry{
+			Path:      module.Path,
+			Version:   module.Version,
+			Main:      module.Main,
+			Indirect:  module.Indirect,
+			GoVersion: module.GoVersion,
+		}
+		if module.Replace != nil {
+			entry.ReplacePath = module.Replace.Path
+			entry.ReplaceVersion = module.Replace.Version
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,171 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestModulesRange(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "deps\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	readmeRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), `module example.com/app
+
+go 1.21
+
+toolchain go1.22.1
+
+require (
+	example.com/a v1.2.0
+	example.com/b v0.1.0 // indirect
+	example.com/c v1.0.0
+)
+
+replace example.com/a => ../a
+
+exclude example.com/c v0.9.0
+
+retract v0.1.0 // published by mistake
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "add deps")
+	baseRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), `module example.com/app
+
+go 1.21
+
+require (
+	example.com/a v1.3.0
+	example.com/c v1.0.0-rc.1
+	example.com/d v0.0.0-20240101000000-abcdefabcdef
+)
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "bump deps")
+	headRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	rangeResult, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:         dbPath,
+		RepoPath:       repoPath,
+		FromRef:        readmeRef,
+		ToRef:          headRef,
+		SourcesDir:     filepath.Join(root, "sources"),
+		IncludeModules: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(rangeResult.Commits) != 2 || rangeResult.Commits[0].CommitHash != baseRef {
+		t.Fatalf("expected two commits, got %+v", rangeResult.Commits)
+	}
+	baseRunID := rangeResult.Commits[0].ModulesRunID
+	headRunID := rangeResult.Commits[1].ModulesRunID
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	requires, err := store.ListModuleRequires(ctx, ModuleRequireFilter{RunID: baseRunID})
+	if err != nil {
+		t.Fatalf("list requires: %v", err)
+	}
+	if len(requires) != 3 || requires[0].Replacement != "../a" || !requires[1].Indirect || requires[0].CommitHash != baseRef {
+		t.Fatalf("unexpected requires: %+v", requires)
+	}
+	var toolchain string
+	var excludes, retracts int
+	if err := db.QueryRowContext(ctx, `
+		SELECT mf.toolchain,
+		       (SELECT COUNT(*) FROM mod_excludes e WHERE e.mod_file_id = mf.id),
+		       (SELECT COUNT(*) FROM mod_retracts r WHERE r.mod_file_id = mf.id AND r.rationale = 'published by mistake')
+		FROM mod_files mf WHERE mf.run_id = ?`, baseRunID).Scan(&toolchain, &excludes, &retracts); err != nil {
+		t.Fatalf("query mod file: %v", err)
+	}
+	if toolchain != "go1.22.1" || excludes != 1 || retracts != 1 {
+		t.Fatalf("unexpected go.mod directives: toolchain=%s excludes=%d retracts=%d", toolchain, excludes, retracts)
+	}
+
+	changes, err := store.ListModuleChanges(ctx, ModuleChangeFilter{BaseRunID: baseRunID, HeadRunID: headRunID})
+	if err != nil {
+		t.Fatalf("list module changes: %v", err)
+	}
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.Module] = change.Change
+	}
+	expected := map[string]string{
+		"example.com/a": ModuleUpgraded,
+		"example.com/b": ModuleRemoved,
+		"example.com/c": ModuleDowngraded,
+		"example.com/d": ModuleAdded,
+	}
+	for module, change := range expected {
+		if got[module] != change {
+			t.Fatalf("expected %s to be %s, got %+v", module, change, changes)
+		}
+	}
+
+	reportDir := filepath.Join(root, "reports")
+	if _, err := GenerateReports(ctx, ReportConfig{DBPath: dbPath, RunID: rangeResult.CommitLineageRunID, OutputDir: reportDir}); err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(reportDir, "dependency-changes.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	for _, row := range []string{
+		"| bump deps | go.mod | example.com/a | v1.2.0 | v1.3.0 | upgraded |",
+		"| bump deps | go.mod | example.com/b | v0.1.0 |  | removed |",
+		"| bump deps | go.mod | example.com/c | v1.0.0 | v1.0.0-rc.1 | downgraded |",
+		"| bump deps | go.mod | example.com/d |  | v0.0.0-20240101000000-abcdefabcdef | added |",
+	} {
+		if !strings.Contains(string(report), row) {
+			t.Fatalf("expected report row %q, got:\n%s", row, report)
+		}
+	}
+}
+
+func TestIngestModulesBuildList(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repoPath, "missing"), 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "missing", "go.mod"), "module example.com/missing\n\ngo 1.21\n\nrequire example.com/notcached v1.0.0\n")
+
+	result, err := IngestModules(ctx, IngestModulesConfig{DBPath: filepath.Join(root, "index.sqlite"), RootDir: repoPath, BuildList: true})
+	if err != nil {
+		t.Fatalf("ingest modules: %v", err)
+	}
+	if result.ModFiles != 2 || result.BuildList != 1 {
+		t.Fatalf("expected the main module build list only, got %+v", result)
+	}
+	if result.LoadErrors != 1 || !result.Partial {
+		t.Fatalf("expected the uncached module to be recorded as a load error, got %+v", result)
+	}
+}
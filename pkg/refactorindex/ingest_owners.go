@@ -0,0 +1,232 @@
+package refactorindex
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IngestOwnersConfig controls CODEOWNERS ingestion at a commit.
+type IngestOwnersConfig struct {
+	DBPath     string
+	RepoPath   string
+	Commit     string
+	SourcesDir string
+	// CodeownersPath overrides the lookup in CodeownersLocations.
+	CodeownersPath string
+}
+
+// IngestOwnersResult reports counts for CODEOWNERS ingestion.
+type IngestOwnersResult struct {
+	RunID        int64
+	Commit       string
+	SourcePath   string
+	Rules        int
+	Files        int
+	OwnedFiles   int
+	OwnerEntries int
+	RunDir       string
+}
+
+// IngestOwners parses CODEOWNERS at a commit into the owners table and
+// computes owners for every row of files. Files of the commit are added to
+// files first so the whole tree is covered.
+func IngestOwners(ctx context.Context, cfg IngestOwnersConfig) (*IngestOwnersResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RepoPath) == "" {
+		return nil, errors.New("repo path is required")
+	}
+	repoPath, err := filepath.Abs(cfg.RepoPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve repo path")
+	}
+	commitRef := cfg.Commit
+	if strings.TrimSpace(commitRef) == "" {
+		commitRef = "HEAD"
+	}
+	out, err := runGit(ctx, repoPath, "rev-parse", "--verify", commitRef+"^{commit}")
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve owners commit")
+	}
+	commitHash := strings.TrimSpace(string(out))
+
+	sourcePath, content, err := readCodeowners(ctx, repoPath, commitHash, cfg.CodeownersPath)
+	if err != nil {
+		return nil, err
+	}
+	codeowners, err := ParseCodeowners(content)
+	if err != nil {
+		return nil, err
+	}
+
+	treeOutput, err := runGit(ctx, repoPath, "ls-tree", "-r", "-z", "--name-only", commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err = filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"repo":        repoPath,
+		"commit":      commitRef,
+		"codeowners":  sourcePath,
+		"sources_dir": sourcesDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		GitTo:       commitHash,
+		RootPath:    repoPath,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+		BaseCommit:  commitHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "codeowners", "CODEOWNERS", content); err != nil {
+		return nil, err
+	}
+
+	result := &IngestOwnersResult{RunID: runID, Commit: commitHash, SourcePath: sourcePath, RunDir: runDir}
+	ruleIDs := make(map[*OwnerRule]int64, len(codeowners.Rules))
+	for i := range codeowners.Rules {
+		rule := &codeowners.Rules[i]
+		id, err := store.InsertOwnerRule(ctx, tx, runID, sourcePath, *rule)
+		if err != nil {
+			return nil, err
+		}
+		ruleIDs[rule] = id
+		result.Rules++
+	}
+
+	for _, path := range bytes.Split(treeOutput, []byte{0}) {
+		if len(path) == 0 {
+			continue
+		}
+		if _, err := store.GetOrCreateFile(ctx, tx, string(path)); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := loadFilePaths(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		result.Files++
+		owners, rules := codeowners.Match(file.path)
+		if len(owners) == 0 {
+			continue
+		}
+		result.OwnedFiles++
+		for _, owner := range owners {
+			ruleID := int64(0)
+			for _, rule := range rules {
+				if slices.Contains(rule.Owners, owner) {
+					ruleID = ruleIDs[rule]
+					break
+				}
+			}
+			if err := store.InsertFileOwner(ctx, tx, runID, file.id, owner, ruleID); err != nil {
+				return nil, err
+			}
+			result.OwnerEntries++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit owners ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// readCodeowners returns the first CODEOWNERS file found at the commit.
+func readCodeowners(ctx context.Context, repoPath string, commitHash string, override string) (string, []byte, error) {
+	candidates := CodeownersLocations
+	if strings.TrimSpace(override) != "" {
+		candidates = []string{override}
+	}
+	for _, candidate := range candidates {
+		if _, err := runGit(ctx, repoPath, "cat-file", "-e", commitHash+":"+candidate); err != nil {
+			continue
+		}
+		content, err := runGit(ctx, repoPath, "show", commitHash+":"+candidate)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "read %s", candidate)
+		}
+		return candidate, content, nil
+	}
+	return "", nil, errors.Errorf("no CODEOWNERS file found at %s (tried %s)", commitHash, strings.Join(candidates, ", "))
+}
+
+type filePathRow struct {
+	id   int64
+	path string
+}
+
+func loadFilePaths(ctx context.Context, tx *sql.Tx) ([]filePathRow, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, path FROM files ORDER BY id")
+	if err != nil {
+		return nil, errors.Wrap(err, "query files")
+	}
+	defer rows.Close()
+
+	var files []filePathRow
+	for rows.Next() {
+		var file filePathRow
+		if err := rows.Scan(&file.id, &file.path); err != nil {
+			return nil, errors.Wrap(err, "scan file")
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate files")
+	}
+	return files, nil
+}
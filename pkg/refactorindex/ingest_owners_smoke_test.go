@@ -0,0 +1,173 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeownersMatch(t *testing.T) {
+	codeowners, err := ParseCodeowners([]byte(`# global owners
+*       @org/everyone
+*.go    @org/go-team
+/docs/  @org/docs
+docs/*  @org/docs-flat
+apps/   @org/apps
+/build/logs/ @org/ops
+internal/generated.go
+my\ file.txt @org/spaces
+
+[Security][2] @org/security
+auth/
+^[Optional docs]
+README.md @org/writers
+`))
+	if err != nil {
+		t.Fatalf("parse codeowners: %v", err)
+	}
+
+	cases := map[string][]string{
+		"main.go":                  {"@org/go-team"},
+		"cmd/tool/main.go":         {"@org/go-team"},
+		"docs/index.md":            {"@org/docs-flat"},
+		"docs/guides/setup.md":     {"@org/docs"},
+		"src/apps/web/app.js":      {"@org/apps"},
+		"build/logs/out.txt":       {"@org/ops"},
+		"x/build/logs/out.txt":     {"@org/everyone"},
+		"internal/generated.go":    nil,
+		"my file.txt":              {"@org/spaces"},
+		"auth/token.go":            {"@org/go-team", "@org/security"},
+		"README.md":                {"@org/everyone", "@org/writers"},
+		"notes/auth/plain.txt":     {"@org/everyone", "@org/security"},
+		"notes/authentication.txt": {"@org/everyone"},
+	}
+	for path, expected := range cases {
+		owners, _ := codeowners.Match(path)
+		if !reflect.DeepEqual(owners, expected) {
+			t.Errorf("%s: expected owners %v, got %v", path, expected, owners)
+		}
+	}
+}
+
+func TestIngestOwnersFilters(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	for _, dir := range []string{".github", "api", "core"} {
+		if err := os.MkdirAll(filepath.Join(repoPath, dir), 0o755); err != nil {
+			t.Fatalf("mkdir repo: %v", err)
+		}
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, ".github", "CODEOWNERS"), "* @org/core\n/api/ @org/api\n")
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/test\n\ngo 1.25\n")
+	writeFile(t, filepath.Join(repoPath, "api", "api.go"), "package api\n\nfunc Serve() {}\n")
+	writeFile(t, filepath.Join(repoPath, "core", "core.go"), "package core\n\nfunc Run() {}\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "api", "api.go"), "package api\n\nfunc Serve() {}\n\nfunc Stop() {}\n")
+	writeFile(t, filepath.Join(repoPath, "core", "core.go"), "package core\n\nfunc Run() {}\n\nfunc Halt() {}\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "update")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	diff, err := IngestDiff(ctx, IngestDiffConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		FromRef:    fromRef,
+		ToRef:      toRef,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest diff: %v", err)
+	}
+	symbols, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: repoPath})
+	if err != nil {
+		t.Fatalf("ingest symbols: %v", err)
+	}
+
+	owners, err := IngestOwners(ctx, IngestOwnersConfig{
+		DBPath:     dbPath,
+		RepoPath:   repoPath,
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest owners: %v", err)
+	}
+	if owners.SourcePath != ".github/CODEOWNERS" || owners.Rules != 2 || owners.OwnedFiles != owners.Files {
+		t.Fatalf("unexpected owners result: %+v", owners)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	files, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: diff.RunID, Owner: "@org/api"})
+	if err != nil {
+		t.Fatalf("list diff files: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "api/api.go" {
+		t.Fatalf("expected only api/api.go for @org/api, got %+v", files)
+	}
+
+	inventory, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: symbols.RunID, Owner: "@org/core"})
+	if err != nil {
+		t.Fatalf("list symbols: %v", err)
+	}
+	for _, record := range inventory {
+		if record.FilePath != "core/core.go" {
+			t.Fatalf("unexpected symbol outside @org/core: %+v", record)
+		}
+	}
+	if len(inventory) != 2 {
+		t.Fatalf("expected 2 @org/core symbols, got %d", len(inventory))
+	}
+
+	fileOwners, err := store.ListFileOwners(ctx, FileOwnerFilter{Path: "api/api.go"})
+	if err != nil {
+		t.Fatalf("list file owners: %v", err)
+	}
+	if len(fileOwners) != 1 || fileOwners[0].Owner != "@org/api" || fileOwners[0].Pattern != "/api/" {
+		t.Fatalf("unexpected owners for api/api.go: %+v", fileOwners)
+	}
+
+	reports, err := GenerateReports(ctx, ReportConfig{
+		DBPath:    dbPath,
+		RunID:     diff.RunID,
+		OutputDir: filepath.Join(root, "reports"),
+		Owner:     "@org/api",
+	})
+	if err != nil {
+		t.Fatalf("generate reports: %v", err)
+	}
+	for _, report := range reports {
+		if report.Name != "diff-files" {
+			continue
+		}
+		if report.RowCount != 1 {
+			t.Fatalf("expected 1 diff-files row for @org/api, got %d", report.RowCount)
+		}
+		content, err := os.ReadFile(report.Path)
+		if err != nil {
+			t.Fatalf("read report: %v", err)
+		}
+		if !strings.Contains(string(content), "Owner: @org/api") {
+			t.Fatalf("expected owner in report header:\n%s", content)
+		}
+	}
+}
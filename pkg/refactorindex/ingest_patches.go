@@ -0,0 +1,233 @@
+package refactorindex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IngestPatchesConfig controls ingestion of mbox / git format-patch files.
+type IngestPatchesConfig struct {
+	DBPath     string
+	Paths      []string
+	SourcesDir string
+
+	// IssuePatterns are regular expressions for issue references in commit
+	// messages. DefaultIssuePatterns is used when empty.
+	IssuePatterns []string
+}
+
+// PatchRunInfo describes the diff run created for one patch of a series.
+type PatchRunInfo struct {
+	PatchFile  string
+	CommitHash string
+	Subject    string
+	DiffRunID  int64
+	Files      int
+	Hunks      int
+	Lines      int
+}
+
+// IngestPatchesResult reports the series run (holding the commits) and one
+// diff run per patch.
+type IngestPatchesResult struct {
+	RunID   int64
+	Commits int
+	Patches []PatchRunInfo
+}
+
+type patchSeriesEntry struct {
+	file     string
+	message  PatchMessage
+	commitID int64
+}
+
+func IngestPatches(ctx context.Context, cfg IngestPatchesConfig) (*IngestPatchesResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if len(cfg.Paths) == 0 {
+		return nil, errors.New("at least one patch file is required")
+	}
+	issuePatterns, err := CompileIssuePatterns(cfg.IssuePatterns)
+	if err != nil {
+		return nil, err
+	}
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err = filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	files := make(map[string][]byte, len(cfg.Paths))
+	var series []patchSeriesEntry
+	for _, path := range cfg.Paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read patch file %s", path)
+		}
+		files[path] = data
+		messages, err := ParseMbox(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse patch file %s", path)
+		}
+		for _, message := range messages {
+			series = append(series, patchSeriesEntry{file: path, message: message})
+		}
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"patches":        strings.Join(cfg.Paths, "\n"),
+		"sources_dir":    sourcesDir,
+		"issue_patterns": strings.Join(cfg.IssuePatterns, "\n"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+		Mode:        DiffModePatch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	for i, path := range cfg.Paths {
+		fileName := fmt.Sprintf("%03d-%s", i+1, filepath.Base(path))
+		if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "patch-file", fileName, files[path]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range series {
+		message := series[i].message
+		commitID, err := store.InsertCommit(ctx, tx, runID, CommitInfo{
+			Hash:          message.Hash,
+			AuthorName:    message.AuthorName,
+			AuthorEmail:   message.AuthorEmail,
+			AuthorDate:    message.Date,
+			CommitterDate: message.Date,
+			Subject:       message.Subject,
+			Body:          message.Body,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := store.InsertCommitMessage(ctx, tx, commitID, ParseCommitMessage(message.Subject, message.Body, issuePatterns)); err != nil {
+			return nil, err
+		}
+		series[i].commitID = commitID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit patch series ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	result := &IngestPatchesResult{RunID: runID, Commits: len(series)}
+	for i, entry := range series {
+		info, err := ingestPatchDiff(ctx, store, sourcesDir, i+1, entry)
+		if err != nil {
+			return nil, err
+		}
+		result.Patches = append(result.Patches, info)
+	}
+	return result, nil
+}
+
+// ingestPatchDiff stores one patch of a series as its own diff run, so the
+// per-commit diff queries and reports apply unchanged.
+func ingestPatchDiff(ctx context.Context, store *Store, sourcesDir string, index int, entry patchSeriesEntry) (PatchRunInfo, error) {
+	message := entry.message
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"patch":       entry.file,
+		"index":       fmt.Sprintf("%d", index),
+		"commit":      message.Hash,
+		"sources_dir": sourcesDir,
+	})
+	if err != nil {
+		return PatchRunInfo{}, err
+	}
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		GitTo:       message.Hash,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+		Mode:        DiffModePatch,
+	})
+	if err != nil {
+		return PatchRunInfo{}, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return PatchRunInfo{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "patch-file", fmt.Sprintf("%04d.patch", index), message.Raw); err != nil {
+		return PatchRunInfo{}, err
+	}
+	writer := newDiffWriter(ctx, store, tx, runID)
+	if err := StreamUnifiedDiff(bytes.NewReader(message.Diff), writer.Callbacks()); err != nil {
+		return PatchRunInfo{}, err
+	}
+	counts, err := writer.Finish()
+	if err != nil {
+		return PatchRunInfo{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PatchRunInfo{}, errors.Wrap(err, "commit patch diff ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return PatchRunInfo{}, err
+	}
+
+	return PatchRunInfo{
+		PatchFile:  entry.file,
+		CommitHash: message.Hash,
+		Subject:    message.Subject,
+		DiffRunID:  runID,
+		Files:      counts.Files,
+		Hunks:      counts.Hunks,
+		Lines:      counts.Lines,
+	}, nil
+}
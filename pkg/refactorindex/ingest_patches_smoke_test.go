@@ -0,0 +1,144 @@
+package refactorindex
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestPatchesSeries(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\nbeta\n")
+	writeFile(t, filepath.Join(repoPath, "fileB.txt"), "bravo\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "feat(core): add beta", "-m", "Explain the change.", "-m", "Refs: #12")
+	first := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+	firstDate := strings.TrimSpace(gitOut(t, repoPath, "show", "-s", "--format=%ad", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\n")
+	git(t, repoPath, "rm", "-q", "fileB.txt")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "revert beta")
+
+	mboxPath := filepath.Join(root, "series.mbox")
+	writeFile(t, mboxPath, gitOut(t, repoPath, "format-patch", "-2", "--stdout"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestPatches(ctx, IngestPatchesConfig{
+		DBPath:     dbPath,
+		Paths:      []string{mboxPath},
+		SourcesDir: filepath.Join(root, "sources"),
+	})
+	if err != nil {
+		t.Fatalf("ingest patches: %v", err)
+	}
+	if result.Commits != 2 || len(result.Patches) != 2 {
+		t.Fatalf("expected 2 commits/patches, got %d/%d", result.Commits, len(result.Patches))
+	}
+	patch := result.Patches[0]
+	if patch.CommitHash != first || patch.Subject != "feat(core): add beta" {
+		t.Fatalf("unexpected first patch %+v", patch)
+	}
+	if patch.Files != 2 || patch.Hunks != 2 {
+		t.Fatalf("expected 2 files and 2 hunks in first patch, got %d/%d", patch.Files, patch.Hunks)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var authorName, authorEmail, authorDate, body string
+	if err := db.QueryRow(
+		"SELECT author_name, author_email, author_date, body FROM commits WHERE run_id = ? AND hash = ?",
+		result.RunID, first,
+	).Scan(&authorName, &authorEmail, &authorDate, &body); err != nil {
+		t.Fatalf("query patch commit: %v", err)
+	}
+	if authorName != "Refactor Index" || authorEmail != "test@example.com" || authorDate != firstDate {
+		t.Fatalf("unexpected author %q <%q> at %q", authorName, authorEmail, authorDate)
+	}
+	if !strings.HasPrefix(body, "Explain the change.") {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	store := NewStore(db)
+	messages, err := store.ListCommitMessages(ctx, CommitMessageFilter{RunID: result.RunID, Issue: "#12"})
+	if err != nil {
+		t.Fatalf("list commit messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Type != "feat" {
+		t.Fatalf("expected conventional commit with #12, got %+v", messages)
+	}
+
+	records, err := store.ListDiffFiles(ctx, DiffFileFilter{RunID: result.Patches[1].DiffRunID})
+	if err != nil {
+		t.Fatalf("list diff files: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, record := range records {
+		statuses[record.Path] = record.Status
+	}
+	if statuses["fileA.txt"] != "M" || statuses["fileB.txt"] != "D" {
+		t.Fatalf("unexpected statuses for second patch: %v", statuses)
+	}
+
+	var rawCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM raw_outputs WHERE run_id = ? AND source = 'patch-file'", result.RunID).Scan(&rawCount); err != nil {
+		t.Fatalf("count raw outputs: %v", err)
+	}
+	if rawCount != 1 {
+		t.Fatalf("expected the mbox in raw_outputs, got %d rows", rawCount)
+	}
+}
+
+func TestParseMboxTransferEncodings(t *testing.T) {
+	diff := "diff --git a/caf\u00e9.txt b/caf\u00e9.txt\n--- a/caf\u00e9.txt\n+++ b/caf\u00e9.txt\n@@ -1 +1 @@\n-x = 1\n+x = 2 // \u00e9t\u00e9\n"
+	header := "From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001\n" +
+		"From: Refactor Index <test@example.com>\n" +
+		"Date: Mon, 1 Jan 2024 10:00:00 +0000\n" +
+		"Subject: [PATCH] =?UTF-8?q?R=C3=A9sum=C3=A9?=\n" +
+		"MIME-Version: 1.0\n" +
+		"Content-Type: text/plain; charset=UTF-8\n"
+	quoted := header + "Content-Transfer-Encoding: quoted-printable\n\n" +
+		"Caf=C3=A9 body with a very long line that the mailer wrapped with a soft=\n line break.\n---\n" +
+		"diff --git a/caf=C3=A9.txt b/caf=C3=A9.txt\n--- a/caf=C3=A9.txt\n+++ b/caf=C3=A9.txt\n@@ -1 +1 @@\n-x =3D 1\n+x =3D 2 // =C3=A9t=C3=A9\n"
+	encoded := header + "Content-Transfer-Encoding: base64\n\n" +
+		base64.StdEncoding.EncodeToString([]byte("Caf\u00e9 body.\n---\n"+diff)) + "\n"
+
+	for name, mbox := range map[string]string{"quoted-printable": quoted, "base64": encoded} {
+		messages, err := ParseMbox([]byte(mbox))
+		if err != nil {
+			t.Fatalf("%s: parse mbox: %v", name, err)
+		}
+		if len(messages) != 1 || messages[0].Subject != "R\u00e9sum\u00e9" {
+			t.Fatalf("%s: unexpected messages %+v", name, messages)
+		}
+		if !strings.HasPrefix(messages[0].Body, "Caf\u00e9 body") || strings.Contains(messages[0].Body, "=\n") {
+			t.Fatalf("%s: unexpected body %q", name, messages[0].Body)
+		}
+		if string(messages[0].Diff) != diff {
+			t.Fatalf("%s: unexpected diff %q", name, messages[0].Diff)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -16,34 +17,80 @@ type RangeIngestConfig struct {
 	ToRef      string
 	SourcesDir string
 
-	IncludeDiff       bool
-	IncludeSymbols    bool
-	IncludeCodeUnits  bool
-	IncludeDocHits    bool
-	IncludeTreeSitter bool
-	IncludeGopls      bool
+	IncludeDiff        bool
+	IncludeSymbols     bool
+	IncludeCodeUnits   bool
+	IncludeDocHits     bool
+	IncludeTreeSitter  bool
+	IncludeGopls       bool
+	IncludeTests       bool
+	IncludeBenchmarks  bool
+	IncludeDiagnostics bool
+	IncludeModules     bool
+	IncludeExternal    bool
+	IncludeCallSites   bool
+	IncludeErrors      bool
+	IncludeDirectives  bool
+	// Tolerant keeps going past commits whose packages fail to load; their
+	// Go passes ingest what loaded and record the rest in load_errors.
+	Tolerant bool
+	// Load applies to the symbols, code units, diagnostics, external uses,
+	// call sites and errors passes.
+	Load GoLoadOptions
+	// Matrix applies to the symbols and code units passes.
+	Matrix []BuildConfig
+	// SkipClassifyFiles skips classifying every indexed file against each
+	// commit's tree after its passes. By default the files table ends up
+	// describing ToRef, whose classification then scopes every commit.
+	SkipClassifyFiles bool
+	// FileScope applies to the doc-hits pass.
+	FileScope
 
 	TermsFile          string
 	TreeSitterLanguage string
 	TreeSitterQueries  string
 	TreeSitterGlob     string
 	GoplsTargets       []GoplsRefTarget
+	IssuePatterns      []string
+	TestPackages       []string
+	TestTags           []string
+	TestTimeout        time.Duration
+	BenchPattern       string
+	BenchCount         int
+	BenchTime          string
+	Analyzers          []string
+	ModuleBuildList    bool
+	CallTargets        []string
 }
 
 type CommitRunInfo struct {
-	CommitHash      string
-	WorktreePath    string
-	DiffRunID       int64
-	SymbolsRunID    int64
-	CodeUnitsRunID  int64
-	DocHitsRunID    int64
-	TreeSitterRunID int64
-	GoplsRunID      int64
+	CommitHash       string
+	WorktreePath     string
+	DiffRunID        int64
+	SymbolsRunID     int64
+	CodeUnitsRunID   int64
+	DocHitsRunID     int64
+	TreeSitterRunID  int64
+	GoplsRunID       int64
+	HunkUnitsRunID   int64
+	TestsRunID       int64
+	TestsStatus      string
+	BenchRunID       int64
+	DiagnosticsRunID int64
+	ModulesRunID     int64
+	ExternalRunID    int64
+	CallSitesRunID   int64
+	ErrorsRunID      int64
+	DirectivesRunID  int64
+	LoadErrors       int
+	Partial          bool
 }
 
 type RangeIngestResult struct {
 	CommitLineageRunID int64
 	Commits            []CommitRunInfo
+	// PartialCommits lists the commits with load errors in any Go pass.
+	PartialCommits []string
 }
 
 func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngestResult, error) {
@@ -62,6 +109,8 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 		RepoPath: cfg.RepoPath,
 		FromRef:  cfg.FromRef,
 		ToRef:    cfg.ToRef,
+
+		IssuePatterns: cfg.IssuePatterns,
 	})
 	if err != nil {
 		return nil, err
@@ -99,6 +148,8 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 	}()
 
 	results := make([]CommitRunInfo, 0, len(commits))
+	codeUnitRuns := make(map[string]int64, len(commits))
+	var partialCommits []string
 	for _, hash := range commits {
 		worktreePath := filepath.Join(worktreeRoot, hash)
 		if err := addWorktree(ctx, cfg.RepoPath, worktreePath, hash); err != nil {
@@ -128,12 +179,16 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 				RootDir:    worktreePath,
 				SourcesDir: cfg.SourcesDir,
 				CommitID:   &commitID,
+				Load:       cfg.Load,
+				Matrix:     cfg.Matrix,
+				Tolerant:   cfg.Tolerant,
 			})
 			if err != nil {
 				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
 				return nil, err
 			}
 			commitRun.SymbolsRunID = symbolsResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, symbolsResult.LoadErrors)
 		}
 
 		if cfg.IncludeCodeUnits {
@@ -142,12 +197,45 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 				RootDir:    worktreePath,
 				SourcesDir: cfg.SourcesDir,
 				CommitID:   &commitID,
+				Load:       cfg.Load,
+				Matrix:     cfg.Matrix,
+				Tolerant:   cfg.Tolerant,
 			})
 			if err != nil {
 				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
 				return nil, err
 			}
 			commitRun.CodeUnitsRunID = codeUnitsResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, codeUnitsResult.LoadErrors)
+			codeUnitRuns[hash] = codeUnitsResult.RunID
+		}
+
+		if commitRun.DiffRunID != 0 && commitRun.CodeUnitsRunID != 0 {
+			// A parent outside the range, such as FromRef for the first
+			// commit, gets its code units ingested here so its hunks link too.
+			parent := parentCommit(ctx, cfg.RepoPath, hash)
+			parentRunID := codeUnitRuns[parent]
+			if parentRunID == 0 && parent != "" {
+				parentRunID, err = ingestParentCodeUnits(ctx, cfg, store, filepath.Join(worktreeRoot, parent), parent)
+				if err != nil {
+					_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+					return nil, err
+				}
+				codeUnitRuns[parent] = parentRunID
+			}
+			if parentRunID != 0 {
+				linkResult, err := IngestHunkCodeUnits(ctx, IngestHunkCodeUnitsConfig{
+					DBPath:            cfg.DBPath,
+					DiffRunID:         commitRun.DiffRunID,
+					OldCodeUnitsRunID: parentRunID,
+					NewCodeUnitsRunID: commitRun.CodeUnitsRunID,
+				})
+				if err != nil {
+					_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+					return nil, err
+				}
+				commitRun.HunkUnitsRunID = linkResult.RunID
+			}
 		}
 
 		if cfg.IncludeDocHits && strings.TrimSpace(cfg.TermsFile) != "" {
@@ -156,6 +244,7 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 				RootDir:    worktreePath,
 				TermsFile:  cfg.TermsFile,
 				SourcesDir: cfg.SourcesDir,
+				FileScope:  cfg.FileScope,
 			})
 			if err != nil {
 				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
@@ -194,16 +283,169 @@ func IngestCommitRange(ctx context.Context, cfg RangeIngestConfig) (*RangeIngest
 			commitRun.GoplsRunID = goplsResult.RunID
 		}
 
+		if cfg.IncludeTests {
+			testsResult, err := IngestTests(ctx, IngestTestsConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Packages:   cfg.TestPackages,
+				Tags:       cfg.TestTags,
+				Timeout:    cfg.TestTimeout,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.TestsRunID = testsResult.RunID
+			commitRun.TestsStatus = testsResult.Status
+		}
+
+		if cfg.IncludeBenchmarks {
+			benchResult, err := IngestBenchmarks(ctx, IngestBenchmarksConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Packages:   cfg.TestPackages,
+				Pattern:    cfg.BenchPattern,
+				Count:      cfg.BenchCount,
+				Benchtime:  cfg.BenchTime,
+				Tags:       cfg.TestTags,
+				Timeout:    cfg.TestTimeout,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.BenchRunID = benchResult.RunID
+		}
+
+		if cfg.IncludeDiagnostics {
+			diagnosticsResult, err := IngestDiagnostics(ctx, IngestDiagnosticsConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Analyzers:  cfg.Analyzers,
+				Load:       cfg.Load,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.DiagnosticsRunID = diagnosticsResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, diagnosticsResult.LoadErrors)
+		}
+
+		if cfg.IncludeExternal {
+			externalResult, err := IngestExternalUses(ctx, IngestExternalUsesConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Load:       cfg.Load,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.ExternalRunID = externalResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, externalResult.LoadErrors)
+		}
+
+		if cfg.IncludeCallSites && len(cfg.CallTargets) > 0 {
+			callSitesResult, err := IngestCallSites(ctx, IngestCallSitesConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Targets:    cfg.CallTargets,
+				Load:       cfg.Load,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.CallSitesRunID = callSitesResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, callSitesResult.LoadErrors)
+		}
+
+		if cfg.IncludeErrors {
+			errorsResult, err := IngestErrors(ctx, IngestErrorsConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Load:       cfg.Load,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.ErrorsRunID = errorsResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, errorsResult.LoadErrors)
+		}
+
+		if cfg.IncludeDirectives {
+			directivesResult, err := IngestDirectives(ctx, IngestDirectivesConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.DirectivesRunID = directivesResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, directivesResult.LoadErrors)
+		}
+
+		if cfg.IncludeModules {
+			modulesResult, err := IngestModules(ctx, IngestModulesConfig{
+				DBPath:     cfg.DBPath,
+				RootDir:    worktreePath,
+				SourcesDir: cfg.SourcesDir,
+				CommitID:   &commitID,
+				BuildList:  cfg.ModuleBuildList,
+				Tolerant:   cfg.Tolerant,
+			})
+			if err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+			commitRun.ModulesRunID = modulesResult.RunID
+			commitRun.LoadErrors = max(commitRun.LoadErrors, modulesResult.LoadErrors)
+		}
+
+		if !cfg.SkipClassifyFiles {
+			if _, err := ClassifyFiles(ctx, ClassifyFilesConfig{DBPath: cfg.DBPath, RootDir: worktreePath}); err != nil {
+				_ = removeWorktree(ctx, cfg.RepoPath, worktreePath)
+				return nil, err
+			}
+		}
+
 		if err := removeWorktree(ctx, cfg.RepoPath, worktreePath); err != nil {
 			return nil, err
 		}
 
+		commitRun.Partial = commitRun.LoadErrors > 0
+		if commitRun.Partial {
+			partialCommits = append(partialCommits, hash)
+		}
 		results = append(results, commitRun)
 	}
 
 	return &RangeIngestResult{
 		CommitLineageRunID: lineageResult.RunID,
 		Commits:            results,
+		PartialCommits:     partialCommits,
 	}, nil
 }
 
@@ -215,6 +457,42 @@ func addWorktree(ctx context.Context, repoPath string, path string, commit strin
 	return nil
 }
 
+// ingestParentCodeUnits ingests code units for a commit outside the range,
+// attaching them to its commits row when an earlier run recorded one.
+func ingestParentCodeUnits(ctx context.Context, cfg RangeIngestConfig, store *Store, worktreePath string, hash string) (int64, error) {
+	commitID, err := store.LatestCommitIDByHash(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+	if err := addWorktree(ctx, cfg.RepoPath, worktreePath, hash); err != nil {
+		return 0, err
+	}
+	result, err := IngestCodeUnits(ctx, IngestCodeUnitsConfig{
+		DBPath:     cfg.DBPath,
+		RootDir:    worktreePath,
+		SourcesDir: cfg.SourcesDir,
+		CommitID:   commitID,
+		Load:       cfg.Load,
+		Matrix:     cfg.Matrix,
+		Tolerant:   cfg.Tolerant,
+	})
+	if removeErr := removeWorktree(ctx, cfg.RepoPath, worktreePath); removeErr != nil && err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.RunID, nil
+}
+
+func parentCommit(ctx context.Context, repoPath string, hash string) string {
+	out, err := runGit(ctx, repoPath, "rev-parse", "--verify", "--quiet", hash+"^")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
 func removeWorktree(ctx context.Context, repoPath string, path string) error {
 	_, err := runGit(ctx, repoPath, "worktree", "remove", "--force", path)
 	if err != nil {
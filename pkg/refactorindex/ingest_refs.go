@@ -0,0 +1,264 @@
+package refactorindex
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IngestRefsConfig controls tag and branch ingestion.
+type IngestRefsConfig struct {
+	DBPath   string
+	RepoPath string
+}
+
+// IngestRefsResult reports ref ingestion counts.
+type IngestRefsResult struct {
+	RunID         int64
+	Tags          int
+	Branches      int
+	LinkedCommits int
+	FirstTags     int
+}
+
+const refFormat = "%(refname)%1f%(refname:short)%1f%(objecttype)%1f%(objectname)%1f%(*objectname)%1f" +
+	"%(taggername)%1f%(taggeremail)%1f%(taggerdate:iso-strict)%1f%(creatordate:iso-strict)%1f%(contents:subject)"
+
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+func IngestRefs(ctx context.Context, cfg IngestRefsConfig) (*IngestRefsResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RepoPath) == "" {
+		return nil, errors.New("repo path is required")
+	}
+	repoPath, err := filepath.Abs(cfg.RepoPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve repo path")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"repo": repoPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    repoPath,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runGit(ctx, repoPath, "for-each-ref", "--format="+refFormat, "refs/tags", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	refs, err := ParseRefs(out)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	tagIDs := make(map[string]int64)
+	tagCount := 0
+	branchCount := 0
+	linkedCount := 0
+	for _, ref := range refs {
+		commitID, err := store.FindLatestCommitIDByHash(ctx, tx, ref.TargetHash)
+		if err != nil {
+			return nil, err
+		}
+		refID, err := store.InsertRef(ctx, tx, runID, commitID, ref)
+		if err != nil {
+			return nil, err
+		}
+		if commitID != nil {
+			linkedCount++
+		}
+		switch ref.Kind {
+		case "tag":
+			tagIDs[ref.Name] = refID
+			tagCount++
+		case "branch":
+			branchCount++
+		}
+	}
+
+	firstTagCount := 0
+	if len(tagIDs) > 0 {
+		commits, err := listCommitHashes(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		firstTags := make(map[string]string)
+		for _, commit := range commits {
+			tagName, ok := firstTags[commit.Hash]
+			if !ok {
+				tagName, err = firstTagContaining(ctx, repoPath, commit.Hash)
+				if err != nil {
+					// Commits from other repositories or pruned history have no tags here.
+					tagName = ""
+				}
+				firstTags[commit.Hash] = tagName
+			}
+			refID, ok := tagIDs[tagName]
+			if !ok {
+				continue
+			}
+			if err := store.InsertCommitFirstTag(ctx, tx, runID, commit.ID, refID); err != nil {
+				return nil, err
+			}
+			firstTagCount++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit refs ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return &IngestRefsResult{
+		RunID:         runID,
+		Tags:          tagCount,
+		Branches:      branchCount,
+		LinkedCommits: linkedCount,
+		FirstTags:     firstTagCount,
+	}, nil
+}
+
+// ParseRefs parses git for-each-ref output produced with refFormat.
+func ParseRefs(data []byte) ([]RefInfo, error) {
+	refs := make([]RefInfo, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.Split(line, "\x1f")
+		if len(parts) < 10 {
+			return nil, errors.New("unexpected for-each-ref format")
+		}
+		ref := RefInfo{
+			Name:       parts[0],
+			ShortName:  parts[1],
+			ObjectType: parts[2],
+			ObjectHash: parts[3],
+			TargetHash: parts[3],
+			RefDate:    parts[8],
+			Message:    parts[9],
+		}
+		if refTime, err := time.Parse(time.RFC3339, ref.RefDate); err == nil {
+			ref.RefTime = refTime.Unix()
+		}
+		switch {
+		case strings.HasPrefix(ref.Name, "refs/tags/"):
+			ref.Kind = "tag"
+			ref.Semver = ParseSemver(ref.ShortName)
+		case strings.HasPrefix(ref.Name, "refs/heads/"):
+			ref.Kind = "branch"
+		default:
+			ref.Kind = "ref"
+		}
+		if ref.ObjectType == "tag" {
+			ref.IsAnnotated = true
+			ref.TargetHash = parts[4]
+			ref.TaggerName = parts[5]
+			ref.TaggerEmail = strings.Trim(parts[6], "<>")
+			ref.TaggerDate = parts[7]
+		} else {
+			// Lightweight tags and branch heads carry no message of their own.
+			ref.Message = ""
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// ParseSemver parses a tag name such as v1.2.3-rc.1+build.5. Path prefixes
+// used by nested Go modules (tools/v1.2.3) are ignored.
+func ParseSemver(name string) *SemverInfo {
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	match := semverPattern.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	return &SemverInfo{
+		Major:      major,
+		Minor:      minor,
+		Patch:      patch,
+		Prerelease: match[4],
+		Build:      match[5],
+	}
+}
+
+type commitHashRow struct {
+	ID   int64
+	Hash string
+}
+
+func listCommitHashes(ctx context.Context, tx *sql.Tx) ([]commitHashRow, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, hash FROM commits ORDER BY id")
+	if err != nil {
+		return nil, errors.Wrap(err, "query commits")
+	}
+	defer rows.Close()
+
+	var results []commitHashRow
+	for rows.Next() {
+		var row commitHashRow
+		if err := rows.Scan(&row.ID, &row.Hash); err != nil {
+			return nil, errors.Wrap(err, "scan commit")
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate commits")
+	}
+	return results, nil
+}
+
+func firstTagContaining(ctx context.Context, repoPath string, hash string) (string, error) {
+	out, err := runGit(ctx, repoPath, "for-each-ref", "--contains", hash,
+		"--sort=version:refname", "--sort=creatordate", "--count=1", "--format=%(refname)", "refs/tags")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
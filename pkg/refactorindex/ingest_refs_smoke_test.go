@@ -0,0 +1,140 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIngestRefsFirstTag(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init", "-b", "main")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	git(t, repoPath, "tag", "v0.1.0")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\nbeta\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "second")
+	git(t, repoPath, "tag", "-a", "v0.2.0-rc.1", "-m", "release candidate")
+	secondRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "fileA.txt"), "alpha\nbeta\ngamma\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "third")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	if _, err := IngestCommits(ctx, IngestCommitsConfig{
+		DBPath:   dbPath,
+		RepoPath: repoPath,
+		FromRef:  fromRef,
+		ToRef:    toRef,
+	}); err != nil {
+		t.Fatalf("ingest commits: %v", err)
+	}
+
+	result, err := IngestRefs(ctx, IngestRefsConfig{
+		DBPath:   dbPath,
+		RepoPath: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("ingest refs: %v", err)
+	}
+	if result.Tags != 2 || result.Branches != 1 {
+		t.Fatalf("expected 2 tags and 1 branch, got %d/%d", result.Tags, result.Branches)
+	}
+	if result.LinkedCommits != 2 {
+		t.Fatalf("expected 2 refs linked to commits, got %d", result.LinkedCommits)
+	}
+	if result.FirstTags != 1 {
+		t.Fatalf("expected 1 first-tag row, got %d", result.FirstTags)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	tag, err := store.GetFirstTagContainingCommit(ctx, secondRef)
+	if err != nil {
+		t.Fatalf("first tag: %v", err)
+	}
+	if tag == nil || tag.ShortName != "v0.2.0-rc.1" {
+		t.Fatalf("expected v0.2.0-rc.1 as first tag, got %+v", tag)
+	}
+	if !tag.IsAnnotated || tag.TaggerName != "Refactor Index" || tag.Message != "release candidate" {
+		t.Fatalf("expected annotated tag metadata, got %+v", tag)
+	}
+	if tag.Semver != "0.2.0-rc.1" {
+		t.Fatalf("expected semver 0.2.0-rc.1, got %q", tag.Semver)
+	}
+
+	untagged, err := store.GetFirstTagContainingCommit(ctx, toRef)
+	if err != nil {
+		t.Fatalf("first tag for untagged commit: %v", err)
+	}
+	if untagged != nil {
+		t.Fatalf("expected no tag for %s, got %s", toRef, untagged.ShortName)
+	}
+
+	refs, err := store.ListRefs(ctx, RefFilter{RunID: result.RunID, Kind: "tag", Name: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("list refs: %v", err)
+	}
+	if len(refs) != 1 || refs[0].IsAnnotated || refs[0].TargetHash != fromRef {
+		t.Fatalf("expected lightweight v0.1.0 pointing at %s, got %+v", fromRef, refs)
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	cases := map[string]SemverInfo{
+		"v1.2.3":             {Major: 1, Minor: 2, Patch: 3},
+		"1.2.3-rc.1+build.5": {Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "build.5"},
+		"tools/v0.4.0":       {Major: 0, Minor: 4, Patch: 0},
+	}
+	for name, expected := range cases {
+		info := ParseSemver(name)
+		if info == nil {
+			t.Fatalf("expected semver for %s", name)
+		}
+		if *info != expected {
+			t.Fatalf("expected %+v for %s, got %+v", expected, name, *info)
+		}
+	}
+	for _, name := range []string{"release-2024", "v1.2", "latest"} {
+		if ParseSemver(name) != nil {
+			t.Fatalf("expected no semver for %s", name)
+		}
+	}
+}
+
+func TestParseRefsRefTime(t *testing.T) {
+	// 10:00+02:00 is earlier than 05:00-05:00 although it sorts later as text.
+	data := "refs/tags/v1\x1fv1\x1fcommit\x1faaa\x1f\x1f\x1f\x1f\x1f2024-01-01T10:00:00+02:00\x1fone\n" +
+		"refs/tags/v2\x1fv2\x1fcommit\x1fbbb\x1f\x1f\x1f\x1f\x1f2024-01-01T05:00:00-05:00\x1ftwo\n"
+	refs, err := ParseRefs([]byte(data))
+	if err != nil {
+		t.Fatalf("parse refs: %v", err)
+	}
+	if len(refs) != 2 || refs[0].RefTime == 0 || refs[0].RefTime >= refs[1].RefTime {
+		t.Fatalf("expected v1 before v2 by ref time, got %+v", refs)
+	}
+}
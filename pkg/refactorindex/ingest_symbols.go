@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -20,6 +21,13 @@ type IngestSymbolsConfig struct {
 	RootDir    string
 	SourcesDir string
 	CommitID   *int64
+	Load       GoLoadOptions
+	// Matrix loads the packages once per build configuration and records
+	// the configurations each occurrence was seen in.
+	Matrix []BuildConfig
+	// Tolerant ingests the packages that loaded cleanly and records the
+	// errors of the others in load_errors instead of failing the run.
+	Tolerant bool
 }
 
 // IngestSymbolsResult reports counts for symbol ingestion.
@@ -29,6 +37,8 @@ type IngestSymbolsResult struct {
 	Occurrences int
 	Packages    int
 	Files       int
+	LoadErrors  int
+	Partial     bool
 }
 
 func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbolsResult, error) {
@@ -56,9 +66,15 @@ func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbols
 		return nil, err
 	}
 
-	argsJSON, err := EncodeArgsJSON(map[string]string{
-		"root": rootDir,
-	})
+	args := map[string]string{
+		"root":     rootDir,
+		"tolerant": strconv.FormatBool(cfg.Tolerant),
+	}
+	cfg.Load.addArgs(args)
+	if len(cfg.Matrix) > 0 {
+		args["matrix"] = buildMatrixArg(cfg.Matrix)
+	}
+	argsJSON, err := EncodeArgsJSON(args)
 	if err != nil {
 		return nil, err
 	}
@@ -73,17 +89,13 @@ func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbols
 		return nil, err
 	}
 
-	pkgConfig := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedFiles | packages.NeedCompiledGoFiles,
-		Dir:  rootDir,
-	}
-	pkgs, err := packages.Load(pkgConfig, "./...")
+	pkgConfig := cfg.Load.packagesConfig(ctx, rootDir, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo|packages.NeedFiles|packages.NeedCompiledGoFiles)
+	loads, loadErrors, err := loadBuildMatrix(pkgConfig, cfg.Load, cfg.Matrix, rootDir, cfg.Tolerant)
 	if err != nil {
-		return nil, errors.Wrap(err, "load packages")
-	}
-	if packages.PrintErrors(pkgs) > 0 {
-		return nil, errors.New("package load errors")
+		return nil, err
 	}
+	pkgs := configLoadPackages(loads)
+	pkgPaths := symbolPackagePaths(rootDir, pkgs)
 
 	tx, err := store.BeginTx(ctx)
 	if err != nil {
@@ -93,82 +105,96 @@ func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbols
 		_ = tx.Rollback()
 	}()
 
+	if err := insertLoadErrors(ctx, store, tx, runID, cfg.CommitID, loadErrors); err != nil {
+		return nil, err
+	}
+	if err := insertGoPackages(ctx, store, tx, runID, rootDir, pkgs, pkgPaths); err != nil {
+		return nil, err
+	}
+	if err := store.InsertRunBuildConfigs(ctx, tx, runID, buildMatrixNames(cfg.Matrix)); err != nil {
+		return nil, err
+	}
+
 	fileIDs := make(map[string]int64)
 	symbolCount := 0
 	occurrenceCount := 0
 	fileCount := 0
 
-	for _, pkg := range pkgs {
-		if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
-			continue
+	// With a matrix the same declaration is seen once per configuration
+	// that compiles it; it is stored once with one config row each.
+	occurrenceIDs := make(map[string]int64)
+	config := ""
+	insertOccurrence := func(fileID int64, symbolID int64, occ symbolOccurrence) error {
+		key := strconv.FormatInt(fileID, 10) + "|" + strconv.FormatInt(symbolID, 10) + "|" + strconv.Itoa(occ.Line) + "|" + strconv.Itoa(occ.Col)
+		occurrenceID, ok := occurrenceIDs[key]
+		if !ok {
+			id, err := store.InsertSymbolOccurrence(ctx, tx, runID, cfg.CommitID, fileID, symbolID, occ.Line, occ.Col, occ.Exported)
+			if err != nil {
+				return err
+			}
+			occurrenceID = id
+			occurrenceIDs[key] = id
+			symbolCount++
+			occurrenceCount++
+		}
+		if config == "" {
+			return nil
 		}
-		qualifier := types.RelativeTo(pkg.Types)
-		pkgPath := pkg.PkgPath
-		for _, file := range pkg.Syntax {
-			filePath := pkg.Fset.Position(file.Pos()).Filename
-			if filePath == "" {
+		return store.InsertSymbolOccurrenceConfig(ctx, tx, occurrenceID, config)
+	}
+
+	for _, load := range loads {
+		config = load.Config
+		for _, pkg := range load.Pkgs {
+			if pkg.Types == nil || pkg.TypesInfo == nil || pkg.Fset == nil {
 				continue
 			}
-			relPath, err := filepath.Rel(rootDir, filePath)
-			if err != nil {
-				return nil, errors.Wrap(err, "relativize file path")
-			}
-			relPath = filepath.ToSlash(relPath)
-			fileID, ok := fileIDs[relPath]
-			if !ok {
-				id, err := store.GetOrCreateFile(ctx, tx, relPath)
+			qualifier := types.RelativeTo(pkg.Types)
+			pkgPath := pkgPaths[pkg]
+			for _, file := range pkg.Syntax {
+				filePath := pkg.Fset.Position(file.Pos()).Filename
+				if filePath == "" {
+					continue
+				}
+				relPath, err := filepath.Rel(rootDir, filePath)
 				if err != nil {
-					return nil, err
+					return nil, errors.Wrap(err, "relativize file path")
 				}
-				fileID = id
-				fileIDs[relPath] = id
-				fileCount++
-			}
-
-			for _, decl := range file.Decls {
-				switch d := decl.(type) {
-				case *ast.FuncDecl:
-					obj := pkg.TypesInfo.Defs[d.Name]
-					if obj == nil {
-						continue
-					}
-					def, occ, err := buildSymbolDef(pkg.Fset, pkgPath, qualifier, obj)
-					if err != nil {
-						return nil, err
-					}
-					symbolID, err := store.GetOrCreateSymbolDef(ctx, tx, def)
+				relPath = filepath.ToSlash(relPath)
+				fileID, ok := fileIDs[relPath]
+				if !ok {
+					id, err := store.GetOrCreateFile(ctx, tx, relPath)
 					if err != nil {
 						return nil, err
 					}
-					symbolCount++
-					if err := store.InsertSymbolOccurrence(ctx, tx, runID, cfg.CommitID, fileID, symbolID, occ.Line, occ.Col, occ.Exported); err != nil {
-						return nil, err
-					}
-					occurrenceCount++
-				case *ast.GenDecl:
-					for _, spec := range d.Specs {
-						switch s := spec.(type) {
-						case *ast.TypeSpec:
-							obj := pkg.TypesInfo.Defs[s.Name]
-							if obj == nil {
-								continue
-							}
-							def, occ, err := buildSymbolDef(pkg.Fset, pkgPath, qualifier, obj)
-							if err != nil {
-								return nil, err
-							}
-							symbolID, err := store.GetOrCreateSymbolDef(ctx, tx, def)
-							if err != nil {
-								return nil, err
-							}
-							symbolCount++
-							if err := store.InsertSymbolOccurrence(ctx, tx, runID, cfg.CommitID, fileID, symbolID, occ.Line, occ.Col, occ.Exported); err != nil {
-								return nil, err
-							}
-							occurrenceCount++
-						case *ast.ValueSpec:
-							for _, name := range s.Names {
-								obj := pkg.TypesInfo.Defs[name]
+					fileID = id
+					fileIDs[relPath] = id
+					fileCount++
+				}
+
+				for _, decl := range file.Decls {
+					switch d := decl.(type) {
+					case *ast.FuncDecl:
+						obj := pkg.TypesInfo.Defs[d.Name]
+						if obj == nil {
+							continue
+						}
+						def, occ, err := buildSymbolDef(pkg.Fset, pkgPath, qualifier, obj)
+						if err != nil {
+							return nil, err
+						}
+						symbolID, err := store.GetOrCreateSymbolDef(ctx, tx, def)
+						if err != nil {
+							return nil, err
+						}
+						if err := insertOccurrence(fileID, symbolID, occ); err != nil {
+							return nil, err
+						}
+					case *ast.GenDecl:
+						for _, spec := range d.Specs {
+							switch s := spec.(type) {
+							case *ast.TypeSpec:
+								obj := pkg.TypesInfo.Defs[s.Name]
 								if obj == nil {
 									continue
 								}
@@ -180,11 +206,27 @@ func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbols
 								if err != nil {
 									return nil, err
 								}
-								symbolCount++
-								if err := store.InsertSymbolOccurrence(ctx, tx, runID, cfg.CommitID, fileID, symbolID, occ.Line, occ.Col, occ.Exported); err != nil {
+								if err := insertOccurrence(fileID, symbolID, occ); err != nil {
 									return nil, err
 								}
-								occurrenceCount++
+							case *ast.ValueSpec:
+								for _, name := range s.Names {
+									obj := pkg.TypesInfo.Defs[name]
+									if obj == nil {
+										continue
+									}
+									def, occ, err := buildSymbolDef(pkg.Fset, pkgPath, qualifier, obj)
+									if err != nil {
+										return nil, err
+									}
+									symbolID, err := store.GetOrCreateSymbolDef(ctx, tx, def)
+									if err != nil {
+										return nil, err
+									}
+									if err := insertOccurrence(fileID, symbolID, occ); err != nil {
+										return nil, err
+									}
+								}
 							}
 						}
 					}
@@ -193,19 +235,29 @@ func IngestSymbols(ctx context.Context, cfg IngestSymbolsConfig) (*IngestSymbols
 		}
 	}
 
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, errors.Wrap(err, "commit symbol ingestion")
 	}
 	if err := store.FinishRun(ctx, runID); err != nil {
 		return nil, err
 	}
+	if len(loadErrors) > 0 {
+		if err := store.SetRunStatus(ctx, runID, RunStatusPartial); err != nil {
+			return nil, err
+		}
+	}
 
 	return &IngestSymbolsResult{
 		RunID:       runID,
 		Symbols:     symbolCount,
 		Occurrences: occurrenceCount,
-		Packages:    len(pkgs),
+		Packages:    countPackagePaths(pkgPaths),
 		Files:       fileCount,
+		LoadErrors:  len(loadErrors),
+		Partial:     len(loadErrors) > 0,
 	}, nil
 }
 
@@ -0,0 +1,323 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	TestStatusPass = "pass"
+	TestStatusFail = "fail"
+	TestStatusSkip = "skip"
+	// TestStatusUnknown marks tests that started but never reported a result,
+	// e.g. because the package timed out or panicked.
+	TestStatusUnknown = "unknown"
+
+	// testOutputExcerptBytes bounds the output kept per test; the tail is
+	// kept because failures are reported last.
+	testOutputExcerptBytes = 4096
+)
+
+// IngestTestsConfig controls go test -json ingestion.
+type IngestTestsConfig struct {
+	DBPath     string
+	RootDir    string
+	SourcesDir string
+	CommitID   *int64
+
+	// Packages defaults to ./...
+	Packages []string
+	Tags     []string
+	// Timeout is passed to go test -timeout; 0 keeps the go default.
+	Timeout time.Duration
+}
+
+// IngestTestsResult reports counts for test ingestion.
+type IngestTestsResult struct {
+	RunID      int64
+	TestRunID  int64
+	ExitCode   int
+	Status     string
+	Packages   int
+	Tests      int
+	Passed     int
+	Failed     int
+	Skipped    int
+	RunDir     string
+	ElapsedSec float64
+}
+
+// TestEvent is one line of go test -json output (see go doc test2json).
+type TestEvent struct {
+	Time        time.Time `json:"Time"`
+	Action      string    `json:"Action"`
+	Package     string    `json:"Package"`
+	Test        string    `json:"Test"`
+	Elapsed     float64   `json:"Elapsed"`
+	Output      string    `json:"Output"`
+	FailedBuild string    `json:"FailedBuild"`
+}
+
+// TestResult is the outcome of one test, subtest or package. Package results
+// have an empty Test.
+type TestResult struct {
+	Package string
+	Test    string
+	Subtest string
+	Status  string
+	Elapsed float64
+	Output  string
+}
+
+func IngestTests(ctx context.Context, cfg IngestTestsConfig) (*IngestTestsResult, error) {
+	if strings.TrimSpace(cfg.DBPath) == "" {
+		return nil, errors.New("db path is required")
+	}
+	if strings.TrimSpace(cfg.RootDir) == "" {
+		return nil, errors.New("root dir is required")
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve root dir")
+	}
+	packages := cfg.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	sourcesDir := cfg.SourcesDir
+	if strings.TrimSpace(sourcesDir) == "" {
+		sourcesDir = "sources"
+	}
+	sourcesDir, err = filepath.Abs(sourcesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve sources dir")
+	}
+
+	db, err := OpenDB(ctx, cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store := NewStore(db)
+	if err := store.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	timeout := ""
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout.String()
+	}
+	argsJSON, err := EncodeArgsJSON(map[string]string{
+		"root":        rootDir,
+		"packages":    strings.Join(packages, " "),
+		"tags":        strings.Join(cfg.Tags, ","),
+		"timeout":     timeout,
+		"sources_dir": sourcesDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runID, err := store.CreateRun(ctx, RunConfig{
+		ToolVersion: ToolVersion,
+		RootPath:    rootDir,
+		SourcesDir:  sourcesDir,
+		ArgsJSON:    argsJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"test", "-json"}
+	if len(cfg.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(cfg.Tags, ","))
+	}
+	if timeout != "" {
+		args = append(args, "-timeout", timeout)
+	}
+	args = append(args, packages...)
+
+	started := time.Now()
+	stdout, stderr, exitCode, err := runGoTest(ctx, rootDir, args)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(started).Seconds()
+
+	results, err := ParseTestEvents(bytes.NewReader(stdout))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	runDir := filepath.Join(sourcesDir, fmt.Sprintf("%d", runID))
+	if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "go-test-json", "go-test.json", stdout); err != nil {
+		return nil, err
+	}
+	if len(stderr) > 0 {
+		if _, err := store.WriteRawOutput(ctx, tx, runDir, runID, "go-test-stderr", "go-test-stderr.txt", stderr); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &IngestTestsResult{RunID: runID, ExitCode: exitCode, RunDir: runDir, ElapsedSec: elapsed}
+	for _, test := range results {
+		if test.Test == "" {
+			result.Packages++
+			continue
+		}
+		result.Tests++
+		switch test.Status {
+		case TestStatusPass:
+			result.Passed++
+		case TestStatusSkip:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+	}
+	result.Status = TestStatusPass
+	if exitCode != 0 || result.Failed > 0 {
+		result.Status = TestStatusFail
+	}
+
+	testRunID, err := store.InsertTestRun(ctx, tx, runID, cfg.CommitID, TestRun{
+		RootPath: rootDir,
+		Packages: strings.Join(packages, " "),
+		Tags:     strings.Join(cfg.Tags, ","),
+		Timeout:  timeout,
+		ExitCode: exitCode,
+		Status:   result.Status,
+		Tests:    result.Tests,
+		Passed:   result.Passed,
+		Failed:   result.Failed,
+		Skipped:  result.Skipped,
+		Elapsed:  elapsed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.TestRunID = testRunID
+	for _, test := range results {
+		if err := store.InsertTestResult(ctx, tx, testRunID, runID, cfg.CommitID, test); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit test ingestion")
+	}
+	if err := store.FinishRun(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// runGoTest runs go test and returns its output. A non-zero exit code from
+// failing tests is not an error; failing to start go is.
+func runGoTest(ctx context.Context, rootDir string, args []string) ([]byte, []byte, int, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = rootDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.Bytes(), stderr.Bytes(), exitErr.ExitCode(), nil
+		}
+		return nil, nil, 0, errors.Wrap(err, "run go test")
+	}
+	return stdout.Bytes(), stderr.Bytes(), 0, nil
+}
+
+// ParseTestEvents folds a go test -json event stream into one result per
+// package, test and subtest, in order of first appearance. Lines that are not
+// JSON (e.g. build errors from older toolchains) are ignored.
+func ParseTestEvents(r io.Reader) ([]TestResult, error) {
+	var order []string
+	byKey := make(map[string]*TestResult)
+	outputs := make(map[string]*bytes.Buffer)
+
+	get := func(event TestEvent) *TestResult {
+		key := event.Package + "\x00" + event.Test
+		result, ok := byKey[key]
+		if !ok {
+			result = &TestResult{Package: event.Package, Status: TestStatusUnknown}
+			if event.Test != "" {
+				result.Test, result.Subtest, _ = strings.Cut(event.Test, "/")
+			}
+			byKey[key] = result
+			outputs[key] = &bytes.Buffer{}
+			order = append(order, key)
+		}
+		return result
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, errors.Wrap(readErr, "read test events")
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] == '{' {
+			var event TestEvent
+			if err := json.Unmarshal(trimmed, &event); err == nil && event.Package != "" {
+				result := get(event)
+				key := event.Package + "\x00" + event.Test
+				switch event.Action {
+				case "output":
+					outputs[key].WriteString(event.Output)
+				case TestStatusPass, TestStatusFail, TestStatusSkip:
+					result.Status = event.Action
+					result.Elapsed = event.Elapsed
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	results := make([]TestResult, 0, len(order))
+	for _, key := range order {
+		result := byKey[key]
+		output := outputs[key].Bytes()
+		if len(output) > testOutputExcerptBytes {
+			start := len(output) - testOutputExcerptBytes
+			// Start on a rune boundary so the excerpt stays valid UTF-8.
+			for start < len(output) && !utf8.RuneStart(output[start]) {
+				start++
+			}
+			output = output[start:]
+		}
+		result.Output = string(output)
+		results = append(results, *result)
+	}
+	return results, nil
+}
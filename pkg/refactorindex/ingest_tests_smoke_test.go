@@ -0,0 +1,174 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	stream := `{"Action":"start","Package":"example.com/m"}
+{"Action":"run","Package":"example.com/m","Test":"TestA"}
+{"Action":"output","Package":"example.com/m","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"run","Package":"example.com/m","Test":"TestA/case_1"}
+{"Action":"output","Package":"example.com/m","Test":"TestA/case_1","Output":"    a_test.go:9: boom\n"}
+{"Action":"fail","Package":"example.com/m","Test":"TestA/case_1","Elapsed":0.01}
+{"Action":"fail","Package":"example.com/m","Test":"TestA","Elapsed":0.02}
+{"Action":"run","Package":"example.com/m","Test":"TestHang"}
+not json
+{"Action":"fail","Package":"example.com/m","Elapsed":1.5}
+`
+	results, err := ParseTestEvents(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("parse test events: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d: %+v", len(results), results)
+	}
+	if results[0].Test != "" || results[0].Status != TestStatusFail || results[0].Elapsed != 1.5 {
+		t.Fatalf("unexpected package result: %+v", results[0])
+	}
+	if results[2].Test != "TestA" || results[2].Subtest != "case_1" || !strings.Contains(results[2].Output, "boom") {
+		t.Fatalf("unexpected subtest result: %+v", results[2])
+	}
+	if results[3].Test != "TestHang" || results[3].Status != TestStatusUnknown {
+		t.Fatalf("unexpected unfinished test: %+v", results[3])
+	}
+
+	// The tail cut would otherwise land inside a two-byte rune.
+	long := strings.Repeat("é", testOutputExcerptBytes/2+50) + "x"
+	results, err = ParseTestEvents(strings.NewReader(`{"Action":"output","Package":"example.com/m","Test":"TestLong","Output":"` + long + `"}
+{"Action":"pass","Package":"example.com/m","Test":"TestLong","Elapsed":0.01}
+`))
+	if err != nil {
+		t.Fatalf("parse long output: %v", err)
+	}
+	if len(results) != 1 || !utf8.ValidString(results[0].Output) || len(results[0].Output) != testOutputExcerptBytes-1 {
+		t.Fatalf("expected a valid UTF-8 excerpt, got %d bytes", len(results[0].Output))
+	}
+}
+
+func TestIngestCommitRangeTests(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "calc\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	baseRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/calc\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int { return a + b }\n")
+	writeFile(t, filepath.Join(repoPath, "calc_test.go"), `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	for _, name := range []string{"small", "large"} {
+		t.Run(name, func(t *testing.T) {
+			if Add(2, 3) != 5 {
+				t.Fatal("Add(2, 3) != 5")
+			}
+		})
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	t.Skip("not yet")
+}
+`)
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+	fromRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	writeFile(t, filepath.Join(repoPath, "calc.go"), "package calc\n\nfunc Add(a, b int) int { return a - b }\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "break add")
+	toRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	dbPath := filepath.Join(root, "index.sqlite")
+	result, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:       dbPath,
+		RepoPath:     repoPath,
+		FromRef:      baseRef,
+		ToRef:        toRef,
+		SourcesDir:   filepath.Join(root, "sources"),
+		IncludeTests: true,
+	})
+	if err != nil {
+		t.Fatalf("ingest range: %v", err)
+	}
+	if len(result.Commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(result.Commits))
+	}
+	if result.Commits[0].TestsStatus != TestStatusPass || result.Commits[1].TestsStatus != TestStatusFail {
+		t.Fatalf("unexpected tests statuses: %q, %q", result.Commits[0].TestsStatus, result.Commits[1].TestsStatus)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	passing, err := store.ListTestResults(ctx, TestResultFilter{RunID: result.Commits[0].TestsRunID})
+	if err != nil {
+		t.Fatalf("list passing results: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, record := range passing {
+		statuses[record.Test+"/"+record.Subtest] = record.Status
+		if record.CommitHash != fromRef {
+			t.Fatalf("expected commit %s, got %s", fromRef, record.CommitHash)
+		}
+	}
+	expected := map[string]string{
+		"TestAdd/":      TestStatusPass,
+		"TestAdd/small": TestStatusPass,
+		"TestAdd/large": TestStatusPass,
+		"TestSkipped/":  TestStatusSkip,
+	}
+	for key, status := range expected {
+		if statuses[key] != status {
+			t.Fatalf("expected %s to be %s, got %q (%v)", key, status, statuses[key], statuses)
+		}
+	}
+
+	failures, err := store.ListTestResults(ctx, TestResultFilter{Status: TestStatusFail, Test: "TestAdd"})
+	if err != nil {
+		t.Fatalf("list failures: %v", err)
+	}
+	if len(failures) != 3 {
+		t.Fatalf("expected 3 failing results, got %d", len(failures))
+	}
+	for _, record := range failures {
+		if record.CommitHash != toRef {
+			t.Fatalf("expected failure pinned to %s, got %s", toRef, record.CommitHash)
+		}
+	}
+	if !strings.Contains(failures[1].Output, "Add(2, 3) != 5") {
+		t.Fatalf("expected failure output excerpt, got %q", failures[1].Output)
+	}
+
+	var exitCode, failed int
+	if err := db.QueryRow("SELECT exit_code, failed FROM test_runs WHERE run_id = ?", result.Commits[1].TestsRunID).Scan(&exitCode, &failed); err != nil {
+		t.Fatalf("query test run: %v", err)
+	}
+	if exitCode == 0 || failed != 3 {
+		t.Fatalf("unexpected test run summary: exit %d, failed %d", exitCode, failed)
+	}
+}
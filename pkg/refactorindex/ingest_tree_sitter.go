@@ -155,6 +155,9 @@ func IngestTreeSitter(ctx context.Context, cfg IngestTreeSitterConfig) (*IngestT
 		}
 	}
 
+	if err := classifyNewFiles(ctx, store, tx, rootDir); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, errors.Wrap(err, "commit tree-sitter ingestion")
 	}
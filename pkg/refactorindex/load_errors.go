@@ -0,0 +1,153 @@
+package refactorindex
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	// RunStatusPartial marks runs that skipped packages with load errors.
+	RunStatusPartial = "partial"
+
+	LoadErrorList    = "list"
+	LoadErrorParse   = "parse"
+	LoadErrorType    = "type"
+	LoadErrorUnknown = "unknown"
+)
+
+// LoadError is a go/packages list, parse or type error recorded by a
+// tolerant run.
+type LoadError struct {
+	Package string
+	Path    string
+	Line    int
+	Col     int
+	Kind    string
+	Message string
+}
+
+// loadGoPackages loads the packages selected by opts, once per module
+// directory found by goModuleDirs. Without tolerant, any load error fails
+// the run as before. With tolerant, packages with errors are dropped and
+// their errors returned, and a failing go list becomes a list error so the
+// caller can still record a (possibly empty) run.
+func loadGoPackages(pkgConfig *packages.Config, opts GoLoadOptions, rootDir string, tolerant bool) ([]*packages.Package, []LoadError, error) {
+	ctx := pkgConfig.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	dirs, workspace, err := goModuleDirs(ctx, rootDir, opts, pkgConfig.Env)
+	if err != nil {
+		return nil, nil, err
+	}
+	env := pkgConfig.Env
+	if workspace {
+		env = workspaceEnv(env)
+	}
+
+	var pkgs []*packages.Package
+	var loadErrors []LoadError
+	for _, dir := range dirs {
+		moduleConfig := *pkgConfig
+		moduleConfig.Dir = dir
+		moduleConfig.Env = env
+		loaded, err := packages.Load(&moduleConfig, opts.patterns()...)
+		if err != nil {
+			if !tolerant {
+				return nil, nil, errors.Wrapf(err, "load packages in %s", dir)
+			}
+			loadErrors = append(loadErrors, LoadError{Kind: LoadErrorList, Message: err.Error()})
+			continue
+		}
+		if pkgConfig.Tests {
+			loaded = dropTestDuplicates(loaded)
+		}
+		pkgs = append(pkgs, loaded...)
+	}
+
+	if !tolerant {
+		if packages.PrintErrors(pkgs) > 0 {
+			return nil, nil, errors.New("package load errors")
+		}
+		return pkgs, nil, nil
+	}
+
+	loaded := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) == 0 {
+			loaded = append(loaded, pkg)
+			continue
+		}
+		for _, pkgErr := range pkg.Errors {
+			loadErrors = append(loadErrors, buildLoadError(rootDir, pkg.PkgPath, pkgErr))
+		}
+	}
+	return loaded, loadErrors, nil
+}
+
+func buildLoadError(rootDir string, pkgPath string, pkgErr packages.Error) LoadError {
+	loadErr := LoadError{Package: pkgPath, Message: pkgErr.Msg}
+	switch pkgErr.Kind {
+	case packages.ListError:
+		loadErr.Kind = LoadErrorList
+	case packages.ParseError:
+		loadErr.Kind = LoadErrorParse
+	case packages.TypeError:
+		loadErr.Kind = LoadErrorType
+	default:
+		loadErr.Kind = LoadErrorUnknown
+	}
+
+	path, line, col := splitErrorPos(pkgErr.Pos)
+	if path != "" {
+		if rel, ok := rootRelativePath(rootDir, path); ok {
+			path = rel
+		}
+	}
+	loadErr.Path, loadErr.Line, loadErr.Col = path, line, col
+	return loadErr
+}
+
+// splitErrorPos splits a "file:line:col" position; either number may be
+// missing and "-" means no position.
+func splitErrorPos(pos string) (string, int, int) {
+	if pos == "" || pos == "-" {
+		return "", 0, 0
+	}
+	var numbers []int
+	for len(numbers) < 2 {
+		idx := strings.LastIndex(pos, ":")
+		if idx < 0 {
+			break
+		}
+		n, err := strconv.Atoi(pos[idx+1:])
+		if err != nil {
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		pos = pos[:idx]
+	}
+	line, col := 0, 0
+	if len(numbers) > 0 {
+		line = numbers[0]
+	}
+	if len(numbers) > 1 {
+		col = numbers[1]
+	}
+	return filepath.Clean(pos), line, col
+}
+
+func insertLoadErrors(ctx context.Context, store *Store, tx *sql.Tx, runID int64, commitID *int64, loadErrors []LoadError) error {
+	for _, loadErr := range loadErrors {
+		if err := store.InsertLoadError(ctx, tx, runID, commitID, loadErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTolerantRangeRecordsLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	if err := os.MkdirAll(repoPath, 0o755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+	dbPath := filepath.Join(root, "index.sqlite")
+
+	git(t, repoPath, "init")
+	git(t, repoPath, "config", "user.email", "test@example.com")
+	git(t, repoPath, "config", "user.name", "Refactor Index")
+
+	writeFile(t, filepath.Join(repoPath, "README.md"), "tolerant\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "readme")
+	baseRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	for _, dir := range []string{"good", "bad"} {
+		if err := os.MkdirAll(filepath.Join(repoPath, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(repoPath, "go.mod"), "module example.com/tolerant\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(repoPath, "good", "good.go"), "package good\n\nfunc Good() int { return 1 }\n")
+	writeFile(t, filepath.Join(repoPath, "bad", "bad.go"), "package bad\n\nfunc Bad() int { return 1 }\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "initial")
+
+	writeFile(t, filepath.Join(repoPath, "bad", "bad.go"), "package bad\n\nfunc Bad() int { return \"one\" }\n")
+	git(t, repoPath, "add", "-A")
+	git(t, repoPath, "commit", "-m", "break bad")
+	brokenRef := strings.TrimSpace(gitOut(t, repoPath, "rev-parse", "HEAD"))
+
+	if _, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: repoPath}); err == nil {
+		t.Fatalf("expected strict symbol ingestion to fail on load errors")
+	}
+
+	result, err := IngestCommitRange(ctx, RangeIngestConfig{
+		DBPath:           dbPath,
+		RepoPath:         repoPath,
+		FromRef:          baseRef,
+		ToRef:            brokenRef,
+		SourcesDir:       filepath.Join(root, "sources"),
+		IncludeSymbols:   true,
+		IncludeCodeUnits: true,
+		Tolerant:         true,
+	})
+	if err != nil {
+		t.Fatalf("ingest tolerant range: %v", err)
+	}
+	if len(result.Commits) != 2 || result.Commits[0].Partial || !result.Commits[1].Partial {
+		t.Fatalf("unexpected range commits: %+v", result.Commits)
+	}
+	if len(result.PartialCommits) != 1 || result.PartialCommits[0] != brokenRef {
+		t.Fatalf("expected broken commit to be reported, got %v", result.PartialCommits)
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	store := NewStore(db)
+
+	symbolsRunID := result.Commits[1].SymbolsRunID
+	records, err := store.ListLoadErrors(ctx, LoadErrorFilter{RunID: symbolsRunID})
+	if err != nil {
+		t.Fatalf("list load errors: %v", err)
+	}
+	// go list reports the compile error as well, so look for the type error.
+	var loadErr LoadErrorRecord
+	for _, record := range records {
+		if record.Kind == LoadErrorType {
+			loadErr = record
+		}
+	}
+	if loadErr.Package != "example.com/tolerant/bad" || loadErr.Path != "bad/bad.go" || loadErr.Line != 3 || loadErr.Kind != LoadErrorType || loadErr.CommitHash != brokenRef {
+		t.Fatalf("unexpected load error: %+v", loadErr)
+	}
+
+	var status string
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(status, '') FROM meta_runs WHERE id = ?", symbolsRunID).Scan(&status); err != nil {
+		t.Fatalf("query run status: %v", err)
+	}
+	if status != RunStatusPartial {
+		t.Fatalf("expected partial run, got %q", status)
+	}
+
+	symbols, err := store.ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: symbolsRunID})
+	if err != nil {
+		t.Fatalf("list symbols: %v", err)
+	}
+	for _, symbol := range symbols {
+		if symbol.Pkg != "example.com/tolerant/good" {
+			t.Fatalf("expected only symbols from the good package, got %+v", symbol)
+		}
+	}
+	if len(symbols) != 1 {
+		t.Fatalf("expected 1 symbol, got %+v", symbols)
+	}
+}
@@ -0,0 +1,94 @@
+package refactorindex
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// GoLoadOptions controls how the go/packages based passes (symbols, code
+// units, diagnostics) load packages.
+type GoLoadOptions struct {
+	// Patterns defaults to ./...
+	Patterns []string
+	Tags     []string
+	// Tests includes _test.go files and external test packages.
+	Tests  bool
+	GOOS   string
+	GOARCH string
+	// Env holds KEY=VALUE overrides applied on top of the process environment.
+	Env []string
+}
+
+func (o GoLoadOptions) patterns() []string {
+	if len(o.Patterns) == 0 {
+		return []string{"./..."}
+	}
+	return o.Patterns
+}
+
+func (o GoLoadOptions) packagesConfig(ctx context.Context, rootDir string, mode packages.LoadMode) *packages.Config {
+	if o.Tests {
+		// go/packages cannot type-check the generated test mains against
+		// export data, so tests are loaded with their dependencies from
+		// source. This is slower and only paid when tests are requested.
+		mode |= packages.NeedImports | packages.NeedDeps
+	}
+	pkgConfig := &packages.Config{
+		Context: ctx,
+		Mode:    mode | packages.NeedModule,
+		Dir:     rootDir,
+		Tests:   o.Tests,
+	}
+	if len(o.Tags) > 0 {
+		pkgConfig.BuildFlags = []string{"-tags=" + strings.Join(o.Tags, ",")}
+	}
+	if len(o.Env) > 0 || o.GOOS != "" || o.GOARCH != "" {
+		env := append(os.Environ(), o.Env...)
+		if o.GOOS != "" {
+			env = append(env, "GOOS="+o.GOOS)
+		}
+		if o.GOARCH != "" {
+			env = append(env, "GOARCH="+o.GOARCH)
+		}
+		pkgConfig.Env = env
+	}
+	return pkgConfig
+}
+
+// addArgs records the options in a run's args so it can be reproduced.
+func (o GoLoadOptions) addArgs(args map[string]string) {
+	args["patterns"] = strings.Join(o.patterns(), " ")
+	args["tags"] = strings.Join(o.Tags, ",")
+	args["tests"] = strconv.FormatBool(o.Tests)
+	args["goos"] = o.GOOS
+	args["goarch"] = o.GOARCH
+	args["env"] = strings.Join(o.Env, " ")
+}
+
+// dropTestDuplicates removes the copies go/packages makes when Tests is set:
+// a package that has an in-package test variant ("p [p.test]") is dropped in
+// favour of that variant, and the generated test mains are dropped, so each
+// file is ingested once.
+func dropTestDuplicates(pkgs []*packages.Package) []*packages.Package {
+	hasVariant := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ID != pkg.PkgPath && strings.HasPrefix(pkg.ID, pkg.PkgPath+" [") {
+			hasVariant[pkg.PkgPath] = true
+		}
+	}
+	result := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") && pkg.Name == "main" {
+			continue
+		}
+		if pkg.ID == pkg.PkgPath && hasVariant[pkg.PkgPath] {
+			continue
+		}
+		result = append(result, pkg)
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package refactorindex
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestIngestSymbolsLoadOptions(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	modPath := filepath.Join(root, "mod")
+	if err := os.MkdirAll(filepath.Join(modPath, "other"), 0o755); err != nil {
+		t.Fatalf("mkdir module: %v", err)
+	}
+	dbPath := filepath.Join(root, "index.sqlite")
+
+	writeFile(t, filepath.Join(modPath, "go.mod"), "module example.com/opts\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(modPath, "opts.go"), "package opts\n\nfunc Plain() {}\n")
+	writeFile(t, filepath.Join(modPath, "integration.go"), "//go:build integration\n\npackage opts\n\nfunc Tagged() {}\n")
+	writeFile(t, filepath.Join(modPath, "opts_windows.go"), "package opts\n\nfunc Windows() {}\n")
+	writeFile(t, filepath.Join(modPath, "opts_test.go"), "package opts\n\nfunc helperInTest() {}\n")
+	writeFile(t, filepath.Join(modPath, "ext_test.go"), "package opts_test\n\nfunc helperExternal() {}\n")
+	writeFile(t, filepath.Join(modPath, "other", "other.go"), "package other\n\nfunc Other() {}\n")
+
+	symbolNames := func(runID int64) []string {
+		db, err := OpenDB(ctx, dbPath)
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		defer func() {
+			_ = db.Close()
+		}()
+		records, err := NewStore(db).ListSymbolInventory(ctx, SymbolInventoryFilter{RunID: runID})
+		if err != nil {
+			t.Fatalf("list symbols: %v", err)
+		}
+		var names []string
+		for _, record := range records {
+			names = append(names, record.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	defaults, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: modPath})
+	if err != nil {
+		t.Fatalf("ingest default symbols: %v", err)
+	}
+	if got := symbolNames(defaults.RunID); len(got) != 2 || got[0] != "Other" || got[1] != "Plain" {
+		t.Fatalf("unexpected default symbols: %v", got)
+	}
+
+	load := GoLoadOptions{
+		Patterns: []string{"."},
+		Tags:     []string{"integration"},
+		Tests:    true,
+		GOOS:     "windows",
+		Env:      []string{"CGO_ENABLED=0"},
+	}
+	tuned, err := IngestSymbols(ctx, IngestSymbolsConfig{DBPath: dbPath, RootDir: modPath, Load: load})
+	if err != nil {
+		t.Fatalf("ingest tuned symbols: %v", err)
+	}
+	want := []string{"Plain", "Tagged", "Windows", "helperExternal", "helperInTest"}
+	got := symbolNames(tuned.RunID)
+	if len(got) != len(want) {
+		t.Fatalf("expected symbols %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected symbols %v, got %v", want, got)
+		}
+	}
+
+	db, err := OpenDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	var argsJSON string
+	if err := db.QueryRowContext(ctx, "SELECT args_json FROM meta_runs WHERE id = ?", tuned.RunID).Scan(&argsJSON); err != nil {
+		t.Fatalf("query args: %v", err)
+	}
+	var args map[string]string
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		t.Fatalf("decode args: %v", err)
+	}
+	if args["patterns"] != "." || args["tags"] != "integration" || args["tests"] != "true" || args["goos"] != "windows" || args["env"] != "CGO_ENABLED=0" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,195 @@
+package refactorindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gitDateLayout matches the default %ad output used for commits ingested from
+// a repository.
+const gitDateLayout = "Mon Jan 2 15:04:05 2006 -0700"
+
+var (
+	mboxSeparatorPattern = regexp.MustCompile(`^From \S+ +\w{3} \w{3} +\d{1,2} \d{2}:\d{2}:\d{2} \d{4}`)
+	patchSubjectPrefix   = regexp.MustCompile(`^(\[[^\]]*\]\s*)+`)
+)
+
+// PatchMessage is one email from a git format-patch series or mbox.
+type PatchMessage struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	Date        string
+	Subject     string
+	Body        string
+	// Diff is the unified diff carried by the message, without the trailing
+	// format-patch signature.
+	Diff []byte
+	Raw  []byte
+}
+
+// ParseMbox splits an mbox or format-patch file into patch messages. A file
+// without "From <hash> <date>" separators is treated as a single message.
+func ParseMbox(data []byte) ([]PatchMessage, error) {
+	var chunks [][]byte
+	var current bytes.Buffer
+	var hashes []string
+	hash := ""
+	started := false
+
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if mboxSeparatorPattern.MatchString(line) {
+				if started {
+					chunks = append(chunks, append([]byte(nil), current.Bytes()...))
+					hashes = append(hashes, hash)
+					current.Reset()
+				}
+				started = true
+				hash = strings.Fields(line)[1]
+			} else {
+				started = true
+				current.WriteString(line)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read mbox")
+		}
+	}
+	if started {
+		chunks = append(chunks, current.Bytes())
+		hashes = append(hashes, hash)
+	}
+
+	messages := make([]PatchMessage, 0, len(chunks))
+	for i, chunk := range chunks {
+		if len(bytes.TrimSpace(chunk)) == 0 {
+			continue
+		}
+		message, err := parsePatchMessage(chunk)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse patch message %d", i+1)
+		}
+		message.Hash = hashes[i]
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func parsePatchMessage(raw []byte) (PatchMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return PatchMessage{}, errors.Wrap(err, "read email headers")
+	}
+	decoder := new(mime.WordDecoder)
+
+	message := PatchMessage{Raw: raw}
+	if from := msg.Header.Get("From"); from != "" {
+		if addr, err := (&mail.AddressParser{WordDecoder: decoder}).Parse(from); err == nil {
+			message.AuthorName = addr.Name
+			message.AuthorEmail = addr.Address
+		} else {
+			message.AuthorName = decodeHeader(decoder, from)
+		}
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		message.Date = date.Format(gitDateLayout)
+	} else {
+		message.Date = msg.Header.Get("Date")
+	}
+	subject := strings.Join(strings.Fields(decodeHeader(decoder, msg.Header.Get("Subject"))), " ")
+	message.Subject = strings.TrimSpace(patchSubjectPrefix.ReplaceAllString(subject, ""))
+
+	content, err := io.ReadAll(transferDecoder(msg.Header.Get("Content-Transfer-Encoding"), msg.Body))
+	if err != nil {
+		return PatchMessage{}, errors.Wrap(err, "read email body")
+	}
+	body, diff := splitPatchBody(string(content))
+	message.Body = body
+	message.Diff = []byte(diff)
+	return message, nil
+}
+
+// transferDecoder undoes the Content-Transfer-Encoding that mailers apply to
+// patches with long lines or non-ASCII text. 7bit, 8bit and binary bodies are
+// read as they are.
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	default:
+		return body
+	}
+}
+
+func decodeHeader(decoder *mime.WordDecoder, value string) string {
+	decoded, err := decoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// splitPatchBody separates the commit message from the diff. The message ends
+// at the "---" line that precedes the diffstat, or at the first "diff --git"
+// line when there is no diffstat.
+func splitPatchBody(content string) (string, string) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	messageEnd := len(lines)
+	diffStart := len(lines)
+	for i, line := range lines {
+		if line == "---" && messageEnd == len(lines) {
+			messageEnd = i
+		}
+		if strings.HasPrefix(line, "diff --git ") || (strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")) {
+			diffStart = i
+			break
+		}
+	}
+	if messageEnd > diffStart {
+		messageEnd = diffStart
+	}
+
+	body := strings.TrimSpace(strings.Join(lines[:messageEnd], "\n"))
+	diffLines := lines[diffStart:]
+
+	// Drop the "-- \n<git version>" signature that format-patch appends.
+	for i := len(diffLines) - 1; i >= 0; i-- {
+		if diffLines[i] != "-- " {
+			continue
+		}
+		trailing := 0
+		for _, rest := range diffLines[i+1:] {
+			if strings.TrimSpace(rest) != "" {
+				trailing++
+			}
+		}
+		if trailing <= 1 {
+			diffLines = diffLines[:i]
+		}
+		break
+	}
+	diff := strings.Join(diffLines, "\n")
+	if diff != "" && !strings.HasSuffix(diff, "\n") {
+		diff += "\n"
+	}
+	return body, diff
+}
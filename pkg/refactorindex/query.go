@@ -3,16 +3,38 @@ package refactorindex
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
 )
 
+type DiffFileFilter struct {
+	RunID          int64
+	ChangeClasses  []string
+	ExcludeClasses []string
+	// Owner keeps files owned by this CODEOWNERS entry (latest owners run).
+	Owner string
+	FileScope
+}
+
 type DiffFileRecord struct {
-	RunID   int64
-	Status  string
-	Path    string
-	OldPath string
-	NewPath string
+	RunID       int64
+	Status      string
+	Path        string
+	OldPath     string
+	NewPath     string
+	Insertions  int
+	Deletions   int
+	Similarity  int
+	IsBinary    bool
+	ChangeClass string
+	// MovedInsertions and MovedDeletions count lines that belong to moved
+	// blocks; they are included in Insertions and Deletions.
+	MovedInsertions int
+	MovedDeletions  int
 }
 
 type SymbolInventoryFilter struct {
@@ -22,7 +44,16 @@ type SymbolInventoryFilter struct {
 	Name         string
 	Pkg          string
 	Path         string
-	Limit        int
+	Owner        string
+	// Module filters by the module path recorded for the occurrence's file.
+	Module string
+	// BuildConfig keeps occurrences seen under one matrix configuration.
+	BuildConfig string
+	// PlatformSpecific keeps occurrences missing from at least one of the
+	// run's matrix configurations.
+	PlatformSpecific bool
+	Limit            int
+	FileScope
 }
 
 type SymbolInventoryRecord struct {
@@ -37,6 +68,10 @@ type SymbolInventoryRecord struct {
 	Line       int
 	Col        int
 	IsExported bool
+	Module     string
+	// BuildConfigs lists the matrix configurations the occurrence exists
+	// in; it is empty for runs without a matrix.
+	BuildConfigs []string
 }
 
 func (s *Store) GetCommitIDByHash(ctx context.Context, runID int64, hash string) (int64, error) {
@@ -47,17 +82,89 @@ func (s *Store) GetCommitIDByHash(ctx context.Context, runID int64, hash string)
 	return id, nil
 }
 
-func (s *Store) ListDiffFiles(ctx context.Context, runID int64) ([]DiffFileRecord, error) {
-	rows, err := s.db.QueryContext(
+// LatestCommitIDByHash returns the newest commits row for a hash, or nil
+// when the commit has not been ingested.
+func (s *Store) LatestCommitIDByHash(ctx context.Context, hash string) (*int64, error) {
+	var id sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, "SELECT MAX(id) FROM commits WHERE hash = ?", hash).Scan(&id); err != nil {
+		return nil, errors.Wrap(err, "fetch commit id")
+	}
+	if !id.Valid {
+		return nil, nil
+	}
+	return &id.Int64, nil
+}
+
+// LatestCodeUnitsRunID returns the newest code-unit run, restricted to a
+// commit when commitID is set.
+func (s *Store) LatestCodeUnitsRunID(ctx context.Context, commitID *int64) (int64, error) {
+	var id sql.NullInt64
+	if err := s.db.QueryRowContext(
 		ctx,
-		`SELECT df.run_id, df.status, f.path, df.old_path, df.new_path
-		 FROM diff_files df
-		 LEFT JOIN files f ON f.id = df.file_id
-		 WHERE (? = 0 OR df.run_id = ?)
-		 ORDER BY df.run_id, f.path`,
-		runID,
-		runID,
-	)
+		"SELECT MAX(run_id) FROM code_unit_snapshots WHERE (? IS NULL OR commit_id = ?)",
+		nullableInt64(commitID),
+		nullableInt64(commitID),
+	).Scan(&id); err != nil {
+		return 0, errors.Wrap(err, "fetch latest code units run")
+	}
+	if !id.Valid {
+		return 0, errors.New("no code units run found; run ingest code-units first")
+	}
+	return id.Int64, nil
+}
+
+// CodeUnitsRunIDForCommit returns the latest code units run ingested for a
+// commit, or 0 when there is none, along with the commit's hash.
+func (s *Store) CodeUnitsRunIDForCommit(ctx context.Context, commitID int64) (int64, string, error) {
+	var id sql.NullInt64
+	var hash sql.NullString
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT (SELECT MAX(run_id) FROM code_unit_snapshots WHERE commit_id = ?), (SELECT hash FROM commits WHERE id = ?)",
+		commitID,
+		commitID,
+	).Scan(&id, &hash); err != nil {
+		return 0, "", errors.Wrap(err, "fetch code units run for commit")
+	}
+	return id.Int64, hash.String, nil
+}
+
+// CodeUnitsCommitCount returns how many commits code units were ingested
+// for, counting runs without a commit as one more.
+func (s *Store) CodeUnitsCommitCount(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT COALESCE(commit_id, 0)) FROM code_unit_snapshots").Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "count code units commits")
+	}
+	return count, nil
+}
+
+func (s *Store) ListDiffFiles(ctx context.Context, filter DiffFileFilter) ([]DiffFileRecord, error) {
+	query := `
+		SELECT df.run_id, df.status, f.path, df.old_path, df.new_path,
+		       df.insertions, df.deletions, df.similarity, df.is_binary, df.change_class,
+		       df.moved_insertions, df.moved_deletions
+		FROM diff_files df
+		LEFT JOIN files f ON f.id = df.file_id
+		WHERE (? = 0 OR df.run_id = ?)`
+	args := []interface{}{filter.RunID, filter.RunID}
+	if len(filter.ChangeClasses) > 0 {
+		query += " AND df.change_class IN (" + placeholders(len(filter.ChangeClasses)) + ")"
+		args = append(args, stringArgs(filter.ChangeClasses)...)
+	}
+	if len(filter.ExcludeClasses) > 0 {
+		query += " AND COALESCE(df.change_class, '') NOT IN (" + placeholders(len(filter.ExcludeClasses)) + ")"
+		args = append(args, stringArgs(filter.ExcludeClasses)...)
+	}
+	if filter.Owner != "" {
+		query += " AND " + ownerFilterSQL("df.file_id")
+		args = append(args, filter.Owner)
+	}
+	query += " AND " + fileScopeSQL("df.file_id")
+	args = append(args, filter.FileScope.args()...)
+	query += " ORDER BY df.run_id, f.path"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "query diff files")
 	}
@@ -68,7 +175,23 @@ func (s *Store) ListDiffFiles(ctx context.Context, runID int64) ([]DiffFileRecor
 		var record DiffFileRecord
 		var oldPath sql.NullString
 		var newPath sql.NullString
-		if err := rows.Scan(&record.RunID, &record.Status, &record.Path, &oldPath, &newPath); err != nil {
+		var insertions, deletions, similarity, binary sql.NullInt64
+		var changeClass sql.NullString
+		var movedInsertions, movedDeletions sql.NullInt64
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Status,
+			&record.Path,
+			&oldPath,
+			&newPath,
+			&insertions,
+			&deletions,
+			&similarity,
+			&binary,
+			&changeClass,
+			&movedInsertions,
+			&movedDeletions,
+		); err != nil {
 			return nil, errors.Wrap(err, "scan diff file")
 		}
 		if oldPath.Valid {
@@ -77,6 +200,13 @@ func (s *Store) ListDiffFiles(ctx context.Context, runID int64) ([]DiffFileRecor
 		if newPath.Valid {
 			record.NewPath = newPath.String
 		}
+		record.Insertions = int(insertions.Int64)
+		record.Deletions = int(deletions.Int64)
+		record.Similarity = int(similarity.Int64)
+		record.IsBinary = binary.Int64 == 1
+		record.ChangeClass = changeClass.String
+		record.MovedInsertions = int(movedInsertions.Int64)
+		record.MovedDeletions = int(movedDeletions.Int64)
 		results = append(results, record)
 	}
 	if err := rows.Err(); err != nil {
@@ -85,10 +215,55 @@ func (s *Store) ListDiffFiles(ctx context.Context, runID int64) ([]DiffFileRecor
 	return results, nil
 }
 
+// ownerFilterSQL matches files owned by a single "?" owner according to the
+// latest owners run.
+func ownerFilterSQL(fileIDColumn string) string {
+	return "EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = " + fileIDColumn + " AND fo.owner = ?)"
+}
+
+// FileScope selects which classified files list queries and reports return.
+// Generated and vendored files are left out unless included; files that were
+// never classified are always returned. Ingestion classifies the files it
+// adds, but files holds one classification per path, taken from the last
+// tree classified (ToRef for a range), so older commits are filtered by
+// that tree's state.
+type FileScope struct {
+	IncludeGenerated bool
+	IncludeVendored  bool
+}
+
+func (scope FileScope) args() []interface{} {
+	return []interface{}{boolToInt(scope.IncludeGenerated), boolToInt(scope.IncludeVendored)}
+}
+
+// fileScopeSQL keeps files allowed by a FileScope; it takes the two "?"
+// arguments returned by FileScope.args.
+func fileScopeSQL(fileIDColumn string) string {
+	return "NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = " + fileIDColumn + " AND ((? = 0 AND sf.is_generated = 1) OR (? = 0 AND sf.is_vendored = 1)))"
+}
+
+// fileModuleSQL selects the module path a file belonged to in a run.
+func fileModuleSQL(runIDColumn string, fileIDColumn string) string {
+	return "SELECT gm.module_path FROM go_package_files gpf JOIN go_packages gp ON gp.id = gpf.package_id JOIN go_modules gm ON gm.id = gp.module_id WHERE gpf.file_id = " + fileIDColumn + " AND gp.run_id = " + runIDColumn + " LIMIT 1"
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
+
 func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryFilter) ([]SymbolInventoryRecord, error) {
 	query := `
 		SELECT o.run_id, d.symbol_hash, d.name, d.kind, d.pkg, d.recv, d.signature,
-		       f.path, o.line, o.col, o.is_exported
+		       f.path, o.line, o.col, o.is_exported, COALESCE((` + fileModuleSQL("o.run_id", "o.file_id") + `), ''),
+		       COALESCE((SELECT group_concat(oc.config, ' ') FROM symbol_occurrence_configs oc WHERE oc.occurrence_id = o.id), '')
 		FROM symbol_occurrences o
 		JOIN symbol_defs d ON d.id = o.symbol_def_id
 		JOIN files f ON f.id = o.file_id
@@ -98,6 +273,12 @@ func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryF
 		  AND (? = '' OR d.pkg = ?)
 		  AND (? = '' OR f.path = ?)
 		  AND (? = 0 OR o.is_exported = 1)
+		  AND (? = '' OR ` + ownerFilterSQL("o.file_id") + `)
+		  AND (? = '' OR (` + fileModuleSQL("o.run_id", "o.file_id") + `) = ?)
+		  AND (? = '' OR EXISTS (SELECT 1 FROM symbol_occurrence_configs oc WHERE oc.occurrence_id = o.id AND oc.config = ?))
+		  AND (? = 0 OR (SELECT COUNT(*) FROM symbol_occurrence_configs oc WHERE oc.occurrence_id = o.id)
+		      < (SELECT COUNT(*) FROM run_build_configs rc WHERE rc.run_id = o.run_id))
+		  AND ` + fileScopeSQL("o.file_id") + `
 		ORDER BY o.run_id, d.pkg, d.name, f.path, o.line, o.col`
 
 	args := []interface{}{
@@ -112,7 +293,15 @@ func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryF
 		filter.Path,
 		filter.Path,
 		boolToInt(filter.ExportedOnly),
+		filter.Owner,
+		filter.Owner,
+		filter.Module,
+		filter.Module,
+		filter.BuildConfig,
+		filter.BuildConfig,
+		boolToInt(filter.PlatformSpecific),
 	}
+	args = append(args, filter.FileScope.args()...)
 
 	if filter.Limit > 0 {
 		query += " LIMIT ?"
@@ -131,6 +320,7 @@ func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryF
 		var recv sql.NullString
 		var signature sql.NullString
 		var exported int
+		var buildConfigs string
 		if err := rows.Scan(
 			&record.RunID,
 			&record.SymbolHash,
@@ -143,9 +333,15 @@ func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryF
 			&record.Line,
 			&record.Col,
 			&exported,
+			&record.Module,
+			&buildConfigs,
 		); err != nil {
 			return nil, errors.Wrap(err, "scan symbol inventory")
 		}
+		if buildConfigs != "" {
+			record.BuildConfigs = strings.Fields(buildConfigs)
+			sort.Strings(record.BuildConfigs)
+		}
 		if recv.Valid {
 			record.Recv = recv.String
 		}
@@ -160,3 +356,2231 @@ func (s *Store) ListSymbolInventory(ctx context.Context, filter SymbolInventoryF
 	}
 	return results, nil
 }
+
+type RefFilter struct {
+	RunID int64
+	Kind  string
+	Name  string
+	Limit int
+}
+
+type RefRecord struct {
+	RunID       int64
+	Name        string
+	ShortName   string
+	Kind        string
+	TargetHash  string
+	IsAnnotated bool
+	TaggerName  string
+	TaggerEmail string
+	TaggerDate  string
+	RefDate     string
+	Message     string
+	Semver      string
+	CommitID    int64
+}
+
+func (s *Store) ListRefs(ctx context.Context, filter RefFilter) ([]RefRecord, error) {
+	query := `
+		SELECT r.run_id, r.name, r.short_name, r.kind, r.target_hash, r.is_annotated,
+		       r.tagger_name, r.tagger_email, r.tagger_date, r.ref_date, r.message,
+		       r.semver_major, r.semver_minor, r.semver_patch, r.semver_prerelease, r.semver_build,
+		       r.commit_id
+		FROM refs r
+		WHERE (? = 0 OR r.run_id = ?)
+		  AND (? = '' OR r.kind = ?)
+		  AND (? = '' OR r.short_name = ? OR r.name = ?)
+		ORDER BY r.run_id, r.kind, r.ref_time, r.name`
+
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Kind,
+		filter.Kind,
+		filter.Name,
+		filter.Name,
+		filter.Name,
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query refs")
+	}
+	defer rows.Close()
+
+	var results []RefRecord
+	for rows.Next() {
+		record, err := scanRefRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate refs")
+	}
+	return results, nil
+}
+
+// GetFirstTagContainingCommit returns the earliest tag that contains the
+// given commit, or nil when no refs ingestion has recorded one.
+func (s *Store) GetFirstTagContainingCommit(ctx context.Context, hash string) (*RefRecord, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT r.run_id, r.name, r.short_name, r.kind, r.target_hash, r.is_annotated,
+		        r.tagger_name, r.tagger_email, r.tagger_date, r.ref_date, r.message,
+		        r.semver_major, r.semver_minor, r.semver_patch, r.semver_prerelease, r.semver_build,
+		        r.commit_id
+		 FROM commit_first_tags ft
+		 JOIN commits c ON c.id = ft.commit_id
+		 JOIN refs r ON r.id = ft.ref_id
+		 WHERE c.hash = ? OR c.hash LIKE ?
+		 ORDER BY ft.run_id DESC
+		 LIMIT 1`,
+		hash,
+		hash+"%",
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "query first tag")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, errors.Wrap(err, "iterate first tag")
+		}
+		return nil, nil
+	}
+	record, err := scanRefRecord(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func scanRefRecord(rows *sql.Rows) (RefRecord, error) {
+	var record RefRecord
+	var targetHash, taggerName, taggerEmail, taggerDate, refDate, message sql.NullString
+	var major, minor, patch, commitID sql.NullInt64
+	var prerelease, build sql.NullString
+	var annotated int
+	if err := rows.Scan(
+		&record.RunID,
+		&record.Name,
+		&record.ShortName,
+		&record.Kind,
+		&targetHash,
+		&annotated,
+		&taggerName,
+		&taggerEmail,
+		&taggerDate,
+		&refDate,
+		&message,
+		&major,
+		&minor,
+		&patch,
+		&prerelease,
+		&build,
+		&commitID,
+	); err != nil {
+		return RefRecord{}, errors.Wrap(err, "scan ref")
+	}
+	record.TargetHash = targetHash.String
+	record.IsAnnotated = annotated == 1
+	record.TaggerName = taggerName.String
+	record.TaggerEmail = taggerEmail.String
+	record.TaggerDate = taggerDate.String
+	record.RefDate = refDate.String
+	record.Message = message.String
+	if major.Valid {
+		record.Semver = fmt.Sprintf("%d.%d.%d", major.Int64, minor.Int64, patch.Int64)
+		if prerelease.Valid {
+			record.Semver += "-" + prerelease.String
+		}
+		if build.Valid {
+			record.Semver += "+" + build.String
+		}
+	}
+	record.CommitID = commitID.Int64
+	return record, nil
+}
+
+type CommitMessageFilter struct {
+	RunID        int64
+	Type         string
+	Scope        string
+	Issue        string
+	BreakingOnly bool
+	Limit        int
+}
+
+type CommitMessageRecord struct {
+	RunID       int64
+	Hash        string
+	AuthorName  string
+	AuthorDate  string
+	Subject     string
+	Type        string
+	Scope       string
+	IsBreaking  bool
+	Description string
+	Issues      string
+	Trailers    string
+}
+
+func (s *Store) ListCommitMessages(ctx context.Context, filter CommitMessageFilter) ([]CommitMessageRecord, error) {
+	query := `
+		SELECT c.run_id, c.hash, c.author_name, c.author_date, c.subject,
+		       cc.type, cc.scope, COALESCE(cc.is_breaking, 0), cc.description,
+		       (SELECT GROUP_CONCAT(i.issue, ',') FROM commit_issue_refs i WHERE i.commit_id = c.id),
+		       (SELECT GROUP_CONCAT(t.key || ': ' || t.value, '; ') FROM commit_trailers t WHERE t.commit_id = c.id)
+		FROM commits c
+		LEFT JOIN commit_conventional cc ON cc.commit_id = c.id
+		WHERE (? = 0 OR c.run_id = ?)
+		  AND (? = '' OR cc.type = ?)
+		  AND (? = '' OR cc.scope = ?)
+		  AND (? = '' OR EXISTS (SELECT 1 FROM commit_issue_refs i2 WHERE i2.commit_id = c.id AND i2.issue = ?))
+		  AND (? = 0 OR cc.is_breaking = 1)
+		ORDER BY c.run_id, c.id`
+
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Type,
+		filter.Type,
+		filter.Scope,
+		filter.Scope,
+		filter.Issue,
+		filter.Issue,
+		boolToInt(filter.BreakingOnly),
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query commit messages")
+	}
+	defer rows.Close()
+
+	var results []CommitMessageRecord
+	for rows.Next() {
+		var record CommitMessageRecord
+		var authorName, authorDate, subject, commitType, scope, description, issues, trailers sql.NullString
+		var breaking int
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Hash,
+			&authorName,
+			&authorDate,
+			&subject,
+			&commitType,
+			&scope,
+			&breaking,
+			&description,
+			&issues,
+			&trailers,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan commit message")
+		}
+		record.AuthorName = authorName.String
+		record.AuthorDate = authorDate.String
+		record.Subject = subject.String
+		record.Type = commitType.String
+		record.Scope = scope.String
+		record.IsBreaking = breaking == 1
+		record.Description = description.String
+		record.Issues = issues.String
+		record.Trailers = trailers.String
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate commit messages")
+	}
+	return results, nil
+}
+
+type ChangedUnitFilter struct {
+	RunID      int64
+	DiffRunID  int64
+	ChangeKind string
+	Kind       string
+	Pkg        string
+	Limit      int
+	FileScope
+}
+
+type ChangedUnitRecord struct {
+	RunID        int64
+	DiffRunID    int64
+	UnitHash     string
+	Name         string
+	Kind         string
+	Pkg          string
+	Recv         string
+	Path         string
+	ChangeKind   string
+	Hunks        int
+	AddedLines   int
+	RemovedLines int
+}
+
+// ListChangedUnits aggregates hunk_code_units per code unit. A unit linked to
+// hunks with different change kinds is reported as modified.
+func (s *Store) ListChangedUnits(ctx context.Context, filter ChangedUnitFilter) ([]ChangedUnitRecord, error) {
+	query := `
+		SELECT * FROM (
+			SELECT hcu.run_id, hcu.diff_run_id, cu.unit_hash, cu.name, cu.kind, cu.pkg, cu.recv,
+			       MIN(f.path),
+			       CASE WHEN COUNT(DISTINCT hcu.change_kind) = 1 THEN MIN(hcu.change_kind) ELSE 'modified' END AS change_kind,
+			       COUNT(DISTINCT hcu.hunk_id), SUM(hcu.added_lines), SUM(hcu.removed_lines)
+			FROM hunk_code_units hcu
+			JOIN code_units cu ON cu.id = hcu.code_unit_id
+			LEFT JOIN files f ON f.id = hcu.file_id
+			WHERE (? = 0 OR hcu.run_id = ?)
+			  AND (? = 0 OR hcu.diff_run_id = ?)
+			  AND (? = '' OR cu.kind = ?)
+			  AND (? = '' OR cu.pkg = ?)
+			  AND ` + fileScopeSQL("hcu.file_id") + `
+			GROUP BY hcu.run_id, hcu.diff_run_id, cu.id
+		)
+		WHERE (? = '' OR change_kind = ?)
+		ORDER BY 1, 8, 4`
+
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.DiffRunID,
+		filter.DiffRunID,
+		filter.Kind,
+		filter.Kind,
+		filter.Pkg,
+		filter.Pkg,
+		boolToInt(filter.IncludeGenerated),
+		boolToInt(filter.IncludeVendored),
+		filter.ChangeKind,
+		filter.ChangeKind,
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query changed units")
+	}
+	defer rows.Close()
+
+	var results []ChangedUnitRecord
+	for rows.Next() {
+		var record ChangedUnitRecord
+		var recv, path sql.NullString
+		if err := rows.Scan(
+			&record.RunID,
+			&record.DiffRunID,
+			&record.UnitHash,
+			&record.Name,
+			&record.Kind,
+			&record.Pkg,
+			&recv,
+			&path,
+			&record.ChangeKind,
+			&record.Hunks,
+			&record.AddedLines,
+			&record.RemovedLines,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan changed unit")
+		}
+		record.Recv = recv.String
+		record.Path = path.String
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate changed units")
+	}
+	return results, nil
+}
+
+type WordEditFilter struct {
+	RunID   int64
+	OldText string
+	NewText string
+	// SingleEdit keeps only line pairs whose sole difference is the edit.
+	SingleEdit bool
+	Limit      int
+	FileScope
+}
+
+type WordEditRecord struct {
+	RunID      int64
+	Path       string
+	OldLine    int
+	NewLine    int
+	Similarity float64
+	EditCount  int
+	Kind       string
+	OldStart   int
+	OldEnd     int
+	NewStart   int
+	NewEnd     int
+	OldText    string
+	NewText    string
+	OldSource  string
+	NewSource  string
+}
+
+// ListWordEdits returns the word-level edits of paired diff lines, e.g. all
+// lines where only OldName was replaced by NewName.
+func (s *Store) ListWordEdits(ctx context.Context, filter WordEditFilter) ([]WordEditRecord, error) {
+	query := `
+		SELECT p.run_id, COALESCE(f.path, ''), COALESCE(ol.line_no_old, 0), COALESCE(nl.line_no_new, 0),
+		       p.similarity, p.edit_count, we.kind, we.old_start, we.old_end, we.new_start, we.new_end,
+		       we.old_text, we.new_text, ol.text, nl.text
+		FROM diff_word_edits we
+		JOIN diff_line_pairs p ON p.id = we.pair_id
+		JOIN diff_lines ol ON ol.id = p.old_line_id
+		JOIN diff_lines nl ON nl.id = p.new_line_id
+		JOIN diff_hunks dh ON dh.id = p.hunk_id
+		JOIN diff_files df ON df.id = dh.diff_file_id
+		LEFT JOIN files f ON f.id = df.file_id
+		WHERE (? = 0 OR p.run_id = ?)
+		  AND (? = '' OR we.old_text = ?)
+		  AND (? = '' OR we.new_text = ?)
+		  AND (? = 0 OR p.edit_count = 1)
+		  AND ` + fileScopeSQL("df.file_id") + `
+		ORDER BY p.run_id, f.path, ol.line_no_old, we.old_start`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.OldText,
+		filter.OldText,
+		filter.NewText,
+		filter.NewText,
+		boolToInt(filter.SingleEdit),
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query word edits")
+	}
+	defer rows.Close()
+
+	var results []WordEditRecord
+	for rows.Next() {
+		var record WordEditRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Path,
+			&record.OldLine,
+			&record.NewLine,
+			&record.Similarity,
+			&record.EditCount,
+			&record.Kind,
+			&record.OldStart,
+			&record.OldEnd,
+			&record.NewStart,
+			&record.NewEnd,
+			&record.OldText,
+			&record.NewText,
+			&record.OldSource,
+			&record.NewSource,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan word edit")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate word edits")
+	}
+	return results, nil
+}
+
+type CodeUnitAuthorFilter struct {
+	BlameRunID     int64
+	CodeUnitsRunID int64
+	Name           string
+	Pkg            string
+	Limit          int
+	FileScope
+}
+
+type CodeUnitAuthorRecord struct {
+	UnitHash    string
+	Name        string
+	Kind        string
+	Pkg         string
+	Path        string
+	AuthorName  string
+	AuthorEmail string
+	Lines       int
+	TotalLines  int
+	Share       float64
+	LastChanged int64
+}
+
+// ListCodeUnitAuthors joins blame lines with code unit snapshot spans and
+// reports, per code unit, how many of its lines each author last touched.
+// The blame run must be taken at the commit the code units were ingested
+// for so spans line up; code units ingested from a working tree without a
+// commit are not checked.
+func (s *Store) ListCodeUnitAuthors(ctx context.Context, filter CodeUnitAuthorFilter) ([]CodeUnitAuthorRecord, error) {
+	var blameCommit, unitsCommit sql.NullString
+	if err := s.db.QueryRowContext(
+		ctx,
+		`SELECT (SELECT git_to FROM meta_runs WHERE id = ?),
+		        (SELECT c.hash FROM code_unit_snapshots cs JOIN commits c ON c.id = cs.commit_id WHERE cs.run_id = ? LIMIT 1)`,
+		filter.BlameRunID,
+		filter.CodeUnitsRunID,
+	).Scan(&blameCommit, &unitsCommit); err != nil {
+		return nil, errors.Wrap(err, "fetch blame and code units run commits")
+	}
+	if unitsCommit.String != "" && blameCommit.String != unitsCommit.String {
+		return nil, errors.Errorf("blame run %d is at %q but code units run %d is at %q; blame the code units' commit", filter.BlameRunID, blameCommit.String, filter.CodeUnitsRunID, unitsCommit.String)
+	}
+
+	query := `
+		SELECT unit_hash, name, kind, pkg, path, author_name, author_email, lines,
+		       SUM(lines) OVER (PARTITION BY snapshot_id) AS total_lines, last_changed
+		FROM (
+			SELECT s.id AS snapshot_id, cu.unit_hash, cu.name, cu.kind, cu.pkg, f.path,
+			       COALESCE(bc.author_name, '') AS author_name, COALESCE(bc.author_email, '') AS author_email,
+			       COUNT(*) AS lines, MAX(COALESCE(bc.author_time, 0)) AS last_changed
+			FROM code_unit_snapshots s
+			JOIN code_units cu ON cu.id = s.code_unit_id
+			JOIN files f ON f.id = s.file_id
+			JOIN blame_lines bl ON bl.run_id = ? AND bl.file_id = s.file_id
+			     AND bl.line BETWEEN s.start_line AND s.end_line
+			JOIN blame_commits bc ON bc.id = bl.blame_commit_id
+			WHERE s.run_id = ?
+			  AND (? = '' OR cu.name = ?)
+			  AND (? = '' OR cu.pkg = ?)
+			  AND ` + fileScopeSQL("s.file_id") + `
+			GROUP BY s.id, bc.author_email, bc.author_name
+		)
+		ORDER BY pkg, name, path, lines DESC, author_email`
+	args := []interface{}{
+		filter.BlameRunID,
+		filter.CodeUnitsRunID,
+		filter.Name,
+		filter.Name,
+		filter.Pkg,
+		filter.Pkg,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query code unit authors")
+	}
+	defer rows.Close()
+
+	var results []CodeUnitAuthorRecord
+	for rows.Next() {
+		var record CodeUnitAuthorRecord
+		if err := rows.Scan(
+			&record.UnitHash,
+			&record.Name,
+			&record.Kind,
+			&record.Pkg,
+			&record.Path,
+			&record.AuthorName,
+			&record.AuthorEmail,
+			&record.Lines,
+			&record.TotalLines,
+			&record.LastChanged,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan code unit author")
+		}
+		if record.TotalLines > 0 {
+			record.Share = float64(record.Lines) / float64(record.TotalLines)
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate code unit authors")
+	}
+	return results, nil
+}
+
+type RemovedLineAuthorFilter struct {
+	BlameRunID int64
+	DiffRunID  int64
+	Path       string
+	Limit      int
+	FileScope
+}
+
+type RemovedLineAuthorRecord struct {
+	Path         string
+	AuthorName   string
+	AuthorEmail  string
+	RemovedLines int
+	Commits      int
+}
+
+// ListRemovedLineAuthors attributes the removed lines of a diff run to the
+// authors who last changed them. The blame run must be taken at the diff's
+// from commit so old line numbers line up; diff runs without a recorded base
+// commit, such as patch files, are not checked.
+func (s *Store) ListRemovedLineAuthors(ctx context.Context, filter RemovedLineAuthorFilter) ([]RemovedLineAuthorRecord, error) {
+	var blameCommit, diffBase sql.NullString
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT (SELECT git_to FROM meta_runs WHERE id = ?), (SELECT base_commit FROM meta_runs WHERE id = ?)",
+		filter.BlameRunID,
+		filter.DiffRunID,
+	).Scan(&blameCommit, &diffBase); err != nil {
+		return nil, errors.Wrap(err, "fetch blame and diff run commits")
+	}
+	if diffBase.String != "" && blameCommit.String != diffBase.String {
+		return nil, errors.Errorf("blame run %d is at %q but diff run %d starts at %q; blame the diff's from commit", filter.BlameRunID, blameCommit.String, filter.DiffRunID, diffBase.String)
+	}
+
+	query := `
+		SELECT f.path, COALESCE(bc.author_name, ''), COALESCE(bc.author_email, ''),
+		       COUNT(*), COUNT(DISTINCT bc.hash)
+		FROM diff_lines dl
+		JOIN diff_hunks dh ON dh.id = dl.hunk_id
+		JOIN diff_files df ON df.id = dh.diff_file_id
+		JOIN files f ON f.path = COALESCE(df.old_path, (SELECT path FROM files WHERE id = df.file_id))
+		JOIN blame_lines bl ON bl.run_id = ? AND bl.file_id = f.id AND bl.line = dl.line_no_old
+		JOIN blame_commits bc ON bc.id = bl.blame_commit_id
+		WHERE df.run_id = ?
+		  AND dl.kind = '-'
+		  AND (? = '' OR f.path = ?)
+		  AND ` + fileScopeSQL("f.id") + `
+		GROUP BY f.path, bc.author_email, bc.author_name
+		ORDER BY f.path, COUNT(*) DESC, bc.author_email`
+	args := []interface{}{
+		filter.BlameRunID,
+		filter.DiffRunID,
+		filter.Path,
+		filter.Path,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query removed line authors")
+	}
+	defer rows.Close()
+
+	var results []RemovedLineAuthorRecord
+	for rows.Next() {
+		var record RemovedLineAuthorRecord
+		if err := rows.Scan(
+			&record.Path,
+			&record.AuthorName,
+			&record.AuthorEmail,
+			&record.RemovedLines,
+			&record.Commits,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan removed line author")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate removed line authors")
+	}
+	return results, nil
+}
+
+type FileOwnerFilter struct {
+	// RunID selects an owners run; 0 uses the latest one.
+	RunID int64
+	Owner string
+	Path  string
+	Limit int
+	FileScope
+}
+
+type FileOwnerRecord struct {
+	RunID   int64
+	Path    string
+	Owner   string
+	Pattern string
+	Line    int
+	Section string
+}
+
+func (s *Store) ListFileOwners(ctx context.Context, filter FileOwnerFilter) ([]FileOwnerRecord, error) {
+	query := `
+		SELECT fo.run_id, f.path, fo.owner, COALESCE(o.pattern, ''), COALESCE(o.line, 0), COALESCE(o.section, '')
+		FROM file_owners fo
+		JOIN files f ON f.id = fo.file_id
+		LEFT JOIN owners o ON o.id = fo.owner_rule_id
+		WHERE fo.run_id = CASE WHEN ? = 0 THEN (SELECT MAX(run_id) FROM file_owners) ELSE ? END
+		  AND (? = '' OR fo.owner = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND ` + fileScopeSQL("fo.file_id") + `
+		ORDER BY f.path, fo.id`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Owner,
+		filter.Owner,
+		filter.Path,
+		filter.Path,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query file owners")
+	}
+	defer rows.Close()
+
+	var results []FileOwnerRecord
+	for rows.Next() {
+		var record FileOwnerRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Path,
+			&record.Owner,
+			&record.Pattern,
+			&record.Line,
+			&record.Section,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan file owner")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate file owners")
+	}
+	return results, nil
+}
+
+type TestResultFilter struct {
+	RunID   int64
+	Package string
+	Test    string
+	Status  string
+	// IncludePackages keeps package-level results, which have no test name.
+	IncludePackages bool
+	Limit           int
+}
+
+type TestResultRecord struct {
+	RunID      int64
+	CommitHash string
+	Package    string
+	Test       string
+	Subtest    string
+	Status     string
+	Elapsed    float64
+	Output     string
+}
+
+func (s *Store) ListTestResults(ctx context.Context, filter TestResultFilter) ([]TestResultRecord, error) {
+	query := `
+		SELECT tr.run_id, COALESCE(c.hash, ''), tr.package, COALESCE(tr.test, ''), COALESCE(tr.subtest, ''), tr.status, tr.elapsed, COALESCE(tr.output, '')
+		FROM test_results tr
+		LEFT JOIN commits c ON c.id = tr.commit_id
+		WHERE (? = 0 OR tr.run_id = ?)
+		  AND (? = '' OR tr.package = ?)
+		  AND (? = '' OR tr.test = ?)
+		  AND (? = '' OR tr.status = ?)
+		  AND (? = 1 OR tr.test IS NOT NULL)
+		ORDER BY tr.run_id, tr.id`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Package,
+		filter.Package,
+		filter.Test,
+		filter.Test,
+		filter.Status,
+		filter.Status,
+		boolToInt(filter.IncludePackages),
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query test results")
+	}
+	defer rows.Close()
+
+	var results []TestResultRecord
+	for rows.Next() {
+		var record TestResultRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Test,
+			&record.Subtest,
+			&record.Status,
+			&record.Elapsed,
+			&record.Output,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan test result")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate test results")
+	}
+	return results, nil
+}
+
+type CodeUnitCoverageFilter struct {
+	// RunID selects a coverage run; 0 uses the latest one.
+	RunID int64
+	Pkg   string
+	Name  string
+	// Below keeps units whose covered share is strictly below this fraction
+	// when greater than zero.
+	Below float64
+	Limit int
+	FileScope
+}
+
+type CodeUnitCoverageRecord struct {
+	RunID             int64
+	Pkg               string
+	Name              string
+	Kind              string
+	Recv              string
+	Path              string
+	StartLine         int
+	EndLine           int
+	CoveredStatements int
+	TotalStatements   int
+}
+
+func (s *Store) ListCodeUnitCoverage(ctx context.Context, filter CodeUnitCoverageFilter) ([]CodeUnitCoverageRecord, error) {
+	query := `
+		SELECT c.run_id, cu.pkg, cu.name, cu.kind, COALESCE(cu.recv, ''), f.path, s.start_line, s.end_line,
+		       c.covered_statements, c.total_statements
+		FROM code_unit_coverage c
+		JOIN code_units cu ON cu.id = c.code_unit_id
+		JOIN code_unit_snapshots s ON s.id = c.code_unit_snapshot_id
+		JOIN files f ON f.id = c.file_id
+		WHERE c.run_id = CASE WHEN ? = 0 THEN (SELECT MAX(run_id) FROM code_unit_coverage) ELSE ? END
+		  AND (? = '' OR cu.pkg = ?)
+		  AND (? = '' OR cu.name = ?)
+		  AND (? <= 0 OR (c.total_statements > 0 AND c.covered_statements < ? * c.total_statements))
+		  AND ` + fileScopeSQL("c.file_id") + `
+		ORDER BY cu.pkg, f.path, s.start_line`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Pkg,
+		filter.Pkg,
+		filter.Name,
+		filter.Name,
+		filter.Below,
+		filter.Below,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query code unit coverage")
+	}
+	defer rows.Close()
+
+	var results []CodeUnitCoverageRecord
+	for rows.Next() {
+		var record CodeUnitCoverageRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Pkg,
+			&record.Name,
+			&record.Kind,
+			&record.Recv,
+			&record.Path,
+			&record.StartLine,
+			&record.EndLine,
+			&record.CoveredStatements,
+			&record.TotalStatements,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan code unit coverage")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate code unit coverage")
+	}
+	return results, nil
+}
+
+type BenchResultFilter struct {
+	RunID int64
+	// Commit matches commit hashes by prefix.
+	Commit  string
+	Package string
+	Name    string
+	Metric  string
+	Limit   int
+}
+
+type BenchResultRecord struct {
+	RunID      int64
+	CommitHash string
+	Package    string
+	Name       string
+	Procs      int
+	Sample     int
+	Iterations int64
+	Metric     string
+	Value      float64
+}
+
+func (s *Store) ListBenchResults(ctx context.Context, filter BenchResultFilter) ([]BenchResultRecord, error) {
+	query := `
+		SELECT br.run_id, COALESCE(c.hash, ''), COALESCE(br.package, ''), br.name, br.procs, br.sample,
+		       br.iterations, br.metric, br.value
+		FROM bench_results br
+		LEFT JOIN commits c ON c.id = br.commit_id
+		WHERE (? = 0 OR br.run_id = ?)
+		  AND (? = '' OR c.hash LIKE ? || '%')
+		  AND (? = '' OR br.package = ?)
+		  AND (? = '' OR br.name = ?)
+		  AND (? = '' OR br.metric = ?)
+		ORDER BY br.run_id, br.package, br.name, br.procs, br.sample, br.id`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Commit,
+		filter.Commit,
+		filter.Package,
+		filter.Package,
+		filter.Name,
+		filter.Name,
+		filter.Metric,
+		filter.Metric,
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query bench results")
+	}
+	defer rows.Close()
+
+	var results []BenchResultRecord
+	for rows.Next() {
+		var record BenchResultRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Name,
+			&record.Procs,
+			&record.Sample,
+			&record.Iterations,
+			&record.Metric,
+			&record.Value,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan bench result")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate bench results")
+	}
+	return results, nil
+}
+
+type DiagnosticFilter struct {
+	RunID    int64
+	Analyzer string
+	Path     string
+	Limit    int
+	FileScope
+}
+
+type DiagnosticRecord struct {
+	ID         int64
+	RunID      int64
+	CommitHash string
+	Analyzer   string
+	Category   string
+	Message    string
+	Path       string
+	StartLine  int
+	StartCol   int
+	EndLine    int
+	EndCol     int
+	CodeUnit   string
+	Fixes      int
+}
+
+func (s *Store) ListDiagnostics(ctx context.Context, filter DiagnosticFilter) ([]DiagnosticRecord, error) {
+	query := `
+		SELECT d.id, d.run_id, COALESCE(c.hash, ''), d.analyzer, COALESCE(d.category, ''), d.message, f.path,
+		       d.start_line, d.start_col, d.end_line, d.end_col,
+		       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, ''),
+		       (SELECT COUNT(DISTINCT fix_index) FROM diagnostic_fixes df WHERE df.diagnostic_id = d.id)
+		FROM diagnostics d
+		JOIN files f ON f.id = d.file_id
+		LEFT JOIN code_units cu ON cu.id = d.code_unit_id
+		LEFT JOIN commits c ON c.id = d.commit_id
+		WHERE (? = 0 OR d.run_id = ?)
+		  AND (? = '' OR d.analyzer = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND ` + fileScopeSQL("d.file_id") + `
+		ORDER BY d.run_id, f.path, d.start_line, d.start_col, d.id`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Analyzer,
+		filter.Analyzer,
+		filter.Path,
+		filter.Path,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query diagnostics")
+	}
+	defer rows.Close()
+
+	var results []DiagnosticRecord
+	for rows.Next() {
+		var record DiagnosticRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.RunID,
+			&record.CommitHash,
+			&record.Analyzer,
+			&record.Category,
+			&record.Message,
+			&record.Path,
+			&record.StartLine,
+			&record.StartCol,
+			&record.EndLine,
+			&record.EndCol,
+			&record.CodeUnit,
+			&record.Fixes,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan diagnostic")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate diagnostics")
+	}
+	return results, nil
+}
+
+type DiagnosticCountFilter struct {
+	// RunIDs restricts counts to these diagnostics runs; all runs when empty.
+	RunIDs   []int64
+	Analyzer string
+	FileScope
+}
+
+type DiagnosticCountRecord struct {
+	RunID      int64
+	CommitHash string
+	Analyzer   string
+	Count      int
+}
+
+// ListDiagnosticCounts counts diagnostics per run and analyzer, so warnings
+// can be tracked commit by commit across a range.
+func (s *Store) ListDiagnosticCounts(ctx context.Context, filter DiagnosticCountFilter) ([]DiagnosticCountRecord, error) {
+	query := `
+		SELECT d.run_id, COALESCE(c.hash, ''), d.analyzer, COUNT(*)
+		FROM diagnostics d
+		LEFT JOIN commits c ON c.id = d.commit_id
+		WHERE (? = '' OR d.analyzer = ?)
+		  AND ` + fileScopeSQL("d.file_id")
+	args := append([]interface{}{filter.Analyzer, filter.Analyzer}, filter.FileScope.args()...)
+	if len(filter.RunIDs) > 0 {
+		query += " AND d.run_id IN (" + placeholders(len(filter.RunIDs)) + ")"
+		for _, id := range filter.RunIDs {
+			args = append(args, id)
+		}
+	}
+	query += " GROUP BY d.run_id, d.analyzer ORDER BY d.run_id, d.analyzer"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query diagnostic counts")
+	}
+	defer rows.Close()
+
+	var results []DiagnosticCountRecord
+	for rows.Next() {
+		var record DiagnosticCountRecord
+		if err := rows.Scan(&record.RunID, &record.CommitHash, &record.Analyzer, &record.Count); err != nil {
+			return nil, errors.Wrap(err, "scan diagnostic count")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate diagnostic counts")
+	}
+	return results, nil
+}
+
+const (
+	DiagnosticIntroduced = "introduced"
+	DiagnosticFixed      = "fixed"
+)
+
+type DiagnosticChangeFilter struct {
+	BaseRunID int64
+	HeadRunID int64
+	Analyzer  string
+	FileScope
+}
+
+type DiagnosticChangeRecord struct {
+	Change   string
+	Analyzer string
+	Path     string
+	CodeUnit string
+	Message  string
+	Count    int
+}
+
+// ListDiagnosticChanges compares two diagnostics runs. Diagnostics are
+// matched by analyzer, file, enclosing code unit and message rather than by
+// line, so edits elsewhere in a file do not show up as churn.
+func (s *Store) ListDiagnosticChanges(ctx context.Context, filter DiagnosticChangeFilter) ([]DiagnosticChangeRecord, error) {
+	if filter.BaseRunID == 0 || filter.HeadRunID == 0 {
+		return nil, errors.New("base and head run ids are required")
+	}
+	query := `
+		WITH keyed AS (
+			SELECT d.run_id, d.analyzer, f.path,
+			       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, '') AS unit,
+			       d.message
+			FROM diagnostics d
+			JOIN files f ON f.id = d.file_id
+			LEFT JOIN code_units cu ON cu.id = d.code_unit_id
+			WHERE d.run_id IN (:base, :head)
+			  AND (:analyzer = '' OR d.analyzer = :analyzer)
+			  AND (:include_generated = 1 OR COALESCE(f.is_generated, 0) = 0)
+			  AND (:include_vendored = 1 OR COALESCE(f.is_vendored, 0) = 0)
+		),
+		counts AS (
+			SELECT analyzer, path, unit, message,
+			       SUM(CASE WHEN run_id = :base THEN 1 ELSE 0 END) AS base_count,
+			       SUM(CASE WHEN run_id = :head THEN 1 ELSE 0 END) AS head_count
+			FROM keyed
+			GROUP BY analyzer, path, unit, message
+		)
+		SELECT CASE WHEN head_count > base_count THEN 'introduced' ELSE 'fixed' END,
+		       analyzer, path, unit, message, ABS(head_count - base_count)
+		FROM counts
+		WHERE head_count != base_count
+		ORDER BY 1, path, analyzer, unit, message`
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		sql.Named("base", filter.BaseRunID),
+		sql.Named("head", filter.HeadRunID),
+		sql.Named("analyzer", filter.Analyzer),
+		sql.Named("include_generated", boolToInt(filter.IncludeGenerated)),
+		sql.Named("include_vendored", boolToInt(filter.IncludeVendored)),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "query diagnostic changes")
+	}
+	defer rows.Close()
+
+	var results []DiagnosticChangeRecord
+	for rows.Next() {
+		var record DiagnosticChangeRecord
+		if err := rows.Scan(&record.Change, &record.Analyzer, &record.Path, &record.CodeUnit, &record.Message, &record.Count); err != nil {
+			return nil, errors.Wrap(err, "scan diagnostic change")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate diagnostic changes")
+	}
+	return results, nil
+}
+
+type LoadErrorFilter struct {
+	RunID int64
+	Kind  string
+	Limit int
+}
+
+type LoadErrorRecord struct {
+	RunID      int64
+	CommitHash string
+	Package    string
+	Path       string
+	Line       int
+	Col        int
+	Kind       string
+	Message    string
+}
+
+func (s *Store) ListLoadErrors(ctx context.Context, filter LoadErrorFilter) ([]LoadErrorRecord, error) {
+	query := `
+		SELECT le.run_id, COALESCE(c.hash, ''), COALESCE(le.package, ''), COALESCE(le.path, ''),
+		       COALESCE(le.line, 0), COALESCE(le.col, 0), le.kind, le.message
+		FROM load_errors le
+		LEFT JOIN commits c ON c.id = le.commit_id
+		WHERE (? = 0 OR le.run_id = ?)
+		  AND (? = '' OR le.kind = ?)
+		ORDER BY le.run_id, le.package, le.path, le.line, le.col, le.id`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Kind,
+		filter.Kind,
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query load errors")
+	}
+	defer rows.Close()
+
+	var results []LoadErrorRecord
+	for rows.Next() {
+		var record LoadErrorRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.Kind,
+			&record.Message,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan load error")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate load errors")
+	}
+	return results, nil
+}
+
+type ModuleRequireFilter struct {
+	RunID int64
+	// Module filters by the required module path.
+	Module     string
+	DirectOnly bool
+	Limit      int
+}
+
+type ModuleRequireRecord struct {
+	RunID      int64
+	CommitHash string
+	ModFile    string
+	MainModule string
+	Module     string
+	Version    string
+	Indirect   bool
+	// Replacement is the matching replace directive target, as path or
+	// path@version.
+	Replacement string
+}
+
+func (s *Store) ListModuleRequires(ctx context.Context, filter ModuleRequireFilter) ([]ModuleRequireRecord, error) {
+	query := `
+		SELECT mf.run_id, COALESCE(c.hash, ''), f.path, COALESCE(mf.module_path, ''),
+		       r.module_path, r.version, r.is_indirect,
+		       COALESCE((
+		         SELECT rp.new_path || CASE WHEN rp.new_version IS NULL THEN '' ELSE '@' || rp.new_version END
+		         FROM mod_replaces rp
+		         WHERE rp.mod_file_id = mf.id AND rp.old_path = r.module_path
+		           AND (rp.old_version IS NULL OR rp.old_version = r.version)
+		         ORDER BY rp.old_version IS NULL
+		         LIMIT 1
+		       ), '')
+		FROM mod_requires r
+		JOIN mod_files mf ON mf.id = r.mod_file_id
+		JOIN files f ON f.id = mf.file_id
+		LEFT JOIN commits c ON c.id = mf.commit_id
+		WHERE (? = 0 OR mf.run_id = ?)
+		  AND (? = '' OR r.module_path = ?)
+		  AND (? = 0 OR r.is_indirect = 0)
+		ORDER BY mf.run_id, f.path, r.module_path`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Module,
+		filter.Module,
+		boolToInt(filter.DirectOnly),
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query module requires")
+	}
+	defer rows.Close()
+
+	var results []ModuleRequireRecord
+	for rows.Next() {
+		var record ModuleRequireRecord
+		var indirect int
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.ModFile,
+			&record.MainModule,
+			&record.Module,
+			&record.Version,
+			&indirect,
+			&record.Replacement,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan module require")
+		}
+		record.Indirect = indirect == 1
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate module requires")
+	}
+	return results, nil
+}
+
+const (
+	ModuleAdded      = "added"
+	ModuleRemoved    = "removed"
+	ModuleUpgraded   = "upgraded"
+	ModuleDowngraded = "downgraded"
+)
+
+type ModuleChangeFilter struct {
+	BaseRunID int64
+	HeadRunID int64
+	// Module filters by the required module path.
+	Module string
+}
+
+type ModuleChangeRecord struct {
+	Change     string
+	ModFile    string
+	Module     string
+	OldVersion string
+	NewVersion string
+	Indirect   bool
+}
+
+// ListModuleChanges compares the go.mod requirements of two module runs,
+// matching requirements by go.mod file and module path.
+func (s *Store) ListModuleChanges(ctx context.Context, filter ModuleChangeFilter) ([]ModuleChangeRecord, error) {
+	if filter.BaseRunID == 0 || filter.HeadRunID == 0 {
+		return nil, errors.New("base and head run ids are required")
+	}
+	query := `
+		WITH requires AS (
+			SELECT mf.run_id, f.path AS mod_file, r.module_path, r.version, r.is_indirect
+			FROM mod_requires r
+			JOIN mod_files mf ON mf.id = r.mod_file_id
+			JOIN files f ON f.id = mf.file_id
+			WHERE mf.run_id IN (:base, :head)
+			  AND (:module = '' OR r.module_path = :module)
+		),
+		base AS (SELECT * FROM requires WHERE run_id = :base),
+		head AS (SELECT * FROM requires WHERE run_id = :head)
+		SELECT b.mod_file, b.module_path, b.version, COALESCE(h.version, ''), COALESCE(h.is_indirect, b.is_indirect)
+		FROM base b
+		LEFT JOIN head h ON h.mod_file = b.mod_file AND h.module_path = b.module_path
+		WHERE h.version IS NULL OR h.version != b.version
+		UNION ALL
+		SELECT h.mod_file, h.module_path, '', h.version, h.is_indirect
+		FROM head h
+		WHERE NOT EXISTS (SELECT 1 FROM base b WHERE b.mod_file = h.mod_file AND b.module_path = h.module_path)
+		ORDER BY 1, 2`
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		sql.Named("base", filter.BaseRunID),
+		sql.Named("head", filter.HeadRunID),
+		sql.Named("module", filter.Module),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "query module changes")
+	}
+	defer rows.Close()
+
+	var results []ModuleChangeRecord
+	for rows.Next() {
+		var record ModuleChangeRecord
+		var indirect int
+		if err := rows.Scan(&record.ModFile, &record.Module, &record.OldVersion, &record.NewVersion, &indirect); err != nil {
+			return nil, errors.Wrap(err, "scan module change")
+		}
+		record.Indirect = indirect == 1
+		switch {
+		case record.OldVersion == "":
+			record.Change = ModuleAdded
+		case record.NewVersion == "":
+			record.Change = ModuleRemoved
+		case semver.Compare(record.NewVersion, record.OldVersion) < 0:
+			record.Change = ModuleDowngraded
+		default:
+			record.Change = ModuleUpgraded
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate module changes")
+	}
+	return results, nil
+}
+
+type ExternalUseFilter struct {
+	RunID   int64
+	Package string
+	Name    string
+	Path    string
+	Limit   int
+	FileScope
+}
+
+type ExternalUseRecord struct {
+	RunID      int64
+	CommitHash string
+	Package    string
+	Module     string
+	Name       string
+	Recv       string
+	Kind       string
+	Path       string
+	Line       int
+	Col        int
+	CodeUnit   string
+}
+
+func (s *Store) ListExternalUses(ctx context.Context, filter ExternalUseFilter) ([]ExternalUseRecord, error) {
+	query := `
+		SELECT u.run_id, COALESCE(c.hash, ''), u.pkg_path, COALESCE(u.module_path, ''), u.name,
+		       COALESCE(u.recv, ''), u.kind, f.path, u.line, u.col,
+		       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, '')
+		FROM external_uses u
+		JOIN files f ON f.id = u.file_id
+		LEFT JOIN commits c ON c.id = u.commit_id
+		LEFT JOIN code_units cu ON cu.id = u.code_unit_id
+		WHERE (? = 0 OR u.run_id = ?)
+		  AND (? = '' OR u.pkg_path = ?)
+		  AND (? = '' OR u.name = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND ` + fileScopeSQL("u.file_id") + `
+		ORDER BY u.run_id, f.path, u.line, u.col`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Package,
+		filter.Package,
+		filter.Name,
+		filter.Name,
+		filter.Path,
+		filter.Path,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query external uses")
+	}
+	defer rows.Close()
+
+	var results []ExternalUseRecord
+	for rows.Next() {
+		var record ExternalUseRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Module,
+			&record.Name,
+			&record.Recv,
+			&record.Kind,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.CodeUnit,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan external use")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate external uses")
+	}
+	return results, nil
+}
+
+type ExternalUsageFilter struct {
+	// RunIDs restricts counts to these external-uses runs; all runs when
+	// empty.
+	RunIDs  []int64
+	Package string
+	Module  string
+	// ByPackage counts per package instead of per package and symbol.
+	ByPackage bool
+	FileScope
+}
+
+type ExternalUsageRecord struct {
+	RunID      int64
+	CommitHash string
+	Package    string
+	Module     string
+	// Name, Recv and Kind are empty when counting by package.
+	Name      string
+	Recv      string
+	Kind      string
+	Uses      int
+	Files     int
+	CodeUnits int
+}
+
+// ListExternalUsage counts external uses per run and package, or per run,
+// package and symbol, so the remaining surface of a dependency can be
+// tracked across a migration.
+func (s *Store) ListExternalUsage(ctx context.Context, filter ExternalUsageFilter) ([]ExternalUsageRecord, error) {
+	symbolColumns := "u.name, COALESCE(u.recv, ''), u.kind"
+	groupBy := "u.run_id, u.pkg_path, u.name, u.recv, u.kind"
+	if filter.ByPackage {
+		symbolColumns = "'', '', ''"
+		groupBy = "u.run_id, u.pkg_path"
+	}
+	query := `
+		SELECT u.run_id, COALESCE(MAX(c.hash), ''), u.pkg_path, COALESCE(MAX(u.module_path), ''), ` + symbolColumns + `,
+		       COUNT(*), COUNT(DISTINCT u.file_id), COUNT(DISTINCT u.code_unit_id)
+		FROM external_uses u
+		LEFT JOIN commits c ON c.id = u.commit_id
+		WHERE (? = '' OR u.pkg_path = ?)
+		  AND (? = '' OR u.module_path = ?)
+		  AND ` + fileScopeSQL("u.file_id")
+	args := []interface{}{filter.Package, filter.Package, filter.Module, filter.Module}
+	args = append(args, filter.FileScope.args()...)
+	if len(filter.RunIDs) > 0 {
+		query += " AND u.run_id IN (" + placeholders(len(filter.RunIDs)) + ")"
+		for _, id := range filter.RunIDs {
+			args = append(args, id)
+		}
+	}
+	query += " GROUP BY " + groupBy + " ORDER BY u.run_id, COUNT(*) DESC, " + groupBy
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query external usage")
+	}
+	defer rows.Close()
+
+	var results []ExternalUsageRecord
+	for rows.Next() {
+		var record ExternalUsageRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Module,
+			&record.Name,
+			&record.Recv,
+			&record.Kind,
+			&record.Uses,
+			&record.Files,
+			&record.CodeUnits,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan external usage")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate external usage")
+	}
+	return results, nil
+}
+
+type APIChangeFilter struct {
+	RunID   int64
+	Package string
+	Change  string
+	Limit   int
+}
+
+type APIChangeRecord struct {
+	RunID        int64
+	Module       string
+	OldVersion   string
+	NewVersion   string
+	Package      string
+	Name         string
+	Recv         string
+	Kind         string
+	Change       string
+	OldSignature string
+	NewSignature string
+}
+
+func (s *Store) ListAPIChanges(ctx context.Context, filter APIChangeFilter) ([]APIChangeRecord, error) {
+	query := `
+		SELECT a.run_id, a.module_path, COALESCE(a.old_version, ''), COALESCE(a.new_version, ''), a.pkg_path,
+		       a.name, COALESCE(a.recv, ''), a.kind, a.change, COALESCE(a.old_signature, ''), COALESCE(a.new_signature, '')
+		FROM api_changes a
+		WHERE (? = 0 OR a.run_id = ?)
+		  AND (? = '' OR a.pkg_path = ?)
+		  AND (? = '' OR a.change = ?)
+		ORDER BY a.run_id, a.pkg_path, a.recv, a.name`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Package,
+		filter.Package,
+		filter.Change,
+		filter.Change,
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query api changes")
+	}
+	defer rows.Close()
+
+	var results []APIChangeRecord
+	for rows.Next() {
+		var record APIChangeRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Module,
+			&record.OldVersion,
+			&record.NewVersion,
+			&record.Package,
+			&record.Name,
+			&record.Recv,
+			&record.Kind,
+			&record.Change,
+			&record.OldSignature,
+			&record.NewSignature,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan api change")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate api changes")
+	}
+	return results, nil
+}
+
+type UpgradeImpactFilter struct {
+	// APIRunID is the api-diff run to apply; required.
+	APIRunID int64
+	// UsesRunID restricts the uses to one external-uses run; all runs when 0.
+	UsesRunID int64
+	Package   string
+	Limit     int
+	FileScope
+}
+
+type UpgradeImpactRecord struct {
+	UsesRunID    int64
+	CommitHash   string
+	Package      string
+	Recv         string
+	Name         string
+	Kind         string
+	Change       string
+	OldSignature string
+	NewSignature string
+	Path         string
+	Line         int
+	Col          int
+	CodeUnit     string
+}
+
+// ListUpgradeImpact joins the removed, changed and unknown symbols of an
+// api-diff run against recorded external uses, listing the sites expected
+// to break, or to need a check, when the dependency is upgraded.
+func (s *Store) ListUpgradeImpact(ctx context.Context, filter UpgradeImpactFilter) ([]UpgradeImpactRecord, error) {
+	if filter.APIRunID == 0 {
+		return nil, errors.New("api run id is required")
+	}
+	query := `
+		SELECT u.run_id, COALESCE(c.hash, ''), a.pkg_path, COALESCE(a.recv, ''), a.name, a.kind, a.change,
+		       COALESCE(a.old_signature, ''), COALESCE(a.new_signature, ''), f.path, u.line, u.col,
+		       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, '')
+		FROM api_changes a
+		JOIN external_uses u ON u.pkg_path = a.pkg_path AND u.name = a.name AND COALESCE(u.recv, '') = COALESCE(a.recv, '')
+		JOIN files f ON f.id = u.file_id
+		LEFT JOIN commits c ON c.id = u.commit_id
+		LEFT JOIN code_units cu ON cu.id = u.code_unit_id
+		WHERE a.run_id = ?
+		  AND (? = 0 OR u.run_id = ?)
+		  AND (? = '' OR a.pkg_path = ?)
+		  AND ` + fileScopeSQL("u.file_id") + `
+		ORDER BY u.run_id, f.path, u.line, u.col`
+	args := []interface{}{
+		filter.APIRunID,
+		filter.UsesRunID,
+		filter.UsesRunID,
+		filter.Package,
+		filter.Package,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query upgrade impact")
+	}
+	defer rows.Close()
+
+	var results []UpgradeImpactRecord
+	for rows.Next() {
+		var record UpgradeImpactRecord
+		if err := rows.Scan(
+			&record.UsesRunID,
+			&record.CommitHash,
+			&record.Package,
+			&record.Recv,
+			&record.Name,
+			&record.Kind,
+			&record.Change,
+			&record.OldSignature,
+			&record.NewSignature,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.CodeUnit,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan upgrade impact")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate upgrade impact")
+	}
+	return results, nil
+}
+
+type CallSiteFilter struct {
+	RunID  int64
+	Callee string
+	Path   string
+	Limit  int
+	FileScope
+}
+
+type CallSiteRecord struct {
+	ID         int64
+	RunID      int64
+	CommitHash string
+	Callee     string
+	Path       string
+	Line       int
+	Col        int
+	EndLine    int
+	EndCol     int
+	CodeUnit   string
+	Args       []CallArg
+}
+
+// ListCallSites lists recorded calls with their arguments. Callee matches
+// the same spellings as IngestCallSitesConfig.Targets.
+func (s *Store) ListCallSites(ctx context.Context, filter CallSiteFilter) ([]CallSiteRecord, error) {
+	query := `
+		SELECT cs.id, cs.run_id, COALESCE(c.hash, ''), cs.callee, f.path, cs.line, cs.col, cs.end_line, cs.end_col,
+		       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, '')
+		FROM call_sites cs
+		JOIN files f ON f.id = cs.file_id
+		LEFT JOIN commits c ON c.id = cs.commit_id
+		LEFT JOIN code_units cu ON cu.id = cs.code_unit_id
+		WHERE (? = 0 OR cs.run_id = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND ` + fileScopeSQL("cs.file_id") + `
+		ORDER BY cs.run_id, f.path, cs.line, cs.col`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Path,
+		filter.Path,
+	}
+	args = append(args, filter.FileScope.args()...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query call sites")
+	}
+	defer rows.Close()
+
+	callee := callTargetKey(filter.Callee)
+	var results []CallSiteRecord
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var record CallSiteRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.RunID,
+			&record.CommitHash,
+			&record.Callee,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.EndLine,
+			&record.EndCol,
+			&record.CodeUnit,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan call site")
+		}
+		if callee != "" && callTargetKey(record.Callee) != callee {
+			continue
+		}
+		if filter.Limit > 0 && len(results) >= filter.Limit {
+			break
+		}
+		byID[record.ID] = len(results)
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate call sites")
+	}
+	rows.Close()
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	argRows, err := s.db.QueryContext(ctx, `
+		SELECT a.call_site_id, a.source_text, COALESCE(a.type, ''), COALESCE(a.const_value, ''), a.const_value IS NOT NULL
+		FROM call_site_args a
+		JOIN call_sites cs ON cs.id = a.call_site_id
+		WHERE (? = 0 OR cs.run_id = ?)
+		ORDER BY a.call_site_id, a.arg_index`, filter.RunID, filter.RunID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query call site args")
+	}
+	defer argRows.Close()
+	for argRows.Next() {
+		var callSiteID int64
+		var arg CallArg
+		if err := argRows.Scan(&callSiteID, &arg.Source, &arg.Type, &arg.Value, &arg.Const); err != nil {
+			return nil, errors.Wrap(err, "scan call site arg")
+		}
+		if i, ok := byID[callSiteID]; ok {
+			results[i].Args = append(results[i].Args, arg)
+		}
+	}
+	if err := argRows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate call site args")
+	}
+	return results, nil
+}
+
+type ErrorSiteFilter struct {
+	RunID   int64
+	Kind    string
+	Callee  string
+	Path    string
+	Message string
+	Limit   int
+	FileScope
+}
+
+type ErrorSiteRecord struct {
+	RunID      int64
+	CommitHash string
+	Kind       string
+	Callee     string
+	Name       string
+	Message    string
+	HasMessage bool
+	Wraps      bool
+	Path       string
+	Line       int
+	Col        int
+	CodeUnit   string
+}
+
+func (s *Store) ListErrorSites(ctx context.Context, filter ErrorSiteFilter) ([]ErrorSiteRecord, error) {
+	query := `
+		SELECT e.run_id, COALESCE(c.hash, ''), e.kind, COALESCE(e.callee, ''), COALESCE(e.name, ''),
+		       COALESCE(e.message, ''), e.message IS NOT NULL, e.wraps, f.path, e.line, e.col,
+		       COALESCE(CASE WHEN cu.recv IS NOT NULL AND cu.recv != '' THEN cu.recv || '.' || cu.name ELSE cu.name END, '')
+		FROM error_sites e
+		JOIN files f ON f.id = e.file_id
+		LEFT JOIN commits c ON c.id = e.commit_id
+		LEFT JOIN code_units cu ON cu.id = e.code_unit_id
+		WHERE (? = 0 OR e.run_id = ?)
+		  AND (? = '' OR e.kind = ?)
+		  AND (? = '' OR e.callee = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND (? = '' OR e.message = ?)
+		  AND ` + fileScopeSQL("e.file_id") + `
+		ORDER BY e.run_id, f.path, e.line, e.col`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Kind,
+		filter.Kind,
+		filter.Callee,
+		filter.Callee,
+		filter.Path,
+		filter.Path,
+		filter.Message,
+		filter.Message,
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query error sites")
+	}
+	defer rows.Close()
+
+	var results []ErrorSiteRecord
+	for rows.Next() {
+		var record ErrorSiteRecord
+		var wraps int
+		if err := rows.Scan(
+			&record.RunID,
+			&record.CommitHash,
+			&record.Kind,
+			&record.Callee,
+			&record.Name,
+			&record.Message,
+			&record.HasMessage,
+			&wraps,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.CodeUnit,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan error site")
+		}
+		record.Wraps = wraps != 0
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate error sites")
+	}
+	return results, nil
+}
+
+type ErrorMessageFilter struct {
+	RunID int64
+	// MinCount only lists messages used at least this many times; 2 lists
+	// duplicates.
+	MinCount int
+	Limit    int
+	FileScope
+}
+
+type ErrorMessageRecord struct {
+	RunID     int64
+	Message   string
+	Sites     int
+	Files     int
+	CodeUnits int
+	Callees   string
+}
+
+// ListErrorMessages counts the error-creating and wrapping sites per
+// constant message, most used first, to spot duplicated messages.
+func (s *Store) ListErrorMessages(ctx context.Context, filter ErrorMessageFilter) ([]ErrorMessageRecord, error) {
+	query := `
+		SELECT e.run_id, e.message, COUNT(*), COUNT(DISTINCT e.file_id), COUNT(DISTINCT e.code_unit_id),
+		       GROUP_CONCAT(DISTINCT e.callee)
+		FROM error_sites e
+		WHERE e.message IS NOT NULL
+		  AND e.kind IN (?, ?, ?)
+		  AND (? = 0 OR e.run_id = ?)
+		  AND ` + fileScopeSQL("e.file_id") + `
+		GROUP BY e.run_id, e.message
+		HAVING COUNT(*) >= ?
+		ORDER BY e.run_id, COUNT(*) DESC, e.message`
+	args := []interface{}{
+		ErrorSiteNew,
+		ErrorSiteWrap,
+		ErrorSiteErrorf,
+		filter.RunID,
+		filter.RunID,
+		boolToInt(filter.IncludeGenerated),
+		boolToInt(filter.IncludeVendored),
+		max(filter.MinCount, 1),
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query error messages")
+	}
+	defer rows.Close()
+
+	var results []ErrorMessageRecord
+	for rows.Next() {
+		var record ErrorMessageRecord
+		if err := rows.Scan(
+			&record.RunID,
+			&record.Message,
+			&record.Sites,
+			&record.Files,
+			&record.CodeUnits,
+			&record.Callees,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan error message")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate error messages")
+	}
+	return results, nil
+}
+
+type DirectiveFilter struct {
+	RunID int64
+	Name  string
+	Path  string
+	// Broken keeps only directives that reference missing files or symbols.
+	Broken bool
+	Limit  int
+	FileScope
+}
+
+type DirectiveRecord struct {
+	ID         int64
+	RunID      int64
+	CommitHash string
+	Name       string
+	Args       string
+	Path       string
+	Line       int
+	Col        int
+	Decl       string
+	DeclKind   string
+	Problem    string
+	Embeds     []DirectiveEmbed
+}
+
+func (s *Store) ListDirectives(ctx context.Context, filter DirectiveFilter) ([]DirectiveRecord, error) {
+	query := `
+		SELECT d.id, d.run_id, COALESCE(c.hash, ''), d.name, d.args, f.path, d.line, d.col,
+		       COALESCE(d.decl, ''), COALESCE(d.decl_kind, ''), COALESCE(d.problem, '')
+		FROM directives d
+		JOIN files f ON f.id = d.file_id
+		LEFT JOIN commits c ON c.id = d.commit_id
+		WHERE (? = 0 OR d.run_id = ?)
+		  AND (? = '' OR d.name = ?)
+		  AND (? = '' OR f.path = ?)
+		  AND (? = 0 OR d.problem IS NOT NULL)
+		  AND ` + fileScopeSQL("d.file_id") + `
+		ORDER BY d.run_id, f.path, d.line, d.col`
+	args := []interface{}{
+		filter.RunID,
+		filter.RunID,
+		filter.Name,
+		filter.Name,
+		filter.Path,
+		filter.Path,
+		boolToInt(filter.Broken),
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query directives")
+	}
+	defer rows.Close()
+
+	var results []DirectiveRecord
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var record DirectiveRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.RunID,
+			&record.CommitHash,
+			&record.Name,
+			&record.Args,
+			&record.Path,
+			&record.Line,
+			&record.Col,
+			&record.Decl,
+			&record.DeclKind,
+			&record.Problem,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan directive")
+		}
+		byID[record.ID] = len(results)
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate directives")
+	}
+	rows.Close()
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	embedRows, err := s.db.QueryContext(ctx, `
+		SELECT e.directive_id, e.pattern, COALESCE(f.path, '')
+		FROM directive_embeds e
+		JOIN directives d ON d.id = e.directive_id
+		LEFT JOIN files f ON f.id = e.file_id
+		WHERE (? = 0 OR d.run_id = ?)
+		ORDER BY e.directive_id, e.id`, filter.RunID, filter.RunID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query directive embeds")
+	}
+	defer embedRows.Close()
+	for embedRows.Next() {
+		var directiveID int64
+		var embed DirectiveEmbed
+		if err := embedRows.Scan(&directiveID, &embed.Pattern, &embed.Path); err != nil {
+			return nil, errors.Wrap(err, "scan directive embed")
+		}
+		if i, ok := byID[directiveID]; ok {
+			results[i].Embeds = append(results[i].Embeds, embed)
+		}
+	}
+	if err := embedRows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate directive embeds")
+	}
+	return results, nil
+}
+
+type FileFilter struct {
+	Path     string
+	Language string
+	// TestOnly keeps test files and test data.
+	TestOnly bool
+	Limit    int
+	FileScope
+}
+
+type FileRecord struct {
+	Path string
+	// Classified is false for files no tree has classified; only the fields
+	// that follow from the path (Ext, Language, Test, Vendored) are then set.
+	Classified bool
+	FileClass
+}
+
+func (s *Store) ListFiles(ctx context.Context, filter FileFilter) ([]FileRecord, error) {
+	query := `
+		SELECT f.path, f.file_exists IS NOT NULL, COALESCE(f.ext, ''), COALESCE(f.language, ''),
+		       COALESCE(f.file_exists, 0), COALESCE(f.is_binary, 0), COALESCE(f.is_generated, 0),
+		       COALESCE(f.is_test, 0), COALESCE(f.is_vendored, 0), COALESCE(f.size, 0)
+		FROM files f
+		WHERE (? = '' OR f.path = ?)
+		  AND (? = '' OR f.language = ?)
+		  AND (? = 0 OR f.is_test = 1)
+		  AND ` + fileScopeSQL("f.id") + `
+		ORDER BY f.path`
+	args := []interface{}{
+		filter.Path,
+		filter.Path,
+		filter.Language,
+		filter.Language,
+		boolToInt(filter.TestOnly),
+	}
+	args = append(args, filter.FileScope.args()...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query files")
+	}
+	defer rows.Close()
+
+	var results []FileRecord
+	for rows.Next() {
+		var record FileRecord
+		if err := rows.Scan(
+			&record.Path,
+			&record.Classified,
+			&record.Ext,
+			&record.Language,
+			&record.Exists,
+			&record.Binary,
+			&record.Generated,
+			&record.Test,
+			&record.Vendored,
+			&record.Size,
+		); err != nil {
+			return nil, errors.Wrap(err, "scan file")
+		}
+		results = append(results, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate files")
+	}
+	return results, nil
+}
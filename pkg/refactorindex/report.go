@@ -17,8 +17,30 @@ type ReportConfig struct {
 	DBPath    string
 	RunID     int64
 	OutputDir string
+	// Owner restricts reports to files owned by this CODEOWNERS entry.
+	Owner string
+	// CoverageThreshold is the covered-statement fraction below which touched
+	// units are reported as low coverage; 0 uses DefaultCoverageThreshold.
+	CoverageThreshold float64
+	// BenchBase and BenchHead name the commits whose benchmark results are
+	// compared; the bench report is empty unless both are set. With RepoPath
+	// they are resolved by git rev-parse, otherwise by ingested refs or a
+	// unique prefix of an ingested commit hash.
+	BenchBase string
+	BenchHead string
+	RepoPath  string
+	// BenchThreshold is the relative change beyond which a benchmark delta is
+	// flagged; 0 uses DefaultBenchThreshold.
+	BenchThreshold float64
+	// FileScope decides whether generated and vendored files are reported.
+	FileScope
 }
 
+const (
+	DefaultCoverageThreshold = 0.5
+	DefaultBenchThreshold    = 0.05
+)
+
 type ReportResult struct {
 	Name     string
 	Path     string
@@ -35,6 +57,12 @@ func GenerateReports(ctx context.Context, cfg ReportConfig) ([]ReportResult, err
 	if strings.TrimSpace(cfg.OutputDir) == "" {
 		cfg.OutputDir = "reports"
 	}
+	if cfg.CoverageThreshold <= 0 {
+		cfg.CoverageThreshold = DefaultCoverageThreshold
+	}
+	if cfg.BenchThreshold <= 0 {
+		cfg.BenchThreshold = DefaultBenchThreshold
+	}
 
 	db, err := OpenDB(ctx, cfg.DBPath)
 	if err != nil {
@@ -44,6 +72,15 @@ func GenerateReports(ctx context.Context, cfg ReportConfig) ([]ReportResult, err
 		_ = db.Close()
 	}()
 
+	benchBase, err := resolveBenchCommit(ctx, db, cfg.RepoPath, cfg.BenchBase)
+	if err != nil {
+		return nil, err
+	}
+	benchHead, err := resolveBenchCommit(ctx, db, cfg.RepoPath, cfg.BenchHead)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
 		return nil, errors.Wrap(err, "create reports dir")
 	}
@@ -69,14 +106,33 @@ func GenerateReports(ctx context.Context, cfg ReportConfig) ([]ReportResult, err
 			return nil, errors.Wrap(err, "read report query")
 		}
 
-		rows, err := queryRows(ctx, db, string(sqlContent), cfg.RunID)
+		rows, err := queryRows(
+			ctx,
+			db,
+			string(sqlContent),
+			sql.Named("run_id", cfg.RunID),
+			sql.Named("owner", cfg.Owner),
+			sql.Named("coverage_threshold", cfg.CoverageThreshold),
+			sql.Named("bench_base", benchBase),
+			sql.Named("bench_head", benchHead),
+			sql.Named("bench_threshold", cfg.BenchThreshold),
+			sql.Named("include_generated", boolToInt(cfg.IncludeGenerated)),
+			sql.Named("include_vendored", boolToInt(cfg.IncludeVendored)),
+		)
 		if err != nil {
 			return nil, err
 		}
 
 		data := map[string]interface{}{
-			"RunID": cfg.RunID,
-			"Rows":  rows,
+			"RunID":             cfg.RunID,
+			"Owner":             cfg.Owner,
+			"CoverageThreshold": cfg.CoverageThreshold,
+			"BenchBase":         cfg.BenchBase,
+			"BenchHead":         cfg.BenchHead,
+			"BenchBaseCommit":   benchBase,
+			"BenchHeadCommit":   benchHead,
+			"BenchThreshold":    cfg.BenchThreshold,
+			"Rows":              rows,
 		}
 
 		reportPath := filepath.Join(cfg.OutputDir, name+".md")
@@ -94,6 +150,60 @@ func GenerateReports(ctx context.Context, cfg ReportConfig) ([]ReportResult, err
 	return results, nil
 }
 
+// resolveBenchCommit turns a benchmark ref into a full commit hash. An empty
+// ref stays empty.
+func resolveBenchCommit(ctx context.Context, db *sql.DB, repoPath string, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", nil
+	}
+	if strings.TrimSpace(repoPath) != "" {
+		out, err := runGit(ctx, repoPath, "rev-parse", "--verify", ref+"^{commit}")
+		if err != nil {
+			return "", errors.Wrapf(err, "resolve bench ref %q", ref)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	var target sql.NullString
+	if err := db.QueryRowContext(
+		ctx,
+		"SELECT COALESCE(target_hash, object_hash) FROM refs WHERE name = ? OR short_name = ? ORDER BY run_id DESC LIMIT 1",
+		ref,
+		ref,
+	).Scan(&target); err != nil && err != sql.ErrNoRows {
+		return "", errors.Wrap(err, "look up bench ref")
+	}
+	if target.String != "" {
+		return target.String, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT hash FROM commits WHERE substr(hash, 1, length(?)) = ? LIMIT 2", ref, ref)
+	if err != nil {
+		return "", errors.Wrap(err, "look up bench commit")
+	}
+	defer rows.Close()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return "", errors.Wrap(err, "scan bench commit")
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return "", errors.Wrap(err, "iterate bench commits")
+	}
+	switch len(hashes) {
+	case 0:
+		return "", errors.Errorf("bench ref %q is not an ingested ref or commit; pass a repository to resolve it", ref)
+	case 1:
+		return hashes[0], nil
+	default:
+		return "", errors.Errorf("bench ref %q matches several commits", ref)
+	}
+}
+
 func queryRows(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]map[string]string, error) {
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -0,0 +1,55 @@
+WITH samples AS (
+  SELECT
+    CASE WHEN c.hash = :bench_base THEN 'base' ELSE 'head' END AS side,
+    COALESCE(br.package, '') AS package, br.name, br.procs, br.metric, br.value
+  FROM bench_results br
+  JOIN commits c ON c.id = br.commit_id
+  WHERE :bench_base != '' AND :bench_head != '' AND c.hash IN (:bench_base, :bench_head)
+),
+ranked AS (
+  SELECT
+    side, package, name, procs, metric, value,
+    ROW_NUMBER() OVER (PARTITION BY side, package, name, procs, metric ORDER BY value) AS rn,
+    COUNT(*) OVER (PARTITION BY side, package, name, procs, metric) AS n
+  FROM samples
+),
+stats AS (
+  SELECT
+    side, package, name, procs, metric,
+    AVG(CASE WHEN rn IN ((n + 1) / 2, (n + 2) / 2) THEN value END) AS median,
+    MIN(value) AS lo, MAX(value) AS hi, MAX(n) AS n
+  FROM ranked
+  GROUP BY side, package, name, procs, metric
+),
+deltas AS (
+  SELECT
+    b.package, b.name, b.procs, b.metric,
+    b.median AS base_median, b.lo AS base_lo, b.hi AS base_hi, b.n AS base_n,
+    h.median AS head_median, h.lo AS head_lo, h.hi AS head_hi, h.n AS head_n,
+    CASE WHEN b.median = 0 THEN NULL ELSE (h.median - b.median) / b.median END AS delta,
+    CASE WHEN b.metric LIKE '%/s' THEN -1 ELSE 1 END AS worse_sign,
+    b.n < 2 OR h.n < 2 OR (b.lo <= h.hi AND h.lo <= b.hi) AS inconclusive
+  FROM stats b
+  JOIN stats h ON h.side = 'head' AND h.package = b.package AND h.name = b.name AND h.procs = b.procs AND h.metric = b.metric
+  WHERE b.side = 'base'
+)
+SELECT
+  package,
+  name,
+  procs,
+  metric,
+  printf('%.4g', base_median) AS base,
+  printf('±%.0f%%', CASE WHEN base_median = 0 THEN 0 ELSE 100.0 * (base_hi - base_lo) / 2 / base_median END) AS base_var,
+  base_n,
+  printf('%.4g', head_median) AS head,
+  printf('±%.0f%%', CASE WHEN head_median = 0 THEN 0 ELSE 100.0 * (head_hi - head_lo) / 2 / head_median END) AS head_var,
+  head_n,
+  CASE WHEN delta IS NULL THEN '~' ELSE printf('%+.2f%%', 100.0 * delta) END AS delta,
+  CASE
+    WHEN delta IS NULL OR inconclusive THEN '~'
+    WHEN worse_sign * delta > :bench_threshold THEN 'regression'
+    WHEN worse_sign * delta < -:bench_threshold THEN 'improvement'
+    ELSE '~'
+  END AS verdict
+FROM deltas
+ORDER BY CASE WHEN delta IS NULL OR inconclusive THEN 0 ELSE worse_sign * delta END DESC, package, name, metric;
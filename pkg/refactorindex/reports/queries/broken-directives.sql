@@ -0,0 +1,21 @@
+WITH directive_commits AS (
+  SELECT c.id, c.hash, c.subject,
+         (SELECT MAX(d.run_id) FROM directives d WHERE d.commit_id = c.id) AS directives_run_id
+  FROM commits c
+  WHERE c.run_id = :run_id
+)
+SELECT
+  substr(dc.hash, 1, 12) AS hash,
+  COALESCE(dc.subject, '') AS subject,
+  f.path AS path,
+  d.line AS line,
+  d.name AS name,
+  d.args AS args,
+  d.problem AS problem
+FROM directive_commits dc
+JOIN directives d ON d.run_id = dc.directives_run_id AND d.commit_id = dc.id
+JOIN files f ON f.id = d.file_id
+WHERE d.problem IS NOT NULL
+  AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = d.file_id AND fo.owner = :owner))
+  AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = d.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+ORDER BY dc.id, f.path, d.line;
@@ -0,0 +1,15 @@
+SELECT
+  cu.pkg AS pkg,
+  cu.name AS name,
+  cu.kind AS kind,
+  CASE WHEN COUNT(DISTINCT hcu.change_kind) = 1 THEN MIN(hcu.change_kind) ELSE 'modified' END AS change,
+  COUNT(DISTINCT hcu.hunk_id) AS hunks,
+  SUM(hcu.added_lines) AS added_lines,
+  SUM(hcu.removed_lines) AS removed_lines
+FROM hunk_code_units hcu
+JOIN code_units cu ON cu.id = hcu.code_unit_id
+WHERE hcu.diff_run_id = :run_id
+  AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = hcu.file_id AND fo.owner = :owner))
+  AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = hcu.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+GROUP BY cu.id
+ORDER BY cu.pkg, cu.name, cu.kind;
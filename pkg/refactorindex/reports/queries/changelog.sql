@@ -0,0 +1,16 @@
+SELECT
+  COALESCE(cc.type, 'other') AS type,
+  COALESCE(cc.scope, '') AS scope,
+  CASE WHEN cc.is_breaking = 1 THEN 'yes' ELSE '' END AS breaking,
+  COALESCE(cc.description, c.subject) AS description,
+  substr(c.hash, 1, 12) AS hash,
+  COALESCE((SELECT GROUP_CONCAT(i.issue, ', ') FROM commit_issue_refs i WHERE i.commit_id = c.id), '') AS issues
+FROM commits c
+LEFT JOIN commit_conventional cc ON cc.commit_id = c.id
+WHERE c.run_id = :run_id
+  AND (:owner = '' OR EXISTS (
+    SELECT 1 FROM commit_files cf
+    JOIN current_file_owners fo ON fo.file_id = cf.file_id
+    WHERE cf.commit_id = c.id AND fo.owner = :owner
+  ))
+ORDER BY CASE WHEN cc.is_breaking = 1 THEN 0 ELSE 1 END, COALESCE(cc.type, 'zzz'), cc.scope, c.id;
@@ -0,0 +1,21 @@
+SELECT
+  u.pkg AS pkg,
+  u.name AS name,
+  u.kind AS kind,
+  COALESCE((
+    SELECT r.short_name
+    FROM code_unit_snapshots s2
+    JOIN commit_first_tags ft ON ft.commit_id = s2.commit_id
+    JOIN refs r ON r.id = ft.ref_id
+    WHERE s2.code_unit_id = u.id
+    ORDER BY r.ref_time, r.semver_major, r.semver_minor, r.semver_patch
+    LIMIT 1
+  ), '') AS introduced_in
+FROM code_units u
+WHERE u.id IN (
+  SELECT s.code_unit_id FROM code_unit_snapshots s
+  WHERE s.run_id = :run_id
+    AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = s.file_id AND fo.owner = :owner))
+    AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = s.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+)
+ORDER BY u.pkg, u.name, u.kind;
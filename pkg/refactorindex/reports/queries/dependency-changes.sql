@@ -0,0 +1,56 @@
+WITH module_commits AS (
+  SELECT c.id, c.hash, c.subject,
+         (SELECT MAX(mf.run_id) FROM mod_files mf WHERE mf.commit_id = c.id) AS mod_run_id
+  FROM commits c
+  WHERE c.run_id = :run_id
+),
+indexed AS (
+  SELECT id, hash, subject, mod_run_id, ROW_NUMBER() OVER (ORDER BY id) AS pos
+  FROM module_commits
+  WHERE mod_run_id IS NOT NULL
+),
+requires AS (
+  SELECT i.pos, f.path AS mod_file, r.module_path, r.version,
+         r.semver_major AS major, r.semver_minor AS minor, r.semver_patch AS patch,
+         COALESCE(r.semver_prerelease, '') AS pre
+  FROM indexed i
+  JOIN mod_files mf ON mf.run_id = i.mod_run_id AND mf.commit_id = i.id
+  JOIN mod_requires r ON r.mod_file_id = mf.id
+  JOIN files f ON f.id = mf.file_id
+),
+changes AS (
+  SELECT cur.pos, o.mod_file, o.module_path, o.version AS old_version, n.version AS new_version,
+    CASE
+      WHEN n.version IS NULL THEN 'removed'
+      WHEN n.major IS NULL OR o.major IS NULL THEN 'changed'
+      WHEN (n.major, n.minor, n.patch) != (o.major, o.minor, o.patch) THEN
+        CASE WHEN (n.major, n.minor, n.patch) > (o.major, o.minor, o.patch) THEN 'upgraded' ELSE 'downgraded' END
+      WHEN n.pre = '' THEN 'upgraded'
+      WHEN o.pre = '' THEN 'downgraded'
+      WHEN n.pre > o.pre THEN 'upgraded'
+      ELSE 'downgraded'
+    END AS change
+  FROM indexed cur
+  JOIN requires o ON o.pos = cur.pos - 1
+  LEFT JOIN requires n ON n.pos = cur.pos AND n.mod_file = o.mod_file AND n.module_path = o.module_path
+  WHERE n.version IS NULL OR n.version != o.version
+  UNION ALL
+  SELECT n.pos, n.mod_file, n.module_path, NULL, n.version, 'added'
+  FROM requires n
+  WHERE n.pos > 1
+    AND NOT EXISTS (
+      SELECT 1 FROM requires o
+      WHERE o.pos = n.pos - 1 AND o.mod_file = n.mod_file AND o.module_path = n.module_path
+    )
+)
+SELECT
+  substr(i.hash, 1, 12) AS hash,
+  COALESCE(i.subject, '') AS subject,
+  ch.mod_file AS mod_file,
+  ch.module_path AS module,
+  COALESCE(ch.old_version, '') AS old_version,
+  COALESCE(ch.new_version, '') AS new_version,
+  ch.change AS change
+FROM changes ch
+JOIN indexed i ON i.pos = ch.pos
+ORDER BY ch.pos, ch.mod_file, ch.module_path;
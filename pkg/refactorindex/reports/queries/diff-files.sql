@@ -2,8 +2,14 @@ SELECT
   df.status AS status,
   f.path AS path,
   df.old_path AS old_path,
-  df.new_path AS new_path
+  df.new_path AS new_path,
+  COALESCE(df.insertions, 0) AS insertions,
+  COALESCE(df.deletions, 0) AS deletions,
+  COALESCE(df.moved_insertions, 0) + COALESCE(df.moved_deletions, 0) AS moved,
+  COALESCE(df.change_class, '') AS change_class
 FROM diff_files df
 LEFT JOIN files f ON f.id = df.file_id
-WHERE df.run_id = ?
+WHERE df.run_id = :run_id
+  AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = df.file_id AND fo.owner = :owner))
+  AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = df.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
 ORDER BY f.path;
@@ -0,0 +1,22 @@
+WITH error_commits AS (
+  SELECT c.id, c.hash, c.subject,
+         (SELECT MAX(e.run_id) FROM error_sites e WHERE e.commit_id = c.id) AS errors_run_id
+  FROM commits c
+  WHERE c.run_id = :run_id
+)
+SELECT
+  substr(ec.hash, 1, 12) AS hash,
+  COALESCE(ec.subject, '') AS subject,
+  SUM(CASE WHEN e.callee LIKE 'github.com/pkg/errors.%' AND e.kind != 'sentinel' THEN 1 ELSE 0 END) AS pkg_errors,
+  SUM(CASE WHEN e.kind = 'errorf' AND e.wraps = 1 THEN 1 ELSE 0 END) AS errorf_w,
+  SUM(CASE WHEN e.kind = 'errorf' AND e.wraps = 0 THEN 1 ELSE 0 END) AS errorf_plain,
+  SUM(CASE WHEN e.kind = 'new' AND e.callee = 'errors.New' THEN 1 ELSE 0 END) AS std_new,
+  SUM(CASE WHEN e.kind = 'sentinel' THEN 1 ELSE 0 END) AS sentinels,
+  SUM(CASE WHEN e.kind = 'discarded' THEN 1 ELSE 0 END) AS discarded,
+  SUM(CASE WHEN e.kind = 'unchecked' THEN 1 ELSE 0 END) AS unchecked
+FROM error_commits ec
+JOIN error_sites e ON e.run_id = ec.errors_run_id AND e.commit_id = ec.id
+WHERE (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = e.file_id AND fo.owner = :owner))
+  AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = e.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+GROUP BY ec.id, ec.hash, ec.subject
+ORDER BY ec.id;
@@ -0,0 +1,38 @@
+WITH changed AS (
+  SELECT DISTINCT hcu.new_snapshot_id AS snapshot_id
+  FROM hunk_code_units hcu
+  WHERE hcu.diff_run_id = :run_id
+    AND hcu.change_kind != 'removed'
+    AND hcu.new_snapshot_id IS NOT NULL
+    AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = hcu.file_id AND fo.owner = :owner))
+    AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = hcu.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+),
+snapshot_coverage AS (
+  SELECT ch.snapshot_id, c.run_id, c.covered_statements, c.total_statements
+  FROM changed ch
+  JOIN code_unit_snapshots ns ON ns.id = ch.snapshot_id
+  JOIN code_unit_coverage c ON c.code_unit_id = ns.code_unit_id
+   AND (c.code_unit_snapshot_id = ns.id OR (ns.commit_id IS NOT NULL AND c.commit_id = ns.commit_id))
+),
+latest_coverage AS (
+  SELECT sc.snapshot_id, MAX(sc.covered_statements) AS covered_statements, MAX(sc.total_statements) AS total_statements
+  FROM snapshot_coverage sc
+  WHERE sc.run_id = (SELECT MAX(sc2.run_id) FROM snapshot_coverage sc2 WHERE sc2.snapshot_id = sc.snapshot_id)
+  GROUP BY sc.snapshot_id
+)
+SELECT
+  cu.pkg AS pkg,
+  cu.name AS name,
+  cu.kind AS kind,
+  COALESCE(cu.recv, '') AS recv,
+  lc.covered_statements AS covered,
+  lc.total_statements AS total,
+  printf('%.1f', 100.0 * lc.covered_statements / lc.total_statements) AS coverage
+FROM latest_coverage lc
+JOIN code_unit_snapshots ns ON ns.id = lc.snapshot_id
+JOIN code_units cu ON cu.id = ns.code_unit_id
+WHERE cu.kind IN ('func', 'method')
+  AND cu.is_exported = 1
+  AND lc.total_statements > 0
+  AND lc.covered_statements < :coverage_threshold * lc.total_statements
+ORDER BY 1.0 * lc.covered_statements / lc.total_statements, cu.pkg, cu.name;
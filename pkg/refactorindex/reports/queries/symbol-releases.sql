@@ -0,0 +1,21 @@
+SELECT
+  d.pkg AS pkg,
+  d.name AS name,
+  d.kind AS kind,
+  COALESCE((
+    SELECT r.short_name
+    FROM symbol_occurrences o2
+    JOIN commit_first_tags ft ON ft.commit_id = o2.commit_id
+    JOIN refs r ON r.id = ft.ref_id
+    WHERE o2.symbol_def_id = d.id
+    ORDER BY r.ref_time, r.semver_major, r.semver_minor, r.semver_patch
+    LIMIT 1
+  ), '') AS introduced_in
+FROM symbol_defs d
+WHERE d.id IN (
+  SELECT o.symbol_def_id FROM symbol_occurrences o
+  WHERE o.run_id = :run_id
+    AND (:owner = '' OR EXISTS (SELECT 1 FROM current_file_owners fo WHERE fo.file_id = o.file_id AND fo.owner = :owner))
+    AND NOT EXISTS (SELECT 1 FROM files sf WHERE sf.id = o.file_id AND ((:include_generated = 0 AND sf.is_generated = 1) OR (:include_vendored = 0 AND sf.is_vendored = 1)))
+)
+ORDER BY d.pkg, d.name, d.kind;
@@ -1,6 +1,6 @@
 package refactorindex
 
-const SchemaVersion = 10
+const SchemaVersion = 34
 
 const schemaSQL = `
 CREATE TABLE IF NOT EXISTS schema_versions (
@@ -17,7 +17,10 @@ CREATE TABLE IF NOT EXISTS meta_runs (
     git_to TEXT,
     root_path TEXT,
     args_json TEXT,
-    sources_dir TEXT
+    sources_dir TEXT,
+    mode TEXT,
+    base_commit TEXT,
+    status TEXT
 );
 
 CREATE TABLE IF NOT EXISTS raw_outputs (
@@ -34,7 +37,12 @@ CREATE TABLE IF NOT EXISTS files (
     path TEXT NOT NULL UNIQUE,
     ext TEXT,
     file_exists INTEGER,
-    is_binary INTEGER
+    is_binary INTEGER,
+    is_generated INTEGER,
+    is_test INTEGER,
+    is_vendored INTEGER,
+    language TEXT,
+    size INTEGER
 );
 
 CREATE TABLE IF NOT EXISTS diff_files (
@@ -44,6 +52,13 @@ CREATE TABLE IF NOT EXISTS diff_files (
     status TEXT NOT NULL,
     old_path TEXT,
     new_path TEXT,
+    insertions INTEGER,
+    deletions INTEGER,
+    similarity INTEGER,
+    is_binary INTEGER,
+    change_class TEXT,
+    moved_insertions INTEGER,
+    moved_deletions INTEGER,
     FOREIGN KEY(run_id) REFERENCES meta_runs(id),
     FOREIGN KEY(file_id) REFERENCES files(id)
 );
@@ -65,9 +80,50 @@ CREATE TABLE IF NOT EXISTS diff_lines (
     line_no_old INTEGER,
     line_no_new INTEGER,
     text TEXT NOT NULL,
+    moved_block_id INTEGER,
     FOREIGN KEY(hunk_id) REFERENCES diff_hunks(id)
 );
 
+CREATE TABLE IF NOT EXISTS diff_line_pairs (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    hunk_id INTEGER NOT NULL,
+    old_line_id INTEGER NOT NULL,
+    new_line_id INTEGER NOT NULL,
+    similarity REAL NOT NULL,
+    edit_count INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(hunk_id) REFERENCES diff_hunks(id),
+    FOREIGN KEY(old_line_id) REFERENCES diff_lines(id),
+    FOREIGN KEY(new_line_id) REFERENCES diff_lines(id)
+);
+
+CREATE TABLE IF NOT EXISTS diff_word_edits (
+    id INTEGER PRIMARY KEY,
+    pair_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    old_start INTEGER NOT NULL,
+    old_end INTEGER NOT NULL,
+    new_start INTEGER NOT NULL,
+    new_end INTEGER NOT NULL,
+    old_text TEXT NOT NULL,
+    new_text TEXT NOT NULL,
+    FOREIGN KEY(pair_id) REFERENCES diff_line_pairs(id)
+);
+
+CREATE TABLE IF NOT EXISTS diff_moved_blocks (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    from_diff_file_id INTEGER NOT NULL,
+    to_diff_file_id INTEGER NOT NULL,
+    old_start_line INTEGER NOT NULL,
+    new_start_line INTEGER NOT NULL,
+    line_count INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(from_diff_file_id) REFERENCES diff_files(id),
+    FOREIGN KEY(to_diff_file_id) REFERENCES diff_files(id)
+);
+
 CREATE TABLE IF NOT EXISTS symbol_defs (
     id INTEGER PRIMARY KEY,
     pkg TEXT NOT NULL,
@@ -100,6 +156,7 @@ CREATE TABLE IF NOT EXISTS code_units (
     pkg TEXT NOT NULL,
     recv TEXT,
     signature TEXT,
+    is_exported INTEGER,
     unit_hash TEXT NOT NULL UNIQUE
 );
 
@@ -144,10 +201,41 @@ CREATE TABLE IF NOT EXISTS commit_files (
     new_path TEXT,
     blob_old TEXT,
     blob_new TEXT,
+    insertions INTEGER,
+    deletions INTEGER,
+    similarity INTEGER,
+    is_binary INTEGER,
+    change_class TEXT,
     FOREIGN KEY(commit_id) REFERENCES commits(id),
     FOREIGN KEY(file_id) REFERENCES files(id)
 );
 
+CREATE TABLE IF NOT EXISTS commit_conventional (
+    id INTEGER PRIMARY KEY,
+    commit_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    scope TEXT,
+    is_breaking INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS commit_trailers (
+    id INTEGER PRIMARY KEY,
+    commit_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS commit_issue_refs (
+    id INTEGER PRIMARY KEY,
+    commit_id INTEGER NOT NULL,
+    issue TEXT NOT NULL,
+    source TEXT NOT NULL,
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
 CREATE TABLE IF NOT EXISTS file_blobs (
     id INTEGER PRIMARY KEY,
     commit_id INTEGER NOT NULL,
@@ -207,9 +295,498 @@ CREATE TABLE IF NOT EXISTS doc_hits (
     FOREIGN KEY(file_id) REFERENCES files(id)
 );
 
+CREATE TABLE IF NOT EXISTS refs (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    name TEXT NOT NULL,
+    short_name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    object_type TEXT,
+    object_hash TEXT NOT NULL,
+    target_hash TEXT,
+    is_annotated INTEGER NOT NULL,
+    tagger_name TEXT,
+    tagger_email TEXT,
+    tagger_date TEXT,
+    ref_date TEXT,
+    ref_time INTEGER,
+    message TEXT,
+    semver_major INTEGER,
+    semver_minor INTEGER,
+    semver_patch INTEGER,
+    semver_prerelease TEXT,
+    semver_build TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS commit_first_tags (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER NOT NULL,
+    ref_id INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(ref_id) REFERENCES refs(id)
+);
+
+CREATE TABLE IF NOT EXISTS hunk_code_units (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    diff_run_id INTEGER NOT NULL,
+    hunk_id INTEGER NOT NULL,
+    file_id INTEGER,
+    code_unit_id INTEGER NOT NULL,
+    old_snapshot_id INTEGER,
+    new_snapshot_id INTEGER,
+    change_kind TEXT NOT NULL,
+    added_lines INTEGER NOT NULL,
+    removed_lines INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(diff_run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(hunk_id) REFERENCES diff_hunks(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id),
+    FOREIGN KEY(old_snapshot_id) REFERENCES code_unit_snapshots(id),
+    FOREIGN KEY(new_snapshot_id) REFERENCES code_unit_snapshots(id)
+);
+
+CREATE TABLE IF NOT EXISTS blame_commits (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    author_name TEXT,
+    author_email TEXT,
+    author_time INTEGER,
+    summary TEXT,
+    is_boundary INTEGER NOT NULL,
+    UNIQUE(run_id, hash),
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS blame_lines (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    file_id INTEGER NOT NULL,
+    line INTEGER NOT NULL,
+    blame_commit_id INTEGER NOT NULL,
+    orig_path TEXT,
+    orig_line INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(blame_commit_id) REFERENCES blame_commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS owners (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    source_path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    section TEXT,
+    is_optional INTEGER NOT NULL,
+    pattern TEXT NOT NULL,
+    owners TEXT NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS file_owners (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    file_id INTEGER NOT NULL,
+    owner TEXT NOT NULL,
+    owner_rule_id INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(owner_rule_id) REFERENCES owners(id)
+);
+
+CREATE VIEW IF NOT EXISTS current_file_owners AS
+SELECT file_id, owner, owner_rule_id
+FROM file_owners
+WHERE run_id = (SELECT MAX(run_id) FROM file_owners);
+
+CREATE TABLE IF NOT EXISTS test_runs (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    root_path TEXT NOT NULL,
+    packages TEXT NOT NULL,
+    tags TEXT,
+    timeout TEXT,
+    exit_code INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    tests INTEGER NOT NULL,
+    passed INTEGER NOT NULL,
+    failed INTEGER NOT NULL,
+    skipped INTEGER NOT NULL,
+    elapsed REAL NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS test_results (
+    id INTEGER PRIMARY KEY,
+    test_run_id INTEGER NOT NULL,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    package TEXT NOT NULL,
+    test TEXT,
+    subtest TEXT,
+    status TEXT NOT NULL,
+    elapsed REAL NOT NULL,
+    output TEXT,
+    FOREIGN KEY(test_run_id) REFERENCES test_runs(id),
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS code_unit_coverage (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    code_unit_snapshot_id INTEGER NOT NULL,
+    code_unit_id INTEGER NOT NULL,
+    file_id INTEGER NOT NULL,
+    covered_statements INTEGER NOT NULL,
+    total_statements INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(code_unit_snapshot_id) REFERENCES code_unit_snapshots(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
+CREATE TABLE IF NOT EXISTS bench_results (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    package TEXT,
+    name TEXT NOT NULL,
+    procs INTEGER NOT NULL,
+    sample INTEGER NOT NULL,
+    iterations INTEGER NOT NULL,
+    metric TEXT NOT NULL,
+    value REAL NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS diagnostics (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    code_unit_id INTEGER,
+    analyzer TEXT NOT NULL,
+    category TEXT,
+    message TEXT NOT NULL,
+    url TEXT,
+    start_line INTEGER NOT NULL,
+    start_col INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    end_col INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id)
+);
+
+CREATE TABLE IF NOT EXISTS diagnostic_fixes (
+    id INTEGER PRIMARY KEY,
+    diagnostic_id INTEGER NOT NULL,
+    fix_index INTEGER NOT NULL,
+    message TEXT,
+    file_id INTEGER NOT NULL,
+    start_line INTEGER NOT NULL,
+    start_col INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    end_col INTEGER NOT NULL,
+    new_text TEXT NOT NULL,
+    FOREIGN KEY(diagnostic_id) REFERENCES diagnostics(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
+CREATE TABLE IF NOT EXISTS load_errors (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    package TEXT,
+    path TEXT,
+    line INTEGER,
+    col INTEGER,
+    kind TEXT NOT NULL,
+    message TEXT NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id)
+);
+
+CREATE TABLE IF NOT EXISTS go_modules (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    module_path TEXT NOT NULL,
+    dir TEXT,
+    go_version TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS go_packages (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    module_id INTEGER,
+    pkg_path TEXT NOT NULL,
+    name TEXT,
+    dir TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(module_id) REFERENCES go_modules(id)
+);
+
+CREATE TABLE IF NOT EXISTS go_package_files (
+    id INTEGER PRIMARY KEY,
+    package_id INTEGER NOT NULL,
+    file_id INTEGER NOT NULL,
+    FOREIGN KEY(package_id) REFERENCES go_packages(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
+CREATE TABLE IF NOT EXISTS run_build_configs (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    config TEXT NOT NULL,
+    UNIQUE(run_id, config),
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS symbol_occurrence_configs (
+    id INTEGER PRIMARY KEY,
+    occurrence_id INTEGER NOT NULL,
+    config TEXT NOT NULL,
+    FOREIGN KEY(occurrence_id) REFERENCES symbol_occurrences(id)
+);
+
+CREATE TABLE IF NOT EXISTS code_unit_snapshot_configs (
+    id INTEGER PRIMARY KEY,
+    snapshot_id INTEGER NOT NULL,
+    config TEXT NOT NULL,
+    FOREIGN KEY(snapshot_id) REFERENCES code_unit_snapshots(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_files (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    module_path TEXT,
+    go_version TEXT,
+    toolchain TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_requires (
+    id INTEGER PRIMARY KEY,
+    mod_file_id INTEGER NOT NULL,
+    module_path TEXT NOT NULL,
+    version TEXT NOT NULL,
+    is_indirect INTEGER NOT NULL DEFAULT 0,
+    semver_major INTEGER,
+    semver_minor INTEGER,
+    semver_patch INTEGER,
+    semver_prerelease TEXT,
+    FOREIGN KEY(mod_file_id) REFERENCES mod_files(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_replaces (
+    id INTEGER PRIMARY KEY,
+    mod_file_id INTEGER NOT NULL,
+    old_path TEXT NOT NULL,
+    old_version TEXT,
+    new_path TEXT NOT NULL,
+    new_version TEXT,
+    FOREIGN KEY(mod_file_id) REFERENCES mod_files(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_excludes (
+    id INTEGER PRIMARY KEY,
+    mod_file_id INTEGER NOT NULL,
+    module_path TEXT NOT NULL,
+    version TEXT NOT NULL,
+    FOREIGN KEY(mod_file_id) REFERENCES mod_files(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_retracts (
+    id INTEGER PRIMARY KEY,
+    mod_file_id INTEGER NOT NULL,
+    low TEXT NOT NULL,
+    high TEXT NOT NULL,
+    rationale TEXT,
+    FOREIGN KEY(mod_file_id) REFERENCES mod_files(id)
+);
+
+CREATE TABLE IF NOT EXISTS mod_build_list (
+    id INTEGER PRIMARY KEY,
+    mod_file_id INTEGER NOT NULL,
+    module_path TEXT NOT NULL,
+    version TEXT,
+    replace_path TEXT,
+    replace_version TEXT,
+    is_main INTEGER NOT NULL DEFAULT 0,
+    is_indirect INTEGER NOT NULL DEFAULT 0,
+    go_version TEXT,
+    FOREIGN KEY(mod_file_id) REFERENCES mod_files(id)
+);
+
+CREATE TABLE IF NOT EXISTS external_uses (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    code_unit_id INTEGER,
+    pkg_path TEXT NOT NULL,
+    module_path TEXT,
+    name TEXT NOT NULL,
+    recv TEXT,
+    kind TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    col INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id)
+);
+
+CREATE TABLE IF NOT EXISTS api_changes (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    module_path TEXT NOT NULL,
+    old_version TEXT,
+    new_version TEXT,
+    pkg_path TEXT NOT NULL,
+    name TEXT NOT NULL,
+    recv TEXT,
+    kind TEXT NOT NULL,
+    change TEXT NOT NULL,
+    old_signature TEXT,
+    new_signature TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS call_sites (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    code_unit_id INTEGER,
+    callee TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    col INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    end_col INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id)
+);
+
+CREATE TABLE IF NOT EXISTS call_site_args (
+    id INTEGER PRIMARY KEY,
+    call_site_id INTEGER NOT NULL,
+    arg_index INTEGER NOT NULL,
+    source_text TEXT NOT NULL,
+    type TEXT,
+    const_value TEXT,
+    FOREIGN KEY(call_site_id) REFERENCES call_sites(id)
+);
+
+CREATE TABLE IF NOT EXISTS error_sites (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    code_unit_id INTEGER,
+    kind TEXT NOT NULL,
+    callee TEXT,
+    name TEXT,
+    message TEXT,
+    wraps INTEGER NOT NULL DEFAULT 0,
+    line INTEGER NOT NULL,
+    col INTEGER NOT NULL,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id),
+    FOREIGN KEY(code_unit_id) REFERENCES code_units(id)
+);
+
+CREATE TABLE IF NOT EXISTS directives (
+    id INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    commit_id INTEGER,
+    file_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    args TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    col INTEGER NOT NULL,
+    decl TEXT,
+    decl_kind TEXT,
+    problem TEXT,
+    FOREIGN KEY(run_id) REFERENCES meta_runs(id),
+    FOREIGN KEY(commit_id) REFERENCES commits(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
+CREATE TABLE IF NOT EXISTS directive_embeds (
+    id INTEGER PRIMARY KEY,
+    directive_id INTEGER NOT NULL,
+    pattern TEXT NOT NULL,
+    file_id INTEGER,
+    FOREIGN KEY(directive_id) REFERENCES directives(id),
+    FOREIGN KEY(file_id) REFERENCES files(id)
+);
+
 CREATE INDEX IF NOT EXISTS idx_diff_files_run_id ON diff_files(run_id);
 CREATE INDEX IF NOT EXISTS idx_diff_hunks_diff_file_id ON diff_hunks(diff_file_id);
 CREATE INDEX IF NOT EXISTS idx_diff_lines_hunk_id ON diff_lines(hunk_id);
+CREATE INDEX IF NOT EXISTS idx_diff_line_pairs_run_id ON diff_line_pairs(run_id);
+CREATE INDEX IF NOT EXISTS idx_diff_word_edits_pair_id ON diff_word_edits(pair_id);
+CREATE INDEX IF NOT EXISTS idx_diff_word_edits_texts ON diff_word_edits(old_text, new_text);
+CREATE INDEX IF NOT EXISTS idx_diff_moved_blocks_run_id ON diff_moved_blocks(run_id);
+CREATE INDEX IF NOT EXISTS idx_blame_lines_file_line ON blame_lines(run_id, file_id, line);
+CREATE INDEX IF NOT EXISTS idx_owners_run_id ON owners(run_id);
+CREATE INDEX IF NOT EXISTS idx_file_owners_run_file ON file_owners(run_id, file_id);
+CREATE INDEX IF NOT EXISTS idx_file_owners_owner ON file_owners(owner);
+CREATE INDEX IF NOT EXISTS idx_test_runs_commit_id ON test_runs(commit_id);
+CREATE INDEX IF NOT EXISTS idx_test_results_test_run_id ON test_results(test_run_id);
+CREATE INDEX IF NOT EXISTS idx_test_results_test ON test_results(package, test);
+CREATE INDEX IF NOT EXISTS idx_code_unit_coverage_run_id ON code_unit_coverage(run_id);
+CREATE INDEX IF NOT EXISTS idx_code_unit_coverage_code_unit_id ON code_unit_coverage(code_unit_id);
+CREATE INDEX IF NOT EXISTS idx_bench_results_commit_id ON bench_results(commit_id);
+CREATE INDEX IF NOT EXISTS idx_bench_results_name ON bench_results(package, name, metric);
+CREATE INDEX IF NOT EXISTS idx_diagnostics_run_id ON diagnostics(run_id);
+CREATE INDEX IF NOT EXISTS idx_diagnostics_commit_id ON diagnostics(commit_id);
+CREATE INDEX IF NOT EXISTS idx_diagnostic_fixes_diagnostic_id ON diagnostic_fixes(diagnostic_id);
+CREATE INDEX IF NOT EXISTS idx_load_errors_run_id ON load_errors(run_id);
+CREATE INDEX IF NOT EXISTS idx_go_modules_run_id ON go_modules(run_id);
+CREATE INDEX IF NOT EXISTS idx_go_packages_run_id ON go_packages(run_id);
+CREATE INDEX IF NOT EXISTS idx_go_package_files_package_id ON go_package_files(package_id);
+CREATE INDEX IF NOT EXISTS idx_go_package_files_file_id ON go_package_files(file_id);
+CREATE INDEX IF NOT EXISTS idx_symbol_occurrence_configs_occurrence_id ON symbol_occurrence_configs(occurrence_id);
+CREATE INDEX IF NOT EXISTS idx_code_unit_snapshot_configs_snapshot_id ON code_unit_snapshot_configs(snapshot_id);
+CREATE INDEX IF NOT EXISTS idx_mod_files_run_id ON mod_files(run_id);
+CREATE INDEX IF NOT EXISTS idx_mod_files_commit_id ON mod_files(commit_id);
+CREATE INDEX IF NOT EXISTS idx_mod_requires_mod_file_id ON mod_requires(mod_file_id);
+CREATE INDEX IF NOT EXISTS idx_mod_build_list_mod_file_id ON mod_build_list(mod_file_id);
+CREATE INDEX IF NOT EXISTS idx_external_uses_run_id ON external_uses(run_id);
+CREATE INDEX IF NOT EXISTS idx_external_uses_pkg_path ON external_uses(pkg_path);
+CREATE INDEX IF NOT EXISTS idx_api_changes_run_id ON api_changes(run_id);
+CREATE INDEX IF NOT EXISTS idx_call_sites_run_id ON call_sites(run_id);
+CREATE INDEX IF NOT EXISTS idx_call_sites_callee ON call_sites(callee);
+CREATE INDEX IF NOT EXISTS idx_call_site_args_call_site_id ON call_site_args(call_site_id);
+CREATE INDEX IF NOT EXISTS idx_error_sites_run_id ON error_sites(run_id);
+CREATE INDEX IF NOT EXISTS idx_error_sites_message ON error_sites(message);
+CREATE INDEX IF NOT EXISTS idx_directives_run_id ON directives(run_id);
+CREATE INDEX IF NOT EXISTS idx_directives_name ON directives(name);
+CREATE INDEX IF NOT EXISTS idx_directive_embeds_directive_id ON directive_embeds(directive_id);
 CREATE INDEX IF NOT EXISTS idx_symbol_defs_hash ON symbol_defs(symbol_hash);
 CREATE INDEX IF NOT EXISTS idx_symbol_occurrences_run_id ON symbol_occurrences(run_id);
 CREATE INDEX IF NOT EXISTS idx_symbol_occurrences_symbol_id ON symbol_occurrences(symbol_def_id);
@@ -218,6 +795,12 @@ CREATE INDEX IF NOT EXISTS idx_code_unit_snapshots_run_id ON code_unit_snapshots
 CREATE INDEX IF NOT EXISTS idx_commits_run_id ON commits(run_id);
 CREATE INDEX IF NOT EXISTS idx_commits_hash ON commits(hash);
 CREATE INDEX IF NOT EXISTS idx_commit_files_commit_id ON commit_files(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_conventional_commit_id ON commit_conventional(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_conventional_type ON commit_conventional(type);
+CREATE INDEX IF NOT EXISTS idx_commit_trailers_commit_id ON commit_trailers(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_trailers_key ON commit_trailers(key);
+CREATE INDEX IF NOT EXISTS idx_commit_issue_refs_commit_id ON commit_issue_refs(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_issue_refs_issue ON commit_issue_refs(issue);
 CREATE INDEX IF NOT EXISTS idx_file_blobs_commit_id ON file_blobs(commit_id);
 CREATE INDEX IF NOT EXISTS idx_symbol_refs_run_id ON symbol_refs(run_id);
 CREATE INDEX IF NOT EXISTS idx_symbol_refs_symbol_id ON symbol_refs(symbol_def_id);
@@ -226,4 +809,11 @@ CREATE INDEX IF NOT EXISTS idx_ts_captures_run_id ON ts_captures(run_id);
 CREATE INDEX IF NOT EXISTS idx_ts_captures_commit_id ON ts_captures(commit_id);
 CREATE INDEX IF NOT EXISTS idx_doc_hits_run_id ON doc_hits(run_id);
 CREATE INDEX IF NOT EXISTS idx_doc_hits_term ON doc_hits(term);
+CREATE INDEX IF NOT EXISTS idx_refs_run_id ON refs(run_id);
+CREATE INDEX IF NOT EXISTS idx_refs_commit_id ON refs(commit_id);
+CREATE INDEX IF NOT EXISTS idx_refs_name ON refs(name);
+CREATE INDEX IF NOT EXISTS idx_commit_first_tags_commit_id ON commit_first_tags(commit_id);
+CREATE INDEX IF NOT EXISTS idx_hunk_code_units_run_id ON hunk_code_units(run_id);
+CREATE INDEX IF NOT EXISTS idx_hunk_code_units_diff_run_id ON hunk_code_units(diff_run_id);
+CREATE INDEX IF NOT EXISTS idx_hunk_code_units_code_unit_id ON hunk_code_units(code_unit_id);
 `
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"go/token"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +25,8 @@ type RunConfig struct {
 	RootPath    string
 	SourcesDir  string
 	ArgsJSON    string
+	Mode        string
+	BaseCommit  string
 }
 
 type RawOutput struct {
@@ -59,6 +62,43 @@ type CommitInfo struct {
 	Body          string
 }
 
+type RefInfo struct {
+	Name        string
+	ShortName   string
+	Kind        string
+	ObjectType  string
+	ObjectHash  string
+	TargetHash  string
+	IsAnnotated bool
+	TaggerName  string
+	TaggerEmail string
+	TaggerDate  string
+	RefDate     string
+	// RefTime is RefDate as unix seconds, for ordering across timezones.
+	RefTime int64
+	Message string
+	Semver  *SemverInfo
+}
+
+type HunkCodeUnit struct {
+	HunkID        int64
+	FileID        *int64
+	CodeUnitID    int64
+	OldSnapshotID *int64
+	NewSnapshotID *int64
+	ChangeKind    string
+	AddedLines    int
+	RemovedLines  int
+}
+
+type SemverInfo struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
 func OpenDB(ctx context.Context, path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
@@ -103,6 +143,63 @@ func (s *Store) InitSchema(ctx context.Context) error {
 	if _, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_code_unit_snapshots_commit_id ON code_unit_snapshots(commit_id)"); err != nil {
 		return errors.Wrap(err, "create code_unit_snapshots commit_id index")
 	}
+	if err := ensureColumn(ctx, tx, "refs", "ref_time", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "code_units", "is_exported", "INTEGER"); err != nil {
+		return err
+	}
+	if err := backfillCodeUnitExported(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "meta_runs", "mode", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "meta_runs", "base_commit", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "meta_runs", "status", "TEXT"); err != nil {
+		return err
+	}
+	for _, column := range []struct {
+		name string
+		def  string
+	}{
+		{"is_generated", "INTEGER"},
+		{"is_test", "INTEGER"},
+		{"is_vendored", "INTEGER"},
+		{"language", "TEXT"},
+		{"size", "INTEGER"},
+	} {
+		if err := ensureColumn(ctx, tx, "files", column.name, column.def); err != nil {
+			return err
+		}
+	}
+	for _, table := range []string{"diff_files", "commit_files"} {
+		for _, column := range []struct {
+			name string
+			def  string
+		}{
+			{"insertions", "INTEGER"},
+			{"deletions", "INTEGER"},
+			{"similarity", "INTEGER"},
+			{"is_binary", "INTEGER"},
+			{"change_class", "TEXT"},
+		} {
+			if err := ensureColumn(ctx, tx, table, column.name, column.def); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ensureColumn(ctx, tx, "diff_files", "moved_insertions", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "diff_files", "moved_deletions", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, tx, "diff_lines", "moved_block_id", "INTEGER"); err != nil {
+		return err
+	}
 	if err := ensureFTS(ctx, tx, "doc_hits", "doc_hits_fts", "match_text"); err != nil {
 		return err
 	}
@@ -136,8 +233,8 @@ func (s *Store) CreateRun(ctx context.Context, cfg RunConfig) (int64, error) {
 	startedAt := time.Now().UTC().Format(time.RFC3339Nano)
 	res, err := s.db.ExecContext(
 		ctx,
-		`INSERT INTO meta_runs (started_at, tool_version, git_from, git_to, root_path, args_json, sources_dir)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO meta_runs (started_at, tool_version, git_from, git_to, root_path, args_json, sources_dir, mode, base_commit)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		startedAt,
 		cfg.ToolVersion,
 		cfg.GitFrom,
@@ -145,6 +242,8 @@ func (s *Store) CreateRun(ctx context.Context, cfg RunConfig) (int64, error) {
 		cfg.RootPath,
 		cfg.ArgsJSON,
 		cfg.SourcesDir,
+		nullIfEmpty(cfg.Mode),
+		nullIfEmpty(cfg.BaseCommit),
 	)
 	if err != nil {
 		return 0, errors.Wrap(err, "insert run")
@@ -170,6 +269,16 @@ func (s *Store) FinishRun(ctx context.Context, runID int64) error {
 	return nil
 }
 
+// SetRunStatus records the outcome of a run, e.g. RunStatusPartial when
+// some packages could not be loaded.
+func (s *Store) SetRunStatus(ctx context.Context, runID int64, status string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE meta_runs SET status = ? WHERE id = ?", status, runID)
+	if err != nil {
+		return errors.Wrap(err, "update run status")
+	}
+	return nil
+}
+
 func (s *Store) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -178,13 +287,19 @@ func (s *Store) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return tx, nil
 }
 
+// GetOrCreateFile returns the id of a path's files row. New rows get the
+// classification that follows from the path alone (see ClassifyFile); the
+// content-based columns are filled by classifyNewFiles or ClassifyFiles.
 func (s *Store) GetOrCreateFile(ctx context.Context, tx *sql.Tx, path string) (int64, error) {
 	ext := strings.TrimPrefix(filepath.Ext(path), ".")
 	_, err := tx.ExecContext(
 		ctx,
-		"INSERT OR IGNORE INTO files (path, ext) VALUES (?, ?)",
+		"INSERT OR IGNORE INTO files (path, ext, language, is_test, is_vendored) VALUES (?, ?, ?, ?, ?)",
 		path,
 		ext,
+		nullIfEmpty(fileLanguage(path)),
+		boolToInt(isTestPath(path)),
+		boolToInt(isVendoredPath(path)),
 	)
 	if err != nil {
 		return 0, errors.Wrap(err, "insert file")
@@ -196,15 +311,74 @@ func (s *Store) GetOrCreateFile(ctx context.Context, tx *sql.Tx, path string) (i
 	return id, nil
 }
 
-func (s *Store) InsertDiffFile(ctx context.Context, tx *sql.Tx, runID int64, fileID int64, status string, oldPath string, newPath string) (int64, error) {
+func (s *Store) SetFileBinary(ctx context.Context, tx *sql.Tx, fileID int64, binary bool) error {
+	_, err := tx.ExecContext(ctx, "UPDATE files SET is_binary = ? WHERE id = ?", boolToInt(binary), fileID)
+	if err != nil {
+		return errors.Wrap(err, "update file is_binary")
+	}
+	return nil
+}
+
+// SetFileClass records a file's classification. The content-derived
+// columns are left alone when the file does not exist in the classified
+// tree.
+func (s *Store) SetFileClass(ctx context.Context, tx *sql.Tx, fileID int64, class FileClass) error {
+	query := "UPDATE files SET ext = ?, language = ?, is_test = ?, is_vendored = ?, file_exists = ? WHERE id = ?"
+	args := []interface{}{class.Ext, nullIfEmpty(class.Language), boolToInt(class.Test), boolToInt(class.Vendored), boolToInt(class.Exists), fileID}
+	if class.Exists {
+		query = "UPDATE files SET ext = ?, language = ?, is_test = ?, is_vendored = ?, file_exists = ?, is_binary = ?, is_generated = ?, size = ? WHERE id = ?"
+		args = []interface{}{class.Ext, nullIfEmpty(class.Language), boolToInt(class.Test), boolToInt(class.Vendored), boolToInt(class.Exists), boolToInt(class.Binary), boolToInt(class.Generated), class.Size, fileID}
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "update file class")
+	}
+	return nil
+}
+
+type filePath struct {
+	id   int64
+	path string
+}
+
+func (s *Store) filePaths(ctx context.Context, tx *sql.Tx, unclassified bool) ([]filePath, error) {
+	query := "SELECT id, path FROM files ORDER BY id"
+	if unclassified {
+		query = "SELECT id, path FROM files WHERE is_generated IS NULL ORDER BY id"
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "query files")
+	}
+	defer rows.Close()
+	var files []filePath
+	for rows.Next() {
+		var file filePath
+		if err := rows.Scan(&file.id, &file.path); err != nil {
+			return nil, errors.Wrap(err, "scan file")
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate files")
+	}
+	return files, nil
+}
+
+func (s *Store) InsertDiffFile(ctx context.Context, tx *sql.Tx, runID int64, fileID int64, status string, oldPath string, newPath string, stats FileChangeStats) (int64, error) {
 	res, err := tx.ExecContext(
 		ctx,
-		"INSERT INTO diff_files (run_id, file_id, status, old_path, new_path) VALUES (?, ?, ?, ?, ?)",
+		`INSERT INTO diff_files (run_id, file_id, status, old_path, new_path, insertions, deletions, similarity, is_binary, change_class)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		runID,
 		fileID,
 		status,
 		nullIfEmpty(oldPath),
 		nullIfEmpty(newPath),
+		nullableInt(stats.Insertions),
+		nullableInt(stats.Deletions),
+		nullableInt(stats.Similarity),
+		boolToInt(stats.IsBinary),
+		nullIfEmpty(stats.ChangeClass),
 	)
 	if err != nil {
 		return 0, errors.Wrap(err, "insert diff file")
@@ -252,6 +426,775 @@ func (s *Store) InsertDiffLine(ctx context.Context, tx *sql.Tx, hunkID int64, ki
 	return nil
 }
 
+// DiffLineRow is one diff_lines row for InsertDiffLines. A zero ID lets
+// SQLite assign one.
+type DiffLineRow struct {
+	ID     int64
+	HunkID int64
+	Line   DiffLine
+}
+
+// InsertDiffLines inserts diff lines with a single multi-row statement.
+func (s *Store) InsertDiffLines(ctx context.Context, tx *sql.Tx, rows []DiffLineRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	query := "INSERT INTO diff_lines (id, hunk_id, kind, line_no_old, line_no_new, text) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", len(rows)), ", ")
+	args := make([]interface{}, 0, len(rows)*6)
+	for _, row := range rows {
+		var id interface{}
+		if row.ID != 0 {
+			id = row.ID
+		}
+		args = append(args, id, row.HunkID, row.Line.Kind, nullableInt(row.Line.OldLine), nullableInt(row.Line.NewLine), row.Line.Text)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "insert diff lines")
+	}
+	return nil
+}
+
+// MaxDiffLineID returns the largest diff_lines id, so a writer holding the
+// transaction can number the lines it streams.
+func (s *Store) MaxDiffLineID(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var id int64
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM diff_lines").Scan(&id); err != nil {
+		return 0, errors.Wrap(err, "fetch max diff line id")
+	}
+	return id, nil
+}
+
+// DeleteDiffLinePairs removes the pairs of a run that use any of the given
+// lines, with their word edits, and reports how many of each were removed.
+func (s *Store) DeleteDiffLinePairs(ctx context.Context, tx *sql.Tx, runID int64, lineIDs []int64) (int, int, error) {
+	pairs, edits := 0, 0
+	for start := 0; start < len(lineIDs); start += diffLineBatchSize {
+		chunk := lineIDs[start:min(start+diffLineBatchSize, len(lineIDs))]
+		in := placeholders(len(chunk))
+		where := "run_id = ? AND (old_line_id IN (" + in + ") OR new_line_id IN (" + in + "))"
+		args := []interface{}{runID}
+		for range 2 {
+			for _, id := range chunk {
+				args = append(args, id)
+			}
+		}
+
+		var pairCount, editCount int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(edit_count), 0) FROM diff_line_pairs WHERE "+where, args...).Scan(&pairCount, &editCount); err != nil {
+			return 0, 0, errors.Wrap(err, "count diff line pairs")
+		}
+		if pairCount == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM diff_word_edits WHERE pair_id IN (SELECT id FROM diff_line_pairs WHERE "+where+")", args...); err != nil {
+			return 0, 0, errors.Wrap(err, "delete diff word edits")
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM diff_line_pairs WHERE "+where, args...); err != nil {
+			return 0, 0, errors.Wrap(err, "delete diff line pairs")
+		}
+		pairs += pairCount
+		edits += editCount
+	}
+	return pairs, edits, nil
+}
+
+// UpdateDiffFileStats stores numstat and classification data computed after
+// a diff file's lines were streamed.
+func (s *Store) UpdateDiffFileStats(ctx context.Context, tx *sql.Tx, diffFileID int64, stats FileChangeStats) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE diff_files SET insertions = ?, deletions = ?, is_binary = ?, change_class = ? WHERE id = ?",
+		nullableInt(stats.Insertions),
+		nullableInt(stats.Deletions),
+		boolToInt(stats.IsBinary),
+		nullIfEmpty(stats.ChangeClass),
+		diffFileID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "update diff file stats")
+	}
+	return nil
+}
+
+// DiffWordEdit is one changed token span between a removed line (old side)
+// and the added line it was paired with (new side). Offsets are in bytes.
+type DiffWordEdit struct {
+	Kind     string
+	OldStart int
+	OldEnd   int
+	NewStart int
+	NewEnd   int
+	OldText  string
+	NewText  string
+}
+
+// DiffMovedBlock is a run of removed lines that reappears verbatim as added
+// lines in another hunk.
+type DiffMovedBlock struct {
+	FromDiffFileID int64
+	ToDiffFileID   int64
+	OldStartLine   int
+	NewStartLine   int
+	LineCount      int
+	OldLineIDs     []int64
+	NewLineIDs     []int64
+}
+
+func (s *Store) InsertDiffLinePair(ctx context.Context, tx *sql.Tx, runID int64, hunkID int64, oldLineID int64, newLineID int64, similarity float64, edits []DiffWordEdit) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO diff_line_pairs (run_id, hunk_id, old_line_id, new_line_id, similarity, edit_count) VALUES (?, ?, ?, ?, ?, ?)",
+		runID,
+		hunkID,
+		oldLineID,
+		newLineID,
+		similarity,
+		len(edits),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert diff line pair")
+	}
+	pairID, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "diff line pair id")
+	}
+	for _, edit := range edits {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO diff_word_edits (pair_id, kind, old_start, old_end, new_start, new_end, old_text, new_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			pairID,
+			edit.Kind,
+			edit.OldStart,
+			edit.OldEnd,
+			edit.NewStart,
+			edit.NewEnd,
+			edit.OldText,
+			edit.NewText,
+		)
+		if err != nil {
+			return 0, errors.Wrap(err, "insert diff word edit")
+		}
+	}
+	return pairID, nil
+}
+
+// InsertDiffMovedBlock stores a moved block and marks its lines on both sides.
+func (s *Store) InsertDiffMovedBlock(ctx context.Context, tx *sql.Tx, runID int64, block DiffMovedBlock) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO diff_moved_blocks (run_id, from_diff_file_id, to_diff_file_id, old_start_line, new_start_line, line_count) VALUES (?, ?, ?, ?, ?, ?)",
+		runID,
+		block.FromDiffFileID,
+		block.ToDiffFileID,
+		block.OldStartLine,
+		block.NewStartLine,
+		block.LineCount,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert diff moved block")
+	}
+	blockID, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "diff moved block id")
+	}
+	lineIDs := append(append([]int64{}, block.OldLineIDs...), block.NewLineIDs...)
+	if len(lineIDs) > 0 {
+		args := make([]interface{}, 0, len(lineIDs)+1)
+		args = append(args, blockID)
+		for _, id := range lineIDs {
+			args = append(args, id)
+		}
+		query := "UPDATE diff_lines SET moved_block_id = ? WHERE id IN (" + placeholders(len(lineIDs)) + ")"
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return 0, errors.Wrap(err, "mark moved diff lines")
+		}
+	}
+	return blockID, nil
+}
+
+// UpdateDiffFileMoved stores how many of a file's insertions and deletions
+// belong to moved blocks.
+func (s *Store) UpdateDiffFileMoved(ctx context.Context, tx *sql.Tx, diffFileID int64, movedInsertions int, movedDeletions int) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE diff_files SET moved_insertions = ?, moved_deletions = ? WHERE id = ?",
+		movedInsertions,
+		movedDeletions,
+		diffFileID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "update diff file moved lines")
+	}
+	return nil
+}
+
+func (s *Store) InsertBlameCommit(ctx context.Context, tx *sql.Tx, runID int64, commit BlameCommit) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO blame_commits (run_id, hash, author_name, author_email, author_time, summary, is_boundary) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		commit.Hash,
+		nullIfEmpty(commit.AuthorName),
+		nullIfEmpty(commit.AuthorEmail),
+		commit.AuthorTime,
+		nullIfEmpty(commit.Summary),
+		boolToInt(commit.Boundary),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert blame commit")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "blame commit id")
+	}
+	return id, nil
+}
+
+// InsertBlameLines stores the blame of one file, batching rows per insert.
+func (s *Store) InsertBlameLines(ctx context.Context, tx *sql.Tx, runID int64, fileID int64, lines []BlameLine, commitIDs map[string]int64) error {
+	for start := 0; start < len(lines); start += diffLineBatchSize {
+		end := start + diffLineBatchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		batch := lines[start:end]
+		query := "INSERT INTO blame_lines (run_id, file_id, line, blame_commit_id, orig_path, orig_line) VALUES " +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", len(batch)), ", ")
+		args := make([]interface{}, 0, len(batch)*6)
+		for _, line := range batch {
+			commitID, ok := commitIDs[line.Commit]
+			if !ok {
+				return errors.Errorf("unknown blame commit %s", line.Commit)
+			}
+			args = append(args, runID, fileID, line.Line, commitID, nullIfEmpty(line.OrigPath), line.OrigLine)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return errors.Wrap(err, "insert blame lines")
+		}
+	}
+	return nil
+}
+
+func (s *Store) InsertOwnerRule(ctx context.Context, tx *sql.Tx, runID int64, sourcePath string, rule OwnerRule) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO owners (run_id, source_path, line, section, is_optional, pattern, owners) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		sourcePath,
+		rule.Line,
+		nullIfEmpty(rule.Section),
+		boolToInt(rule.Optional),
+		rule.Pattern,
+		strings.Join(rule.Owners, " "),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert owner rule")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "owner rule id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertFileOwner(ctx context.Context, tx *sql.Tx, runID int64, fileID int64, owner string, ruleID int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO file_owners (run_id, file_id, owner, owner_rule_id) VALUES (?, ?, ?, ?)",
+		runID,
+		fileID,
+		owner,
+		ruleID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert file owner")
+	}
+	return nil
+}
+
+// TestRun summarizes one go test invocation.
+type TestRun struct {
+	RootPath string
+	Packages string
+	Tags     string
+	Timeout  string
+	ExitCode int
+	Status   string
+	Tests    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Elapsed  float64
+}
+
+func (s *Store) InsertTestRun(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, run TestRun) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO test_runs (run_id, commit_id, root_path, packages, tags, timeout, exit_code, status, tests, passed, failed, skipped, elapsed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		nullableInt64(commitID),
+		run.RootPath,
+		run.Packages,
+		nullIfEmpty(run.Tags),
+		nullIfEmpty(run.Timeout),
+		run.ExitCode,
+		run.Status,
+		run.Tests,
+		run.Passed,
+		run.Failed,
+		run.Skipped,
+		run.Elapsed,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert test run")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "test run id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertTestResult(ctx context.Context, tx *sql.Tx, testRunID int64, runID int64, commitID *int64, result TestResult) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO test_results (test_run_id, run_id, commit_id, package, test, subtest, status, elapsed, output) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		testRunID,
+		runID,
+		nullableInt64(commitID),
+		result.Package,
+		nullIfEmpty(result.Test),
+		nullIfEmpty(result.Subtest),
+		result.Status,
+		result.Elapsed,
+		nullIfEmpty(result.Output),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert test result")
+	}
+	return nil
+}
+
+func (s *Store) InsertCodeUnitCoverage(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, coverage CodeUnitCoverage) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO code_unit_coverage (run_id, commit_id, code_unit_snapshot_id, code_unit_id, file_id, covered_statements, total_statements) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		nullableInt64(commitID),
+		coverage.SnapshotID,
+		coverage.CodeUnitID,
+		coverage.FileID,
+		coverage.CoveredStatements,
+		coverage.TotalStatements,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert code unit coverage")
+	}
+	return nil
+}
+
+// InsertBenchResult stores one row per metric of a benchmark sample.
+func (s *Store) InsertBenchResult(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, bench BenchResult) error {
+	for _, metric := range bench.Metrics {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO bench_results (run_id, commit_id, package, name, procs, sample, iterations, metric, value) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			runID,
+			nullableInt64(commitID),
+			nullIfEmpty(bench.Package),
+			bench.Name,
+			bench.Procs,
+			bench.Sample,
+			bench.Iterations,
+			metric.Unit,
+			metric.Value,
+		)
+		if err != nil {
+			return errors.Wrap(err, "insert bench result")
+		}
+	}
+	return nil
+}
+
+func (s *Store) InsertDiagnostic(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID *int64, diagnostic Diagnostic) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO diagnostics (run_id, commit_id, file_id, code_unit_id, analyzer, category, message, url, start_line, start_col, end_line, end_col) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		nullableInt64(codeUnitID),
+		diagnostic.Analyzer,
+		nullIfEmpty(diagnostic.Category),
+		diagnostic.Message,
+		nullIfEmpty(diagnostic.URL),
+		diagnostic.StartLine,
+		diagnostic.StartCol,
+		diagnostic.EndLine,
+		diagnostic.EndCol,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert diagnostic")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "diagnostic id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertDiagnosticFix(ctx context.Context, tx *sql.Tx, diagnosticID int64, fixIndex int, message string, fileID int64, edit DiagnosticEdit) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO diagnostic_fixes (diagnostic_id, fix_index, message, file_id, start_line, start_col, end_line, end_col, new_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		diagnosticID,
+		fixIndex,
+		nullIfEmpty(message),
+		fileID,
+		edit.StartLine,
+		edit.StartCol,
+		edit.EndLine,
+		edit.EndCol,
+		edit.NewText,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert diagnostic fix")
+	}
+	return nil
+}
+
+func (s *Store) InsertLoadError(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, loadErr LoadError) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO load_errors (run_id, commit_id, package, path, line, col, kind, message)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		nullIfEmpty(loadErr.Package),
+		nullIfEmpty(loadErr.Path),
+		loadErr.Line,
+		loadErr.Col,
+		loadErr.Kind,
+		loadErr.Message,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert load error")
+	}
+	return nil
+}
+
+func (s *Store) InsertGoModule(ctx context.Context, tx *sql.Tx, runID int64, modulePath string, dir string, goVersion string) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO go_modules (run_id, module_path, dir, go_version) VALUES (?, ?, ?, ?)",
+		runID,
+		modulePath,
+		nullIfEmpty(dir),
+		nullIfEmpty(goVersion),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert go module")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read go module id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertGoPackage(ctx context.Context, tx *sql.Tx, runID int64, moduleID *int64, pkgPath string, name string, dir string) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO go_packages (run_id, module_id, pkg_path, name, dir) VALUES (?, ?, ?, ?, ?)",
+		runID,
+		nullableInt64(moduleID),
+		pkgPath,
+		nullIfEmpty(name),
+		nullIfEmpty(dir),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert go package")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read go package id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertGoPackageFile(ctx context.Context, tx *sql.Tx, packageID int64, fileID int64) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO go_package_files (package_id, file_id) VALUES (?, ?)", packageID, fileID)
+	if err != nil {
+		return errors.Wrap(err, "insert go package file")
+	}
+	return nil
+}
+
+func (s *Store) InsertModFile(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, modFile ModFile) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO mod_files (run_id, commit_id, file_id, module_path, go_version, toolchain) VALUES (?, ?, ?, ?, ?, ?)",
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		nullIfEmpty(modFile.ModulePath),
+		nullIfEmpty(modFile.GoVersion),
+		nullIfEmpty(modFile.Toolchain),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert mod file")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read mod file id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertModRequire(ctx context.Context, tx *sql.Tx, modFileID int64, require ModRequire) error {
+	var major, minor, patch interface{}
+	var prerelease interface{}
+	if info := ParseSemver(require.Version); info != nil {
+		major, minor, patch = info.Major, info.Minor, info.Patch
+		prerelease = info.Prerelease
+	}
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO mod_requires (mod_file_id, module_path, version, is_indirect, semver_major, semver_minor, semver_patch, semver_prerelease)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		modFileID,
+		require.Path,
+		require.Version,
+		boolToInt(require.Indirect),
+		major,
+		minor,
+		patch,
+		prerelease,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert mod require")
+	}
+	return nil
+}
+
+func (s *Store) InsertModReplace(ctx context.Context, tx *sql.Tx, modFileID int64, replace ModReplace) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO mod_replaces (mod_file_id, old_path, old_version, new_path, new_version) VALUES (?, ?, ?, ?, ?)",
+		modFileID,
+		replace.OldPath,
+		nullIfEmpty(replace.OldVersion),
+		replace.NewPath,
+		nullIfEmpty(replace.NewVersion),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert mod replace")
+	}
+	return nil
+}
+
+func (s *Store) InsertModExclude(ctx context.Context, tx *sql.Tx, modFileID int64, path string, version string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO mod_excludes (mod_file_id, module_path, version) VALUES (?, ?, ?)", modFileID, path, version)
+	if err != nil {
+		return errors.Wrap(err, "insert mod exclude")
+	}
+	return nil
+}
+
+func (s *Store) InsertModRetract(ctx context.Context, tx *sql.Tx, modFileID int64, retract ModRetract) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO mod_retracts (mod_file_id, low, high, rationale) VALUES (?, ?, ?, ?)",
+		modFileID,
+		retract.Low,
+		retract.High,
+		nullIfEmpty(retract.Rationale),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert mod retract")
+	}
+	return nil
+}
+
+func (s *Store) InsertModBuildListEntry(ctx context.Context, tx *sql.Tx, modFileID int64, entry ModBuildListEntry) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO mod_build_list (mod_file_id, module_path, version, replace_path, replace_version, is_main, is_indirect, go_version)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		modFileID,
+		entry.Path,
+		nullIfEmpty(entry.Version),
+		nullIfEmpty(entry.ReplacePath),
+		nullIfEmpty(entry.ReplaceVersion),
+		boolToInt(entry.Main),
+		boolToInt(entry.Indirect),
+		nullIfEmpty(entry.GoVersion),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert mod build list entry")
+	}
+	return nil
+}
+
+func (s *Store) InsertExternalUse(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID *int64, use ExternalUse) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO external_uses (run_id, commit_id, file_id, code_unit_id, pkg_path, module_path, name, recv, kind, line, col)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		nullableInt64(codeUnitID),
+		use.Pkg,
+		nullIfEmpty(use.Module),
+		use.Name,
+		nullIfEmpty(use.Recv),
+		use.Kind,
+		use.Line,
+		use.Col,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert external use")
+	}
+	return nil
+}
+
+func (s *Store) InsertAPIChange(ctx context.Context, tx *sql.Tx, runID int64, change APIChange) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO api_changes (run_id, module_path, old_version, new_version, pkg_path, name, recv, kind, change, old_signature, new_signature)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		change.Module,
+		nullIfEmpty(change.OldVersion),
+		nullIfEmpty(change.NewVersion),
+		change.Pkg,
+		change.Name,
+		nullIfEmpty(change.Recv),
+		change.Kind,
+		change.Change,
+		nullIfEmpty(change.OldSignature),
+		nullIfEmpty(change.NewSignature),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert api change")
+	}
+	return nil
+}
+
+func (s *Store) InsertCallSite(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID *int64, call CallSite) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO call_sites (run_id, commit_id, file_id, code_unit_id, callee, line, col, end_line, end_col)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		nullableInt64(codeUnitID),
+		call.Callee,
+		call.Line,
+		call.Col,
+		call.EndLine,
+		call.EndCol,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert call site")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read call site id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertCallSiteArg(ctx context.Context, tx *sql.Tx, callSiteID int64, index int, arg CallArg) error {
+	var value interface{}
+	if arg.Const {
+		value = arg.Value
+	}
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO call_site_args (call_site_id, arg_index, source_text, type, const_value) VALUES (?, ?, ?, ?, ?)",
+		callSiteID,
+		index,
+		arg.Source,
+		nullIfEmpty(arg.Type),
+		value,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert call site arg")
+	}
+	return nil
+}
+
+func (s *Store) InsertErrorSite(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID *int64, site ErrorSite) error {
+	var message interface{}
+	if site.HasMessage {
+		message = site.Message
+	}
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO error_sites (run_id, commit_id, file_id, code_unit_id, kind, callee, name, message, wraps, line, col)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		nullableInt64(codeUnitID),
+		site.Kind,
+		nullIfEmpty(site.Callee),
+		nullIfEmpty(site.Name),
+		message,
+		boolToInt(site.Wraps),
+		site.Line,
+		site.Col,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert error site")
+	}
+	return nil
+}
+
+func (s *Store) InsertDirective(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, directive Directive) (int64, error) {
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO directives (run_id, commit_id, file_id, name, args, line, col, decl, decl_kind, problem)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		fileID,
+		directive.Name,
+		directive.Args,
+		directive.Line,
+		directive.Col,
+		nullIfEmpty(directive.Decl),
+		nullIfEmpty(directive.DeclKind),
+		nullIfEmpty(directive.Problem),
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert directive")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read directive id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertDirectiveEmbed(ctx context.Context, tx *sql.Tx, directiveID int64, pattern string, fileID *int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO directive_embeds (directive_id, pattern, file_id) VALUES (?, ?, ?)",
+		directiveID,
+		pattern,
+		nullableInt64(fileID),
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert directive embed")
+	}
+	return nil
+}
+
 func (s *Store) InsertRawOutput(ctx context.Context, tx *sql.Tx, runID int64, source string, path string) error {
 	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
 	_, err := tx.ExecContext(
@@ -293,8 +1236,8 @@ func (s *Store) GetOrCreateSymbolDef(ctx context.Context, tx *sql.Tx, def Symbol
 	return id, nil
 }
 
-func (s *Store) InsertSymbolOccurrence(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, symbolDefID int64, line int, col int, exported bool) error {
-	_, err := tx.ExecContext(
+func (s *Store) InsertSymbolOccurrence(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, symbolDefID int64, line int, col int, exported bool) (int64, error) {
+	res, err := tx.ExecContext(
 		ctx,
 		`INSERT INTO symbol_occurrences (run_id, commit_id, file_id, symbol_def_id, line, col, is_exported)
 		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
@@ -307,7 +1250,19 @@ func (s *Store) InsertSymbolOccurrence(ctx context.Context, tx *sql.Tx, runID in
 		boolToInt(exported),
 	)
 	if err != nil {
-		return errors.Wrap(err, "insert symbol occurrence")
+		return 0, errors.Wrap(err, "insert symbol occurrence")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read symbol occurrence id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertSymbolOccurrenceConfig(ctx context.Context, tx *sql.Tx, occurrenceID int64, config string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO symbol_occurrence_configs (occurrence_id, config) VALUES (?, ?)", occurrenceID, config)
+	if err != nil {
+		return errors.Wrap(err, "insert symbol occurrence config")
 	}
 	return nil
 }
@@ -318,13 +1273,14 @@ func (s *Store) GetOrCreateCodeUnit(ctx context.Context, tx *sql.Tx, def CodeUni
 	}
 	_, err := tx.ExecContext(
 		ctx,
-		`INSERT OR IGNORE INTO code_units (kind, name, pkg, recv, signature, unit_hash)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
+		`INSERT OR IGNORE INTO code_units (kind, name, pkg, recv, signature, is_exported, unit_hash)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		def.Kind,
 		def.Name,
 		def.Pkg,
 		nullIfEmpty(def.Recv),
 		nullIfEmpty(def.Signature),
+		boolToInt(token.IsExported(def.Name)),
 		def.Hash,
 	)
 	if err != nil {
@@ -337,8 +1293,8 @@ func (s *Store) GetOrCreateCodeUnit(ctx context.Context, tx *sql.Tx, def CodeUni
 	return id, nil
 }
 
-func (s *Store) InsertCodeUnitSnapshot(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID int64, startLine int, startCol int, endLine int, endCol int, bodyHash string, bodyText string, docText string) error {
-	_, err := tx.ExecContext(
+func (s *Store) InsertCodeUnitSnapshot(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, fileID int64, codeUnitID int64, startLine int, startCol int, endLine int, endCol int, bodyHash string, bodyText string, docText string) (int64, error) {
+	res, err := tx.ExecContext(
 		ctx,
 		`INSERT INTO code_unit_snapshots (run_id, commit_id, file_id, code_unit_id, start_line, start_col, end_line, end_col, body_hash, body_text, doc_text)
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
@@ -355,7 +1311,29 @@ func (s *Store) InsertCodeUnitSnapshot(ctx context.Context, tx *sql.Tx, runID in
 		nullIfEmpty(docText),
 	)
 	if err != nil {
-		return errors.Wrap(err, "insert code unit snapshot")
+		return 0, errors.Wrap(err, "insert code unit snapshot")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read code unit snapshot id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertCodeUnitSnapshotConfig(ctx context.Context, tx *sql.Tx, snapshotID int64, config string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO code_unit_snapshot_configs (snapshot_id, config) VALUES (?, ?)", snapshotID, config)
+	if err != nil {
+		return errors.Wrap(err, "insert code unit snapshot config")
+	}
+	return nil
+}
+
+// InsertRunBuildConfigs records the build matrix a run was loaded under.
+func (s *Store) InsertRunBuildConfigs(ctx context.Context, tx *sql.Tx, runID int64, configs []string) error {
+	for _, config := range configs {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO run_build_configs (run_id, config) VALUES (?, ?)", runID, config); err != nil {
+			return errors.Wrap(err, "insert run build config")
+		}
 	}
 	return nil
 }
@@ -387,11 +1365,69 @@ func (s *Store) InsertCommit(ctx context.Context, tx *sql.Tx, runID int64, info
 	return id, nil
 }
 
-func (s *Store) InsertCommitFile(ctx context.Context, tx *sql.Tx, commitID int64, fileID int64, status string, oldPath string, newPath string, blobOld string, blobNew string) error {
+func (s *Store) InsertCommitMessage(ctx context.Context, tx *sql.Tx, commitID int64, msg CommitMessageInfo) error {
+	if msg.Conventional != nil {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO commit_conventional (commit_id, type, scope, is_breaking, description)
+			 VALUES (?, ?, ?, ?, ?)`,
+			commitID,
+			msg.Conventional.Type,
+			nullIfEmpty(msg.Conventional.Scope),
+			boolToInt(msg.Conventional.Breaking),
+			msg.Conventional.Description,
+		)
+		if err != nil {
+			return errors.Wrap(err, "insert commit conventional fields")
+		}
+	}
+	for _, trailer := range msg.Trailers {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO commit_trailers (commit_id, key, value) VALUES (?, ?, ?)",
+			commitID,
+			trailer.Key,
+			trailer.Value,
+		)
+		if err != nil {
+			return errors.Wrap(err, "insert commit trailer")
+		}
+	}
+	for _, issue := range msg.IssueRefs {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO commit_issue_refs (commit_id, issue, source) VALUES (?, ?, ?)",
+			commitID,
+			issue.Issue,
+			issue.Source,
+		)
+		if err != nil {
+			return errors.Wrap(err, "insert commit issue ref")
+		}
+	}
+	return nil
+}
+
+func (s *Store) FindLatestCommitIDByHash(ctx context.Context, tx *sql.Tx, hash string) (*int64, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM commits WHERE hash = ? ORDER BY id DESC LIMIT 1", hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch commit id by hash")
+	}
+	return &id, nil
+}
+
+func (s *Store) InsertCommitFile(ctx context.Context, tx *sql.Tx, commitID int64, fileID int64, status string, oldPath string, newPath string, blobOld string, blobNew string, stats FileChangeStats) error {
 	_, err := tx.ExecContext(
 		ctx,
-		`INSERT INTO commit_files (commit_id, file_id, status, old_path, new_path, blob_old, blob_new)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO commit_files (commit_id, file_id, status, old_path, new_path, blob_old, blob_new, insertions, deletions, similarity, is_binary, change_class)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		commitID,
 		fileID,
 		status,
@@ -399,6 +1435,11 @@ func (s *Store) InsertCommitFile(ctx context.Context, tx *sql.Tx, commitID int64
 		nullIfEmpty(newPath),
 		nullIfEmpty(blobOld),
 		nullIfEmpty(blobNew),
+		nullableInt(stats.Insertions),
+		nullableInt(stats.Deletions),
+		nullableInt(stats.Similarity),
+		boolToInt(stats.IsBinary),
+		nullIfEmpty(stats.ChangeClass),
 	)
 	if err != nil {
 		return errors.Wrap(err, "insert commit file")
@@ -426,6 +1467,95 @@ func (s *Store) InsertFileBlob(ctx context.Context, tx *sql.Tx, commitID int64,
 	return nil
 }
 
+func (s *Store) InsertRef(ctx context.Context, tx *sql.Tx, runID int64, commitID *int64, info RefInfo) (int64, error) {
+	if info.Name == "" || info.ObjectHash == "" {
+		return 0, errors.New("ref name and object hash are required")
+	}
+	var major, minor, patch sql.NullInt64
+	var prerelease, build interface{}
+	if info.Semver != nil {
+		major = sql.NullInt64{Int64: int64(info.Semver.Major), Valid: true}
+		minor = sql.NullInt64{Int64: int64(info.Semver.Minor), Valid: true}
+		patch = sql.NullInt64{Int64: int64(info.Semver.Patch), Valid: true}
+		prerelease = nullIfEmpty(info.Semver.Prerelease)
+		build = nullIfEmpty(info.Semver.Build)
+	}
+	var refTime interface{}
+	if info.RefTime != 0 {
+		refTime = info.RefTime
+	}
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO refs (run_id, commit_id, name, short_name, kind, object_type, object_hash, target_hash, is_annotated,
+		                   tagger_name, tagger_email, tagger_date, ref_date, ref_time, message,
+		                   semver_major, semver_minor, semver_patch, semver_prerelease, semver_build)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID,
+		nullableInt64(commitID),
+		info.Name,
+		info.ShortName,
+		info.Kind,
+		nullIfEmpty(info.ObjectType),
+		info.ObjectHash,
+		nullIfEmpty(info.TargetHash),
+		boolToInt(info.IsAnnotated),
+		nullIfEmpty(info.TaggerName),
+		nullIfEmpty(info.TaggerEmail),
+		nullIfEmpty(info.TaggerDate),
+		nullIfEmpty(info.RefDate),
+		refTime,
+		nullIfEmpty(info.Message),
+		major,
+		minor,
+		patch,
+		prerelease,
+		build,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "insert ref")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "read ref id")
+	}
+	return id, nil
+}
+
+func (s *Store) InsertCommitFirstTag(ctx context.Context, tx *sql.Tx, runID int64, commitID int64, refID int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO commit_first_tags (run_id, commit_id, ref_id) VALUES (?, ?, ?)",
+		runID,
+		commitID,
+		refID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert commit first tag")
+	}
+	return nil
+}
+
+func (s *Store) InsertHunkCodeUnit(ctx context.Context, tx *sql.Tx, runID int64, diffRunID int64, link HunkCodeUnit) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO hunk_code_units (run_id, diff_run_id, hunk_id, file_id, code_unit_id, old_snapshot_id, new_snapshot_id, change_kind, added_lines, removed_lines) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		runID,
+		diffRunID,
+		link.HunkID,
+		nullableInt64(link.FileID),
+		link.CodeUnitID,
+		nullableInt64(link.OldSnapshotID),
+		nullableInt64(link.NewSnapshotID),
+		link.ChangeKind,
+		link.AddedLines,
+		link.RemovedLines,
+	)
+	if err != nil {
+		return errors.Wrap(err, "insert hunk code unit")
+	}
+	return nil
+}
+
 func (s *Store) GetSymbolDefIDByHash(ctx context.Context, tx *sql.Tx, hash string) (int64, error) {
 	if hash == "" {
 		return 0, errors.New("symbol hash is required")
@@ -513,6 +1643,24 @@ func (s *Store) WriteRawOutput(ctx context.Context, tx *sql.Tx, runDir string, r
 	return path, nil
 }
 
+// CreateRawOutput registers a raw output file and returns it open for
+// writing, for outputs that are streamed rather than buffered.
+func (s *Store) CreateRawOutput(ctx context.Context, tx *sql.Tx, runDir string, runID int64, source string, fileName string) (*os.File, error) {
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "create sources dir")
+	}
+	path := filepath.Join(runDir, fileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "create raw output")
+	}
+	if err := s.InsertRawOutput(ctx, tx, runID, source, path); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
 func EncodeArgsJSON(args map[string]string) (string, error) {
 	if len(args) == 0 {
 		return "", nil
@@ -538,6 +1686,36 @@ func nullableInt64(value *int64) sql.NullInt64 {
 	return sql.NullInt64{Int64: *value, Valid: true}
 }
 
+// backfillCodeUnitExported sets is_exported on code units recorded before
+// the column existed.
+func backfillCodeUnitExported(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, name FROM code_units WHERE is_exported IS NULL")
+	if err != nil {
+		return errors.Wrap(err, "query code units without is_exported")
+	}
+	exported := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			_ = rows.Close()
+			return errors.Wrap(err, "scan code unit name")
+		}
+		exported[id] = token.IsExported(name)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return errors.Wrap(err, "iterate code unit names")
+	}
+	_ = rows.Close()
+	for id, isExported := range exported {
+		if _, err := tx.ExecContext(ctx, "UPDATE code_units SET is_exported = ? WHERE id = ?", boolToInt(isExported), id); err != nil {
+			return errors.Wrap(err, "backfill code unit is_exported")
+		}
+	}
+	return nil
+}
+
 func ensureColumn(ctx context.Context, tx *sql.Tx, table string, column string, columnDef string) error {
 	rows, err := tx.QueryContext(ctx, "PRAGMA table_info("+table+")")
 	if err != nil {